@@ -0,0 +1,317 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+/**
+ * 配置加载
+ *
+ * 配置按以下顺序加载，后者覆盖前者：
+ * 1. 默认值（见 Default）；
+ * 2. JSON 配置文件，路径由命令行参数 -config 或环境变量 MINICHAIN_CONFIG 指定，文件中只需要写出要修改的键；
+ *    只支持扩展名为 .json 的文件，YAML、TOML 等其他格式的文件会被拒绝；
+ * 3. 环境变量，每个键的环境变量名在 settings 中固定写出，为 MINICHAIN_ 加上键名的大写下划线形式，
+ *    例如 maxPoolSize 对应 MINICHAIN_MAX_POOL_SIZE，p2pListen 对应 MINICHAIN_P2P_LISTEN；
+ * 4. 命令行参数，名称与键名相同，例如 -maxPoolSize=1024。
+ * 全部来源合并之后再检查配置是否有效，配置文件中无法识别的键同样视为错误，以免拼写错误被悄悄忽略。
+ */
+
+// envPrefix 环境变量名称的前缀。
+const envPrefix = "MINICHAIN_"
+
+// configKey 指定配置文件路径的命令行参数名，对应的环境变量为 MINICHAIN_CONFIG。
+const configKey = "config"
+
+// setting 描述一项可以从外部修改的配置。
+// 字段说明：
+// - name: 配置文件中的键，同时也是命令行参数名。
+// - env: 对应的环境变量名，固定写出而不是由键名推导，避免 p2pListen 这样包含数字的键名被拆分成难以猜到的名称。
+// - usage: 说明。
+// - value: 指向 Config 中对应字段的指针，类型为 *int、*int64、*string 或 *bool。
+type setting struct {
+	name  string
+	env   string
+	usage string
+	value interface{}
+}
+
+// settings 返回全部可以从外部修改的配置项，value 指向 c 的字段。
+func (c *Config) settings() []setting {
+	return []setting{
+		{"difficulty", "MINICHAIN_DIFFICULTY", "initial mining difficulty, as leading zero hex digits of the target", &c.difficulty},
+		{"maxTransactionCount", "MINICHAIN_MAX_TRANSACTION_COUNT", "maximum number of transactions in a block, including the coinbase", &c.maxTransactionCount},
+		{"maxBlockSize", "MINICHAIN_MAX_BLOCK_SIZE", "maximum encoded size of the transactions in a block, in bytes", &c.maxBlockSize},
+		{"nbAccount", "MINICHAIN_NB_ACCOUNT", "number of accounts funded by the genesis block", &c.nbAccount},
+		{"initAmount", "MINICHAIN_INIT_AMOUNT", "initial amount of every account", &c.initAmount},
+		{"spvEnabled", "MINICHAIN_SPV_ENABLED", "attach an SPV peer to every account", &c.spvEnabled},
+		{"dataDir", "MINICHAIN_DATA_DIR", "directory for blocks and keys, empty keeps everything in memory", &c.dataDir},
+		{"blockSubsidy", "MINICHAIN_BLOCK_SUBSIDY", "initial block subsidy", &c.blockSubsidy},
+		{"halvingInterval", "MINICHAIN_HALVING_INTERVAL", "blocks between subsidy halvings, 0 disables halving", &c.halvingInterval},
+		{"targetBlockInterval", "MINICHAIN_TARGET_BLOCK_INTERVAL", "target seconds between blocks", &c.targetBlockInterval},
+		{"retargetInterval", "MINICHAIN_RETARGET_INTERVAL", "blocks between difficulty adjustments, 0 disables retargeting", &c.retargetInterval},
+		{"minerThreads", "MINICHAIN_MINER_THREADS", "mining worker goroutines, 0 uses the number of CPUs", &c.minerThreads},
+		{"maxPoolTransactions", "MINICHAIN_MAX_POOL_TRANSACTIONS", "maximum number of transactions in the pool", &c.maxPoolTransactions},
+		{"maxPoolSize", "MINICHAIN_MAX_POOL_SIZE", "maximum encoded size of the transactions in the pool, in bytes", &c.maxPoolSize},
+		{"poolExpiry", "MINICHAIN_POOL_EXPIRY", "seconds a transaction may stay in the pool, 0 disables expiry", &c.poolExpiry},
+		{"minRelayFeeRate", "MINICHAIN_MIN_RELAY_FEE_RATE", "minimum fee per 1000 bytes accepted by the pool, 0 disables the minimum", &c.minRelayFeeRate},
+		{"workload", "MINICHAIN_WORKLOAD", "workload strategy: uniform, hot, consolidation, fanout, invalid or mixed", &c.workload},
+		{"workloadSeed", "MINICHAIN_WORKLOAD_SEED", "random seed of the network, 0 uses the current time", &c.workloadSeed},
+		{"simulationBlocks", "MINICHAIN_SIMULATION_BLOCKS", "run a deterministic simulation of this many blocks and exit, 0 runs the node", &c.simulationBlocks},
+		{"p2pListen", "MINICHAIN_P2P_LISTEN", "address of the peer-to-peer listener, empty disables it", &c.p2pListen},
+		{"rpcListen", "MINICHAIN_RPC_LISTEN", "address of the JSON-RPC listener, empty disables it", &c.rpcListen},
+		{"rpcToken", "MINICHAIN_RPC_TOKEN", "token required by JSON-RPC requests, required when rpcListen is not a loopback address", &c.rpcToken},
+		{"peers", "MINICHAIN_PEERS", "comma-separated addresses of peers to connect to", &c.peers},
+		{"maxPeers", "MINICHAIN_MAX_PEERS", "maximum number of peer connections", &c.maxPeers},
+		{"genesisSeed", "MINICHAIN_GENESIS_SEED", "derive the accounts and the genesis block from this seed, 0 generates them randomly", &c.genesisSeed},
+		{"mining", "MINICHAIN_MINING", "run the miner", &c.mining},
+	}
+}
+
+// parse 将字符串解析为配置项的类型并写入对应字段。
+func (s setting) parse(text string) error {
+	switch v := s.value.(type) {
+	case *int:
+		n, err := strconv.Atoi(strings.TrimSpace(text))
+		if err != nil {
+			return fmt.Errorf("%s: %q is not an integer", s.name, text)
+		}
+		*v = n
+	case *int64:
+		n, err := strconv.ParseInt(strings.TrimSpace(text), 10, 64)
+		if err != nil {
+			return fmt.Errorf("%s: %q is not an integer", s.name, text)
+		}
+		*v = n
+	case *string:
+		*v = text
+	case *bool:
+		b, err := strconv.ParseBool(strings.TrimSpace(text))
+		if err != nil {
+			return fmt.Errorf("%s: %q is not a boolean", s.name, text)
+		}
+		*v = b
+	}
+	return nil
+}
+
+// format 返回配置项当前值的字符串形式。
+func (s setting) format() string {
+	switch v := s.value.(type) {
+	case *int:
+		return strconv.Itoa(*v)
+	case *int64:
+		return strconv.FormatInt(*v, 10)
+	case *string:
+		return *v
+	case *bool:
+		return strconv.FormatBool(*v)
+	}
+	return ""
+}
+
+// flagValue 记录命令行参数的值，在配置文件与环境变量之后再写入配置，使命令行参数的优先级最高。
+type flagValue struct {
+	setting setting
+	pending map[string]string
+}
+
+func (f *flagValue) String() string {
+	if f == nil || f.setting.value == nil {
+		return ""
+	}
+	return f.setting.format()
+}
+
+func (f *flagValue) Set(text string) error {
+	f.pending[f.setting.name] = text
+	return nil
+}
+
+// IsBoolFlag 使布尔配置项可以写成 -spvEnabled 而不必写出 =true。
+func (f *flagValue) IsBoolFlag() bool {
+	_, ok := f.setting.value.(*bool)
+	return ok
+}
+
+// Default 返回默认配置。
+func Default() Config {
+	return Config{
+		difficulty:          4,
+		maxTransactionCount: 16,
+		maxBlockSize:        64 * 1024,
+		nbAccount:           100,
+		initAmount:          10000,
+		spvEnabled:          true,
+		dataDir:             "chaindata",
+		blockSubsidy:        50,
+		halvingInterval:     100,
+		targetBlockInterval: 5,
+		retargetInterval:    10,
+		minerThreads:        0,
+		maxPoolTransactions: 256,
+		maxPoolSize:         256 * 1024,
+		poolExpiry:          600,
+		minRelayFeeRate:     1,
+		workload:            "uniform",
+		workloadSeed:        0,
+		simulationBlocks:    0,
+		p2pListen:           "127.0.0.1:9333",
+		rpcListen:           "127.0.0.1:9332",
+		rpcToken:            "",
+		peers:               "",
+		maxPeers:            8,
+		genesisSeed:         0,
+		mining:              true,
+	}
+}
+
+// Load 按默认值、配置文件、环境变量、命令行参数的顺序加载配置，并检查配置是否有效。
+// 参数:
+// - name: 程序名称，用于命令行帮助信息。
+// - args: 命令行参数，不包括程序名称。
+// - lookupEnv: 读取环境变量的函数，通常为 os.LookupEnv。
+// 返回值:
+// 返回加载得到的配置；命令行参数包含 -h 或 -help 时返回 flag.ErrHelp，其余情况下返回第一个发现的错误。
+func Load(name string, args []string, lookupEnv func(string) (string, bool)) (Config, error) {
+	c := Default()
+	settings := c.settings()
+
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	pending := make(map[string]string)
+	configPath, _ := lookupEnv(envPrefix + strings.ToUpper(configKey))
+	fs.StringVar(&configPath, configKey, configPath, "path of a JSON config file, also read from "+envPrefix+strings.ToUpper(configKey))
+	for _, s := range settings {
+		fs.Var(&flagValue{setting: s, pending: pending}, s.name, s.usage+" (env "+s.env+")")
+	}
+	if err := fs.Parse(args); err != nil {
+		return c, err
+	}
+	if fs.NArg() > 0 {
+		return c, fmt.Errorf("unexpected argument %q", fs.Arg(0))
+	}
+
+	if configPath != "" {
+		if err := c.loadFile(configPath); err != nil {
+			return c, err
+		}
+	}
+	for _, s := range settings {
+		if text, ok := lookupEnv(s.env); ok {
+			if err := s.parse(text); err != nil {
+				return c, fmt.Errorf("environment variable %s: %w", s.env, err)
+			}
+		}
+	}
+	for _, s := range settings {
+		if text, ok := pending[s.name]; ok {
+			if err := s.parse(text); err != nil {
+				return c, fmt.Errorf("flag -%w", err)
+			}
+		}
+	}
+	return c, c.Validate()
+}
+
+// loadFile 从 JSON 配置文件中读取配置，文件中没有出现的键保持原值。
+func (c *Config) loadFile(path string) error {
+	if ext := strings.ToLower(filepath.Ext(path)); ext != ".json" {
+		return fmt.Errorf("config file %s: unsupported format %q, only .json files are supported", path, ext)
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read config file: %w", err)
+	}
+	values := make(map[string]json.RawMessage)
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.UseNumber()
+	if err := decoder.Decode(&values); err != nil {
+		return fmt.Errorf("config file %s: %w", path, err)
+	}
+	for _, s := range c.settings() {
+		raw, ok := values[s.name]
+		if !ok {
+			continue
+		}
+		if err := json.Unmarshal(raw, s.value); err != nil {
+			return fmt.Errorf("config file %s: %s: %w", path, s.name, err)
+		}
+		delete(values, s.name)
+	}
+	for key := range values {
+		return fmt.Errorf("config file %s: unknown key %q", path, key)
+	}
+	return nil
+}
+
+// Validate 检查配置是否有效。
+// 返回值:
+// 返回第一个发现的错误，配置有效时返回 nil。
+func (c *Config) Validate() error {
+	switch {
+	case c.difficulty < 1 || c.difficulty > 63:
+		return errors.New("difficulty must be between 1 and 63")
+	case c.maxTransactionCount < 2:
+		return errors.New("maxTransactionCount must leave room for the coinbase transaction")
+	case c.maxBlockSize <= 0:
+		return errors.New("maxBlockSize must be positive")
+	case c.nbAccount < 1:
+		return errors.New("nbAccount must be positive")
+	case c.initAmount < 0:
+		return errors.New("initAmount must not be negative")
+	case c.blockSubsidy < 0:
+		return errors.New("blockSubsidy must not be negative")
+	case c.targetBlockInterval <= 0:
+		return errors.New("targetBlockInterval must be positive")
+	case c.maxPoolTransactions <= 0:
+		return errors.New("maxPoolTransactions must be positive")
+	case c.maxPoolSize <= 0:
+		return errors.New("maxPoolSize must be positive")
+	case c.workload == "":
+		return errors.New("workload must not be empty")
+	case c.simulationBlocks < 0:
+		return errors.New("simulationBlocks must not be negative")
+	case c.maxPeers < 1:
+		return errors.New("maxPeers must be positive")
+	}
+	for _, address := range []struct{ name, value string }{{"p2pListen", c.p2pListen}, {"rpcListen", c.rpcListen}} {
+		if address.value == "" {
+			continue
+		}
+		if _, _, err := net.SplitHostPort(address.value); err != nil {
+			return fmt.Errorf("%s: %w", address.name, err)
+		}
+	}
+	if c.rpcListen != "" && c.rpcToken == "" && !isLoopback(c.rpcListen) {
+		// stop 等方法可以控制节点，没有令牌时只允许本机访问
+		return fmt.Errorf("rpcListen: %s is not a loopback address, set rpcToken to accept remote JSON-RPC requests", c.rpcListen)
+	}
+	for _, peer := range c.GetPeers() {
+		if _, _, err := net.SplitHostPort(peer); err != nil {
+			return fmt.Errorf("peers: %w", err)
+		}
+	}
+	return nil
+}
+
+// isLoopback 判断监听地址是否只接受本机的连接，主机为空（监听全部地址）时返回 false。
+func isLoopback(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
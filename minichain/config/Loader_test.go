@@ -0,0 +1,164 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// noEnv 不读取任何环境变量，使测试不受运行环境影响。
+func noEnv(string) (string, bool) {
+	return "", false
+}
+
+func TestRPCListenRequiresTokenOffLoopback(t *testing.T) {
+	for _, tc := range []struct {
+		args []string
+		ok   bool
+	}{
+		{[]string{"-rpcListen=127.0.0.1:9332"}, true},
+		{[]string{"-rpcListen=localhost:9332"}, true},
+		{[]string{"-rpcListen=[::1]:9332"}, true},
+		{[]string{"-rpcListen="}, true},
+		{[]string{"-rpcListen=0.0.0.0:9332"}, false},
+		{[]string{"-rpcListen=:9332"}, false},
+		{[]string{"-rpcListen=192.168.1.10:9332"}, false},
+		{[]string{"-rpcListen=0.0.0.0:9332", "-rpcToken=secret"}, true},
+	} {
+		_, err := Load("config.test", tc.args, noEnv)
+		if tc.ok && err != nil {
+			t.Errorf("%v: unexpected error %v", tc.args, err)
+		}
+		if !tc.ok && (err == nil || !strings.Contains(err.Error(), "rpcToken")) {
+			t.Errorf("%v: returned %v, want an error asking for rpcToken", tc.args, err)
+		}
+	}
+}
+
+// envMap 返回只读取 env 中环境变量的函数。
+func envMap(env map[string]string) func(string) (string, bool) {
+	return func(name string) (string, bool) {
+		value, ok := env[name]
+		return value, ok
+	}
+}
+
+// writeConfigFile 在临时目录中写入配置文件，返回文件路径。
+func writeConfigFile(t *testing.T, name string, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestEnvNames(t *testing.T) {
+	for name, env := range map[string]string{
+		"p2pListen":           "MINICHAIN_P2P_LISTEN",
+		"rpcListen":           "MINICHAIN_RPC_LISTEN",
+		"rpcToken":            "MINICHAIN_RPC_TOKEN",
+		"maxPoolSize":         "MINICHAIN_MAX_POOL_SIZE",
+		"minRelayFeeRate":     "MINICHAIN_MIN_RELAY_FEE_RATE",
+		"maxTransactionCount": "MINICHAIN_MAX_TRANSACTION_COUNT",
+		"nbAccount":           "MINICHAIN_NB_ACCOUNT",
+	} {
+		found := false
+		for _, s := range new(Config).settings() {
+			if s.name == name {
+				found = true
+				if s.env != env {
+					t.Errorf("%s: env name %s, want %s", name, s.env, env)
+				}
+			}
+		}
+		if !found {
+			t.Errorf("unknown key %s", name)
+		}
+	}
+
+	// 每个键都有唯一的环境变量名，且设置该环境变量确实会修改对应的配置
+	seen := make(map[string]string)
+	for _, s := range new(Config).settings() {
+		if !strings.HasPrefix(s.env, envPrefix) || strings.ToUpper(s.env) != s.env {
+			t.Errorf("%s: env name %s is not an upper-case %s name", s.name, s.env, envPrefix)
+		}
+		if other, ok := seen[s.env]; ok {
+			t.Errorf("%s and %s share the env name %s", s.name, other, s.env)
+		}
+		seen[s.env] = s.name
+
+		var value string
+		switch v := s.value.(type) {
+		case *int, *int64:
+			value = "7"
+		case *string:
+			value = "127.0.0.1:7"
+		case *bool:
+			value = strconv.FormatBool(!*v)
+		}
+		c, err := Load("config.test", nil, envMap(map[string]string{s.env: value}))
+		if err != nil {
+			t.Errorf("%s=%s: %v", s.env, value, err)
+			continue
+		}
+		for _, loaded := range c.settings() {
+			if loaded.name == s.name && loaded.format() != value {
+				t.Errorf("%s=%s: %s is %s", s.env, value, s.name, loaded.format())
+			}
+		}
+	}
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	path := writeConfigFile(t, "minichain.json", `{"maxPoolSize": 100, "difficulty": 2, "nbAccount": 3}`)
+	env := map[string]string{"MINICHAIN_DIFFICULTY": "5", "MINICHAIN_NB_ACCOUNT": "6"}
+	want := func(c Config) {
+		t.Helper()
+		if c.GetMaxPoolSize() != 100 || c.GetDifficulty() != 5 || c.GetAccountNumber() != 9 {
+			t.Fatalf("maxPoolSize %d, difficulty %d, nbAccount %d; want 100 from the file, 5 from the env, 9 from the flag",
+				c.GetMaxPoolSize(), c.GetDifficulty(), c.GetAccountNumber())
+		}
+	}
+
+	c, err := Load("config.test", []string{"-config=" + path, "-nbAccount=9"}, envMap(env))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want(c)
+	// 配置文件路径也可以由环境变量指定
+	env["MINICHAIN_CONFIG"] = path
+	c, err = Load("config.test", []string{"-nbAccount=9"}, envMap(env))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want(c)
+}
+
+func TestLoadRejectsUnknownKeysAndBadValues(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		args []string
+		env  map[string]string
+		want string
+	}{
+		{"unknown key", []string{"-config=" + writeConfigFile(t, "typo.json", `{"maxPoolSzie": 1}`)}, nil, `unknown key "maxPoolSzie"`},
+		{"unsupported format", []string{"-config=" + writeConfigFile(t, "minichain.yaml", "difficulty: 2")}, nil, "only .json"},
+		{"malformed file", []string{"-config=" + writeConfigFile(t, "broken.json", `{"difficulty": `)}, nil, "broken.json"},
+		{"file value type", []string{"-config=" + writeConfigFile(t, "type.json", `{"difficulty": "2"}`)}, nil, "difficulty"},
+		{"flag not an integer", []string{"-difficulty=two"}, nil, `difficulty: "two" is not an integer`},
+		{"env not a boolean", nil, map[string]string{"MINICHAIN_SPV_ENABLED": "maybe"}, "MINICHAIN_SPV_ENABLED"},
+		{"unknown flag", []string{"-difficulity=2"}, nil, "difficulity"},
+		{"out of range", []string{"-difficulty=0"}, nil, "difficulty must be between"},
+		{"bad address", []string{"-p2pListen=localhost"}, nil, "p2pListen"},
+		{"bad peer", []string{"-peers=127.0.0.1:1,nohost"}, nil, "peers"},
+		{"extra argument", []string{"-difficulty=2", "extra"}, nil, `unexpected argument "extra"`},
+	} {
+		_, err := Load("config.test", tc.args, envMap(tc.env))
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%s: returned %v, want an error containing %q", tc.name, err, tc.want)
+		}
+	}
+}
@@ -1,17 +1,70 @@
 package config
 
+import (
+	"runtime"
+	"strings"
+)
+
 /**
  * 配置文件
+ *
+ * MiniChainConfig 初始为默认配置，程序启动时由 Load 从配置文件、环境变量与命令行参数加载后替换。
  */
 
-// Config 该类为配置类，主要有两个字段：
-// difficulty: 挖矿的难度值，即规定了新的区块的哈希值至少以几个0开头才满足难度条件
-// maxTransactionCount: 交易池大小；TransactionProducer需要随机生成交易，放入交易池中，直至达到该大小
+// Config 该类为配置类，主要有以下字段：
+// difficulty: 初始挖矿难度值，即规定了新的区块的哈希值至少以几个0开头才满足难度条件，之后按区块时间动态调整
+// maxTransactionCount: 每个区块最多包含的交易个数（包括 coinbase 交易）
+// nbAccount: 创世块中发放初始余额的账户个数
+// initAmount: 每个账户的初始余额
+// spvEnabled: 是否为每个账户创建 SPV 节点
+// dataDir: 区块与账户的持久化目录，为空时仅保存在内存中
+// blockSubsidy: 区块的初始挖矿奖励，由 coinbase 交易发放给矿工
+// halvingInterval: 挖矿奖励减半的区块间隔，不大于 0 时奖励不减半
+// maxBlockSize: 区块中全部交易（包括 coinbase 交易）规范编码的最大字节数
+// targetBlockInterval: 期望的出块间隔（秒）
+// retargetInterval: 难度调整的区块间隔，不大于 0 时难度保持不变
+// minerThreads: 矿工并行计算哈希的工作协程个数，不大于 0 时使用 CPU 核数
+// maxPoolTransactions: 交易池最多容纳的交易个数，超过时移除手续费率最低的交易
+// maxPoolSize: 交易池中全部交易规范编码的最大字节数
+// poolExpiry: 交易在交易池中停留的最长时间（秒），不大于 0 时交易不会过期
+// minRelayFeeRate: 交易池接收交易的最低手续费率（每 1000 字节），不大于 0 时不要求手续费
+// workload: 交易负载生成器的策略，可选 uniform、hot、consolidation、fanout、invalid、mixed
+// workloadSeed: 网络的随机数种子，决定交易负载与区块头的随机字段（模拟运行时还决定账户密钥），为 0 时使用当前时间
+// simulationBlocks: 大于 0 时以确定性模拟方式运行，生成指定个数的区块后退出
+// p2pListen: 节点间通信的监听地址，为空时不监听
+// rpcListen: JSON-RPC 服务的监听地址，为空时不监听
+// rpcToken: JSON-RPC 请求需要携带的令牌，为空时不要求令牌，此时 rpcListen 只能是本机回环地址
+// peers: 启动时主动连接的其他节点地址，以逗号分隔
+// maxPeers: 最多的节点连接个数
+// genesisSeed: 不为 0 时账户密钥与创世块都由它派生，使多个节点拥有相同的创世块
+// mining: 是否运行矿工，为 false 时节点只同步与转发区块和交易
 type Config struct {
 	difficulty          int
 	maxTransactionCount int
+	maxBlockSize        int
 	nbAccount           int
 	initAmount          int
+	spvEnabled          bool
+	dataDir             string
+	blockSubsidy        int
+	halvingInterval     int
+	targetBlockInterval int
+	retargetInterval    int
+	minerThreads        int
+	maxPoolTransactions int
+	maxPoolSize         int
+	poolExpiry          int
+	minRelayFeeRate     int
+	workload            string
+	workloadSeed        int64
+	simulationBlocks    int
+	p2pListen           string
+	rpcListen           string
+	rpcToken            string
+	peers               string
+	maxPeers            int
+	genesisSeed         int64
+	mining              bool
 }
 
 func (c *Config) GetDifficulty() int {
@@ -21,6 +74,10 @@ func (c *Config) GetMaxTransactionCount() int {
 	return c.maxTransactionCount
 }
 
+func (c *Config) GetMaxBlockSize() int {
+	return c.maxBlockSize
+}
+
 func (c *Config) GetAccountNumber() int {
 	return c.nbAccount
 }
@@ -29,9 +86,98 @@ func (c *Config) GetInitAmount() int {
 	return c.initAmount
 }
 
-var MiniChainConfig = Config{
-	difficulty:          4,
-	maxTransactionCount: 16,
-	nbAccount:           100,
-	initAmount:          10000,
+func (c *Config) IsSPVEnabled() bool {
+	return c.spvEnabled
+}
+
+func (c *Config) GetDataDir() string {
+	return c.dataDir
+}
+
+func (c *Config) GetBlockSubsidy() int {
+	return c.blockSubsidy
+}
+
+func (c *Config) GetHalvingInterval() int {
+	return c.halvingInterval
+}
+
+func (c *Config) GetTargetBlockInterval() int {
+	return c.targetBlockInterval
+}
+
+func (c *Config) GetRetargetInterval() int {
+	return c.retargetInterval
+}
+
+func (c *Config) GetMinerThreads() int {
+	if c.minerThreads <= 0 {
+		return runtime.NumCPU()
+	}
+	return c.minerThreads
+}
+
+func (c *Config) GetMaxPoolTransactions() int {
+	return c.maxPoolTransactions
+}
+
+func (c *Config) GetMaxPoolSize() int {
+	return c.maxPoolSize
+}
+
+func (c *Config) GetPoolExpiry() int {
+	return c.poolExpiry
+}
+
+func (c *Config) GetMinRelayFeeRate() int {
+	return c.minRelayFeeRate
+}
+
+func (c *Config) GetWorkload() string {
+	return c.workload
+}
+
+func (c *Config) GetWorkloadSeed() int64 {
+	return c.workloadSeed
+}
+
+func (c *Config) GetSimulationBlocks() int {
+	return c.simulationBlocks
+}
+
+func (c *Config) GetP2PListen() string {
+	return c.p2pListen
+}
+
+func (c *Config) GetRPCListen() string {
+	return c.rpcListen
 }
+
+func (c *Config) GetRPCToken() string {
+	return c.rpcToken
+}
+
+// GetPeers 返回启动时主动连接的节点地址列表。
+func (c *Config) GetPeers() []string {
+	peers := make([]string, 0)
+	for _, peer := range strings.Split(c.peers, ",") {
+		if peer = strings.TrimSpace(peer); peer != "" {
+			peers = append(peers, peer)
+		}
+	}
+	return peers
+}
+
+func (c *Config) GetMaxPeers() int {
+	return c.maxPeers
+}
+
+func (c *Config) GetGenesisSeed() int64 {
+	return c.genesisSeed
+}
+
+func (c *Config) IsMiningEnabled() bool {
+	return c.mining
+}
+
+var MiniChainConfig = Default()
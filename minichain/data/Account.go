@@ -2,9 +2,10 @@ package data
 
 import (
 	"Go-Minichain/utils"
+	"bytes"
 	"crypto/ecdsa"
-	"crypto/elliptic"
 	"encoding/asn1"
+	"errors"
 )
 
 // Account 表示一个账户，包含公钥和私钥。
@@ -25,6 +26,19 @@ func NewAccount() *Account {
 	}
 }
 
+// NewAccountFromSeed 根据种子确定性地创建账户，相同的种子总是得到相同的密钥对，用于可复现的模拟运行。
+// 参数:
+// - seed: 种子字节序列。
+// 返回值:
+// 返回一个指向新创建的 Account 的指针。
+func NewAccountFromSeed(seed []byte) *Account {
+	privateKey, publicKey := utils.Secp256k1Derive(seed)
+	return &Account{
+		PublicKey:  publicKey,
+		PrivateKey: privateKey,
+	}
+}
+
 // GetWalletAddress 生成并返回该账户的钱包地址。
 // 钱包地址的生成过程如下：
 // 1. 对公钥进行SHA-256哈希，然后对结果进行RIPEMD-160哈希。
@@ -32,9 +46,20 @@ func NewAccount() *Account {
 // 3. 对上述数据进行两次SHA-256哈希，并取前4个字节作为校验码。
 // 4. 将版本前缀、公钥哈希和校验码组合在一起，并进行Base58编码。
 func (a *Account) GetWalletAddress() string {
-	publicKey := a.GetPublicKey()
-	publicKeyBytes := elliptic.Marshal(publicKey, publicKey.X, publicKey.Y)
-	publicKeyHash := utils.Ripemd160Digest(utils.Sha256Digest(publicKeyBytes))
+	return WalletAddress(a.GetPublicKey())
+}
+
+// WalletAddress 根据公钥生成钱包地址，生成过程见 Account.GetWalletAddress。
+// 参数:
+// - publicKey: 公钥。
+// 返回值:
+// 返回 Base58 编码的钱包地址。
+func WalletAddress(publicKey ecdsa.PublicKey) string {
+	return walletAddressFromHash(PublicKeyHash(publicKey))
+}
+
+// walletAddressFromHash 根据公钥哈希生成钱包地址。
+func walletAddressFromHash(publicKeyHash []byte) string {
 	data := make([]byte, 1+len(publicKeyHash))
 	data = append(data, 0)                // 添加版本前缀
 	data = append(data, publicKeyHash...) // 添加公钥哈希
@@ -50,6 +75,40 @@ func (a *Account) GetWalletAddress() string {
 	return walletAddress
 }
 
+// publicKeyHashSize 公钥哈希的字节数。
+var publicKeyHashSize = len(utils.Ripemd160Digest(utils.Sha256Digest(nil)))
+
+// ErrInvalidAddress 钱包地址不是合法的 Base58 编码，或校验码不匹配。
+var ErrInvalidAddress = errors.New("invalid wallet address")
+
+// ParseWalletAddress 从钱包地址中取出公钥哈希，使只知道地址的一方也可以构造付款输出。
+// 地址的 Base58 解码结果末尾依次为公钥哈希与 4 字节的校验码，取出公钥哈希后重新生成地址，与原地址一致才认为合法。
+// 参数:
+// - address: 钱包地址。
+// 返回值:
+// 返回公钥哈希；地址不合法时返回 ErrInvalidAddress。
+func ParseWalletAddress(address string) ([]byte, error) {
+	b := utils.NewBase58Util()
+	for i := 0; i < len(address); i++ {
+		if bytes.IndexByte(b.ALPHABET, address[i]) < 0 {
+			return nil, ErrInvalidAddress
+		}
+	}
+	decoded := b.Decode(address)
+	size := publicKeyHashSize + 4
+	if len(decoded) > size {
+		return nil, ErrInvalidAddress
+	}
+	// 前导零字节在 Base58 解码时丢失，补齐到固定长度
+	padded := make([]byte, size)
+	copy(padded[size-len(decoded):], decoded)
+	publicKeyHash := padded[:publicKeyHashSize]
+	if walletAddressFromHash(publicKeyHash) != address {
+		return nil, ErrInvalidAddress
+	}
+	return publicKeyHash, nil
+}
+
 // ToString 返回该账户的字符串表示形式。
 // 包括公钥和私钥的十六进制编码。
 func (a *Account) ToString() string {
@@ -86,3 +145,31 @@ func (a *Account) GetAmount(trueUtxo []*UTXO) int {
 	}
 	return amount
 }
+
+// Encode 返回账户的规范二进制编码，依次包含未压缩格式的公钥与 32 字节的私钥标量。
+// 编码结果包含私钥，只应写入受保护的存储中。
+func (a *Account) Encode() []byte {
+	e := NewEncoder()
+	e.WriteBytes(utils.MarshalPublicKey(a.PublicKey))
+	e.WriteBytes(a.PrivateKey.D.FillBytes(make([]byte, 32)))
+	return e.Bytes()
+}
+
+// DecodeAccount 从规范二进制编码中恢复账户，并校验公钥与私钥是否匹配。
+// 参数:
+// - b: Encode 生成的字节序列。
+// 返回值:
+// 返回解码得到的账户以及可能出现的错误。
+func DecodeAccount(b []byte) (*Account, error) {
+	d := NewDecoder(b)
+	publicKeyBytes := d.ReadBytes()
+	privateKeyBytes := d.ReadBytes()
+	if err := d.Finish(); err != nil {
+		return nil, err
+	}
+	privateKey, publicKey := utils.Secp256k1FromBytes(privateKeyBytes)
+	if !bytes.Equal(utils.MarshalPublicKey(publicKey), publicKeyBytes) {
+		return nil, errors.New("public key does not match private key")
+	}
+	return &Account{PublicKey: publicKey, PrivateKey: privateKey}, nil
+}
@@ -32,3 +32,31 @@ func (b *Block) ToString() string {
 func (b *Block) SetNonce(nonce int64) {
 	b.header.SetNonce(nonce)
 }
+
+// Encode 返回区块的规范二进制编码，依次包含区块头与区块体。
+func (b *Block) Encode() []byte {
+	e := NewEncoder()
+	b.header.encodeTo(e)
+	b.body.encodeTo(e)
+	return e.Bytes()
+}
+
+// DecodeBlock 从规范二进制编码中恢复区块。
+// 参数:
+// - raw: Encode 生成的字节序列。
+// 返回值:
+// 返回解码得到的区块以及可能出现的错误。
+func DecodeBlock(raw []byte) (*Block, error) {
+	d := NewDecoder(raw)
+	header := decodeBlockHeader(d)
+	body := decodeBlockBody(d)
+	if err := d.Finish(); err != nil {
+		return nil, err
+	}
+	return NewBlock(header, body), nil
+}
+
+// Hash 返回区块的哈希值，即区块头的哈希值。
+func (b *Block) Hash() string {
+	return b.header.Hash()
+}
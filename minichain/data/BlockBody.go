@@ -1,6 +1,7 @@
 package data
 
 import (
+	"Go-Minichain/utils"
 	"strings"
 )
 
@@ -22,6 +23,35 @@ func (b *BlockBody) GetMerkleRootHash() string {
 	return b.merkleRootHash
 }
 
+// ComputeMerkleRootHash 根据交易列表计算 Merkle 树根哈希值。
+// 叶子节点为交易的 TxID，每一层两两拼接后计算哈希，奇数个节点时最后一个节点与自身拼接。
+// 参数:
+// - transactions: 区块中的交易列表。
+// 返回值:
+// 返回 Merkle 树根哈希值，交易列表为空时返回空字符串。
+func ComputeMerkleRootHash(transactions []Transaction) string {
+	if len(transactions) == 0 {
+		return ""
+	}
+	hashes := make([]string, 0, len(transactions))
+	for i := range transactions {
+		hashes = append(hashes, transactions[i].TxID())
+	}
+
+	for len(hashes) > 1 {
+		var newLevel []string
+		for i := 0; i < len(hashes); i += 2 {
+			if i+1 == len(hashes) {
+				newLevel = append(newLevel, utils.GetSha256Digest(hashes[i]+hashes[i]))
+			} else {
+				newLevel = append(newLevel, utils.GetSha256Digest(hashes[i]+hashes[i+1]))
+			}
+		}
+		hashes = newLevel
+	}
+	return hashes[0]
+}
+
 func (b *BlockBody) toString() string {
 	// 将每个 transaction 使用 ToString 方法表示
 	transactionStrings := make([]string, len(b.transactions))
@@ -34,3 +64,44 @@ func (b *BlockBody) toString() string {
 		", transactions=" + strings.Join(transactionStrings, " ") +
 		"}"
 }
+
+// encodeTo 将区块体写入编码器。
+func (b *BlockBody) encodeTo(e *Encoder) {
+	e.WriteString(b.merkleRootHash)
+	e.WriteUint32(uint32(len(b.transactions)))
+	for i := range b.transactions {
+		b.transactions[i].encodeTo(e)
+	}
+}
+
+// Encode 返回区块体的规范二进制编码。
+func (b *BlockBody) Encode() []byte {
+	e := NewEncoder()
+	b.encodeTo(e)
+	return e.Bytes()
+}
+
+// decodeBlockBody 从解码器中读取区块体。
+func decodeBlockBody(d *Decoder) BlockBody {
+	merkleRootHash := d.ReadString()
+	n := d.ReadCount(32)
+	transactions := make([]Transaction, 0, n)
+	for i := 0; i < n && d.Err() == nil; i++ {
+		transactions = append(transactions, *decodeTransaction(d))
+	}
+	return BlockBody{merkleRootHash: merkleRootHash, transactions: transactions}
+}
+
+// DecodeBlockBody 从规范二进制编码中恢复区块体。
+// 参数:
+// - raw: Encode 生成的字节序列。
+// 返回值:
+// 返回解码得到的区块体以及可能出现的错误。
+func DecodeBlockBody(raw []byte) (*BlockBody, error) {
+	d := NewDecoder(raw)
+	body := decodeBlockBody(d)
+	if err := d.Finish(); err != nil {
+		return nil, err
+	}
+	return &body, nil
+}
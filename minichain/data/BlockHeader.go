@@ -2,6 +2,8 @@ package data
 
 import (
 	"Go-Minichain/config"
+	"Go-Minichain/utils"
+	"math/big"
 	"strconv"
 	"time"
 )
@@ -10,8 +12,8 @@ type BlockHeader struct {
 	version        int    // 版本号，默认为1，无需提供该参数
 	preBlockHash   string // 前一个区块的哈希值，创建新的区块头对象时需要提供该参数
 	merkleRootHash string // 该区块头对应区块体中的交易的Merkle根哈希值，创建新的区块头对象时需要提供该参数
-	timestamp      int    // 时间戳，创建区块头对象时会自动填充，无需提供该参数
-	difficulty     int    // 挖矿难度，默认为系统配置中的难度值，无需提供该参数
+	timestamp      int64  // 时间戳（Unix 秒），创建区块头对象时会自动填充为当前时间，矿工可以通过 SetTimestamp 调整
+	bits           uint32 // 紧凑格式的工作量证明目标值，默认为系统配置的初始难度对应的目标值，矿工需要通过 SetBits 设置为难度调整后的值
 	nonce          int64  // 随机字段，创建新的区块头对象时需要提供该参数
 }
 
@@ -29,14 +31,14 @@ func NewBlockHeader(preBlockHash string, merkleRootHash string, nonce int64) *Bl
 	// 设置区块头的版本号。
 	header.version = 1
 
-	// 记录区块创建的精确时间。
-	header.timestamp = time.Now().Nanosecond()
+	// 记录区块创建的时间。
+	header.timestamp = time.Now().Unix()
 
 	// 设置前一个区块的哈希值。
 	header.preBlockHash = preBlockHash
 
-	// 获取当前网络的难度值。
-	header.difficulty = config.MiniChainConfig.GetDifficulty()
+	// 根据系统配置的初始难度值设置目标值。
+	header.bits = utils.BigToCompact(utils.LeadingZerosTarget(config.MiniChainConfig.GetDifficulty()))
 
 	// 设置挖矿算法中的随机数。
 	header.nonce = nonce
@@ -57,11 +59,16 @@ func (h *BlockHeader) GetPreBlockHash() string {
 func (h *BlockHeader) GetMerkleRootHash() string {
 	return h.merkleRootHash
 }
-func (h *BlockHeader) GetTimestamp() int {
+func (h *BlockHeader) GetTimestamp() int64 {
 	return h.timestamp
 }
-func (h *BlockHeader) GetDifficulty() int {
-	return h.difficulty
+func (h *BlockHeader) GetBits() uint32 {
+	return h.bits
+}
+
+// GetTarget 返回工作量证明的目标值，区块哈希不大于该值时满足工作量证明。
+func (h *BlockHeader) GetTarget() *big.Int {
+	return utils.CompactToBig(h.bits)
 }
 func (h *BlockHeader) GetNonce() int64 {
 	return h.nonce
@@ -71,14 +78,71 @@ func (h *BlockHeader) SetNonce(nonce int64) {
 	h.nonce = nonce
 }
 
+func (h *BlockHeader) SetTimestamp(timestamp int64) {
+	h.timestamp = timestamp
+}
+
+func (h *BlockHeader) SetBits(bits uint32) {
+	h.bits = bits
+}
+
 func (h *BlockHeader) toString() string {
 	return "BlockHeader{" +
 		"version=" + strconv.Itoa(h.version) +
 		", preBlockHash=" + h.preBlockHash +
 		", merkleRootHash=" + h.merkleRootHash +
-		", timeStamp=" + strconv.Itoa(h.timestamp) +
-		", difficulty=" + strconv.Itoa(h.difficulty) +
+		", timeStamp=" + strconv.FormatInt(h.timestamp, 10) +
+		", bits=" + strconv.FormatUint(uint64(h.bits), 16) +
 		", nonce=" + strconv.FormatInt(h.nonce, 10) +
 		"}"
 
 }
+
+// encodeTo 将区块头的全部字段写入编码器。
+func (h *BlockHeader) encodeTo(e *Encoder) {
+	e.WriteInt(h.version)
+	e.WriteString(h.preBlockHash)
+	e.WriteString(h.merkleRootHash)
+	e.WriteInt64(h.timestamp)
+	e.WriteUint32(h.bits)
+	e.WriteInt64(h.nonce)
+}
+
+// Encode 返回区块头的规范二进制编码。
+func (h *BlockHeader) Encode() []byte {
+	e := NewEncoder()
+	h.encodeTo(e)
+	return e.Bytes()
+}
+
+// decodeBlockHeader 从解码器中读取区块头。
+func decodeBlockHeader(d *Decoder) BlockHeader {
+	return BlockHeader{
+		version:        d.ReadInt(),
+		preBlockHash:   d.ReadString(),
+		merkleRootHash: d.ReadString(),
+		timestamp:      d.ReadInt64(),
+		bits:           d.ReadUint32(),
+		nonce:          d.ReadInt64(),
+	}
+}
+
+// DecodeBlockHeader 从规范二进制编码中恢复区块头。
+// 参数:
+// - b: Encode 生成的字节序列。
+// 返回值:
+// 返回解码得到的区块头以及可能出现的错误。
+func DecodeBlockHeader(b []byte) (*BlockHeader, error) {
+	d := NewDecoder(b)
+	header := decodeBlockHeader(d)
+	if err := d.Finish(); err != nil {
+		return nil, err
+	}
+	return &header, nil
+}
+
+// Hash 返回区块头的哈希值，即区块头规范编码的 SHA-256 哈希值（大写十六进制）。
+// 区块头中包含 Merkle 根哈希，因此该值同时确定了区块体中的全部交易。
+func (h *BlockHeader) Hash() string {
+	return utils.GetBytesSha256Digest(h.Encode())
+}
@@ -0,0 +1,195 @@
+package data
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+/**
+ * 规范二进制编码
+ *
+ * data 包中所有类型的哈希、签名与存储都基于本文件定义的确定性二进制编码，
+ * 不再依赖 ToString 的字符串拼接或 fmt 的输出格式，便于其他实现重新计算并校验。
+ *
+ * 编码规则：
+ * - 顶层对象（区块、区块头、区块体、交易、UTXO、账户）以 1 字节的版本号开头；
+ * - 整数一律使用定长大端序，int 按 int64 编码；
+ * - 字节序列与字符串使用 4 字节大端长度前缀；
+ * - 列表使用 4 字节大端元素个数前缀，随后依次编码每个元素（不再重复版本号）。
+ */
+
+// EncodingVersion 当前的编码版本号。
+const EncodingVersion byte = 1
+
+// ErrEncodingVersion 表示待解码数据的版本号不受支持。
+var ErrEncodingVersion = errors.New("unsupported encoding version")
+
+// ErrUnexpectedEnd 表示数据在解码完成之前就已结束，即数据被截断。
+var ErrUnexpectedEnd = errors.New("unexpected end of data")
+
+// Encoder 按照规范编码规则向缓冲区中追加数据。
+type Encoder struct {
+	buf []byte
+}
+
+// NewEncoder 创建一个以版本号开头的编码器。
+func NewEncoder() *Encoder {
+	return &Encoder{buf: []byte{EncodingVersion}}
+}
+
+// Bytes 返回已编码的字节序列。
+func (e *Encoder) Bytes() []byte {
+	return e.buf
+}
+
+// WriteUint8 写入 1 字节无符号整数。
+func (e *Encoder) WriteUint8(v uint8) {
+	e.buf = append(e.buf, v)
+}
+
+// WriteUint32 写入 4 字节大端无符号整数。
+func (e *Encoder) WriteUint32(v uint32) {
+	e.buf = binary.BigEndian.AppendUint32(e.buf, v)
+}
+
+// WriteInt64 写入 8 字节大端有符号整数。
+func (e *Encoder) WriteInt64(v int64) {
+	e.buf = binary.BigEndian.AppendUint64(e.buf, uint64(v))
+}
+
+// WriteInt 将 int 按 int64 写入，保证在不同平台上编码一致。
+func (e *Encoder) WriteInt(v int) {
+	e.WriteInt64(int64(v))
+}
+
+// WriteBool 写入布尔值，true 为 1，false 为 0。
+func (e *Encoder) WriteBool(v bool) {
+	if v {
+		e.WriteUint8(1)
+	} else {
+		e.WriteUint8(0)
+	}
+}
+
+// WriteBytes 写入带长度前缀的字节序列。
+func (e *Encoder) WriteBytes(v []byte) {
+	e.WriteUint32(uint32(len(v)))
+	e.buf = append(e.buf, v...)
+}
+
+// WriteString 写入带长度前缀的字符串。
+func (e *Encoder) WriteString(v string) {
+	e.WriteBytes([]byte(v))
+}
+
+// Decoder 按照规范编码规则从字节序列中读取数据。
+// 读取过程中出现的第一个错误会被记录下来，之后的读取都返回零值，调用方只需在最后检查 Err。
+type Decoder struct {
+	data []byte
+	pos  int
+	err  error
+}
+
+// NewDecoder 创建解码器并校验开头的版本号。
+func NewDecoder(data []byte) *Decoder {
+	d := &Decoder{data: data}
+	if version := d.ReadUint8(); d.err == nil && version != EncodingVersion {
+		d.err = fmt.Errorf("%w: %d", ErrEncodingVersion, version)
+	}
+	return d
+}
+
+// Err 返回解码过程中出现的第一个错误。
+func (d *Decoder) Err() error {
+	return d.err
+}
+
+// Finish 检查数据是否被完整读取，返回解码过程中的错误。
+func (d *Decoder) Finish() error {
+	if d.err == nil && d.pos != len(d.data) {
+		d.err = fmt.Errorf("%d trailing bytes after decoding", len(d.data)-d.pos)
+	}
+	return d.err
+}
+
+func (d *Decoder) next(n int) []byte {
+	if d.err != nil {
+		return nil
+	}
+	if n < 0 || len(d.data)-d.pos < n {
+		d.err = fmt.Errorf("%w at offset %d", ErrUnexpectedEnd, d.pos)
+		return nil
+	}
+	b := d.data[d.pos : d.pos+n]
+	d.pos += n
+	return b
+}
+
+// ReadUint8 读取 1 字节无符号整数。
+func (d *Decoder) ReadUint8() uint8 {
+	b := d.next(1)
+	if b == nil {
+		return 0
+	}
+	return b[0]
+}
+
+// ReadUint32 读取 4 字节大端无符号整数。
+func (d *Decoder) ReadUint32() uint32 {
+	b := d.next(4)
+	if b == nil {
+		return 0
+	}
+	return binary.BigEndian.Uint32(b)
+}
+
+// ReadInt64 读取 8 字节大端有符号整数。
+func (d *Decoder) ReadInt64() int64 {
+	b := d.next(8)
+	if b == nil {
+		return 0
+	}
+	return int64(binary.BigEndian.Uint64(b))
+}
+
+// ReadInt 读取按 int64 编码的 int。
+func (d *Decoder) ReadInt() int {
+	return int(d.ReadInt64())
+}
+
+// ReadBool 读取布尔值，只接受 0 和 1。
+func (d *Decoder) ReadBool() bool {
+	v := d.ReadUint8()
+	if v > 1 && d.err == nil {
+		d.err = fmt.Errorf("invalid bool value %d", v)
+	}
+	return v == 1
+}
+
+// ReadBytes 读取带长度前缀的字节序列，返回值是独立的副本。
+func (d *Decoder) ReadBytes() []byte {
+	n := d.ReadUint32()
+	b := d.next(int(n))
+	if b == nil {
+		return nil
+	}
+	return append([]byte{}, b...)
+}
+
+// ReadString 读取带长度前缀的字符串。
+func (d *Decoder) ReadString() string {
+	return string(d.ReadBytes())
+}
+
+// ReadCount 读取列表的元素个数，并粗略检查剩余数据是否足够，避免恶意数据导致过量分配。
+// 参数:
+// - minSize: 单个元素编码后的最小字节数。
+func (d *Decoder) ReadCount(minSize int) int {
+	n := int(d.ReadUint32())
+	if d.err == nil && minSize > 0 && n > (len(d.data)-d.pos)/minSize {
+		d.err = fmt.Errorf("%w: list length %d exceeds remaining data", ErrUnexpectedEnd, n)
+		return 0
+	}
+	return n
+}
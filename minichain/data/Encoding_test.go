@@ -0,0 +1,143 @@
+package data
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// testBlock 返回一个包含 coinbase 交易与一笔已签名交易的区块。
+func testBlock(t *testing.T) *Block {
+	t.Helper()
+	tx, owner := signedTransaction(t)
+	coinbase := NewCoinbaseTransaction(7, []*UTXO{NewUTXO(50, owner.GetPublicKey())})
+	transactions := []Transaction{*coinbase, *tx}
+	header := NewBlockHeader("0000", ComputeMerkleRootHash(transactions), 42)
+	header.SetTimestamp(1700000000)
+	header.SetBits(0x1f00ffff)
+	return NewBlock(*header, *NewBlockBody(header.GetMerkleRootHash(), transactions))
+}
+
+// codec 一种顶层对象的编码与解码，解码结果重新编码后用于比较。
+type codec struct {
+	name   string
+	raw    []byte
+	decode func([]byte) ([]byte, error)
+}
+
+// codecs 返回区块、区块头、交易与 UTXO 的编码及对应的解码函数。
+func codecs(t *testing.T) []codec {
+	block := testBlock(t)
+	header := block.GetBlockHeader()
+	body := block.GetBlockBody()
+	tx := body.GetTransctions()[1]
+	utxo := tx.GetOutUTXOs()[1]
+	return []codec{
+		{"block", block.Encode(), func(b []byte) ([]byte, error) {
+			decoded, err := DecodeBlock(b)
+			if err != nil {
+				return nil, err
+			}
+			return decoded.Encode(), nil
+		}},
+		{"header", header.Encode(), func(b []byte) ([]byte, error) {
+			decoded, err := DecodeBlockHeader(b)
+			if err != nil {
+				return nil, err
+			}
+			return decoded.Encode(), nil
+		}},
+		{"transaction", tx.Encode(), func(b []byte) ([]byte, error) {
+			decoded, err := DecodeTransaction(b)
+			if err != nil {
+				return nil, err
+			}
+			return decoded.Encode(), nil
+		}},
+		{"utxo", utxo.Encode(), func(b []byte) ([]byte, error) {
+			decoded, err := DecodeUTXO(b)
+			if err != nil {
+				return nil, err
+			}
+			return decoded.Encode(), nil
+		}},
+	}
+}
+
+func TestEncodingRoundTrip(t *testing.T) {
+	for _, c := range codecs(t) {
+		encoded, err := c.decode(c.raw)
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if !bytes.Equal(encoded, c.raw) {
+			t.Fatalf("%s: re-encoding the decoded value gives %x, want %x", c.name, encoded, c.raw)
+		}
+	}
+
+	// 解码得到的区块保留了哈希、交易标识与输出位置
+	block := testBlock(t)
+	decoded, err := DecodeBlock(block.Encode())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Hash() != block.Hash() {
+		t.Fatalf("decoded block hash %s, want %s", decoded.Hash(), block.Hash())
+	}
+	body, decodedBody := block.GetBlockBody(), decoded.GetBlockBody()
+	txs := body.GetTransctions()
+	for i, tx := range decodedBody.GetTransctions() {
+		if tx.TxID() != txs[i].TxID() || tx.IsCoinbase() != (i == 0) {
+			t.Fatalf("transaction %d decoded as %s, want %s", i, tx.TxID(), txs[i].TxID())
+		}
+	}
+	if header := decoded.GetBlockHeader(); ComputeMerkleRootHash(decodedBody.GetTransctions()) != header.GetMerkleRootHash() {
+		t.Fatal("decoded transactions do not match the merkle root")
+	}
+	utxo := txs[1].GetOutUTXOs()[0]
+	decodedUTXO, err := DecodeUTXO(utxo.Encode())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decodedUTXO.GetOutpoint() != utxo.GetOutpoint() || decodedUTXO.GetWalletAddress() != utxo.GetWalletAddress() ||
+		decodedUTXO.GetAmount() != utxo.GetAmount() {
+		t.Fatalf("decoded utxo %s, want %s", decodedUTXO.ToString(), utxo.ToString())
+	}
+}
+
+func TestDecodeRejectsMalformedInput(t *testing.T) {
+	for _, c := range codecs(t) {
+		wrongVersion := append([]byte{EncodingVersion + 1}, c.raw[1:]...)
+		if _, err := c.decode(wrongVersion); !errors.Is(err, ErrEncodingVersion) {
+			t.Errorf("%s with version %d: returned %v, want %v", c.name, EncodingVersion+1, err, ErrEncodingVersion)
+		}
+		// 任意位置截断都被识别为数据提前结束
+		for length := 0; length < len(c.raw); length++ {
+			if _, err := c.decode(c.raw[:length]); !errors.Is(err, ErrUnexpectedEnd) {
+				t.Fatalf("%s truncated to %d of %d bytes: returned %v, want %v", c.name, length, len(c.raw), err, ErrUnexpectedEnd)
+			}
+		}
+		trailing := append(append([]byte{}, c.raw...), 0)
+		if _, err := c.decode(trailing); err == nil || !strings.Contains(err.Error(), "1 trailing bytes") {
+			t.Errorf("%s with a trailing byte: returned %v, want a trailing bytes error", c.name, err)
+		}
+	}
+}
+
+func TestBlockHeaderHashVector(t *testing.T) {
+	header := NewBlockHeader("0000", "ABCD", 42)
+	header.SetTimestamp(1700000000)
+	header.SetBits(0x1f00ffff)
+	// 版本号、区块头版本、两个带长度前缀的字符串、时间戳、目标值与随机数，均为大端序
+	wantEncoding := "01" + "0000000000000001" + "00000004" + "30303030" + "00000004" + "41424344" +
+		"000000006553f100" + "1f00ffff" + "000000000000002a"
+	if encoding := hex.EncodeToString(header.Encode()); encoding != wantEncoding {
+		t.Fatalf("header encodes to %s, want %s", encoding, wantEncoding)
+	}
+	wantHash := "6C01D2D01C613BE0A25C22C6E7F8496E467B354ACC0A79E94072DA4AFA9332A8"
+	if hash := header.Hash(); hash != wantHash {
+		t.Fatalf("header hash %s, want %s", hash, wantHash)
+	}
+}
@@ -0,0 +1,63 @@
+package data
+
+import "strconv"
+
+// Outpoint 唯一标识一个交易输出，由产生该输出的交易标识与输出在交易中的序号组成。
+// UTXO 集合以 Outpoint 为键保存未花费的输出，交易输入同样通过 Outpoint 引用其花费的输出。
+type Outpoint struct {
+	txID  string // 产生该输出的交易的 TxID
+	index int    // 输出在交易输出列表中的序号，从 0 开始
+}
+
+// NewOutpoint 创建一个新的 Outpoint。
+// 参数:
+// - txID: 产生该输出的交易标识。
+// - index: 输出在交易中的序号。
+// 返回值:
+// 返回新创建的 Outpoint。
+func NewOutpoint(txID string, index int) Outpoint {
+	return Outpoint{txID: txID, index: index}
+}
+
+func (o Outpoint) GetTxID() string {
+	return o.txID
+}
+
+func (o Outpoint) GetIndex() int {
+	return o.index
+}
+
+// NewCoinbaseOutpoint 返回 coinbase 交易输入使用的空 Outpoint，其序号记录区块高度，
+// 使不同高度的 coinbase 交易即使输出相同也有不同的交易标识。
+func NewCoinbaseOutpoint(height int) Outpoint {
+	return Outpoint{index: height}
+}
+
+// IsNull 判断是否为不引用任何输出的空 Outpoint。
+func (o Outpoint) IsNull() bool {
+	return o.txID == ""
+}
+
+// String 返回 "TxID:序号" 形式的字符串。
+func (o Outpoint) String() string {
+	return o.txID + ":" + strconv.Itoa(o.index)
+}
+
+// Less 按交易标识、输出序号的顺序比较两个 Outpoint，用于得到确定的遍历顺序。
+func (o Outpoint) Less(other Outpoint) bool {
+	if o.txID != other.txID {
+		return o.txID < other.txID
+	}
+	return o.index < other.index
+}
+
+// encodeTo 将 Outpoint 写入编码器。
+func (o Outpoint) encodeTo(e *Encoder) {
+	e.WriteString(o.txID)
+	e.WriteInt(o.index)
+}
+
+// decodeOutpoint 从解码器中读取 Outpoint。
+func decodeOutpoint(d *Decoder) Outpoint {
+	return Outpoint{txID: d.ReadString(), index: d.ReadInt()}
+}
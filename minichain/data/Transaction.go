@@ -3,7 +3,6 @@ package data
 import (
 	"Go-Minichain/utils"
 	"crypto/ecdsa"
-	"crypto/elliptic"
 	"strconv"
 	"strings"
 	"time"
@@ -11,62 +10,225 @@ import (
 
 /**
  * 对交易的抽象
+ *
+ * 交易输入通过 Outpoint（交易标识 + 输出序号）引用此前交易的输出，并携带所有者的解锁签名；
+ * 交易输出即新产生的 UTXO，包含金额以及锁定该输出的公钥哈希。
+ * 交易只包含可以序列化的数据，不依赖同一进程中的 UTXO 对象，因此可以在节点之间传递并被独立验证。
+ * 交易可以声明自己允许被替换（replace-by-fee）：在其确认之前，花费相同输出且支付更高手续费的交易可以在交易池中取代它。
  */
 
 type Transaction struct {
-	timestamp     int
-	inUTXO        []*UTXO
-	outUTXO       []*UTXO
-	sendSign      []byte
-	sendPublicKey ecdsa.PublicKey
-}
-
-func NewTransaction(inUTXO []*UTXO, outUTXO []*UTXO, sendSign []byte, sendPublicKey ecdsa.PublicKey) *Transaction {
-	return &Transaction{
-		inUTXO:        inUTXO,
-		outUTXO:       outUTXO,
-		sendSign:      sendSign,
-		sendPublicKey: sendPublicKey,
-		timestamp:     time.Now().Nanosecond(),
+	timestamp   int
+	replaceable bool
+	inputs      []*TxInput
+	outUTXO     []*UTXO
+}
+
+// NewTransaction 创建一笔新交易，并根据交易标识为每个输出设置其位置（Outpoint）。
+// 交易输入需要随后通过 Sign 或 SignInput 签名。
+// 参数:
+// - inputs: 交易输入。
+// - outUTXO: 交易产生的新 UTXO。
+// 返回值:
+// 返回指向新交易的指针。
+func NewTransaction(inputs []*TxInput, outUTXO []*UTXO) *Transaction {
+	t := &Transaction{
+		inputs:    inputs,
+		outUTXO:   outUTXO,
+		timestamp: int(time.Now().Unix()),
 	}
+	t.assignOutpoints()
+	return t
 }
 
-func (t *Transaction) GetInUTXOs() []*UTXO {
-	return t.inUTXO
+// NewCoinbaseTransaction 创建区块中的 coinbase 交易，用于向矿工发放挖矿奖励与交易手续费。
+// coinbase 交易只有一个不引用任何输出、也没有签名的输入，其 Outpoint 序号记录区块高度。
+// 参数:
+// - height: 区块高度。
+// - outUTXO: 发放奖励的输出。
+// 返回值:
+// 返回指向新交易的指针。
+func NewCoinbaseTransaction(height int, outUTXO []*UTXO) *Transaction {
+	return NewTransaction([]*TxInput{{outpoint: NewCoinbaseOutpoint(height)}}, outUTXO)
 }
 
-func (t *Transaction) GetOutUTXOs() []*UTXO {
-	return t.outUTXO
+// IsCoinbase 判断是否为 coinbase 交易。
+func (t *Transaction) IsCoinbase() bool {
+	return len(t.inputs) == 1 && t.inputs[0].outpoint.IsNull()
+}
+
+// assignOutpoints 根据交易标识设置每个输出的位置，交易内容（包括签名）改变后需要重新设置。
+func (t *Transaction) assignOutpoints() {
+	txID := t.TxID()
+	for i, out := range t.outUTXO {
+		out.outpoint = NewOutpoint(txID, i)
+	}
 }
 
-func (t *Transaction) GeSendSign() []byte {
-	return t.sendSign
+func (t *Transaction) GetInputs() []*TxInput {
+	return t.inputs
 }
 
-func (t *Transaction) GetSendPublicKey() ecdsa.PublicKey {
-	return t.sendPublicKey
+func (t *Transaction) GetOutUTXOs() []*UTXO {
+	return t.outUTXO
 }
+
 func (t *Transaction) GetTimeStamp() int {
 	return t.timestamp
 }
 
-func (t *Transaction) ToString() string {
-	publicKey := t.sendPublicKey
-	publicKeyBytes := elliptic.Marshal(publicKey, publicKey.X, publicKey.Y)
+// IsReplaceable 判断交易是否声明了允许在交易池中被支付更高手续费的冲突交易替换。
+func (t *Transaction) IsReplaceable() bool {
+	return t.replaceable
+}
 
-	inUTXOStrings := make([]string, len(t.inUTXO))
-	for i, iu := range t.inUTXO {
-		inUTXOStrings[i] = iu.ToString()
+// SetReplaceable 设置交易是否允许被替换。该标记包含在交易签名数据中，需要在签名之前设置。
+func (t *Transaction) SetReplaceable(replaceable bool) {
+	t.replaceable = replaceable
+	t.assignOutpoints()
+}
+
+// SetTimestamp 设置交易的时间戳（Unix 时间，单位为秒），用于由注入的时钟而不是本地时间决定交易标识。时间戳包含在交易签名数据中，需要在签名之前设置。
+func (t *Transaction) SetTimestamp(timestamp int) {
+	t.timestamp = timestamp
+	t.assignOutpoints()
+}
+
+// GetOutputAmount 返回交易全部输出的金额之和。
+// 交易输入只引用此前的输出，输入金额与手续费（输入金额之和减去输出金额之和）需要结合 UTXO 集合计算。
+func (t *Transaction) GetOutputAmount() int {
+	amount := 0
+	for _, out := range t.outUTXO {
+		amount += out.amount
+	}
+	return amount
+}
+
+// Size 返回交易规范编码的字节数，手续费率按该大小计算。
+func (t *Transaction) Size() int {
+	return len(t.Encode())
+}
+
+// SigningBytes 返回交易输入签名的数据：时间戳、是否允许替换、全部输入引用的 Outpoint 以及全部输出，不包含签名本身。
+func (t *Transaction) SigningBytes() []byte {
+	e := NewEncoder()
+	e.WriteInt(t.timestamp)
+	e.WriteBool(t.replaceable)
+	e.WriteUint32(uint32(len(t.inputs)))
+	for _, in := range t.inputs {
+		in.outpoint.encodeTo(e)
+	}
+	encodeOutputs(e, t.outUTXO)
+	return e.Bytes()
+}
+
+// SignInput 使用私钥为指定的交易输入签名。
+// 参数:
+// - index: 交易输入的序号。
+// - privateKey: 被花费输出的所有者私钥。
+func (t *Transaction) SignInput(index int, privateKey *ecdsa.PrivateKey) {
+	in := t.inputs[index]
+	in.signature = utils.Signature(t.SigningBytes(), privateKey)
+	in.publicKey = privateKey.PublicKey
+	t.assignOutpoints()
+}
+
+// Sign 使用同一个私钥为全部交易输入签名，适用于所有输入都属于同一个账户的交易。
+func (t *Transaction) Sign(privateKey *ecdsa.PrivateKey) {
+	signature := utils.Signature(t.SigningBytes(), privateKey)
+	for _, in := range t.inputs {
+		in.signature = signature
+		in.publicKey = privateKey.PublicKey
+	}
+	t.assignOutpoints()
+}
+
+// VerifyInput 检查指定交易输入的签名是否由该输入携带的公钥对交易签名数据生成。
+// 公钥是否有权花费被引用的输出需要由调用方对照该输出的公钥哈希检查。
+func (t *Transaction) VerifyInput(index int) bool {
+	in := t.inputs[index]
+	publicKey := in.publicKey
+	if publicKey.Curve == nil {
+		return false
+	}
+	return utils.Verify(t.SigningBytes(), in.signature, &publicKey)
+}
+
+// UnlockInput 使用被引用输出的锁定脚本（与 UTXO.UnlockScript 相同）检查指定的交易输入：
+// 输入携带的公钥哈希必须与输出的公钥哈希一致，且签名为该公钥对交易签名数据的有效签名。
+// 参数:
+// - index: 交易输入的序号。
+// - utxo: 该输入引用的输出。
+// 返回值:
+// 返回布尔值，表示输入是否有权花费该输出。
+func (t *Transaction) UnlockInput(index int, utxo *UTXO) bool {
+	in := t.inputs[index]
+	return utxo.runScript(t.SigningBytes(), in.signature, in.publicKey)
+}
+
+func (t *Transaction) ToString() string {
+	inputStrings := make([]string, len(t.inputs))
+	for i, in := range t.inputs {
+		inputStrings[i] = in.ToString()
 	}
 	outUTXOStrings := make([]string, len(t.outUTXO))
 	for i, ou := range t.outUTXO {
 		outUTXOStrings[i] = ou.ToString()
 	}
 	return "Transaction{" +
-		"inUTXO=" + strings.Join(inUTXOStrings, "\n") +
+		"inputs=" + strings.Join(inputStrings, "\n") +
 		", outUTXO=" + strings.Join(outUTXOStrings, "\n") +
-		", sendSign=" + utils.Byte2HexString(t.sendSign) +
-		", sendPublicKey=" + utils.Byte2HexString(publicKeyBytes) +
 		", timestamp=" + strconv.Itoa(t.timestamp) +
+		", replaceable=" + strconv.FormatBool(t.replaceable) +
 		"}"
 }
+
+// Encode 返回交易的规范二进制编码。
+func (t *Transaction) Encode() []byte {
+	e := NewEncoder()
+	t.encodeTo(e)
+	return e.Bytes()
+}
+
+// encodeTo 将交易的全部字段写入编码器。
+func (t *Transaction) encodeTo(e *Encoder) {
+	e.WriteInt(t.timestamp)
+	e.WriteBool(t.replaceable)
+	encodeTxInputs(e, t.inputs)
+	encodeOutputs(e, t.outUTXO)
+}
+
+// decodeTransaction 从解码器中读取一笔交易。
+func decodeTransaction(d *Decoder) *Transaction {
+	t := new(Transaction)
+	t.timestamp = d.ReadInt()
+	t.replaceable = d.ReadBool()
+	t.inputs = decodeTxInputs(d)
+	t.outUTXO = decodeOutputs(d)
+	if d.Err() != nil {
+		return t
+	}
+	t.assignOutpoints()
+	return t
+}
+
+// DecodeTransaction 从规范二进制编码中恢复交易。
+// 参数:
+// - b: Encode 生成的字节序列。
+// 返回值:
+// 返回解码得到的交易以及可能出现的错误。
+func DecodeTransaction(b []byte) (*Transaction, error) {
+	d := NewDecoder(b)
+	t := decodeTransaction(d)
+	if err := d.Finish(); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// TxID 返回交易的标识，即交易规范编码的 SHA-256 哈希值（大写十六进制）。
+// 该值同时作为 Merkle 树的叶子节点以及 SPV 证明中的交易哈希。
+// 编码中包含签名，但 utils.Verify 只接受唯一的 low-S 签名，转发交易的节点无法在签名仍然有效的前提下改变交易标识。
+func (t *Transaction) TxID() string {
+	return utils.GetBytesSha256Digest(t.Encode())
+}
@@ -0,0 +1,81 @@
+package data
+
+import (
+	"Go-Minichain/utils"
+	"math/big"
+	"testing"
+)
+
+// signedTransaction 返回一笔由固定种子派生的账户签名、花费两个输出的交易。
+func signedTransaction(t *testing.T) (*Transaction, *Account) {
+	t.Helper()
+	owner := NewAccountFromSeed([]byte("owner"))
+	payee := NewAccountFromSeed([]byte("payee"))
+	inputs := []*TxInput{
+		NewTxInput(NewOutpoint(utils.GetSha256Digest("funding"), 0), owner.GetPublicKey()),
+		NewTxInput(NewOutpoint(utils.GetSha256Digest("funding"), 1), owner.GetPublicKey()),
+	}
+	tx := NewTransaction(inputs, []*UTXO{NewUTXO(70, payee.GetPublicKey()), NewUTXO(20, owner.GetPublicKey())})
+	tx.SetTimestamp(1700000000)
+	tx.Sign(owner.GetPrivateKey())
+	return tx, owner
+}
+
+func TestTxIDIsStable(t *testing.T) {
+	tx, owner := signedTransaction(t)
+	txID := tx.TxID()
+
+	// 签名是确定性的，重新签名不改变交易标识
+	tx.Sign(owner.GetPrivateKey())
+	if tx.TxID() != txID {
+		t.Fatalf("re-signing changed the txid from %s to %s", txID, tx.TxID())
+	}
+	// 编码往返后交易标识与输出位置不变
+	decoded, err := DecodeTransaction(tx.Encode())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded.TxID() != txID {
+		t.Fatalf("decoded txid %s, want %s", decoded.TxID(), txID)
+	}
+	for i, out := range decoded.GetOutUTXOs() {
+		if out.GetOutpoint() != NewOutpoint(txID, i) {
+			t.Fatalf("output %d at %v, want %v", i, out.GetOutpoint(), NewOutpoint(txID, i))
+		}
+	}
+}
+
+func TestMalleatedSignatureIsRejected(t *testing.T) {
+	tx, owner := signedTransaction(t)
+	n := owner.GetPrivateKey().Curve.Params().N
+	half := new(big.Int).Rsh(n, 1)
+	for i, in := range tx.GetInputs() {
+		if s := new(big.Int).SetBytes(in.signature[32:]); s.Cmp(half) > 0 {
+			t.Fatalf("input %d: signature is not low-S", i)
+		}
+		if !tx.VerifyInput(i) {
+			t.Fatalf("input %d: valid signature rejected", i)
+		}
+	}
+
+	// (r, N-s) 在数学上也是有效的 ECDSA 签名，改写后交易标识会变化，因此必须被拒绝
+	malleated, err := DecodeTransaction(tx.Encode())
+	if err != nil {
+		t.Fatal(err)
+	}
+	in := malleated.GetInputs()[0]
+	signature := append([]byte(nil), in.signature...)
+	s := new(big.Int).SetBytes(signature[32:])
+	new(big.Int).Sub(n, s).FillBytes(signature[32:])
+	in.signature = signature
+	malleated.assignOutpoints()
+	if malleated.TxID() == tx.TxID() {
+		t.Fatal("changing the signature did not change the txid")
+	}
+	if malleated.VerifyInput(0) {
+		t.Fatal("high-S signature accepted, the txid is malleable")
+	}
+	if !malleated.VerifyInput(1) {
+		t.Fatal("untouched input rejected")
+	}
+}
@@ -0,0 +1,106 @@
+package data
+
+import (
+	"Go-Minichain/utils"
+	"crypto/ecdsa"
+)
+
+// TxInput 定义了交易输入，通过 Outpoint 引用此前某笔交易的输出，并携带解锁该输出的签名。
+// 字段说明：
+// - outpoint: 被花费的输出的位置。
+// - signature: 输出所有者对交易签名数据（Transaction.SigningBytes）的签名。
+// - publicKey: 签名公钥，其哈希必须与被花费输出中的公钥哈希一致。
+type TxInput struct {
+	outpoint  Outpoint
+	signature []byte
+	publicKey ecdsa.PublicKey
+}
+
+// NewTxInput 创建一个尚未签名的交易输入，签名需要在交易创建后通过 Transaction.SignInput 完成。
+// 参数:
+// - outpoint: 被花费的输出的位置。
+// - publicKey: 被花费输出的所有者公钥。
+// 返回值:
+// 返回指向新交易输入的指针。
+func NewTxInput(outpoint Outpoint, publicKey ecdsa.PublicKey) *TxInput {
+	return &TxInput{outpoint: outpoint, publicKey: publicKey}
+}
+
+func (in *TxInput) GetOutpoint() Outpoint {
+	return in.outpoint
+}
+
+func (in *TxInput) GetSignature() []byte {
+	return in.signature
+}
+
+func (in *TxInput) GetPublicKey() ecdsa.PublicKey {
+	return in.publicKey
+}
+
+// GetWalletAddress 返回签名公钥对应的钱包地址，即被花费输出的所有者地址，coinbase 输入返回空字符串。
+func (in *TxInput) GetWalletAddress() string {
+	if in.publicKey.Curve == nil {
+		return ""
+	}
+	return WalletAddress(in.publicKey)
+}
+
+// marshalPublicKey 序列化签名公钥，coinbase 输入没有公钥，编码为空字节序列。
+func (in *TxInput) marshalPublicKey() []byte {
+	if in.publicKey.Curve == nil {
+		return []byte{}
+	}
+	return utils.MarshalPublicKey(in.publicKey)
+}
+
+// ToString 将交易输入转换为字符串格式。
+func (in *TxInput) ToString() string {
+	return "TxInput{" +
+		"outpoint=" + in.outpoint.String() + "," +
+		"signature=" + utils.Byte2HexString(in.signature) + "," +
+		"publicKey=" + utils.Byte2HexString(in.marshalPublicKey()) +
+		"}"
+}
+
+// encodeTo 将交易输入写入编码器。
+func (in *TxInput) encodeTo(e *Encoder) {
+	in.outpoint.encodeTo(e)
+	e.WriteBytes(in.signature)
+	e.WriteBytes(in.marshalPublicKey())
+}
+
+// decodeTxInput 从解码器中读取交易输入。
+func decodeTxInput(d *Decoder) *TxInput {
+	in := &TxInput{outpoint: decodeOutpoint(d)}
+	in.signature = d.ReadBytes()
+	publicKeyBytes := d.ReadBytes()
+	if d.Err() != nil || len(publicKeyBytes) == 0 {
+		return in
+	}
+	publicKey, err := utils.UnmarshalPublicKey(publicKeyBytes)
+	if err != nil {
+		d.err = err
+		return in
+	}
+	in.publicKey = publicKey
+	return in
+}
+
+// encodeTxInputs 写入带元素个数前缀的交易输入列表。
+func encodeTxInputs(e *Encoder, inputs []*TxInput) {
+	e.WriteUint32(uint32(len(inputs)))
+	for _, in := range inputs {
+		in.encodeTo(e)
+	}
+}
+
+// decodeTxInputs 读取带元素个数前缀的交易输入列表。
+func decodeTxInputs(d *Decoder) []*TxInput {
+	n := d.ReadCount(20)
+	inputs := make([]*TxInput, 0, n)
+	for i := 0; i < n && d.Err() == nil; i++ {
+		inputs = append(inputs, decodeTxInput(d))
+	}
+	return inputs
+}
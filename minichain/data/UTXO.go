@@ -5,42 +5,78 @@ import (
 	"bytes"
 	"crypto/ecdsa"
 	"crypto/elliptic"
-	"fmt"
 	"strconv"
 )
 
 // UTXO 定义了一个未花费的交易输出（UTXO）结构体。
+// 钱包地址总是由公钥哈希派生，不参与编码，因此输出的地址与能够花费它的公钥不会不一致。
 type UTXO struct {
-	walletAddress string // 接收方的钱包地址
-	amount        int    // 该 UTXO 所包含的金额
-	publicKeyHash []byte // 接收方公钥的哈希值，用于验证所有权
-	used          bool   // 该 UTXO 是否已经被使用
+	outpoint      Outpoint // 该输出的位置，在其所属交易创建时确定
+	walletAddress string   // 接收方的钱包地址，由 publicKeyHash 派生
+	amount        int      // 该 UTXO 所包含的金额
+	publicKeyHash []byte   // 接收方公钥的哈希值，用于验证所有权
 }
 
 // NewUTXO 创建一个新的 UTXO 实例。
 // 参数:
-// - address: 接收方的钱包地址。
 // - amount: 该 UTXO 所包含的金额。
-// - publicKey: 接收方的公钥。
+// - publicKey: 接收方的公钥，钱包地址由其公钥哈希派生。
 // 返回值:
 // 返回一个指向新创建的 UTXO 实例的指针。
-func NewUTXO(address string, amount int, publicKey ecdsa.PublicKey) *UTXO {
-	publicKeyBytes := elliptic.Marshal(publicKey, publicKey.X, publicKey.Y)
+func NewUTXO(amount int, publicKey ecdsa.PublicKey) *UTXO {
+	return newUTXO(amount, PublicKeyHash(publicKey))
+}
+
+// newUTXO 根据公钥哈希创建 UTXO，并派生其钱包地址。
+func newUTXO(amount int, publicKeyHash []byte) *UTXO {
 	return &UTXO{
-		walletAddress: address,
+		walletAddress: walletAddressFromHash(publicKeyHash),
 		amount:        amount,
-		publicKeyHash: utils.Ripemd160Digest(utils.Sha256Digest(publicKeyBytes)),
-		used:          false,
+		publicKeyHash: publicKeyHash,
+	}
+}
+
+// NewUTXOToAddress 创建一个付款给钱包地址的 UTXO，公钥哈希从地址中取出，不需要知道接收方的公钥。
+// 参数:
+// - address: 接收方的钱包地址。
+// - amount: 该 UTXO 所包含的金额。
+// 返回值:
+// 返回一个指向新创建的 UTXO 实例的指针；地址不合法时返回 ErrInvalidAddress。
+func NewUTXOToAddress(address string, amount int) (*UTXO, error) {
+	publicKeyHash, err := ParseWalletAddress(address)
+	if err != nil {
+		return nil, err
 	}
+	return newUTXO(amount, publicKeyHash), nil
+}
+
+// PublicKeyHash 计算公钥的哈希值，即 UTXO 锁定脚本中保存的公钥哈希。
+// 参数:
+// - publicKey: 公钥。
+// 返回值:
+// 返回公钥哈希的字节序列。
+func PublicKeyHash(publicKey ecdsa.PublicKey) []byte {
+	publicKeyBytes := elliptic.Marshal(publicKey, publicKey.X, publicKey.Y)
+	return utils.Ripemd160Digest(utils.Sha256Digest(publicKeyBytes))
 }
 
 // UnlockScript 验证签名是否正确，并检查公钥哈希是否匹配。
+// 签名的数据为公钥本身，用于确认调用方持有该 UTXO 对应的私钥。
 // 参数:
 // - sign: 签名数据。
 // - publicKey: 公钥。
 // 返回值:
 // 返回布尔值，表示签名和公钥是否通过验证。
 func (utxo *UTXO) UnlockScript(sign []byte, publicKey ecdsa.PublicKey) bool {
+	publicKeyBytes := elliptic.Marshal(publicKey, publicKey.X, publicKey.Y)
+	return utxo.runScript(publicKeyBytes, sign, publicKey)
+}
+
+// runScript 执行锁定脚本：检查公钥哈希与 UTXO 中保存的公钥哈希是否一致，并验证 sign 是否为 message 的有效签名。
+func (utxo *UTXO) runScript(message []byte, sign []byte, publicKey ecdsa.PublicKey) bool {
+	if publicKey.Curve == nil {
+		return false
+	}
 	stack := make([][]byte, 0)
 	// 将签名压入栈中。
 	stack = append(stack, sign)
@@ -63,25 +99,16 @@ func (utxo *UTXO) UnlockScript(sign []byte, publicKey ecdsa.PublicKey) bool {
 		return false
 	}
 	// 验证签名是否正确。
-	publicKeyEncoded := stack[len(stack)-1]
-	sign1 := stack[len(stack)-2]
-	return utils.Verify(publicKeyEncoded, sign1, &publicKey)
-}
-
-// SetUsed 标记该 UTXO 已被使用。
-// 如果该 UTXO 已经被标记为已使用，则会触发 panic。
-func (utxo *UTXO) SetUsed() {
-	if utxo.used {
-		panic("UTXO already used")
-	}
-	utxo.used = true
+	stack = stack[:len(stack)-1]
+	sign1 := stack[len(stack)-1]
+	return utils.Verify(message, sign1, &publicKey)
 }
 
-// IsUsed 检查该 UTXO 是否已被使用。
+// GetOutpoint 获取该 UTXO 的位置，即产生它的交易标识与其在交易输出中的序号。
 // 返回值:
-// 返回布尔值，表示该 UTXO 是否已被使用。
-func (utxo *UTXO) IsUsed() bool {
-	return utxo.used
+// 返回 Outpoint，UTXO 尚未加入交易时为零值。
+func (utxo *UTXO) GetOutpoint() Outpoint {
+	return utxo.outpoint
 }
 
 // GetWalletAddress 获取接收方的钱包地址。
@@ -110,18 +137,76 @@ func (utxo *UTXO) GetPublicKeyHash() []byte {
 // 返回字符串类型的 UTXO 表示。
 func (utxo *UTXO) ToString() string {
 	return "UTXO{" +
+		"outpoint=" + utxo.outpoint.String() + "," +
 		"walletAddress=" + utxo.walletAddress + "," +
 		"amount=" + strconv.Itoa(utxo.amount) + "," +
 		"publicKeyHash=" + utils.Byte2HexString(utxo.publicKeyHash) +
 		"}"
 }
 
-// UTXO2Bytes 将输入和输出的 UTXO 列表序列化为字节数组。
+// encodeContentTo 将 UTXO 的内容（不含位置）写入编码器。
+// 交易输出的位置由交易标识决定，因此交易输出只编码内容，避免交易标识依赖其自身；钱包地址由公钥哈希派生，同样不编码。
+func (utxo *UTXO) encodeContentTo(e *Encoder) {
+	e.WriteInt(utxo.amount)
+	e.WriteBytes(utxo.publicKeyHash)
+}
+
+// encodeTo 将 UTXO 的位置与内容写入编码器，用于单独编码的 UTXO。
+func (utxo *UTXO) encodeTo(e *Encoder) {
+	utxo.outpoint.encodeTo(e)
+	utxo.encodeContentTo(e)
+}
+
+// Encode 返回 UTXO 的规范二进制编码，包含其位置与内容。
+func (utxo *UTXO) Encode() []byte {
+	e := NewEncoder()
+	utxo.encodeTo(e)
+	return e.Bytes()
+}
+
+// decodeUTXOContent 从解码器中读取 UTXO 的内容，位置需要由调用方设置。
+func decodeUTXOContent(d *Decoder) *UTXO {
+	amount := d.ReadInt()
+	publicKeyHash := d.ReadBytes()
+	return newUTXO(amount, publicKeyHash)
+}
+
+// decodeUTXO 从解码器中读取带位置的 UTXO。
+func decodeUTXO(d *Decoder) *UTXO {
+	outpoint := decodeOutpoint(d)
+	utxo := decodeUTXOContent(d)
+	utxo.outpoint = outpoint
+	return utxo
+}
+
+// DecodeUTXO 从规范二进制编码中恢复 UTXO。
 // 参数:
-// - inUTXO: 输入的 UTXO 列表，表示未花费的交易输出集合。
-// - outUTXO: 输出的 UTXO 列表，表示新的未花费交易输出集合。
+// - b: Encode 生成的字节序列。
 // 返回值:
-// 返回字节数组类型的序列化结果。
-func UTXO2Bytes(inUTXO []*UTXO, outUTXO []*UTXO) []byte {
-	return []byte(fmt.Sprint(inUTXO) + fmt.Sprint(outUTXO))
+// 返回解码得到的 UTXO 以及可能出现的错误。
+func DecodeUTXO(b []byte) (*UTXO, error) {
+	d := NewDecoder(b)
+	utxo := decodeUTXO(d)
+	if err := d.Finish(); err != nil {
+		return nil, err
+	}
+	return utxo, nil
+}
+
+// encodeOutputs 写入带元素个数前缀的交易输出列表，输出只包含内容。
+func encodeOutputs(e *Encoder, utxos []*UTXO) {
+	e.WriteUint32(uint32(len(utxos)))
+	for _, utxo := range utxos {
+		utxo.encodeContentTo(e)
+	}
+}
+
+// decodeOutputs 读取带元素个数前缀的交易输出列表，输出的位置需要在计算出交易标识后设置。
+func decodeOutputs(d *Decoder) []*UTXO {
+	n := d.ReadCount(16)
+	utxos := make([]*UTXO, 0, n)
+	for i := 0; i < n && d.Err() == nil; i++ {
+		utxos = append(utxos, decodeUTXOContent(d))
+	}
+	return utxos
 }
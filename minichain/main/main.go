@@ -1,8 +1,64 @@
 package main
 
-import "Go-Minichain/network"
+import (
+	"Go-Minichain/config"
+	"Go-Minichain/network"
+	"Go-Minichain/rpc"
+	"Go-Minichain/workload"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
 
 func main() {
-	network := network.NewNetWork()
-	network.Start()
+	// 依次从默认值、配置文件、环境变量与命令行参数加载配置
+	cfg, err := config.Load(os.Args[0], os.Args[1:], os.LookupEnv)
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err == nil {
+		_, err = workload.ByName(cfg.GetWorkload())
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Config error: "+err.Error())
+		os.Exit(2)
+	}
+	config.MiniChainConfig = cfg
+
+	if blocks := config.MiniChainConfig.GetSimulationBlocks(); blocks > 0 {
+		simulate(blocks)
+		return
+	}
+	// 收到中断或终止信号、或者通过 JSON-RPC 调用 stop 时停止矿工与交易池，并关闭区块存储
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	n := network.NewNetWork()
+	if addr := config.MiniChainConfig.GetRPCListen(); addr != "" {
+		// 节点从存储中恢复区块期间 JSON-RPC 服务已经可以连接，查询方法返回正在加载的错误
+		if err := rpc.NewServer(n, addr, config.MiniChainConfig.GetRPCToken(), stop).Start(ctx); err != nil {
+			fmt.Fprintln(os.Stderr, "Start JSON-RPC server error: "+err.Error())
+			os.Exit(1)
+		}
+	}
+	n.Start(ctx)
+}
+
+// simulate 以确定性模拟方式生成指定个数的区块，相同种子的两次运行输出相同的最新区块哈希。
+func simulate(blocks int) {
+	seed := config.MiniChainConfig.GetWorkloadSeed()
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	n := network.NewSimulatedNetWork(seed, network.NewSimulatedClock(network.SimulationEpoch))
+	if err := n.Simulate(blocks); err != nil {
+		fmt.Println("Simulation error: " + err.Error())
+		os.Exit(1)
+	}
+	fmt.Println("Simulated", blocks, "Blocks with seed", seed)
+	fmt.Println("And the hash of newest Block is : " + n.GetNewestBlock().Hash())
 }
@@ -0,0 +1,380 @@
+package main
+
+import (
+	"Go-Minichain/data"
+	"Go-Minichain/rpc"
+	"Go-Minichain/utils"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+)
+
+// newFlagSet 创建子命令的参数集合，子命令之后同样可以指定 -json。
+func (c *cli) newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.BoolVar(&c.json, "json", c.json, "print the JSON returned by the node")
+	c.flags = fs
+	return fs
+}
+
+// parse 解析子命令的参数并检查位置参数的个数，参数与位置参数可以交错出现，"--" 之后的全部作为位置参数。
+// 参数:
+// - fs: 子命令的参数集合。
+// - args: 子命令名称之后的参数。
+// - min / max: 位置参数的最少与最多个数。
+// 返回值:
+// 返回位置参数；参数错误时返回 *usageError，指定 -h 时返回 flag.ErrHelp。
+func (c *cli) parse(fs *flag.FlagSet, args []string, min int, max int) ([]string, error) {
+	positional := make([]string, 0)
+	for {
+		if err := fs.Parse(args); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				return nil, err
+			}
+			return nil, newUsageError("%s", err.Error())
+		}
+		rest := fs.Args()
+		if len(rest) == 0 {
+			break
+		}
+		if consumed := len(args) - len(rest); consumed > 0 && args[consumed-1] == "--" {
+			positional = append(positional, rest...)
+			break
+		}
+		positional = append(positional, rest[0])
+		args = rest[1:]
+	}
+	if len(positional) < min {
+		return nil, newUsageError("missing arguments")
+	}
+	if len(positional) > max {
+		return nil, newUsageError("too many arguments")
+	}
+	return positional, nil
+}
+
+// printJSON 以缩进格式输出 JSON。
+func (c *cli) printJSON(v interface{}) error {
+	out, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(c.out, string(out))
+	return err
+}
+
+func runInfo(c *cli, args []string) error {
+	if _, err := c.parse(c.newFlagSet("info"), args, 0, 0); err != nil {
+		return err
+	}
+	var info rpc.BlockchainInfoResult
+	if err := c.client.Call("getblockchaininfo", nil, &info); err != nil {
+		return err
+	}
+	if c.json {
+		return c.printJSON(info)
+	}
+	printInfo(c.out, info)
+	return nil
+}
+
+// runBlock 显示区块：-raw 输出原始编码，否则解析原始编码后按区块结构输出。
+func runBlock(c *cli, args []string) error {
+	fs := c.newFlagSet("block")
+	raw := fs.Bool("raw", false, "print the raw block in hex")
+	args, err := c.parse(fs, args, 0, 1)
+	if err != nil {
+		return err
+	}
+	var ref interface{}
+	if len(args) == 0 {
+		var best string
+		if err := c.client.Call("getbestblockhash", nil, &best); err != nil {
+			return err
+		}
+		ref = best
+	} else if height, err := strconv.Atoi(args[0]); err == nil {
+		ref = height
+	} else {
+		ref = args[0]
+	}
+	var rawHex string
+	if err := c.client.Call("getblock", []interface{}{ref, false}, &rawHex); err != nil {
+		return err
+	}
+	if *raw {
+		_, err := fmt.Fprintln(c.out, rawHex)
+		return err
+	}
+	var result rpc.BlockResult
+	if err := c.client.Call("getblock", []interface{}{ref, true}, &result); err != nil {
+		return err
+	}
+	if c.json {
+		return c.printJSON(result)
+	}
+	decoded, err := hex.DecodeString(rawHex)
+	var block *data.Block
+	if err == nil {
+		block, err = data.DecodeBlock(decoded)
+	}
+	if err != nil {
+		return fmt.Errorf("decode block error: %w", err)
+	}
+	printBlock(c.out, result, block)
+	return nil
+}
+
+// runTx 显示交易：-raw 输出原始编码，否则解析原始编码后按交易结构输出，并给出交易在交易池或主链中的位置。
+func runTx(c *cli, args []string) error {
+	fs := c.newFlagSet("tx")
+	raw := fs.Bool("raw", false, "print the raw transaction in hex")
+	args, err := c.parse(fs, args, 1, 1)
+	if err != nil {
+		return err
+	}
+	var result rpc.TransactionResult
+	if err := c.client.Call("gettransaction", []interface{}{args[0]}, &result); err != nil {
+		return err
+	}
+	if *raw {
+		_, err := fmt.Fprintln(c.out, result.Hex)
+		return err
+	}
+	if c.json {
+		return c.printJSON(result)
+	}
+	decoded, err := hex.DecodeString(result.Hex)
+	var tx *data.Transaction
+	if err == nil {
+		tx, err = data.DecodeTransaction(decoded)
+	}
+	if err != nil {
+		return fmt.Errorf("decode transaction error: %w", err)
+	}
+	printTransaction(c.out, result, tx)
+	return nil
+}
+
+func runBalance(c *cli, args []string) error {
+	fs := c.newFlagSet("balance")
+	listUTXOs := fs.Bool("utxos", false, "also list the unspent outputs")
+	mempool := fs.Bool("mempool", false, "list the outputs spendable after the pool transactions instead of the confirmed ones")
+	args, err := c.parse(fs, args, 1, 1)
+	if err != nil {
+		return err
+	}
+	address := args[0]
+	var balance rpc.BalanceResult
+	if err := c.client.Call("getbalance", []interface{}{address}, &balance); err != nil {
+		return err
+	}
+	var utxos []rpc.UnspentResult
+	if *listUTXOs {
+		if err := c.client.Call("listunspent", []interface{}{address, *mempool}, &utxos); err != nil {
+			return err
+		}
+	}
+	if c.json {
+		if !*listUTXOs {
+			return c.printJSON(balance)
+		}
+		return c.printJSON(struct {
+			rpc.BalanceResult
+			UTXOs []rpc.UnspentResult `json:"utxos"`
+		}{balance, utxos})
+	}
+	printBalance(c.out, balance, utxos, *listUTXOs)
+	return nil
+}
+
+// runSend 提交交易的原始编码，参数为 - 时从标准输入读取。
+func runSend(c *cli, args []string) error {
+	fs := c.newFlagSet("send")
+	args, err := c.parse(fs, args, 1, 1)
+	if err != nil {
+		return err
+	}
+	rawHex := args[0]
+	if rawHex == "-" {
+		input, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return fmt.Errorf("read stdin error: %w", err)
+		}
+		rawHex = string(input)
+	}
+	var txID string
+	if err := c.client.Call("sendrawtransaction", []interface{}{strings.TrimSpace(rawHex)}, &txID); err != nil {
+		return err
+	}
+	if c.json {
+		return c.printJSON(map[string]string{"txid": txID})
+	}
+	_, err = fmt.Fprintln(c.out, "Transaction accepted: "+txID)
+	return err
+}
+
+func runMempool(c *cli, args []string) error {
+	fs := c.newFlagSet("mempool")
+	list := fs.Bool("list", false, "also list the transactions by fee rate")
+	if _, err := c.parse(fs, args, 0, 0); err != nil {
+		return err
+	}
+	var info rpc.MempoolInfoResult
+	if err := c.client.Call("getmempoolinfo", nil, &info); err != nil {
+		return err
+	}
+	var txIDs []string
+	if *list {
+		if err := c.client.Call("getrawmempool", nil, &txIDs); err != nil {
+			return err
+		}
+	}
+	if c.json {
+		if !*list {
+			return c.printJSON(info)
+		}
+		return c.printJSON(struct {
+			rpc.MempoolInfoResult
+			Transactions []string `json:"transactions"`
+		}{info, txIDs})
+	}
+	printMempool(c.out, info, txIDs, *list)
+	return nil
+}
+
+// runProof 显示交易的 Merkle 证明，并用证明路径重新计算 Merkle 根，与所在区块的区块头比对。
+func runProof(c *cli, args []string) error {
+	fs := c.newFlagSet("proof")
+	args, err := c.parse(fs, args, 1, 1)
+	if err != nil {
+		return err
+	}
+	var proof rpc.ProofResult
+	if err := c.client.Call("getproof", []interface{}{args[0]}, &proof); err != nil {
+		return err
+	}
+	var block rpc.BlockResult
+	if err := c.client.Call("getblock", []interface{}{proof.BlockHash}, &block); err != nil {
+		return err
+	}
+	root := proofRoot(proof)
+	valid := root == proof.MerkleRoot && root == block.MerkleRoot
+	if c.json {
+		if err := c.printJSON(proof); err != nil {
+			return err
+		}
+	} else {
+		printProof(c.out, proof, root, valid)
+	}
+	if !valid {
+		return errors.New("the proof does not match the Merkle root in the block header")
+	}
+	return nil
+}
+
+func runPeers(c *cli, args []string) error {
+	if _, err := c.parse(c.newFlagSet("peers"), args, 0, 0); err != nil {
+		return err
+	}
+	var peers []rpc.PeerResult
+	if err := c.client.Call("getpeerinfo", nil, &peers); err != nil {
+		return err
+	}
+	if c.json {
+		return c.printJSON(peers)
+	}
+	printPeers(c.out, peers)
+	return nil
+}
+
+// runEvents 订阅节点的事件流，每个事件输出一行，-json 时每行输出一个 JSON 对象，按 Ctrl-C 结束。
+func runEvents(c *cli, args []string) error {
+	fs := c.newFlagSet("events")
+	types := fs.String("types", "", "comma-separated event types: blockconnected, blockdisconnected, txaccepted, txremoved")
+	if _, err := c.parse(fs, args, 0, 0); err != nil {
+		return err
+	}
+	var typeList []string
+	if *types != "" {
+		typeList = strings.Split(*types, ",")
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	err := c.client.SubscribeEvents(ctx, typeList, func(event rpc.EventResult) error {
+		if c.json {
+			out, err := json.Marshal(event)
+			if err != nil {
+				return err
+			}
+			_, err = fmt.Fprintln(c.out, string(out))
+			return err
+		}
+		printEvent(c.out, event)
+		return nil
+	})
+	if ctx.Err() != nil {
+		return nil
+	}
+	return err
+}
+
+func runStop(c *cli, args []string) error {
+	if _, err := c.parse(c.newFlagSet("stop"), args, 0, 0); err != nil {
+		return err
+	}
+	var message string
+	if err := c.client.Call("stop", nil, &message); err != nil {
+		return err
+	}
+	if c.json {
+		return c.printJSON(message)
+	}
+	_, err := fmt.Fprintln(c.out, message)
+	return err
+}
+
+// runCall 调用任意方法，参数能解析为 JSON 时按 JSON 传递，否则作为字符串传递，结果总是以 JSON 输出。
+func runCall(c *cli, args []string) error {
+	fs := c.newFlagSet("call")
+	args, err := c.parse(fs, args, 1, 1<<16)
+	if err != nil {
+		return err
+	}
+	params := make([]interface{}, 0, len(args)-1)
+	for _, arg := range args[1:] {
+		var value interface{}
+		if json.Unmarshal([]byte(arg), &value) == nil {
+			params = append(params, json.RawMessage(arg))
+		} else {
+			params = append(params, arg)
+		}
+	}
+	var result json.RawMessage
+	if err := c.client.Call(args[0], params, &result); err != nil {
+		return err
+	}
+	return c.printJSON(result)
+}
+
+// proofRoot 沿证明路径从交易标识重新计算 Merkle 根，计算方法与 SPV 节点的验证相同。
+func proofRoot(proof rpc.ProofResult) string {
+	hash := proof.TxHash
+	for _, node := range proof.Path {
+		if node.Orientation == "left" {
+			hash = utils.GetSha256Digest(node.Hash + hash)
+		} else {
+			hash = utils.GetSha256Digest(hash + node.Hash)
+		}
+	}
+	return hash
+}
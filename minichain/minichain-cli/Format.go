@@ -0,0 +1,219 @@
+package main
+
+import (
+	"Go-Minichain/data"
+	"Go-Minichain/rpc"
+	"Go-Minichain/wallet"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+	"time"
+)
+
+// formatTime 将 Unix 秒格式化为 UTC 时间。
+func formatTime(seconds int64) string {
+	return time.Unix(seconds, 0).UTC().Format("2006-01-02 15:04:05 UTC")
+}
+
+// formatConfirmations 描述区块的确认数，侧链上的区块确认数为 -1。
+func formatConfirmations(confirmations int) string {
+	if confirmations < 0 {
+		return "not on the main chain"
+	}
+	if confirmations == 1 {
+		return "1 confirmation"
+	}
+	return strconv.Itoa(confirmations) + " confirmations"
+}
+
+func printInfo(w io.Writer, info rpc.BlockchainInfoResult) {
+	status := "synced"
+	if info.Syncing {
+		status = fmt.Sprintf("syncing (%d blocks in flight, %d pending)", info.InFlight, info.Pending)
+	}
+	fmt.Fprintf(w, "Height:       %d\n", info.Height)
+	fmt.Fprintf(w, "Headers:      %d\n", info.Headers)
+	fmt.Fprintf(w, "Best block:   %s\n", info.BestBlockHash)
+	fmt.Fprintf(w, "Next bits:    0x%08x\n", info.Bits)
+	fmt.Fprintf(w, "Total work:   %s\n", info.TotalWork)
+	fmt.Fprintf(w, "Median time:  %s\n", formatTime(info.MedianTime))
+	fmt.Fprintf(w, "Sync:         %s\n", status)
+	fmt.Fprintf(w, "Peers:        %d\n", info.Peers)
+}
+
+// printBlock 按区块头与区块体的结构输出区块，位置信息来自节点返回的结果。
+func printBlock(w io.Writer, info rpc.BlockResult, block *data.Block) {
+	header := block.GetBlockHeader()
+	body := block.GetBlockBody()
+	txs := body.GetTransctions()
+	fmt.Fprintf(w, "Block %s\n", block.Hash())
+	fmt.Fprintf(w, "  Height:         %d (%s)\n", info.Height, formatConfirmations(info.Confirmations))
+	fmt.Fprintf(w, "  Version:        %d\n", header.GetVersion())
+	fmt.Fprintf(w, "  Previous block: %s\n", header.GetPreBlockHash())
+	fmt.Fprintf(w, "  Merkle root:    %s\n", header.GetMerkleRootHash())
+	fmt.Fprintf(w, "  Time:           %s\n", formatTime(header.GetTimestamp()))
+	fmt.Fprintf(w, "  Bits:           0x%08x\n", header.GetBits())
+	fmt.Fprintf(w, "  Nonce:          %d\n", header.GetNonce())
+	fmt.Fprintf(w, "  Size:           %d bytes\n", info.Size)
+	fmt.Fprintf(w, "  Transactions:   %d\n", len(txs))
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for i := range txs {
+		tx := &txs[i]
+		kind := fmt.Sprintf("%d in / %d out", len(tx.GetInputs()), len(tx.GetOutUTXOs()))
+		if tx.IsCoinbase() {
+			kind = "coinbase"
+		}
+		fmt.Fprintf(tw, "    #%d\t%s\t%s\t%d\n", i, tx.TxID(), kind, tx.GetOutputAmount())
+	}
+	tw.Flush()
+}
+
+// printTransaction 按输入与输出的结构输出交易，位置与手续费来自节点返回的结果。
+func printTransaction(w io.Writer, info rpc.TransactionResult, tx *data.Transaction) {
+	fmt.Fprintf(w, "Transaction %s\n", tx.TxID())
+	if info.BlockHash == "" {
+		status := "in the transaction pool"
+		if info.Fee != nil {
+			status += ", fee " + strconv.Itoa(*info.Fee)
+		}
+		fmt.Fprintf(w, "  Status:       %s\n", status)
+	} else {
+		fmt.Fprintf(w, "  Status:       in block %s at height %d (%s)\n", info.BlockHash, info.Height,
+			formatConfirmations(info.Confirmations))
+	}
+	fmt.Fprintf(w, "  Size:         %d bytes\n", tx.Size())
+	fmt.Fprintf(w, "  Timestamp:    %d\n", tx.GetTimeStamp())
+	fmt.Fprintf(w, "  Replaceable:  %t\n", tx.IsReplaceable())
+	if tx.IsCoinbase() {
+		fmt.Fprintln(w, "  Inputs:       coinbase")
+	} else {
+		fmt.Fprintf(w, "  Inputs:       %d\n", len(tx.GetInputs()))
+		for i, in := range tx.GetInputs() {
+			fmt.Fprintf(w, "    [%d] %s from %s\n", i, in.GetOutpoint().String(), in.GetWalletAddress())
+		}
+	}
+	fmt.Fprintf(w, "  Outputs:      %d, total %d\n", len(tx.GetOutUTXOs()), tx.GetOutputAmount())
+	for i, utxo := range tx.GetOutUTXOs() {
+		fmt.Fprintf(w, "    [%d] %d to %s\n", i, utxo.GetAmount(), utxo.GetWalletAddress())
+	}
+}
+
+func printBalance(w io.Writer, balance rpc.BalanceResult, utxos []rpc.UnspentResult, listUTXOs bool) {
+	fmt.Fprintf(w, "Address:    %s\n", balance.Address)
+	fmt.Fprintf(w, "Confirmed:  %d\n", balance.Confirmed)
+	fmt.Fprintf(w, "Spendable:  %d\n", balance.Spendable)
+	if !listUTXOs {
+		return
+	}
+	fmt.Fprintf(w, "Unspent outputs: %d\n", len(utxos))
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	for _, utxo := range utxos {
+		fmt.Fprintf(tw, "  %s:%d\t%d\t\n", utxo.TxID, utxo.Vout, utxo.Amount)
+	}
+	tw.Flush()
+}
+
+func printMempool(w io.Writer, info rpc.MempoolInfoResult, txIDs []string, list bool) {
+	fmt.Fprintf(w, "Transactions:      %d / %d\n", info.Size, info.MaxCount)
+	fmt.Fprintf(w, "Bytes:             %d / %d\n", info.Bytes, info.MaxBytes)
+	fmt.Fprintf(w, "Total fees:        %d\n", info.Fees)
+	fmt.Fprintf(w, "Min relay fee:     %d per 1000 bytes\n", info.MinRelayFeeRate)
+	fmt.Fprintf(w, "Full:              %t\n", info.Full)
+	if !list {
+		return
+	}
+	for _, txID := range txIDs {
+		fmt.Fprintln(w, "  "+txID)
+	}
+}
+
+func printProof(w io.Writer, proof rpc.ProofResult, root string, valid bool) {
+	check := "matches the block header"
+	if !valid {
+		check = "DOES NOT match the block header"
+	}
+	fmt.Fprintf(w, "Transaction:  %s\n", proof.TxHash)
+	fmt.Fprintf(w, "Block:        %s at height %d\n", proof.BlockHash, proof.Height)
+	fmt.Fprintf(w, "Merkle root:  %s\n", proof.MerkleRoot)
+	fmt.Fprintf(w, "Path:         %d nodes\n", len(proof.Path))
+	for i, node := range proof.Path {
+		fmt.Fprintf(w, "  [%d] %-5s %s\n", i, node.Orientation, node.Hash)
+	}
+	fmt.Fprintf(w, "Computed root %s %s\n", root, check)
+}
+
+func printPeers(w io.Writer, peers []rpc.PeerResult) {
+	if len(peers) == 0 {
+		fmt.Fprintln(w, "No connected peers")
+		return
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ADDRESS\tDIRECTION\tLISTEN\tAGENT\tHEIGHT\tCONNECTED\tSENT\tRECEIVED\tPING")
+	for _, p := range peers {
+		direction := "outbound"
+		if p.Inbound {
+			direction = "inbound"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%d\t%s\t%d\t%d\t%.3fs\n", p.Addr, direction, p.ListenAddr, p.UserAgent,
+			p.StartHeight, formatTime(p.ConnTime), p.BytesSent, p.BytesRecv, p.PingTime)
+	}
+	tw.Flush()
+}
+
+// printEvent 将一个事件输出为一行。
+func printEvent(w io.Writer, event rpc.EventResult) {
+	prefix := fmt.Sprintf("%s  #%-6d %-17s", formatTime(event.Time), event.Sequence, event.Type)
+	if event.Height != nil {
+		fmt.Fprintf(w, "%s height %d  %s\n", prefix, *event.Height, event.BlockHash)
+		return
+	}
+	detail := ""
+	if event.Fee != nil {
+		detail = fmt.Sprintf("  fee %d, %d bytes", *event.Fee, event.Size)
+	}
+	if event.Reason != "" {
+		detail += " (" + event.Reason + ")"
+	}
+	fmt.Fprintf(w, "%s %s%s\n", prefix, event.TxID, detail)
+}
+
+// printWalletBalance 输出钱包的余额，以及每个地址的余额。
+func printWalletBalance(w io.Writer, addresses []wallet.AddressInfo, utxos []rpc.UnspentResult, listUTXOs bool) {
+	amounts := make(map[string]int, len(addresses))
+	for _, utxo := range utxos {
+		amounts[utxo.Address] += utxo.Amount
+	}
+	fmt.Fprintf(w, "Spendable:  %d\n", sumUnspent(utxos))
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, info := range addresses {
+		fmt.Fprintf(tw, "  %s\t%s\t%d\n", info.Address, info.Label, amounts[info.Address])
+	}
+	tw.Flush()
+	if !listUTXOs {
+		return
+	}
+	fmt.Fprintf(w, "Unspent outputs: %d\n", len(utxos))
+	tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	for _, utxo := range utxos {
+		fmt.Fprintf(tw, "  %s:%d\t%d\t\n", utxo.TxID, utxo.Vout, utxo.Amount)
+	}
+	tw.Flush()
+}
+
+// printWalletSend 输出付款交易与选币结果。
+func printWalletSend(w io.Writer, tx *data.Transaction, selection wallet.Selection, strategy string, dryRun bool) {
+	status := "submitted"
+	if dryRun {
+		status = "not submitted (dry run)"
+	}
+	fmt.Fprintf(w, "Transaction:  %s\n", tx.TxID())
+	fmt.Fprintf(w, "Status:       %s\n", status)
+	fmt.Fprintf(w, "Size:         %d bytes\n", tx.Size())
+	fmt.Fprintf(w, "Fee:          %d\n", selection.Fee)
+	fmt.Fprintf(w, "Change:       %d\n", selection.Change)
+	fmt.Fprintf(w, "Inputs:       %d, selected by %s\n", len(selection.Coins), strategy)
+	for _, coin := range selection.Coins {
+		fmt.Fprintf(w, "  %s:%d  %d\n", coin.Outpoint.GetTxID(), coin.Outpoint.GetIndex(), coin.Amount)
+	}
+}
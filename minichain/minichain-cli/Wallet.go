@@ -0,0 +1,401 @@
+package main
+
+import (
+	"Go-Minichain/data"
+	"Go-Minichain/rpc"
+	"Go-Minichain/wallet"
+	"bufio"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+/**
+ * 钱包子命令
+ *
+ * 钱包文件保存在本地（-file，默认为环境变量 MINICHAIN_WALLET 或当前目录下的 wallet.json），
+ * 余额与可以花费的输出通过 JSON-RPC 从节点查询，付款交易在本地签名后以 sendrawtransaction 提交，私钥不会发送给节点。
+ * 需要私钥的操作从环境变量 MINICHAIN_WALLET_PASSPHRASE 读取口令，未设置时从标准输入读取一行。
+ */
+
+// walletCommand 一个钱包子命令。
+type walletCommand struct {
+	name    string
+	args    string
+	summary string
+	run     func(c *cli, args []string) error
+}
+
+// walletCommands 全部钱包子命令，按帮助信息中的顺序排列。
+var walletCommands = []walletCommand{
+	{"create", "", "create a wallet file with a new default address", runWalletCreate},
+	{"addresses", "", "list the addresses in the wallet", runWalletAddresses},
+	{"new", "[label]", "add a new address", runWalletNew},
+	{"import", "[-label label] <key|->", "import a hex private key, read from stdin when the argument is -", runWalletImport},
+	{"export", "<address>", "print the hex private key of an address", runWalletExport},
+	{"balance", "[-utxos]", "show the spendable balance of the wallet", runWalletBalance},
+	{"send", "[options] <address> <amount> [<address> <amount>...]", "pay one or more addresses", runWalletSend},
+}
+
+// stdin 标准输入，口令与私钥共用同一个带缓冲的读取器。
+var stdin = bufio.NewReader(os.Stdin)
+
+// runWallet 分派钱包子命令。
+func runWallet(c *cli, args []string) error {
+	if len(args) == 0 {
+		return newUsageError("missing wallet command")
+	}
+	if args[0] == "-h" || args[0] == "-help" {
+		return flag.ErrHelp
+	}
+	for _, cmd := range walletCommands {
+		if cmd.name == args[0] {
+			c.usage = strings.TrimSpace("wallet " + cmd.name + " " + cmd.args)
+			return cmd.run(c, args[1:])
+		}
+	}
+	return newUsageError("unknown wallet command: %s", args[0])
+}
+
+// printWalletUsage 输出钱包子命令的帮助信息与环境变量。
+func printWalletUsage(w io.Writer) {
+	fmt.Fprintln(w, "wallet commands:")
+	for _, cmd := range walletCommands {
+		fmt.Fprintf(w, "  %-10s %-54s %s\n", cmd.name, cmd.args, cmd.summary)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "environment: MINICHAIN_WALLET sets the default wallet file, MINICHAIN_WALLET_PASSPHRASE the passphrase")
+}
+
+// newWalletFlagSet 创建钱包子命令的参数集合，包括钱包文件的路径。
+func (c *cli) newWalletFlagSet(name string) (*flag.FlagSet, *string) {
+	fs := c.newFlagSet("wallet " + name)
+	path := "wallet.json"
+	if env, ok := os.LookupEnv("MINICHAIN_WALLET"); ok && env != "" {
+		path = env
+	}
+	file := fs.String("file", path, "path of the wallet file")
+	return fs, file
+}
+
+// readLine 从标准输入读取一行，去掉行尾的换行符。
+func readLine() (string, error) {
+	line, err := stdin.ReadString('\n')
+	if err != nil && line == "" {
+		return "", fmt.Errorf("read stdin error: %w", err)
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// readPassphrase 从环境变量 MINICHAIN_WALLET_PASSPHRASE 读取口令，未设置时提示并从标准输入读取。
+// 参数:
+// - confirm: 是否要求再输入一次确认，用于创建钱包。
+func readPassphrase(confirm bool) (string, error) {
+	if passphrase, ok := os.LookupEnv("MINICHAIN_WALLET_PASSPHRASE"); ok {
+		return passphrase, nil
+	}
+	fmt.Fprint(os.Stderr, "Wallet passphrase: ")
+	passphrase, err := readLine()
+	if err != nil || !confirm {
+		return passphrase, err
+	}
+	fmt.Fprint(os.Stderr, "Repeat passphrase: ")
+	repeated, err := readLine()
+	if err != nil {
+		return "", err
+	}
+	if repeated != passphrase {
+		return "", errors.New("passphrases do not match")
+	}
+	return passphrase, nil
+}
+
+// openWallet 打开钱包文件，unlock 为 true 时读取口令并解锁。
+func openWallet(path string, unlock bool) (*wallet.Wallet, error) {
+	w, err := wallet.Open(path)
+	if err != nil || !unlock {
+		return w, err
+	}
+	passphrase, err := readPassphrase(false)
+	if err != nil {
+		return nil, err
+	}
+	if err := w.Unlock(passphrase); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func runWalletCreate(c *cli, args []string) error {
+	fs, file := c.newWalletFlagSet("create")
+	if _, err := c.parse(fs, args, 0, 0); err != nil {
+		return err
+	}
+	passphrase, err := readPassphrase(true)
+	if err != nil {
+		return err
+	}
+	w, err := wallet.Create(*file, passphrase)
+	if err != nil {
+		return err
+	}
+	if c.json {
+		return c.printJSON(map[string]string{"file": w.GetPath(), "address": w.GetDefaultAddress()})
+	}
+	fmt.Fprintln(c.out, "Created wallet "+w.GetPath())
+	_, err = fmt.Fprintln(c.out, "Default address: "+w.GetDefaultAddress())
+	return err
+}
+
+// addressResult 钱包中一个地址的 JSON 输出。
+type addressResult struct {
+	Address  string `json:"address"`
+	Label    string `json:"label"`
+	Imported bool   `json:"imported"`
+	Created  int64  `json:"created"`
+}
+
+func runWalletAddresses(c *cli, args []string) error {
+	fs, file := c.newWalletFlagSet("addresses")
+	if _, err := c.parse(fs, args, 0, 0); err != nil {
+		return err
+	}
+	w, err := openWallet(*file, false)
+	if err != nil {
+		return err
+	}
+	infos := w.GetAddresses()
+	if c.json {
+		results := make([]addressResult, len(infos))
+		for i, info := range infos {
+			results[i] = addressResult{Address: info.Address, Label: info.Label, Imported: info.Imported, Created: info.CreatedAt.Unix()}
+		}
+		return c.printJSON(results)
+	}
+	tw := tabwriter.NewWriter(c.out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ADDRESS\tLABEL\tSOURCE\tCREATED")
+	for _, info := range infos {
+		source := "generated"
+		if info.Imported {
+			source = "imported"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", info.Address, info.Label, source, formatTime(info.CreatedAt.Unix()))
+	}
+	return tw.Flush()
+}
+
+func runWalletNew(c *cli, args []string) error {
+	fs, file := c.newWalletFlagSet("new")
+	args, err := c.parse(fs, args, 0, 1)
+	if err != nil {
+		return err
+	}
+	label := ""
+	if len(args) == 1 {
+		label = args[0]
+	}
+	w, err := openWallet(*file, true)
+	if err != nil {
+		return err
+	}
+	address, err := w.NewAddress(label)
+	if err != nil {
+		return err
+	}
+	if c.json {
+		return c.printJSON(map[string]string{"address": address})
+	}
+	_, err = fmt.Fprintln(c.out, address)
+	return err
+}
+
+func runWalletImport(c *cli, args []string) error {
+	fs, file := c.newWalletFlagSet("import")
+	label := fs.String("label", "", "label of the imported address")
+	args, err := c.parse(fs, args, 1, 1)
+	if err != nil {
+		return err
+	}
+	key := args[0]
+	if key == "-" {
+		if key, err = readLine(); err != nil {
+			return err
+		}
+	}
+	w, err := openWallet(*file, true)
+	if err != nil {
+		return err
+	}
+	address, err := w.ImportKey(strings.TrimSpace(key), *label)
+	if err != nil {
+		return err
+	}
+	if c.json {
+		return c.printJSON(map[string]string{"address": address})
+	}
+	_, err = fmt.Fprintln(c.out, "Imported "+address)
+	return err
+}
+
+func runWalletExport(c *cli, args []string) error {
+	fs, file := c.newWalletFlagSet("export")
+	args, err := c.parse(fs, args, 1, 1)
+	if err != nil {
+		return err
+	}
+	w, err := openWallet(*file, true)
+	if err != nil {
+		return err
+	}
+	key, err := w.ExportKey(args[0])
+	if err != nil {
+		return err
+	}
+	if c.json {
+		return c.printJSON(map[string]string{"address": args[0], "key": key})
+	}
+	_, err = fmt.Fprintln(c.out, key)
+	return err
+}
+
+func runWalletBalance(c *cli, args []string) error {
+	fs, file := c.newWalletFlagSet("balance")
+	listUTXOs := fs.Bool("utxos", false, "also list the spendable outputs")
+	if _, err := c.parse(fs, args, 0, 0); err != nil {
+		return err
+	}
+	w, err := openWallet(*file, false)
+	if err != nil {
+		return err
+	}
+	coins, err := w.ListUnspent(&rpcBackend{client: c.client})
+	if err != nil {
+		return err
+	}
+	utxos := make([]rpc.UnspentResult, len(coins))
+	for i, coin := range coins {
+		utxos[i] = rpc.UnspentResult{TxID: coin.Outpoint.GetTxID(), Vout: coin.Outpoint.GetIndex(), Address: coin.Address, Amount: coin.Amount}
+	}
+	if c.json {
+		result := struct {
+			Balance int                 `json:"balance"`
+			UTXOs   []rpc.UnspentResult `json:"utxos,omitempty"`
+		}{Balance: sumUnspent(utxos)}
+		if *listUTXOs {
+			result.UTXOs = utxos
+		}
+		return c.printJSON(result)
+	}
+	printWalletBalance(c.out, w.GetAddresses(), utxos, *listUTXOs)
+	return nil
+}
+
+func runWalletSend(c *cli, args []string) error {
+	fs, file := c.newWalletFlagSet("send")
+	feeRate := fs.Int("feerate", 0, "fee per 1000 bytes, the node's minimum relay fee rate when lower")
+	strategy := fs.String("strategy", "branch-and-bound", "coin selection: largest-first, branch-and-bound or minimize-change")
+	change := fs.String("change", "", "change address, the wallet's default address when empty")
+	from := fs.String("from", "", "comma-separated addresses whose outputs may be spent, all wallet addresses when empty")
+	replaceable := fs.Bool("replaceable", false, "allow the transaction to be replaced by one paying a higher fee")
+	dryRun := fs.Bool("dry-run", false, "print the signed transaction without submitting it")
+	args, err := c.parse(fs, args, 2, 1<<16)
+	if err != nil {
+		return err
+	}
+	if len(args)%2 != 0 {
+		return newUsageError("payments must be given as <address> <amount> pairs")
+	}
+	payments := make([]wallet.Payment, 0, len(args)/2)
+	for i := 0; i < len(args); i += 2 {
+		amount, err := strconv.Atoi(args[i+1])
+		if err != nil {
+			return newUsageError("invalid amount %q", args[i+1])
+		}
+		payments = append(payments, wallet.Payment{Address: args[i], Amount: amount})
+	}
+	selector, err := wallet.SelectorByName(*strategy)
+	if err != nil {
+		return newUsageError("%s", err.Error())
+	}
+	options := wallet.SendOptions{FeeRate: *feeRate, Selector: selector, ChangeAddress: *change, Replaceable: *replaceable}
+	if *from != "" {
+		options.From = strings.Split(*from, ",")
+	}
+	w, err := openWallet(*file, true)
+	if err != nil {
+		return err
+	}
+	backend := &rpcBackend{client: c.client}
+	var tx *data.Transaction
+	var selection wallet.Selection
+	if *dryRun {
+		tx, selection, err = w.CreateTransaction(backend, payments, options)
+	} else {
+		tx, selection, err = w.Send(backend, payments, options)
+	}
+	if err != nil {
+		return err
+	}
+	rawHex := hex.EncodeToString(tx.Encode())
+	if c.json {
+		return c.printJSON(struct {
+			TxID      string `json:"txid"`
+			Fee       int    `json:"fee"`
+			Change    int    `json:"change"`
+			Inputs    int    `json:"inputs"`
+			Size      int    `json:"size"`
+			Submitted bool   `json:"submitted"`
+			Hex       string `json:"hex"`
+		}{tx.TxID(), selection.Fee, selection.Change, len(selection.Coins), tx.Size(), !*dryRun, rawHex})
+	}
+	printWalletSend(c.out, tx, selection, selector.Name(), *dryRun)
+	if *dryRun {
+		_, err = fmt.Fprintln(c.out, rawHex)
+	}
+	return err
+}
+
+// rpcBackend 通过 JSON-RPC 访问节点的钱包 Backend。
+type rpcBackend struct {
+	client *rpc.Client
+}
+
+// ListUnspent 包括交易池中未确认交易的找零，使连续付款不会重复花费同一个输出。
+func (b *rpcBackend) ListUnspent(address string) ([]wallet.Coin, error) {
+	var utxos []rpc.UnspentResult
+	if err := b.client.Call("listunspent", []interface{}{address, true}, &utxos); err != nil {
+		return nil, err
+	}
+	coins := make([]wallet.Coin, len(utxos))
+	for i, utxo := range utxos {
+		coins[i] = wallet.Coin{Outpoint: data.NewOutpoint(utxo.TxID, utxo.Vout), Address: utxo.Address, Amount: utxo.Amount}
+	}
+	return coins, nil
+}
+
+func (b *rpcBackend) GetFeeRate() (int, error) {
+	var info rpc.MempoolInfoResult
+	if err := b.client.Call("getmempoolinfo", nil, &info); err != nil {
+		return 0, err
+	}
+	return info.MinRelayFeeRate, nil
+}
+
+func (b *rpcBackend) Broadcast(tx *data.Transaction) error {
+	return b.client.Call("sendrawtransaction", []interface{}{hex.EncodeToString(tx.Encode())}, nil)
+}
+
+// sumUnspent 返回输出的金额之和。
+func sumUnspent(utxos []rpc.UnspentResult) int {
+	total := 0
+	for _, utxo := range utxos {
+		total += utxo.Amount
+	}
+	return total
+}
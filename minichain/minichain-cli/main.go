@@ -0,0 +1,200 @@
+package main
+
+import (
+	"Go-Minichain/rpc"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"time"
+)
+
+/**
+ * minichain-cli 命令行客户端
+ *
+ * 通过 JSON-RPC 调用节点的方法，每个子命令对应一个或几个方法，结果默认以便于阅读的文本输出，
+ * 指定 -json 时输出方法返回的 JSON，便于脚本处理。区块与交易先取得原始编码，再解析为 data 包中的结构输出。
+ * 退出码：
+ * - 0: 成功；
+ * - 1: 节点返回错误，例如区块或交易不存在、交易被拒绝；
+ * - 2: 命令行用法错误；
+ * - 3: 无法连接节点或请求超时。
+ */
+
+const (
+	exitOK          = 0
+	exitFailure     = 1
+	exitUsage       = 2
+	exitUnavailable = 3
+)
+
+// defaultRPCAddr 默认的节点地址，与节点配置 rpcListen 的默认值相同，可以用环境变量 MINICHAIN_RPC_LISTEN 修改。
+const defaultRPCAddr = "127.0.0.1:9332"
+
+// usageError 命令行用法错误。
+type usageError struct {
+	message string
+}
+
+func (e *usageError) Error() string {
+	return e.message
+}
+
+// newUsageError 生成命令行用法错误。
+func newUsageError(format string, args ...interface{}) error {
+	return &usageError{message: fmt.Sprintf(format, args...)}
+}
+
+// command 一个子命令。
+// 字段说明：
+// - name: 子命令名称。
+// - args: 参数说明。
+// - summary: 功能说明。
+// - run: 执行子命令，args 为子命令名称之后的参数。
+type command struct {
+	name    string
+	args    string
+	summary string
+	run     func(c *cli, args []string) error
+}
+
+// commands 全部子命令，按帮助信息中的顺序排列。
+var commands = []command{
+	{"info", "", "show the main chain and sync status", runInfo},
+	{"block", "[-raw] [hash|height]", "show a block, the newest block by default", runBlock},
+	{"tx", "[-raw] <txid>", "show a transaction in the pool or the main chain", runTx},
+	{"balance", "[-utxos] [-mempool] <address>", "show the balance and unspent outputs of an address", runBalance},
+	{"send", "<hex|->", "submit a raw transaction, read from stdin when the argument is -", runSend},
+	{"mempool", "[-list]", "show the transaction pool", runMempool},
+	{"proof", "<txid>", "show and check the Merkle proof of a transaction", runProof},
+	{"peers", "", "list connected peers", runPeers},
+	{"events", "[-types type,...]", "stream block and transaction pool events until interrupted", runEvents},
+	{"wallet", "<command> [args]", "manage a local wallet and send payments, see wallet -h", runWallet},
+	{"stop", "", "stop the node", runStop},
+	{"call", "<method> [param...]", "call any RPC method, params are parsed as JSON or taken as strings", runCall},
+}
+
+// cli 子命令共享的状态。
+// 字段说明：
+// - client: JSON-RPC 客户端。
+// - json: 是否输出 JSON。
+// - out: 输出目标。
+// - flags: 正在执行的子命令的参数集合，用于输出帮助信息。
+// - usage: 不为空时代替子命令的用法说明，用于钱包子命令。
+type cli struct {
+	client *rpc.Client
+	json   bool
+	out    io.Writer
+	flags  *flag.FlagSet
+	usage  string
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run 解析全局参数并执行子命令。
+// 返回值:
+// 返回进程的退出码。
+func run(args []string, stdout io.Writer, stderr io.Writer) int {
+	fs := flag.NewFlagSet("minichain-cli", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	rpcAddr := defaultRPCAddr
+	if addr, ok := os.LookupEnv("MINICHAIN_RPC_LISTEN"); ok && addr != "" {
+		rpcAddr = addr
+	}
+	fs.StringVar(&rpcAddr, "rpc", rpcAddr, "address or URL of the node's JSON-RPC server")
+	rpcToken, _ := os.LookupEnv("MINICHAIN_RPC_TOKEN")
+	fs.StringVar(&rpcToken, "rpcToken", rpcToken, "token configured as rpcToken on the node (env MINICHAIN_RPC_TOKEN)")
+	jsonOutput := fs.Bool("json", false, "print the JSON returned by the node")
+	timeout := fs.Duration("timeout", 30*time.Second, "timeout of each request")
+	fs.Usage = func() { usage(fs, stderr) }
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+	if fs.NArg() == 0 {
+		usage(fs, stderr)
+		return exitUsage
+	}
+	name := fs.Arg(0)
+	for _, cmd := range commands {
+		if cmd.name != name {
+			continue
+		}
+		client := rpc.NewClient(rpcAddr, *timeout)
+		client.SetToken(rpcToken)
+		c := &cli{client: client, json: *jsonOutput, out: stdout}
+		return report(c, cmd, cmd.run(c, fs.Args()[1:]), stderr)
+	}
+	fmt.Fprintln(stderr, "unknown command: "+name)
+	usage(fs, stderr)
+	return exitUsage
+}
+
+// report 输出子命令的错误并返回对应的退出码。
+func report(c *cli, cmd command, err error, stderr io.Writer) int {
+	if err == nil {
+		return exitOK
+	}
+	var usageErr *usageError
+	var rpcErr *rpc.Error
+	var urlErr *url.Error
+	switch {
+	case errors.Is(err, flag.ErrHelp):
+		commandUsage(c, cmd, stderr)
+		return exitOK
+	case errors.As(err, &usageErr):
+		fmt.Fprintln(stderr, "error: "+usageErr.message)
+		commandUsage(c, cmd, stderr)
+		return exitUsage
+	case errors.As(err, &rpcErr):
+		fmt.Fprintln(stderr, "error: "+rpcErr.Error())
+		if detail, err := json.Marshal(rpcErr.Data); err == nil && rpcErr.Data != nil {
+			fmt.Fprintln(stderr, "detail: "+string(detail))
+		}
+		return exitFailure
+	case errors.As(err, &urlErr):
+		fmt.Fprintln(stderr, "error: cannot reach the node at "+c.client.GetURL()+": "+urlErr.Err.Error())
+		return exitUnavailable
+	default:
+		fmt.Fprintln(stderr, "error: "+err.Error())
+		return exitFailure
+	}
+}
+
+// commandUsage 输出子命令的用法与参数。
+func commandUsage(c *cli, cmd command, w io.Writer) {
+	line := cmd.name + " " + cmd.args
+	if c.usage != "" {
+		line = c.usage
+	}
+	fmt.Fprintln(w, "usage: minichain-cli [options] "+line)
+	if c.flags != nil {
+		c.flags.SetOutput(w)
+		c.flags.PrintDefaults()
+	}
+	if cmd.name == "wallet" && c.flags == nil {
+		printWalletUsage(w)
+	}
+}
+
+// usage 输出全局参数与子命令的帮助信息。
+func usage(fs *flag.FlagSet, w io.Writer) {
+	fmt.Fprintln(w, "usage: minichain-cli [options] <command> [args]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "commands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-8s %-32s %s\n", cmd.name, cmd.args, cmd.summary)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "options:")
+	fs.PrintDefaults()
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "exit codes: 0 success, 1 the node returned an error, 2 usage error, 3 the node is unreachable")
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"Go-Minichain/rpc"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeNode 模拟节点的 JSON-RPC 服务，按方法名返回预先设置的结果或错误。
+// 字段说明：
+// - token: 不为空时要求请求携带该令牌。
+// - results / errors: 方法的结果与错误。
+// - methods: 收到的请求依次调用的方法。
+type fakeNode struct {
+	token   string
+	results map[string]interface{}
+	errors  map[string]*rpc.Error
+	mutex   sync.Mutex
+	methods []string
+}
+
+func (f *fakeNode) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if f.token != "" && r.Header.Get("Authorization") != "Bearer "+f.token {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	var req rpc.Request
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	f.mutex.Lock()
+	f.methods = append(f.methods, req.Method)
+	f.mutex.Unlock()
+	resp := rpc.Response{JSONRPC: "2.0", ID: req.ID, Result: f.results[req.Method], Error: f.errors[req.Method]}
+	if resp.Result == nil && resp.Error == nil {
+		resp.Error = &rpc.Error{Code: -32601, Message: "method not found"}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// called 返回节点收到的请求依次调用的方法。
+func (f *fakeNode) called() []string {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return append([]string(nil), f.methods...)
+}
+
+// startFakeNode 启动模拟节点，测试结束时关闭。
+func startFakeNode(t *testing.T, node *fakeNode) string {
+	t.Helper()
+	t.Setenv("MINICHAIN_RPC_TOKEN", "")
+	server := httptest.NewServer(node)
+	t.Cleanup(server.Close)
+	return server.URL
+}
+
+// runCLI 执行命令行客户端，返回退出码与标准输出、标准错误的内容。
+func runCLI(args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	code := run(args, &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestUsageErrors(t *testing.T) {
+	// 用法错误在连接节点之前就被发现，节点不会收到请求
+	node := &fakeNode{}
+	url := startFakeNode(t, node)
+	for _, tc := range []struct {
+		args   []string
+		code   int
+		stderr string
+	}{
+		{nil, exitUsage, "usage: minichain-cli [options] <command>"},
+		{[]string{"frobnicate"}, exitUsage, "unknown command: frobnicate"},
+		{[]string{"-bogus", "info"}, exitUsage, "flag provided but not defined: -bogus"},
+		{[]string{"-h"}, exitOK, "exit codes:"},
+		{[]string{"tx"}, exitUsage, "error: missing arguments\nusage: minichain-cli [options] tx [-raw] <txid>"},
+		{[]string{"block", "1", "2"}, exitUsage, "error: too many arguments"},
+		{[]string{"info", "-bogus"}, exitUsage, "error: flag provided but not defined: -bogus"},
+		{[]string{"tx", "-h"}, exitOK, "-raw"},
+		{[]string{"wallet", "frobnicate"}, exitUsage, "frobnicate"},
+	} {
+		code, stdout, stderr := runCLI(append([]string{"-rpc", url}, tc.args...)...)
+		if code != tc.code || !strings.Contains(stderr, tc.stderr) {
+			t.Errorf("%v: exit %d with stderr %q, want exit %d with %q", tc.args, code, stderr, tc.code, tc.stderr)
+		}
+		if stdout != "" {
+			t.Errorf("%v: printed %q to stdout", tc.args, stdout)
+		}
+	}
+	if called := node.called(); len(called) != 0 {
+		t.Fatalf("usage errors called %v", called)
+	}
+}
+
+func TestParseInterleavedFlags(t *testing.T) {
+	c := &cli{}
+	fs := c.newFlagSet("balance")
+	utxos := fs.Bool("utxos", false, "")
+	// 参数可以出现在位置参数之后，"--" 之后的都是位置参数，子命令之后同样可以指定 -json
+	args, err := c.parse(fs, []string{"addr", "-utxos", "-json", "--", "-mempool"}, 1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(args, []string{"addr", "-mempool"}) || !*utxos || !c.json {
+		t.Fatalf("parsed %v, utxos %v, json %v; want [addr -mempool] with both flags set", args, *utxos, c.json)
+	}
+}
+
+func TestNodeErrorOutput(t *testing.T) {
+	node := &fakeNode{errors: map[string]*rpc.Error{
+		"gettransaction": {Code: -5, Message: "transaction not found", Data: map[string]string{"txid": "AB"}},
+	}}
+	url := startFakeNode(t, node)
+	code, stdout, stderr := runCLI("-rpc", url, "tx", "AB")
+	if code != exitFailure || stdout != "" {
+		t.Fatalf("exit %d with stdout %q, want exit %d and no output", code, stdout, exitFailure)
+	}
+	if want := "error: transaction not found (code -5)\ndetail: {\"txid\":\"AB\"}\n"; stderr != want {
+		t.Fatalf("stderr %q, want %q", stderr, want)
+	}
+	if called := node.called(); !reflect.DeepEqual(called, []string{"gettransaction"}) {
+		t.Fatalf("called %v, want gettransaction", called)
+	}
+}
+
+func TestTokenAndOutput(t *testing.T) {
+	info := rpc.BlockchainInfoResult{Height: 3, Headers: 3, BestBlockHash: "00AB", Bits: 0x1f0fffff, TotalWork: "48"}
+	node := &fakeNode{token: "secret", results: map[string]interface{}{"getblockchaininfo": info}}
+	url := startFakeNode(t, node)
+
+	code, _, stderr := runCLI("-rpc", url, "-rpcToken", "wrong", "info")
+	if code != exitFailure || !strings.Contains(stderr, "missing or invalid RPC token") {
+		t.Fatalf("wrong token: exit %d with stderr %q", code, stderr)
+	}
+
+	code, stdout, stderr := runCLI("-rpc", url, "-rpcToken", "secret", "info")
+	if code != exitOK || stderr != "" || !strings.Contains(stdout, "Best block:   00AB") {
+		t.Fatalf("info: exit %d, stdout %q, stderr %q", code, stdout, stderr)
+	}
+	code, stdout, _ = runCLI("-rpc", url, "-rpcToken", "secret", "info", "-json")
+	var decoded rpc.BlockchainInfoResult
+	if err := json.Unmarshal([]byte(stdout), &decoded); code != exitOK || err != nil || decoded != info {
+		t.Fatalf("info -json: exit %d, printed %q (%v), want %+v", code, stdout, err, info)
+	}
+}
+
+func TestNodeUnreachable(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	url := server.URL
+	server.Close()
+	t.Setenv("MINICHAIN_RPC_TOKEN", "")
+	code, _, stderr := runCLI("-rpc", url, "info")
+	if code != exitUnavailable || !strings.Contains(stderr, "error: cannot reach the node at "+url) {
+		t.Fatalf("exit %d with stderr %q, want exit %d naming %s", code, stderr, exitUnavailable, url)
+	}
+}
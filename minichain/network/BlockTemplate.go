@@ -0,0 +1,146 @@
+package network
+
+import (
+	"Go-Minichain/config"
+	"Go-Minichain/data"
+	"sort"
+)
+
+/**
+ * 区块模板
+ *
+ * 矿工从交易池中按手续费率从高到低选择交易，直到达到区块的交易个数或字节数上限。
+ * 交易可能花费交易池中其他交易的输出，这样的交易只有在其父交易已经被选中之后才能被选中，
+ * 并且排在父交易之后，保证区块中的交易可以按顺序通过验证。
+ *
+ * 选择时按交易包（package）的手续费率比较：交易包由一笔交易及其尚未被选中的全部池中祖先交易组成，
+ * 手续费率为包内手续费之和除以包内交易大小之和。因此手续费很高的子交易可以带动手续费很低的父交易一起被打包
+ * （child-pays-for-parent）。
+ */
+
+// BuildBlockTemplate 选择打包进下一个区块的交易，不包含 coinbase 交易。
+// 每一轮都选择交易包手续费率最高且放得下的一笔交易，连同其尚未被选中的祖先交易按依赖顺序一起加入区块。
+// 参数:
+// - height: 要打包的区块高度，用于估算 coinbase 交易的大小。
+// 返回值:
+// 返回按打包顺序排列的交易列表。
+func (m *MinerNode) BuildBlockTemplate(height int) []data.Transaction {
+	entries := m.network.txPool.snapshot()
+	// 为 coinbase 交易预留一个位置以及其编码大小；金额为定长编码，只要 coinbase 交易有一个输出，
+	// 其大小就与发放的金额无关，因此按至少 1 的手续费估算
+	maxCount := config.MiniChainConfig.GetMaxTransactionCount() - 1
+	maxSize := config.MiniChainConfig.GetMaxBlockSize() - m.newCoinbaseTransaction(height, 1).Size()
+
+	index := make(map[string]int, len(entries))
+	for i, entry := range entries {
+		index[entry.txID] = i
+	}
+	selected := make(map[string]bool)
+	// 放不下的交易包。包内没有交易被选中时交易包保持不变，而区块只会越来越满，因此跳过它；
+	// 包内的祖先交易被选中后交易包发生变化，不再跳过，下一轮重新计算
+	skipped := make(map[string]*txPackage)
+	transactions := make([]data.Transaction, 0)
+	size := 0
+	for len(transactions) < maxCount {
+		var best *txPackage
+		for _, entry := range entries {
+			if selected[entry.txID] {
+				continue
+			}
+			if _, ok := skipped[entry.txID]; ok {
+				continue
+			}
+			pkg := newTxPackage(entry, entries, index, selected)
+			if pkg == nil || len(transactions)+len(pkg.entries) > maxCount || size+pkg.size > maxSize {
+				skipped[entry.txID] = pkg
+				continue
+			}
+			// entries 按加入顺序排列，交易包手续费率相同时选择较早加入的交易
+			if best == nil || pkg.higherFeeRate(best) {
+				best = pkg
+			}
+		}
+		if best == nil {
+			break
+		}
+		for _, entry := range best.entries {
+			selected[entry.txID] = true
+			transactions = append(transactions, entry.tx)
+		}
+		size += best.size
+		for txID, pkg := range skipped {
+			if pkg != nil && pkg.containsAny(selected) {
+				delete(skipped, txID)
+			}
+		}
+	}
+	return transactions
+}
+
+// txPackage 一笔交易及其尚未被选中的全部池中祖先交易。
+// 字段说明：
+// - entries: 包内交易，按加入交易池的顺序排列，祖先交易总是排在后代交易前面。
+// - fee: 包内交易的手续费之和。
+// - size: 包内交易规范编码的字节数之和。
+type txPackage struct {
+	entries []*poolEntry
+	fee     int
+	size    int
+}
+
+// newTxPackage 构造以指定交易结尾的交易包。
+// 参数:
+// - entry: 交易包中的最后一笔交易。
+// - entries: 交易池中的全部交易，按加入顺序排列。
+// - index: 交易标识到其在 entries 中位置的映射。
+// - selected: 已经被选中的交易，不再计入交易包。
+// 返回值:
+// 返回交易包；祖先交易已不在交易池中时返回 nil。
+func newTxPackage(entry *poolEntry, entries []*poolEntry, index map[string]int, selected map[string]bool) *txPackage {
+	members := map[int]bool{index[entry.txID]: true}
+	stack := []*poolEntry{entry}
+	for len(stack) > 0 {
+		current := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		for _, parentID := range current.parents {
+			if selected[parentID] {
+				continue
+			}
+			i, ok := index[parentID]
+			if !ok {
+				return nil
+			}
+			if !members[i] {
+				members[i] = true
+				stack = append(stack, entries[i])
+			}
+		}
+	}
+	positions := make([]int, 0, len(members))
+	for i := range members {
+		positions = append(positions, i)
+	}
+	sort.Ints(positions)
+	pkg := &txPackage{entries: make([]*poolEntry, 0, len(positions))}
+	for _, i := range positions {
+		pkg.entries = append(pkg.entries, entries[i])
+		pkg.fee += entries[i].fee
+		pkg.size += entries[i].size
+	}
+	return pkg
+}
+
+// containsAny 判断交易包中是否有交易属于指定集合。
+func (pkg *txPackage) containsAny(txIDs map[string]bool) bool {
+	for _, entry := range pkg.entries {
+		if txIDs[entry.txID] {
+			return true
+		}
+	}
+	return false
+}
+
+// higherFeeRate 判断该交易包的手续费率是否高于另一个交易包，以交叉相乘代替除法避免精度损失。
+func (pkg *txPackage) higherFeeRate(other *txPackage) bool {
+	return pkg.fee*other.size > other.fee*pkg.size
+}
@@ -0,0 +1,120 @@
+package network
+
+import (
+	"Go-Minichain/data"
+	"context"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+// txIDs 返回交易列表中每笔交易的标识。
+func txIDs(transactions []data.Transaction) []string {
+	ids := make([]string, len(transactions))
+	for i := range transactions {
+		ids[i] = transactions[i].TxID()
+	}
+	return ids
+}
+
+// acceptPayments 由 accounts[i] 向 accounts[9] 支付一笔手续费为 fees[i] 的交易，返回这些交易。
+func acceptPayments(t *testing.T, n *NetWork, fees ...int) []data.Transaction {
+	t.Helper()
+	accounts := n.GetAccounts()
+	payments := make([]data.Transaction, len(fees))
+	for i, fee := range fees {
+		payments[i] = mustPayment(t, n, accounts[i], accounts[9], 100, fee)
+		if err := n.AcceptTransaction(payments[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return payments
+}
+
+func TestBlockTemplateChildPaysForParent(t *testing.T) {
+	// 区块中除 coinbase 外只能放两笔交易
+	useConfig(t, "-maxTransactionCount=3")
+	n := newTestNetWork(t, 1)
+	accounts := n.GetAccounts()
+	parent := mustPayment(t, n, accounts[0], accounts[9], 100, 10)
+	middle := mustPayment(t, n, accounts[1], accounts[9], 100, 500)
+	if err := n.AcceptTransaction(parent); err != nil {
+		t.Fatal(err)
+	}
+	child := mustPayment(t, n, accounts[0], accounts[9], 100, 5000)
+	for _, tx := range []data.Transaction{child, middle} {
+		if err := n.AcceptTransaction(tx); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 父交易自身的手续费率低于 middle，但与子交易组成的交易包手续费率更高，两者一起被选中且父交易在前
+	template := n.miner.BuildBlockTemplate(1)
+	if len(template) != 2 || template[0].TxID() != parent.TxID() || template[1].TxID() != child.TxID() {
+		t.Fatalf("template %v, want parent %s then child %s", txIDs(template), parent.TxID(), child.TxID())
+	}
+}
+
+func TestBlockTemplateOrderedByFeeRate(t *testing.T) {
+	n := newTestNetWork(t, 1)
+	// 交易的输入与输出个数相同，大小相同，手续费率的顺序即手续费的顺序
+	p := acceptPayments(t, n, 300, 100, 500, 200, 400)
+	want := txIDs([]data.Transaction{p[2], p[4], p[0], p[3], p[1]})
+	if got := txIDs(n.miner.BuildBlockTemplate(1)); !reflect.DeepEqual(got, want) {
+		t.Fatalf("template %v, want %v", got, want)
+	}
+
+	// 区块中除 coinbase 外只能放三笔交易时选择手续费率最高的三笔
+	useConfig(t, "-maxTransactionCount=4")
+	if got := txIDs(n.miner.BuildBlockTemplate(1)); !reflect.DeepEqual(got, want[:3]) {
+		t.Fatalf("template %v, want %v", got, want[:3])
+	}
+}
+
+func TestBlockTemplateRespectsSizeLimit(t *testing.T) {
+	n := newTestNetWork(t, 1)
+	accounts := n.GetAccounts()
+	p := acceptPayments(t, n, 100, 300)
+	// 手续费率最高的交易有很多输出，区块放不下它
+	utxos := n.GetSpendableUTXOs(accounts[2].GetWalletAddress())
+	large := spendOutputs(t, n, accounts[2], accounts[9], utxos, 5000, false, 10, 10, 10, 10, 10, 10, 10, 10, 10, 10, 10, 10)
+	if err := n.AcceptTransaction(large); err != nil {
+		t.Fatal(err)
+	}
+
+	limit := n.miner.newCoinbaseTransaction(1, 1).Size() + p[0].Size() + p[1].Size()
+	if large.Size() <= p[0].Size()+p[1].Size() {
+		t.Fatalf("large transaction of %d bytes fits in place of both payments", large.Size())
+	}
+	useConfig(t, "-maxBlockSize="+strconv.Itoa(limit))
+	template := n.miner.BuildBlockTemplate(1)
+	if got, want := txIDs(template), txIDs([]data.Transaction{p[1], p[0]}); !reflect.DeepEqual(got, want) {
+		t.Fatalf("template %v, want %v", got, want)
+	}
+
+	// 少一个字节时只能放下手续费较高的一笔
+	useConfig(t, "-maxBlockSize="+strconv.Itoa(limit-1))
+	if got, want := txIDs(n.miner.BuildBlockTemplate(1)), txIDs(p[1:]); !reflect.DeepEqual(got, want) {
+		t.Fatalf("template %v, want %v", got, want)
+	}
+}
+
+func TestBlockTemplateFeesInCoinbase(t *testing.T) {
+	n := newTestNetWork(t, 1)
+	p := acceptPayments(t, n, 300, 100, 500)
+	if err := n.miner.mineNext(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	block := n.GetNewestBlock()
+	body := block.GetBlockBody()
+	transactions := body.GetTransctions()
+	if got := txIDs(transactions[1:]); !reflect.DeepEqual(got, txIDs([]data.Transaction{p[2], p[0], p[1]})) {
+		t.Fatalf("block holds %v, want the payments by fee rate", got)
+	}
+	if reward := transactions[0].GetOutputAmount(); reward != BlockSubsidy(1)+900 {
+		t.Fatalf("coinbase pays %d, want the subsidy %d plus fees 900", reward, BlockSubsidy(1))
+	}
+	if supply, err := n.GetTotalAmount(); err != nil {
+		t.Fatalf("supply %d fails the audit: %v", supply, err)
+	}
+}
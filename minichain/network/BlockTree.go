@@ -0,0 +1,222 @@
+package network
+
+import (
+	"Go-Minichain/data"
+	"Go-Minichain/utils"
+	"fmt"
+	"math/big"
+)
+
+/**
+ * 区块树与链重组
+ *
+ * 区块链中保存所有已知区块组成的区块树（按区块哈希索引），其中累计工作量最大的分支为主链。
+ * 当某个分支的累计工作量超过当前主链时，从分叉点开始回滚当前主链上的区块，
+ * 再依次验证并连接新分支上的区块，同时对已确认的 UTXO 集合做相应的回滚与前滚。
+ */
+
+// blockNode 区块树中的一个节点。
+// 字段说明：
+// - block: 区块本身。
+// - hash: 区块哈希。
+// - parent: 父区块节点，创世块为 nil。
+// - height: 区块高度，创世块为 0。
+// - work: 从创世块到该区块的累计工作量。
+// - invalid: 该区块在连接到主链时未通过验证，其后代区块同样不会被接受。
+// - spent: 区块在主链上时，其交易按顺序花费的输出，用于回滚区块时恢复 UTXO 集合。
+type blockNode struct {
+	block   data.Block
+	hash    string
+	parent  *blockNode
+	height  int
+	work    *big.Int
+	invalid bool
+	spent   []*data.UTXO
+}
+
+// newBlockNode 创建区块树节点并计算其高度与累计工作量。
+func newBlockNode(block data.Block, hash string, parent *blockNode) *blockNode {
+	node := &blockNode{block: block, hash: hash, parent: parent}
+	header := block.GetBlockHeader()
+	node.work = blockWork(header.GetBits())
+	if parent != nil {
+		node.height = parent.height + 1
+		node.work.Add(node.work, parent.work)
+	}
+	return node
+}
+
+// chainUpdate 记录一次加入区块引起的主链变化，供交易池同步未确认交易。
+// 字段说明：
+// - disconnected: 从主链上移除的区块，按从新到旧的顺序排列。
+// - connected: 新连接到主链上的区块，按从旧到新的顺序排列。
+type chainUpdate struct {
+	disconnected []data.Block
+	connected    []data.Block
+}
+
+// blockWork 计算单个区块的工作量，即满足目标值平均需要尝试的哈希次数 2^256/(target+1)。
+// 目标值无效（不大于 0）时工作量为 0。
+func blockWork(bits uint32) *big.Int {
+	target := utils.CompactToBig(bits)
+	if target.Sign() <= 0 {
+		return big.NewInt(0)
+	}
+	denominator := new(big.Int).Add(target, big.NewInt(1))
+	return new(big.Int).Div(new(big.Int).Lsh(big.NewInt(1), 256), denominator)
+}
+
+// acceptBlock 将区块加入区块树，必要时进行链重组。调用方需要持有 c.mutex。
+// 参数:
+// - block: 新区块。
+// - persist: 是否写入存储，从存储中重新加载区块时为 false。
+// 返回值:
+// 返回主链的变化；区块被加入主链或作为侧链保存时错误为 nil，否则返回对应错误，此时主链保持不变。
+func (c *BlockChain) acceptBlock(block data.Block, persist bool) (chainUpdate, error) {
+	update := chainUpdate{}
+	hash := block.Hash()
+	if _, ok := c.index[hash]; ok {
+		return update, &BlockValidationError{Kind: ErrDuplicateBlock, BlockHash: hash}
+	}
+
+	header := block.GetBlockHeader()
+	var parent *blockNode
+	if c.tip == nil {
+		if header.GetPreBlockHash() != "" {
+			return update, &BlockValidationError{Kind: ErrBadParent, BlockHash: hash, Detail: "the first block must be a genesis block"}
+		}
+	} else {
+		parent = c.index[header.GetPreBlockHash()]
+		if parent == nil {
+			return update, &BlockValidationError{Kind: ErrBadParent, BlockHash: hash, Detail: "unknown previous block " + header.GetPreBlockHash()}
+		}
+		if parent.invalid {
+			return update, &BlockValidationError{Kind: ErrBadParent, BlockHash: hash, Detail: "previous block is invalid"}
+		}
+	}
+	if err := c.checkBlock(block, parent); err != nil {
+		return update, err
+	}
+
+	node := newBlockNode(block, hash, parent)
+	// 直接延长主链：完整验证后再写入存储，无效区块不会被持久化
+	if parent == c.tip {
+		if err := c.validateTransactions(block, parent == nil); err != nil {
+			return update, err
+		}
+		if err := c.storeBlock(block, persist); err != nil {
+			return update, err
+		}
+		c.index[hash] = node
+		delete(c.headers, hash)
+		c.connectNode(node)
+		update.connected = append(update.connected, block)
+		return update, nil
+	}
+
+	// 侧链区块：其中的交易要到该分支成为主链时才能验证
+	if err := c.storeBlock(block, persist); err != nil {
+		return update, err
+	}
+	c.index[hash] = node
+	delete(c.headers, hash)
+	if node.work.Cmp(c.tip.work) <= 0 {
+		return update, nil
+	}
+	return c.reorganize(node)
+}
+
+// storeBlock 在需要时将区块写入存储后端。
+func (c *BlockChain) storeBlock(block data.Block, persist bool) error {
+	if !persist {
+		return nil
+	}
+	return c.store.Append(block)
+}
+
+// reorganize 将主链切换到以 newTip 结尾的分支。
+// 如果新分支上有区块未通过验证，则将其及其全部后代（包括 newTip）标记为无效并恢复原来的主链。
+// 新分支中已通过验证的部分在该区块到达之前就已保存，其累计工作量不会超过原主链，因此不需要保留。
+// 调用方需要持有 c.mutex。
+func (c *BlockChain) reorganize(newTip *blockNode) (chainUpdate, error) {
+	update := chainUpdate{}
+	fork := findFork(c.tip, newTip)
+
+	oldBranch := make([]*blockNode, 0)
+	for node := c.tip; node != fork; node = node.parent {
+		oldBranch = append([]*blockNode{node}, oldBranch...)
+	}
+	newBranch := make([]*blockNode, 0)
+	for node := newTip; node != fork; node = node.parent {
+		newBranch = append([]*blockNode{node}, newBranch...)
+	}
+
+	// 复制主链切片，避免覆盖调用方通过 GetBlocks 持有的旧主链
+	c.chain = append([]data.Block(nil), c.chain...)
+	for c.tip != fork {
+		c.disconnectTip()
+	}
+	for _, node := range newBranch {
+		if err := c.validateTransactions(node.block, false); err != nil {
+			c.invalidateDescendants(node)
+			for c.tip != fork {
+				c.disconnectTip()
+			}
+			for _, old := range oldBranch {
+				c.connectNode(old)
+			}
+			return update, err
+		}
+		c.connectNode(node)
+	}
+	for i := len(oldBranch) - 1; i >= 0; i-- {
+		update.disconnected = append(update.disconnected, oldBranch[i].block)
+	}
+	for _, node := range newBranch {
+		update.connected = append(update.connected, node.block)
+	}
+	fmt.Println("Chain reorganization: disconnect", len(oldBranch), "Blocks and connect", len(newBranch),
+		"Blocks, the newest Block is", newTip.hash)
+	return update, nil
+}
+
+// invalidateDescendants 将未通过验证的区块及其在区块树中的全部后代标记为无效，
+// 之后连接在这些区块之后的区块与区块头都会被拒绝。调用方需要持有 c.mutex。
+func (c *BlockChain) invalidateDescendants(bad *blockNode) {
+	bad.invalid = true
+	for _, node := range c.index {
+		if node.height > bad.height && node.ancestor(bad.height) == bad {
+			node.invalid = true
+		}
+	}
+}
+
+// findFork 返回两个区块节点的最近公共祖先。
+func findFork(a *blockNode, b *blockNode) *blockNode {
+	for a.height > b.height {
+		a = a.parent
+	}
+	for b.height > a.height {
+		b = b.parent
+	}
+	for a != b {
+		a = a.parent
+		b = b.parent
+	}
+	return a
+}
+
+// connectNode 将区块节点连接到主链末尾，并更新已确认的 UTXO 集合。
+func (c *BlockChain) connectNode(node *blockNode) {
+	node.spent = c.connectBlock(node.block)
+	c.chain = append(c.chain, node.block)
+	c.tip = node
+}
+
+// disconnectTip 从主链末尾移除最新区块，并回滚已确认的 UTXO 集合。
+func (c *BlockChain) disconnectTip() {
+	c.disconnectBlock(c.tip.block, c.tip.spent)
+	c.tip.spent = nil
+	c.chain = c.chain[:len(c.chain)-1]
+	c.tip = c.tip.parent
+}
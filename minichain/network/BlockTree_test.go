@@ -0,0 +1,131 @@
+package network
+
+import (
+	"Go-Minichain/data"
+	"Go-Minichain/utils"
+	"errors"
+	"math/big"
+	"testing"
+)
+
+// assertMainChain 检查主链由创世块与指定的区块依次组成，且 UTXO 集合只包含这些区块的输出。
+func assertMainChain(t *testing.T, n *NetWork, genesis data.Block, branch []data.Block) {
+	t.Helper()
+	chain := n.GetBlocks()
+	if len(chain) != len(branch)+1 {
+		t.Fatalf("main chain has %d blocks, want %d", len(chain), len(branch)+1)
+	}
+	expected := append([]data.Block{genesis}, branch...)
+	for i := range expected {
+		if chain[i].Hash() != expected[i].Hash() {
+			t.Fatalf("main chain block %d is %s, want %s", i, chain[i].Hash(), expected[i].Hash())
+		}
+	}
+	if height := n.blockchain.GetHeight(); height != len(branch) {
+		t.Fatalf("height %d, want %d", height, len(branch))
+	}
+	for _, block := range branch {
+		body := block.GetBlockBody()
+		for _, out := range body.GetTransctions()[0].GetOutUTXOs() {
+			if _, ok := n.blockchain.UTXOs.Get(out.GetOutpoint()); !ok {
+				t.Fatalf("coinbase output %s of the main chain is missing", out.GetOutpoint().String())
+			}
+		}
+	}
+	if _, err := n.GetTotalAmount(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReorganizeToHeavierBranch(t *testing.T) {
+	n := newTestNetWork(t, 1)
+	genesis := *n.GetNewestBlock()
+	branchA := mineBranch(t, n, genesis.Hash(), 2, 1)
+	assertMainChain(t, n, genesis, branchA)
+
+	// 与主链工作量相同的分支只作为侧链保存
+	branchB := mineBranch(t, n, genesis.Hash(), 2, 2)
+	assertMainChain(t, n, genesis, branchA)
+	for _, block := range branchB {
+		if info, ok := n.blockchain.GetBlockInfo(block.Hash()); !ok || info.MainChain {
+			t.Fatalf("side chain block %s: found %v, main chain %v", block.Hash(), ok, info.MainChain)
+		}
+	}
+
+	// 侧链的累计工作量超过主链后切换到侧链，原主链上的输出被回滚
+	branchB = append(branchB, mineBranch(t, n, branchB[1].Hash(), 1, 2)...)
+	assertMainChain(t, n, genesis, branchB)
+	for _, block := range branchA {
+		body := block.GetBlockBody()
+		for _, out := range body.GetTransctions()[0].GetOutUTXOs() {
+			if _, ok := n.blockchain.UTXOs.Get(out.GetOutpoint()); ok {
+				t.Fatalf("coinbase output %s of the old main chain is still unspent", out.GetOutpoint().String())
+			}
+		}
+	}
+
+	// 原主链重新超过侧链时再切换回去
+	branchA = append(branchA, mineBranch(t, n, branchA[1].Hash(), 2, 1)...)
+	assertMainChain(t, n, genesis, branchA)
+}
+
+func TestReorganizeRejectsInvalidBranch(t *testing.T) {
+	n := newTestNetWork(t, 1)
+	genesis := *n.GetNewestBlock()
+	branchA := mineBranch(t, n, genesis.Hash(), 2, 1)
+
+	// 侧链的第二个区块发放了过多的奖励，只有在连接到主链时才会被发现
+	b1 := mineBlock(t, n, genesis.Hash(), BlockSubsidy(1), 2)
+	if err := n.AddNewBlock(b1); err != nil {
+		t.Fatal(err)
+	}
+	b2 := mineBlock(t, n, b1.Hash(), BlockSubsidy(2)+1, 2)
+	if err := n.AddNewBlock(b2); err != nil {
+		t.Fatalf("side chain block with an invalid coinbase should be stored: %v", err)
+	}
+	b3 := mineBlock(t, n, b2.Hash(), BlockSubsidy(3), 2)
+	if err := n.AddNewBlock(b3); !errors.Is(err, ErrBadCoinbase) {
+		t.Fatalf("reorganization onto an invalid branch returned %v, want %v", err, ErrBadCoinbase)
+	}
+	assertMainChain(t, n, genesis, branchA)
+
+	// 无效区块的后代同样无效，之后延长该分支的区块与区块头都被拒绝，不会再次触发链重组
+	n.blockchain.mutex.Lock()
+	for _, block := range []data.Block{b2, b3} {
+		node := n.blockchain.index[block.Hash()]
+		if !node.invalid || !n.blockchain.headerInvalid(node) {
+			t.Errorf("block %s on the invalid branch is not marked invalid", block.Hash())
+		}
+	}
+	if n.blockchain.index[b1.Hash()].invalid {
+		t.Errorf("valid block %s before the invalid one is marked invalid", b1.Hash())
+	}
+	n.blockchain.mutex.Unlock()
+
+	b4 := mineBlock(t, n, b3.Hash(), BlockSubsidy(4), 2)
+	if err := n.AddNewBlock(b4); !errors.Is(err, ErrBadParent) {
+		t.Fatalf("block after an invalid branch returned %v, want %v", err, ErrBadParent)
+	}
+	b4Header := b4.GetBlockHeader()
+	if _, err := n.blockchain.AcceptHeaders([]data.BlockHeader{b4Header}); !errors.Is(err, ErrBadParent) {
+		t.Fatalf("header after an invalid branch returned %v, want %v", err, ErrBadParent)
+	}
+	assertMainChain(t, n, genesis, branchA)
+}
+
+func TestBlockWork(t *testing.T) {
+	// 要求 zeros 个十六进制前导 0 的目标值平均需要尝试 16^zeros 次，紧凑格式的截断不影响整数部分
+	for zeros := 1; zeros <= 3; zeros++ {
+		bits := utils.BigToCompact(utils.LeadingZerosTarget(zeros))
+		want := new(big.Int).Lsh(big.NewInt(1), uint(4*zeros))
+		if work := blockWork(bits); work.Cmp(want) != 0 {
+			t.Errorf("%d zeros: work %v, want %v", zeros, work, want)
+		}
+	}
+	// 目标值为 0、负数或超过 256 位时区块没有工作量
+	for _, bits := range []uint32{0x00000000, 0x1f8fffff, 0xff123456} {
+		if work := blockWork(bits); work.Sign() != 0 {
+			t.Errorf("bits %08x: work %v, want 0", bits, work)
+		}
+	}
+}
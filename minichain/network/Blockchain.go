@@ -3,146 +3,433 @@ package network
 import (
 	"Go-Minichain/config"
 	"Go-Minichain/data"
-	"Go-Minichain/utils"
+	"Go-Minichain/store"
+	"errors"
 	"fmt"
-	"math/rand"
+	"math/big"
 	"strconv"
 	"sync"
 )
 
 // BlockChain 定义了一个区块链的结构体。
 // 字段说明：
-// - chain: 存储区块链中的所有区块。
+// - chain: 按高度顺序存储主链上的所有区块。
+// - index: 按区块哈希索引的区块树，包含主链与所有侧链上的区块。
+// - tip: 主链上的最新区块节点，即累计工作量最大的分支末端。
+// - headers: 已经通过验证、但区块尚未加入区块树的区块头，见 HeaderChain.go。
+// - bestHeader: 区块头链中累计工作量最大的节点，为 nil 时与 tip 相同。
 // - network: 网络对象，用于与网络交互。
-// - UTXOs: 存储当前未花费的交易输出（UTXO）列表。
+// - UTXOs: 已确认的 UTXO 集合，只随主链上区块的连接与回滚而变化。
+// - store: 区块存储后端，新区块会同步写入其中。
 // - mutex: 用于保护并发访问的互斥锁。
 type BlockChain struct {
-	chain   []data.Block
-	network *NetWork
-	UTXOs   []*data.UTXO
-	mutex   sync.Mutex
+	chain      []data.Block
+	index      map[string]*blockNode
+	tip        *blockNode
+	headers    map[string]*blockNode
+	bestHeader *blockNode
+	network    *NetWork
+	UTXOs      *UTXOSet
+	store      store.BlockStore
+	mutex      sync.Mutex
 }
 
 // NewBlockChain 创建一个新的区块链实例。
 // 参数:
 // - network: 网络对象，用于初始化区块链。
+// - blockStore: 区块存储后端。
 // 返回值:
 // 返回一个指向新创建的区块链实例的指针。
-func NewBlockChain(network *NetWork) *BlockChain {
+func NewBlockChain(network *NetWork, blockStore store.BlockStore) *BlockChain {
 	chain := new(BlockChain)
 	chain.chain = make([]data.Block, 0)
-	chain.UTXOs = make([]*data.UTXO, 0)
+	chain.index = make(map[string]*blockNode)
+	chain.headers = make(map[string]*blockNode)
+	chain.UTXOs = NewUTXOSet()
 	chain.network = network
+	chain.store = blockStore
 	return chain
 }
 
-// SetUp 初始化区块链，生成创世块并加入区块链中。
+// SetUp 初始化区块链。
+// 如果存储后端中已有区块，则重新验证并加载这些区块，恢复区块树、主链以及已确认的 UTXO 集合；
+// 否则生成创世块并加入区块链中。
 func (c *BlockChain) SetUp() {
+	blocks, err := c.store.Load()
+	if err != nil {
+		panic("Load blocks error: " + err.Error())
+	}
+	if len(blocks) > 0 {
+		c.mutex.Lock()
+		for _, block := range blocks {
+			// 存储中可能包含后来被判定为无效的侧链区块，跳过即可
+			if _, err := c.acceptBlock(block, false); err != nil {
+				fmt.Println("Skip stored Block: " + err.Error())
+			}
+		}
+		c.mutex.Unlock()
+		if c.tip == nil {
+			panic("Reload blocks error: no valid genesis Block in the block store")
+		}
+		fmt.Println("Reload " + strconv.Itoa(len(blocks)) + " Blocks from the block store, the height of main chain is " +
+			strconv.Itoa(c.tip.height) + "! ")
+		fmt.Println("And the hash of newest Block is : " + c.GetNewestBlock().Hash())
+		fmt.Println()
+		return
+	}
+
 	transactions := c.GenesisTransactions()
-	header := data.NewBlockHeader("", "", rand.Int63())
 	body := c.network.miner.GetBlockBody(transactions)
+	// 区块哈希只对区块头计算，创世块头同样需要记录 Merkle 根哈希以确定其中的交易
+	header := data.NewBlockHeader("", body.GetMerkleRootHash(), c.network.genesisNonce())
+	header.SetTimestamp(c.network.genesisTime().Unix())
 	genesisBlock := data.NewBlock(*header, body)
 	fmt.Println("Create the genesis Block! ")
-	fmt.Println("And the hash of genesis Block is : " + utils.GetSha256Digest(genesisBlock.ToString()) +
+	fmt.Println("And the hash of genesis Block is : " + genesisBlock.Hash() +
 		", you will see the hash value in next Block's preBlockHash field.")
 	fmt.Println()
-	c.AddNewBlock(*genesisBlock)
+	if err := c.AddNewBlock(*genesisBlock); err != nil {
+		panic("Add genesis Block error: " + err.Error())
+	}
 }
 
-// AddNewBlock 将新区块添加到区块链中。
+// AddNewBlock 验证新区块并将其加入区块树。
+// 区块延长主链时会被直接连接；位于侧链时先保存下来，一旦该分支的累计工作量超过主链则自动进行链重组。
 // 参数:
 // - block: 要添加的新区块。
-func (c *BlockChain) AddNewBlock(block data.Block) {
-	c.chain = append(c.chain, block)
+// 主链发生变化后，交易池会移除已被确认的交易，并将被回滚区块中的交易放回交易池重新验证。
+// 返回值:
+// 区块未通过验证时返回 *BlockValidationError，写入存储失败时返回对应错误，此时主链保持不变。
+func (c *BlockChain) AddNewBlock(block data.Block) error {
+	c.mutex.Lock()
+	update, err := c.acceptBlock(block, true)
+	if err != nil {
+		c.invalidateHeader(err)
+	}
+	c.publishUpdate(update)
+	c.mutex.Unlock()
+	// 在释放区块链的锁之后再通知交易池，交易池重新验证交易时需要再次获取该锁
+	if c.network.txPool != nil && (len(update.connected) > 0 || len(update.disconnected) > 0) {
+		c.network.txPool.Update(update.disconnected, update.connected)
+	}
+	return err
+}
+
+// publishUpdate 发布主链变化的事件：先按高度从高到低发布被移除的区块，再按高度从低到高发布新连接的区块。
+// 调用方需要持有 c.mutex。
+func (c *BlockChain) publishUpdate(update chainUpdate) {
+	events := c.network.events
+	for _, block := range update.disconnected {
+		hash := block.Hash()
+		events.Publish(Event{Type: EventBlockDisconnected, BlockHash: hash, Height: c.index[hash].height})
+	}
+	for _, block := range update.connected {
+		hash := block.Hash()
+		events.Publish(Event{Type: EventBlockConnected, BlockHash: hash, Height: c.index[hash].height})
+	}
 }
 
 // GetNewestBlock 获取区块链中的最新区块。
 // 返回值:
-// 返回指向最新区块的指针。
+// 返回指向最新区块副本的指针，之后的链重组不会改变它。
 func (c *BlockChain) GetNewestBlock() *data.Block {
-	return &c.chain[len(c.chain)-1]
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	block := c.chain[len(c.chain)-1]
+	return &block
 }
 
-// GenesisTransactions 生成创世块的初始交易。
+// HasBlock 判断区块树中是否已有指定哈希的区块，包括侧链上的区块。
+func (c *BlockChain) HasBlock(hash string) bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	_, ok := c.index[hash]
+	return ok
+}
+
+// GetLocator 返回主链的区块定位器：从最新区块开始向前的区块哈希，前 10 个连续，之后间隔按倍数增加，最后一个总是创世块。
+// 对方据此找到双方主链的分叉点，而不需要交换全部区块哈希。
 // 返回值:
-// 返回包含创世交易的列表。
-func (c *BlockChain) GenesisTransactions() []data.Transaction {
-	outUTXOs := make([]*data.UTXO, len(c.network.GetAccounts()))
-	for i := 0; i < len(outUTXOs); i++ {
-		account := c.network.GetAccount(i)
-		outUTXOs[i] = data.NewUTXO(account.GetWalletAddress(), config.MiniChainConfig.GetInitAmount(), account.GetPublicKey())
+// 返回从新到旧排列的区块哈希，最多 64 个。
+func (c *BlockChain) GetLocator() []string {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.locator(c.tip)
+}
+
+// locator 返回从指定节点开始向前的区块定位器。调用方需要持有 c.mutex。
+func (c *BlockChain) locator(node *blockNode) []string {
+	hashes := make([]string, 0)
+	step := 1
+	for node != nil && len(hashes) < 63 {
+		hashes = append(hashes, node.hash)
+		if node.parent == nil {
+			return hashes
+		}
+		if len(hashes) >= 10 {
+			step *= 2
+		}
+		for i := 0; i < step && node.parent != nil; i++ {
+			node = node.parent
+		}
+	}
+	for node != nil && node.parent != nil {
+		node = node.parent
 	}
-	c.ProcessTransactionUTXO([]*data.UTXO{}, outUTXOs)
-	daydreamPrivateKey, daydreamPublicKey := utils.Secp256k1Generate()
-	sign := utils.Signature([]byte("Wecome to Blockchain Lab!!!"), daydreamPrivateKey)
-	return []data.Transaction{*data.NewTransaction(make([]*data.UTXO, 0), outUTXOs, sign, daydreamPublicKey)}
+	if node != nil {
+		hashes = append(hashes, node.hash)
+	}
+	return hashes
 }
 
-// ProcessTransactionUTXO 处理交易中的未花费交易输出（UTXO）。
+// GetHeadersAfter 根据对方的区块定位器找到分叉点，返回主链上分叉点之后的区块头。
 // 参数:
-// - inUTXO: 表示交易中作为输入的 UTXO 列表，这些 UTXO 将被标记为已使用。
-// - outUTXO: 表示交易中作为输出的 UTXO 列表，这些 UTXO 将被添加到区块链中。
-func (c *BlockChain) ProcessTransactionUTXO(inUTXO []*data.UTXO, outUTXO []*data.UTXO) {
+// - locator: 对方主链的区块定位器，没有任何哈希位于本节点主链上时从创世块之后开始。
+// - stop: 返回到该区块为止，为空时不限制。
+// - max: 最多返回的区块头个数。
+// 返回值:
+// 返回从旧到新排列的区块头。
+func (c *BlockChain) GetHeadersAfter(locator []string, stop string, max int) []data.BlockHeader {
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
-
-	for _, utxo := range inUTXO {
-		utxo.SetUsed()
+	start := 1
+	for _, hash := range locator {
+		if node, ok := c.index[hash]; ok && c.onMainChain(node) {
+			start = node.height + 1
+			break
+		}
 	}
+	headers := make([]data.BlockHeader, 0)
+	for height := start; height < len(c.chain) && len(headers) < max; height++ {
+		headers = append(headers, c.chain[height].GetBlockHeader())
+		if stop != "" && c.chain[height].Hash() == stop {
+			break
+		}
+	}
+	return headers
+}
 
-	for _, utxo := range outUTXO {
-		c.AddUTXO(utxo)
+// onMainChain 判断节点是否位于主链上。调用方需要持有 c.mutex。
+func (c *BlockChain) onMainChain(node *blockNode) bool {
+	if node.height >= len(c.chain) {
+		return false
+	}
+	ancestor := c.tip
+	for ancestor != nil && ancestor.height > node.height {
+		ancestor = ancestor.parent
 	}
+	return ancestor == node
 }
 
-// AddUTXO 将新的 UTXO 添加到区块链中。
+// GetBlock 根据区块哈希在区块树中查找区块，包括侧链上的区块。
 // 参数:
-// - u: 要添加的 UTXO。
-func (c *BlockChain) AddUTXO(u *data.UTXO) {
-	c.UTXOs = append(c.UTXOs, u)
+// - hash: 区块哈希。
+// 返回值:
+// 返回找到的区块以及是否找到。
+func (c *BlockChain) GetBlock(hash string) (data.Block, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	node, ok := c.index[hash]
+	if !ok {
+		return data.Block{}, false
+	}
+	return node.block, true
 }
 
-// GetTrueUTXOs 获取指定钱包地址的有效 UTXO 列表。
+// BlockInfo 区块及其在区块树中的位置。
+// 字段说明：
+// - Block: 区块。
+// - Height: 区块高度。
+// - MainChain: 区块是否位于主链上。
+// - TipHeight: 查询时主链的高度，用于计算确认数。
+type BlockInfo struct {
+	Block     data.Block
+	Height    int
+	MainChain bool
+	TipHeight int
+}
+
+// GetConfirmations 返回区块的确认数：主链上的最新区块为 1，侧链上的区块为 -1。
+func (b BlockInfo) GetConfirmations() int {
+	if !b.MainChain {
+		return -1
+	}
+	return b.TipHeight - b.Height + 1
+}
+
+// GetBlockInfo 根据区块哈希在区块树中查找区块及其位置，包括侧链上的区块。
 // 参数:
-// - walletAddress: 钱包地址。
+// - hash: 区块哈希。
 // 返回值:
-// 返回该钱包地址对应的所有有效 UTXO 列表。
-func (c *BlockChain) GetTrueUTXOs(walletAddress string) []*data.UTXO {
-	trueUTXOs := make([]*data.UTXO, 0)
-	for _, utxo := range c.UTXOs {
-		if utxo.GetWalletAddress() == walletAddress && !utxo.IsUsed() {
-			trueUTXOs = append(trueUTXOs, utxo)
-		}
+// 返回区块信息以及是否找到。
+func (c *BlockChain) GetBlockInfo(hash string) (BlockInfo, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	node, ok := c.index[hash]
+	if !ok {
+		return BlockInfo{}, false
 	}
-	return trueUTXOs
+	return BlockInfo{Block: node.block, Height: node.height, MainChain: c.onMainChain(node), TipHeight: c.tip.height}, true
 }
 
-// GetAllAmount 计算区块链中所有账户的总金额，并验证余额是否正确。
+// GetBlockInfoAt 返回主链上指定高度的区块。
+// 参数:
+// - height: 区块高度，创世块为 0。
 // 返回值:
-// 返回区块链中所有账户的总金额。
-func (c *BlockChain) GetAllAmount() int {
+// 返回区块信息，高度超出主链范围时第二个返回值为 false。
+func (c *BlockChain) GetBlockInfoAt(height int) (BlockInfo, bool) {
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
-	sumAccount := 0
+	if height < 0 || height >= len(c.chain) {
+		return BlockInfo{}, false
+	}
+	return BlockInfo{Block: c.chain[height], Height: height, MainChain: true, TipHeight: c.tip.height}, true
+}
 
-	for _, account := range c.network.accounts {
-		utxos := c.GetTrueUTXOs(account.GetWalletAddress())
-		for _, utxo := range utxos {
-			if utxo.IsUsed() {
-				panic("error")
+// FindTransaction 从最新区块开始向前在主链中查找交易。
+// 参数:
+// - txID: 交易标识。
+// 返回值:
+// 返回交易与所在区块的信息，交易不在主链上时第三个返回值为 false。
+func (c *BlockChain) FindTransaction(txID string) (data.Transaction, BlockInfo, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for height := len(c.chain) - 1; height >= 0; height-- {
+		body := c.chain[height].GetBlockBody()
+		for _, tx := range body.GetTransctions() {
+			if tx.TxID() == txID {
+				return tx, BlockInfo{Block: c.chain[height], Height: height, MainChain: true, TipHeight: c.tip.height}, true
 			}
 		}
-		sumAccount += account.GetAmount(utxos)
 	}
-	if sumAccount != config.MiniChainConfig.GetAccountNumber()*config.MiniChainConfig.GetInitAmount() {
-		panic("error Balance:" + strconv.Itoa(sumAccount))
+	return data.Transaction{}, BlockInfo{}, false
+}
+
+// GetTotalWork 返回主链的累计工作量。
+func (c *BlockChain) GetTotalWork() *big.Int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.tip == nil {
+		return big.NewInt(0)
 	}
-	return sumAccount
+	return new(big.Int).Set(c.tip.work)
+}
+
+// GenesisTransactions 生成创世块的初始交易。
+// 返回值:
+// 返回包含创世交易的列表。
+func (c *BlockChain) GenesisTransactions() []data.Transaction {
+	outUTXOs := make([]*data.UTXO, len(c.network.GetAccounts()))
+	for i := 0; i < len(outUTXOs); i++ {
+		account := c.network.GetAccount(i)
+		outUTXOs[i] = data.NewUTXO(config.MiniChainConfig.GetInitAmount(), account.GetPublicKey())
+	}
+	// 创世交易没有输入，凭空发行初始余额
+	genesis := data.NewTransaction(make([]*data.TxInput, 0), outUTXOs)
+	genesis.SetTimestamp(int(c.network.genesisTime().Unix()))
+	return []data.Transaction{*genesis}
+}
+
+// GetTrueUTXOs 获取指定钱包地址已确认的 UTXO 列表，不包含交易池中未确认交易的影响。
+// 参数:
+// - walletAddress: 钱包地址。
+// 返回值:
+// 返回该钱包地址对应的所有已确认 UTXO 列表。
+func (c *BlockChain) GetTrueUTXOs(walletAddress string) []*data.UTXO {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.UTXOs.GetUTXOs(walletAddress)
+}
+
+// getUTXOs 返回在已确认的 UTXO 集合上叠加 delta 之后属于指定钱包地址的 UTXO 列表。
+func (c *BlockChain) getUTXOs(delta *UTXODelta, walletAddress string) []*data.UTXO {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return delta.getUTXOs(c.UTXOs, walletAddress)
+}
+
+// GetAllAmount 计算已确认的 UTXO 集合中的货币总量（包括系统账户与矿工账户），并审计货币供应量：
+// 总量不能超过当前高度下预期发行的货币总量（见 ExpectedSupply），矿工领取的奖励可以少于上限，此时总量会更小。
+// 创世块发行的初始余额取自主链上的创世块。
+// 返回值:
+// 返回已确认的货币总量；总量超过预期发行的货币总量时同时返回错误。
+func (c *BlockChain) GetAllAmount() (int, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	supply := c.UTXOs.GetTotalAmount()
+	genesisBody := c.chain[0].GetBlockBody()
+	genesisAmount := 0
+	for _, tx := range genesisBody.GetTransctions() {
+		genesisAmount += tx.GetOutputAmount()
+	}
+	expected := ExpectedSupply(genesisAmount, c.tip.height)
+	if supply > expected {
+		return supply, errors.New("error Balance: " + strconv.Itoa(supply) + " exceeds the expected supply " +
+			strconv.Itoa(expected) + " at height " + strconv.Itoa(c.tip.height))
+	}
+	return supply, nil
+}
+
+// GetHeight 返回主链的高度，即最新区块的高度，创世块高度为 0。
+func (c *BlockChain) GetHeight() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.tip.height
+}
+
+// ChainTip 主链最新区块以及紧接其后的区块需要满足的条件，由 GetTip 在同一次加锁中读取，彼此一致。
+// 字段说明：
+// - Hash: 最新区块哈希，即下一个区块的前一个区块哈希。
+// - Height: 最新区块高度，下一个区块的高度为 Height+1。
+// - Bits: 下一个区块应当使用的紧凑格式目标值。
+// - MedianTimePast: 下一个区块的时间戳必须大于该值。
+type ChainTip struct {
+	Hash           string
+	Height         int
+	Bits           uint32
+	MedianTimePast int64
+}
+
+// GetTip 一次性读取主链的最新区块及下一个区块的目标值与时间下限。
+// 矿工据此构造 coinbase 交易与区块头，避免分别读取高度与最新区块之间主链发生变化。
+func (c *BlockChain) GetTip() ChainTip {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return ChainTip{Hash: c.tip.hash, Height: c.tip.height, Bits: nextBits(c.tip), MedianTimePast: c.tip.medianTimePast()}
+}
+
+// ComputeFees 假设交易按顺序被打包进紧接最新区块之后的区块，验证交易并计算手续费之和。
+// 参数:
+// - transactions: 待打包的交易，不包含 coinbase 交易。
+// 返回值:
+// 返回手续费之和；有交易未通过验证时返回对应的错误类别。
+func (c *BlockChain) ComputeFees(transactions []data.Transaction) (int, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	delta := NewUTXODelta()
+	fees := 0
+	for i := range transactions {
+		fee, err := c.validateTransaction(&transactions[i], delta)
+		if err != nil {
+			return 0, err
+		}
+		fees += fee
+	}
+	return fees, nil
+}
+
+// Close 关闭区块存储后端，之后不能再加入新区块。
+func (c *BlockChain) Close() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.store.Close()
 }
 
 // GetBlocks 获取区块链中的所有区块。
 // 返回值:
 // 返回存储在区块链中的所有区块。
 func (c *BlockChain) GetBlocks() []data.Block {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
 	return c.chain
 }
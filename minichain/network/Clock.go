@@ -0,0 +1,60 @@
+package network
+
+import (
+	"sync"
+	"time"
+)
+
+/**
+ * 时钟
+ *
+ * 区块时间戳、交易时间戳、交易池过期以及区块时间规则都通过 NetWork 的时钟获取当前时间，而不是直接读取本地时间。
+ * 正常运行时使用 SystemClock；模拟运行时使用 SimulatedClock，时间只在每个区块之前按出块间隔前进，
+ * 使相同种子的两次运行得到完全相同的区块链。
+ */
+
+// SimulationEpoch 模拟运行默认的起始时间。
+var SimulationEpoch = time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// Clock 提供当前时间。
+type Clock interface {
+	Now() time.Time
+}
+
+// SystemClock 返回本地时间的时钟。
+type SystemClock struct{}
+
+func (SystemClock) Now() time.Time {
+	return time.Now()
+}
+
+// SimulatedClock 只在调用 Advance 时前进的时钟，可以被多个协程并发访问。
+// 字段说明：
+// - now: 当前时间。
+// - mutex: 保护 now 的互斥锁。
+type SimulatedClock struct {
+	now   time.Time
+	mutex sync.Mutex
+}
+
+// NewSimulatedClock 创建一个从指定时间开始的模拟时钟。
+// 参数:
+// - start: 起始时间。
+// 返回值:
+// 返回一个指向新创建的模拟时钟的指针。
+func NewSimulatedClock(start time.Time) *SimulatedClock {
+	return &SimulatedClock{now: start}
+}
+
+func (c *SimulatedClock) Now() time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.now
+}
+
+// Advance 使时钟前进指定的时长。
+func (c *SimulatedClock) Advance(d time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.now = c.now.Add(d)
+}
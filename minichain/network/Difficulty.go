@@ -0,0 +1,122 @@
+package network
+
+import (
+	"Go-Minichain/config"
+	"Go-Minichain/utils"
+	"math/big"
+	"sort"
+)
+
+/**
+ * 难度调整与区块时间
+ *
+ * 区块头记录 Unix 秒级时间戳以及紧凑格式的工作量证明目标值（bits）。
+ * 每经过 retargetInterval 个区块，按这段时间内的实际出块时间与 retargetInterval*targetBlockInterval 的比值
+ * 等比例调整目标值：出块太快时目标值变小（难度变大），太慢时目标值变大。
+ * 单次调整的幅度限制在 4 倍以内，目标值不超过 powLimit。
+ *
+ * 区块时间戳必须大于前 medianTimeBlocks 个区块时间戳的中位数（median-time-past），
+ * 且不能超过本地时间 maxFutureBlockTime 秒以上。
+ */
+
+const (
+	// medianTimeBlocks 计算 median-time-past 使用的区块个数。
+	medianTimeBlocks = 11
+	// maxFutureBlockTime 区块时间戳允许超过本地时间的最大秒数。
+	maxFutureBlockTime = 2 * 60 * 60
+	// retargetLimit 单次难度调整中目标值最多变化的倍数。
+	retargetLimit = 4
+)
+
+// powLimit 允许的最大目标值，即区块哈希至少以 1 个十六进制 0 开头。
+var powLimit = utils.LeadingZerosTarget(1)
+
+// timestamp 返回区块头中的时间戳。
+func (node *blockNode) timestamp() int64 {
+	header := node.block.GetBlockHeader()
+	return header.GetTimestamp()
+}
+
+// bits 返回区块头中紧凑格式的目标值。
+func (node *blockNode) bits() uint32 {
+	header := node.block.GetBlockHeader()
+	return header.GetBits()
+}
+
+// ancestor 返回该节点所在分支上指定高度的祖先节点，高度超出范围时返回 nil。
+func (node *blockNode) ancestor(height int) *blockNode {
+	if height < 0 || height > node.height {
+		return nil
+	}
+	for node.height > height {
+		node = node.parent
+	}
+	return node
+}
+
+// medianTimePast 返回以该节点结尾的最近 medianTimeBlocks 个区块时间戳的中位数。
+func (node *blockNode) medianTimePast() int64 {
+	timestamps := make([]int64, 0, medianTimeBlocks)
+	for n := node; n != nil && len(timestamps) < medianTimeBlocks; n = n.parent {
+		timestamps = append(timestamps, n.timestamp())
+	}
+	sort.Slice(timestamps, func(i, j int) bool { return timestamps[i] < timestamps[j] })
+	return timestamps[len(timestamps)/2]
+}
+
+// nextBits 计算紧接 parent 之后的区块应当使用的紧凑格式目标值。
+// 参数:
+// - parent: 父区块节点。
+// 返回值:
+// 返回紧凑格式的目标值。
+func nextBits(parent *blockNode) uint32 {
+	bits := parent.bits()
+	interval := config.MiniChainConfig.GetRetargetInterval()
+	height := parent.height + 1
+	if interval <= 0 || height%interval != 0 {
+		return bits
+	}
+
+	// 最近 interval 个区块间隔的实际用时，限制在期望用时的 1/4 到 4 倍之间；
+	// 第一次调整时从创世块开始计算，只有 interval-1 个间隔
+	first := parent.ancestor(height - interval - 1)
+	if first == nil {
+		first = parent.ancestor(0)
+	}
+	expected := int64(parent.height-first.height) * int64(config.MiniChainConfig.GetTargetBlockInterval())
+	if expected <= 0 {
+		return bits
+	}
+	actual := parent.timestamp() - first.timestamp()
+	if minActual := expected / retargetLimit; actual < minActual {
+		actual = minActual
+	}
+	if actual < 1 {
+		actual = 1
+	}
+	if maxActual := expected * retargetLimit; actual > maxActual {
+		actual = maxActual
+	}
+
+	target := utils.CompactToBig(bits)
+	target.Mul(target, big.NewInt(actual))
+	target.Div(target, big.NewInt(expected))
+	if target.Cmp(powLimit) > 0 {
+		target.Set(powLimit)
+	}
+	return utils.BigToCompact(target)
+}
+
+// NextBits 返回紧接最新区块之后的区块应当使用的紧凑格式目标值。
+func (c *BlockChain) NextBits() uint32 {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return nextBits(c.tip)
+}
+
+// GetMedianTimePast 返回主链最近 medianTimeBlocks 个区块时间戳的中位数，下一个区块的时间戳必须大于该值。
+func (c *BlockChain) GetMedianTimePast() int64 {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.tip.medianTimePast()
+}
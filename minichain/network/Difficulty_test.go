@@ -0,0 +1,125 @@
+package network
+
+import (
+	"Go-Minichain/data"
+	"Go-Minichain/utils"
+	"math/big"
+	"strconv"
+	"testing"
+)
+
+// nodeChain 构造一条从高度 0 开始的区块树分支，每个区块使用相同的目标值与依次给出的时间戳，返回最后一个节点。
+// 区块只包含区块头，用于检查难度调整与 median-time-past，不经过验证。
+func nodeChain(bits uint32, timestamps ...int64) *blockNode {
+	var node *blockNode
+	for i, timestamp := range timestamps {
+		header := data.NewBlockHeader("", "", 0)
+		header.SetBits(bits)
+		header.SetTimestamp(timestamp)
+		block := data.NewBlock(*header, *data.NewBlockBody("", nil))
+		node = newBlockNode(*block, strconv.Itoa(i), node)
+	}
+	return node
+}
+
+// spacedTimestamps 返回 count 个以 spacing 秒为间隔的时间戳。
+func spacedTimestamps(count int, spacing int64) []int64 {
+	timestamps := make([]int64, count)
+	for i := range timestamps {
+		timestamps[i] = SimulationEpoch.Unix() + int64(i)*spacing
+	}
+	return timestamps
+}
+
+// scaledBits 返回 bits 对应的目标值乘以 num/den 后的紧凑格式。
+func scaledBits(bits uint32, num int64, den int64) uint32 {
+	target := utils.CompactToBig(bits)
+	target.Mul(target, big.NewInt(num))
+	target.Div(target, big.NewInt(den))
+	return utils.BigToCompact(target)
+}
+
+func TestNextBitsRetarget(t *testing.T) {
+	// 每 4 个区块调整一次，期望用时为 4*10 秒
+	useConfig(t, "-retargetInterval=4", "-targetBlockInterval=10")
+	bits := utils.BigToCompact(utils.LeadingZerosTarget(3))
+
+	for _, tc := range []struct {
+		name    string
+		blocks  int
+		spacing int64
+		want    uint32
+	}{
+		// 第一次调整时父区块高度为 3，只有高度 0 到 3 之间的 3 个间隔
+		{"first retarget on schedule", 4, 10, bits},
+		{"on schedule", 8, 10, bits},
+		{"twice as fast", 8, 5, scaledBits(bits, 1, 2)},
+		{"twice as slow", 8, 20, scaledBits(bits, 2, 1)},
+		// 实际用时限制在期望用时的 1/4 到 4 倍之间
+		{"clamped when too fast", 8, 0, scaledBits(bits, 1, 4)},
+		{"clamped when too slow", 8, 1000, scaledBits(bits, 4, 1)},
+	} {
+		parent := nodeChain(bits, spacedTimestamps(tc.blocks, tc.spacing)...)
+		if got := nextBits(parent); got != tc.want {
+			t.Errorf("%s: bits %08x, want %08x", tc.name, got, tc.want)
+		}
+	}
+
+	// 目标值不超过 powLimit
+	limit := utils.BigToCompact(powLimit)
+	if got := nextBits(nodeChain(limit, spacedTimestamps(4, 1000)...)); got != limit {
+		t.Errorf("bits %08x above the pow limit %08x", got, limit)
+	}
+}
+
+func TestNextBitsOnlyAtIntervalBoundary(t *testing.T) {
+	useConfig(t, "-retargetInterval=4", "-targetBlockInterval=10")
+	bits := utils.BigToCompact(utils.LeadingZerosTarget(3))
+	timestamps := spacedTimestamps(9, 1000)
+	for height := 0; height < len(timestamps); height++ {
+		got := nextBits(nodeChain(bits, timestamps[:height+1]...))
+		// 只有高度为 4 的倍数的区块调整难度
+		want := bits
+		if (height+1)%4 == 0 {
+			want = scaledBits(bits, 4, 1)
+		}
+		if got != want {
+			t.Errorf("block after height %d: bits %08x, want %08x", height, got, want)
+		}
+	}
+
+	// retargetInterval 为 0 时难度保持不变
+	useConfig(t, "-retargetInterval=0")
+	if got := nextBits(nodeChain(bits, timestamps[:4]...)); got != bits {
+		t.Errorf("retargeting disabled: bits %08x, want %08x", got, bits)
+	}
+}
+
+func TestMedianTimePast(t *testing.T) {
+	// 最近 11 个区块时间戳乱序，中位数只取决于这 11 个区块
+	timestamps := []int64{1000, 5, 9, 1, 8, 2, 7, 3, 6, 4, 11, 10}
+	if got := nodeChain(0x1f0fffff, timestamps...).medianTimePast(); got != 6 {
+		t.Fatalf("median time past %d, want 6", got)
+	}
+	if got := nodeChain(0x1f0fffff, 3, 1, 2).medianTimePast(); got != 2 {
+		t.Fatalf("median time past of a short chain %d, want 2", got)
+	}
+}
+
+func TestTimestampMustExceedMedianTimePast(t *testing.T) {
+	n := newTestNetWork(t, 1)
+	mineBranch(t, n, n.blockchain.GetTip().Hash, 3, 0)
+	medianTime := n.blockchain.GetMedianTimePast()
+	for _, timestamp := range []int64{medianTime - 1, medianTime} {
+		block := buildBlock(t, n, BlockSubsidy(4), nil, func(header *data.BlockHeader) {
+			header.SetTimestamp(timestamp)
+		})
+		assertInvalid(t, n, block, ErrBadTimestamp, "")
+	}
+	block := buildBlock(t, n, BlockSubsidy(4), nil, func(header *data.BlockHeader) {
+		header.SetTimestamp(medianTime + 1)
+	})
+	if err := n.blockchain.ValidateBlock(block); err != nil {
+		t.Fatalf("timestamp after median time past rejected: %v", err)
+	}
+}
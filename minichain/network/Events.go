@@ -0,0 +1,185 @@
+package network
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+/**
+ * 事件总线
+ *
+ * 区块链与交易池在状态变化时向事件总线发布事件，钱包、索引服务等订阅者据此更新自己的状态，而不必轮询：
+ * - 区块连接到主链、因链重组从主链上移除；
+ * - 交易进入交易池、离开交易池（被确认、被替换、被移除、过期或与新的主链冲突）。
+ * 事件按发布顺序编号，同一订阅者收到的事件序号严格递增。发布事件不会阻塞区块链与交易池：
+ * 订阅者的缓冲区已满时，该订阅被关闭，订阅者可以通过 Err 得知原因后重新订阅并重新查询当前状态。
+ * 节点启动时从存储中恢复的区块不发布事件。
+ */
+
+// EventType 事件类型。
+type EventType string
+
+const (
+	// EventBlockConnected 区块连接到主链，链重组时新分支上的区块按高度从低到高依次发布。
+	EventBlockConnected EventType = "blockconnected"
+	// EventBlockDisconnected 区块因链重组从主链上移除，按高度从高到低依次发布，且先于新分支上区块的连接事件。
+	EventBlockDisconnected EventType = "blockdisconnected"
+	// EventTxAccepted 交易进入交易池，包括链重组时从被移除区块放回交易池的交易。
+	EventTxAccepted EventType = "txaccepted"
+	// EventTxRemoved 交易离开交易池，原因见 Reason。
+	EventTxRemoved EventType = "txremoved"
+)
+
+// 交易离开交易池的原因。
+const (
+	// RemoveConfirmed 交易被连接到主链的区块确认。
+	RemoveConfirmed = "confirmed"
+	// RemoveReplaced 交易（或其祖先）被手续费更高的交易替换。
+	RemoveReplaced = "replaced"
+	// RemoveEvicted 交易池超过上限时手续费率最低的交易被移除，或矿工发现交易无法打包。
+	RemoveEvicted = "evicted"
+	// RemoveExpired 交易在交易池中停留的时间超过 poolExpiry。
+	RemoveExpired = "expired"
+	// RemoveConflict 主链变化后交易的输入已被花费或不再存在。
+	RemoveConflict = "conflict"
+)
+
+// AcceptReorg 交易因链重组从被移除的区块放回交易池，作为交易进入交易池事件的原因。
+const AcceptReorg = "reorg"
+
+// ErrSubscriberLagged 订阅者没有及时接收事件，缓冲区已满，订阅被关闭。
+var ErrSubscriberLagged = errors.New("subscriber lagged behind, events were dropped")
+
+// Event 区块链或交易池中发生的一次变化。
+// 字段说明：
+// - Sequence: 事件序号，从 1 开始递增。
+// - Type: 事件类型。
+// - Time: 网络时钟上的发布时间。
+// - BlockHash / Height: 区块事件中的区块哈希与高度。
+// - TxID: 交易事件中的交易标识。
+// - Fee / Size: 交易进入交易池时的手续费与交易规范编码的字节数。
+// - Reason: 交易离开交易池的原因，交易因链重组重新进入交易池时为 AcceptReorg。
+type Event struct {
+	Sequence  uint64
+	Type      EventType
+	Time      time.Time
+	BlockHash string
+	Height    int
+	TxID      string
+	Fee       int
+	Size      int
+	Reason    string
+}
+
+// Subscription 一个事件订阅。
+// 字段说明：
+// - bus: 所属的事件总线。
+// - types: 订阅的事件类型，为 nil 时订阅全部类型。
+// - events: 事件通道，订阅关闭时被关闭。
+// - err: 订阅因订阅者过慢而关闭时为 ErrSubscriberLagged。
+type Subscription struct {
+	bus    *EventBus
+	types  map[EventType]bool
+	events chan Event
+	err    error
+}
+
+// Events 返回接收事件的通道，订阅关闭后通道被关闭。
+func (s *Subscription) Events() <-chan Event {
+	return s.events
+}
+
+// Err 返回订阅关闭的原因：订阅者过慢时为 ErrSubscriberLagged，主动取消或仍在订阅时为 nil。
+func (s *Subscription) Err() error {
+	s.bus.mutex.Lock()
+	defer s.bus.mutex.Unlock()
+	return s.err
+}
+
+// Unsubscribe 取消订阅并关闭事件通道，可以重复调用。
+func (s *Subscription) Unsubscribe() {
+	s.bus.mutex.Lock()
+	defer s.bus.mutex.Unlock()
+	s.bus.remove(s)
+}
+
+// EventBus 事件总线。
+// 字段说明：
+// - subscribers: 全部订阅。
+// - sequence: 最近一次发布的事件序号。
+// - clock: 提供事件时间的时钟。
+// - mutex: 保护 subscribers、sequence 与订阅状态的互斥锁。
+type EventBus struct {
+	subscribers map[*Subscription]bool
+	sequence    uint64
+	clock       Clock
+	mutex       sync.Mutex
+}
+
+// NewEventBus 创建事件总线。
+// 参数:
+// - clock: 提供事件时间的时钟。
+// 返回值:
+// 返回一个指向新创建的事件总线的指针。
+func NewEventBus(clock Clock) *EventBus {
+	return &EventBus{subscribers: make(map[*Subscription]bool), clock: clock}
+}
+
+// Subscribe 订阅事件。
+// 参数:
+// - buffer: 事件通道的缓冲区大小，订阅者未及时接收的事件超过该数量时订阅被关闭。
+// - types: 订阅的事件类型，为空时订阅全部类型。
+// 返回值:
+// 返回新的订阅，不再需要时应调用 Unsubscribe。
+func (b *EventBus) Subscribe(buffer int, types ...EventType) *Subscription {
+	s := &Subscription{bus: b, events: make(chan Event, buffer)}
+	if len(types) > 0 {
+		s.types = make(map[EventType]bool, len(types))
+		for _, t := range types {
+			s.types[t] = true
+		}
+	}
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.subscribers[s] = true
+	return s
+}
+
+// Publish 为事件编号并发送给订阅了该类型的全部订阅者，不会阻塞。
+// 参数:
+// - event: 要发布的事件，Sequence 与 Time 由事件总线填写。
+func (b *EventBus) Publish(event Event) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.sequence++
+	event.Sequence = b.sequence
+	event.Time = b.clock.Now()
+	for s := range b.subscribers {
+		if s.types != nil && !s.types[event.Type] {
+			continue
+		}
+		select {
+		case s.events <- event:
+		default:
+			s.err = ErrSubscriberLagged
+			b.remove(s)
+		}
+	}
+}
+
+// GetSubscriberCount 返回当前的订阅个数。
+func (b *EventBus) GetSubscriberCount() int {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return len(b.subscribers)
+}
+
+// remove 移除订阅并关闭其事件通道。调用方需要持有 b.mutex。
+func (b *EventBus) remove(s *Subscription) {
+	if !b.subscribers[s] {
+		return
+	}
+	delete(b.subscribers, s)
+	close(s.events)
+}
@@ -0,0 +1,115 @@
+package network
+
+import (
+	"testing"
+	"time"
+)
+
+// receive 从订阅中取出一个事件，超时则测试失败。
+func receive(t *testing.T, sub *Subscription) Event {
+	t.Helper()
+	select {
+	case event, ok := <-sub.Events():
+		if !ok {
+			t.Fatalf("subscription closed: %v", sub.Err())
+		}
+		return event
+	case <-time.After(time.Second):
+		t.Fatal("no event was delivered")
+	}
+	return Event{}
+}
+
+func TestEventsAreDeliveredInOrder(t *testing.T) {
+	bus := NewEventBus(NewSimulatedClock(SimulationEpoch))
+	first := bus.Subscribe(8)
+	defer first.Unsubscribe()
+	second := bus.Subscribe(8)
+	defer second.Unsubscribe()
+
+	published := []Event{
+		{Type: EventBlockConnected, Height: 1},
+		{Type: EventTxAccepted, TxID: "a"},
+		{Type: EventTxRemoved, TxID: "a", Reason: RemoveConfirmed},
+		{Type: EventBlockDisconnected, Height: 1},
+	}
+	for _, event := range published {
+		bus.Publish(event)
+	}
+	for _, sub := range []*Subscription{first, second} {
+		for i, want := range published {
+			event := receive(t, sub)
+			if event.Sequence != uint64(i+1) || event.Type != want.Type || event.TxID != want.TxID {
+				t.Fatalf("event %d is %+v, want sequence %d of type %s", i, event, i+1, want.Type)
+			}
+		}
+	}
+}
+
+func TestLaggingSubscriberIsDropped(t *testing.T) {
+	bus := NewEventBus(NewSimulatedClock(SimulationEpoch))
+	slow := bus.Subscribe(1)
+	fast := bus.Subscribe(8)
+	defer fast.Unsubscribe()
+
+	// 缓冲区已满时发布者不等待 slow 接收事件
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 3; i++ {
+			bus.Publish(Event{Type: EventTxAccepted})
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("publish blocked on a lagging subscriber")
+	}
+
+	// slow 仍能读到缓冲区中的事件，随后通道被关闭并说明原因
+	if event := receive(t, slow); event.Sequence != 1 {
+		t.Fatalf("lagging subscriber received %+v, want the first event", event)
+	}
+	if _, ok := <-slow.Events(); ok {
+		t.Fatal("lagging subscription is still open")
+	}
+	if slow.Err() != ErrSubscriberLagged {
+		t.Fatalf("lagging subscription reports %v, want %v", slow.Err(), ErrSubscriberLagged)
+	}
+	if count := bus.GetSubscriberCount(); count != 1 {
+		t.Fatalf("bus has %d subscribers, want 1", count)
+	}
+	slow.Unsubscribe()
+
+	for i := 1; i <= 3; i++ {
+		if event := receive(t, fast); event.Sequence != uint64(i) {
+			t.Fatalf("subscriber received sequence %d, want %d", event.Sequence, i)
+		}
+	}
+	if fast.Err() != nil {
+		t.Fatalf("subscriber that kept up reports %v", fast.Err())
+	}
+}
+
+func TestSubscribeFiltersTypes(t *testing.T) {
+	bus := NewEventBus(NewSimulatedClock(SimulationEpoch))
+	blocks := bus.Subscribe(8, EventBlockConnected, EventBlockDisconnected)
+	defer blocks.Unsubscribe()
+
+	bus.Publish(Event{Type: EventTxAccepted})
+	bus.Publish(Event{Type: EventBlockConnected})
+	bus.Publish(Event{Type: EventTxRemoved})
+	bus.Publish(Event{Type: EventBlockDisconnected})
+
+	// 序号按总线上的发布顺序编号，被过滤的事件仍占用序号
+	for _, want := range []Event{{Sequence: 2, Type: EventBlockConnected}, {Sequence: 4, Type: EventBlockDisconnected}} {
+		if event := receive(t, blocks); event.Sequence != want.Sequence || event.Type != want.Type {
+			t.Fatalf("received %+v, want sequence %d of type %s", event, want.Sequence, want.Type)
+		}
+	}
+	select {
+	case event := <-blocks.Events():
+		t.Fatalf("filtered subscription received %+v", event)
+	default:
+	}
+}
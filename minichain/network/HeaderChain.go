@@ -0,0 +1,161 @@
+package network
+
+import (
+	"Go-Minichain/data"
+	"errors"
+)
+
+/**
+ * 区块头链
+ *
+ * 节点同步时先下载区块头（headers-first），验证区块头之间的链接、难度、工作量证明与时间戳之后，
+ * 再从多个节点并行下载区块体。区块头链保存已经通过验证、但区块尚未加入区块树的区块头，
+ * 以区块树中的区块为根，其中累计工作量最大的分支决定需要下载哪些区块。
+ * 区块加入区块树后对应的区块头从区块头链中移除；区块未通过验证时，对应的区块头及其后代不再参与选择。
+ */
+
+// blockLocation 区块头链中一个尚未下载的区块。
+// 字段说明：
+// - hash: 区块哈希。
+// - height: 区块高度。
+type blockLocation struct {
+	hash   string
+	height int
+}
+
+// AcceptHeaders 验证一组区块头并加入区块头链，每个区块头的前一个区块必须已知或位于它之前。
+// 参数:
+// - headers: 按高度从低到高排列的区块头。
+// 返回值:
+// 返回新加入的区块头个数；某个区块头未通过验证时停止处理并返回 *BlockValidationError，之前的区块头仍被保留。
+func (c *BlockChain) AcceptHeaders(headers []data.BlockHeader) (int, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	added := 0
+	for i := range headers {
+		header := headers[i]
+		hash := header.Hash()
+		if c.findHeader(hash) != nil {
+			continue
+		}
+		parent := c.findHeader(header.GetPreBlockHash())
+		if parent == nil {
+			return added, &BlockValidationError{Kind: ErrBadParent, BlockHash: hash, Detail: "unknown previous block " + header.GetPreBlockHash()}
+		}
+		if c.headerInvalid(parent) {
+			return added, &BlockValidationError{Kind: ErrBadParent, BlockHash: hash, Detail: "previous block is invalid"}
+		}
+		if err := c.checkHeader(header, hash, parent); err != nil {
+			return added, err
+		}
+		node := newBlockNode(*data.NewBlock(header, data.BlockBody{}), hash, parent)
+		c.headers[hash] = node
+		if node.work.Cmp(c.headerTip().work) > 0 {
+			c.bestHeader = node
+		}
+		added++
+	}
+	return added, nil
+}
+
+// HasHeader 判断区块头链或区块树中是否有指定哈希的区块头。
+func (c *BlockChain) HasHeader(hash string) bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.findHeader(hash) != nil
+}
+
+// GetHeaderHeight 返回区块头链中累计工作量最大的分支的高度，不小于主链的高度。
+func (c *BlockChain) GetHeaderHeight() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.headerTip().height
+}
+
+// GetHeaderHeightOf 返回指定区块头的高度，区块头未知时第二个返回值为 false。
+func (c *BlockChain) GetHeaderHeightOf(hash string) (int, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	node := c.findHeader(hash)
+	if node == nil {
+		return 0, false
+	}
+	return node.height, true
+}
+
+// GetHeaderLocator 返回区块头链最长分支的区块定位器，用于继续请求之后的区块头。
+func (c *BlockChain) GetHeaderLocator() []string {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.locator(c.headerTip())
+}
+
+// missingBlocks 返回区块头链最长分支上尚未加入区块树的区块，按高度从低到高排列。
+// 参数:
+// - max: 最多返回的区块个数，从分叉点之后的第一个区块开始计算。
+func (c *BlockChain) missingBlocks(max int) []blockLocation {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	missing := make([]blockLocation, 0)
+	for node := c.headerTip(); node != nil && c.index[node.hash] == nil; node = node.parent {
+		missing = append(missing, blockLocation{hash: node.hash, height: node.height})
+	}
+	// 从低到高排列并截取前 max 个
+	for i, j := 0, len(missing)-1; i < j; i, j = i+1, j-1 {
+		missing[i], missing[j] = missing[j], missing[i]
+	}
+	if len(missing) > max {
+		missing = missing[:max]
+	}
+	return missing
+}
+
+// findHeader 在区块树与区块头链中查找节点。调用方需要持有 c.mutex。
+func (c *BlockChain) findHeader(hash string) *blockNode {
+	if node, ok := c.index[hash]; ok {
+		return node
+	}
+	return c.headers[hash]
+}
+
+// headerTip 返回区块头链中累计工作量最大的有效节点，没有比主链更长的有效分支时返回 tip。调用方需要持有 c.mutex。
+func (c *BlockChain) headerTip() *blockNode {
+	if c.bestHeader != nil && c.headerInvalid(c.bestHeader) {
+		c.bestHeader = nil
+		for _, node := range c.headers {
+			if (c.bestHeader == nil || node.work.Cmp(c.bestHeader.work) > 0) && !c.headerInvalid(node) {
+				c.bestHeader = node
+			}
+		}
+	}
+	if c.bestHeader != nil && c.bestHeader.work.Cmp(c.tip.work) > 0 {
+		return c.bestHeader
+	}
+	return c.tip
+}
+
+// headerInvalid 判断区块头节点或其祖先对应的区块是否未通过验证。调用方需要持有 c.mutex。
+// 区块树中的节点只会连接在有效的父区块之后，因此遇到第一个位于区块树中的祖先即可停止。
+func (c *BlockChain) headerInvalid(node *blockNode) bool {
+	for ; node != nil; node = node.parent {
+		if block, ok := c.index[node.hash]; ok {
+			return block.invalid
+		}
+		if node.invalid {
+			return true
+		}
+	}
+	return false
+}
+
+// invalidateHeader 区块未通过验证时将对应的区块头标记为无效，之后不再下载该分支上的区块。调用方需要持有 c.mutex。
+// 前一个区块未知或重复的区块并不说明区块本身无效，因此不做标记。
+func (c *BlockChain) invalidateHeader(err error) {
+	var validationErr *BlockValidationError
+	if !errors.As(err, &validationErr) || errors.Is(err, ErrDuplicateBlock) || errors.Is(err, ErrBadParent) {
+		return
+	}
+	if node, ok := c.headers[validationErr.BlockHash]; ok {
+		node.invalid = true
+	}
+}
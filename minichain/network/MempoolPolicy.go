@@ -0,0 +1,220 @@
+package network
+
+import (
+	"Go-Minichain/config"
+	"Go-Minichain/data"
+	"errors"
+	"strconv"
+)
+
+/**
+ * 交易池接收策略
+ *
+ * 交易在进入交易池之前必须通过 AcceptTransaction 的检查，而不是等到矿工打包时才发现问题：
+ * 输入引用的输出存在且未被花费、没有与池中其他交易冲突、输入通过被引用输出的锁定脚本（见 data.UTXO.UnlockScript），
+ * 输出金额为正数且不超过输入金额，手续费不低于 minRelayFeeRate 规定的最低手续费。
+ * 交易被拒绝时返回 *TxRejectError，其中的 Kind 为下列错误类别或区块验证的错误类别之一，
+ * 调用方可以使用 errors.Is 判断具体原因，例如输入缺失时等待父交易，手续费不足时提高手续费后重新提交。
+ *
+ * 替换（replace-by-fee）需要被替换的交易主动声明（data.Transaction.SetReplaceable）。新交易与池中交易冲突时，
+ * 所有直接冲突的交易都必须允许被替换，新交易不能花费被替换交易的输出，
+ * 其手续费必须严格高于被替换交易及其后代的手续费之和，且高出的部分不低于新交易自身的最低手续费。
+ */
+
+// maxReplacements 一笔交易最多替换的交易个数，包括直接冲突的交易及其后代。
+const maxReplacements = 100
+
+var (
+	// ErrTxInPool 交易池中已有相同标识的交易。
+	ErrTxInPool = errors.New("transaction already in pool")
+	// ErrPoolFull 交易池已满，且新交易的手续费率不高于池中后代交易包手续费率最低的交易。
+	ErrPoolFull = errors.New("transaction pool is full")
+	// ErrMissingInputs 交易没有输入，或输入引用的输出不存在、已被区块链中的交易花费。
+	ErrMissingInputs = errors.New("missing inputs")
+	// ErrPoolConflict 输入引用的输出已被交易池中的其他交易花费，或同一交易重复引用同一个输出。
+	ErrPoolConflict = errors.New("conflicts with pool transaction")
+	// ErrUnlockFailed 输入的公钥与被引用输出的公钥哈希不一致，或签名无效。
+	ErrUnlockFailed = errors.New("unlock script failed")
+	// ErrNonPositiveOutput 交易存在金额不为正数的输出。
+	ErrNonPositiveOutput = errors.New("non-positive output amount")
+	// ErrFeeTooLow 交易手续费低于按交易大小计算的最低手续费。
+	ErrFeeTooLow = errors.New("fee too low")
+	// ErrReplacementFee 替换交易的手续费没有超过被替换交易的手续费之和，或高出的部分低于其最低手续费。
+	ErrReplacementFee = errors.New("insufficient replacement fee")
+)
+
+// TxRejectError 描述交易未被交易池接收的原因。
+// 字段说明：
+// - Kind: 错误类别，为上面定义的 Err* 或区块验证的错误类别之一。
+// - TxID: 被拒绝的交易标识。
+// - Input: 出错的交易输入序号，与具体输入无关时为 -1。
+// - Detail: 便于排查问题的补充说明。
+type TxRejectError struct {
+	Kind   error
+	TxID   string
+	Input  int
+	Detail string
+}
+
+func (e *TxRejectError) Error() string {
+	msg := "transaction " + e.TxID + " rejected: " + e.Kind.Error()
+	if e.Input >= 0 {
+		msg += " at input " + strconv.Itoa(e.Input)
+	}
+	if e.Detail != "" {
+		msg += " (" + e.Detail + ")"
+	}
+	return msg
+}
+
+// Unwrap 返回错误类别，使 errors.Is(err, ErrFeeTooLow) 等判断可以生效。
+func (e *TxRejectError) Unwrap() error {
+	return e.Kind
+}
+
+// MinRelayFee 返回指定大小的交易需要支付的最低手续费，即 minRelayFeeRate（每 1000 字节）按大小折算后向上取整。
+// 参数:
+// - size: 交易规范编码的字节数。
+// 返回值:
+// 返回最低手续费。
+func MinRelayFee(size int) int {
+	rate := config.MiniChainConfig.GetMinRelayFeeRate()
+	if rate <= 0 {
+		return 0
+	}
+	return (size*rate + 999) / 1000
+}
+
+// replacementSet 找出交易在交易池中直接冲突的交易，并检查它们能否被替换。调用方需要持有 p.mutex。
+// 参数:
+// - tx: 新交易。
+// 返回值:
+// 返回需要被替换的交易标识，包括直接冲突的交易及其后代，没有冲突时为 nil；
+// 冲突的交易不允许被替换、新交易花费了被替换交易的输出或需要替换的交易过多时返回 ErrPoolConflict。
+func (p *TransactionPool) replacementSet(tx *data.Transaction) (map[string]bool, error) {
+	txID := tx.TxID()
+	var replaced map[string]bool
+	for i, in := range tx.GetInputs() {
+		spender, ok := p.spentBy[in.GetOutpoint()]
+		if !ok {
+			continue
+		}
+		if !p.byID[spender].tx.IsReplaceable() {
+			return nil, &TxRejectError{Kind: ErrPoolConflict, TxID: txID, Input: i,
+				Detail: "output " + in.GetOutpoint().String() + " is spent by " + spender + ", which is not replaceable"}
+		}
+		if replaced == nil {
+			replaced = make(map[string]bool)
+		}
+		replaced[spender] = true
+	}
+	if replaced == nil {
+		return nil, nil
+	}
+	p.addDescendants(replaced)
+	if len(replaced) > maxReplacements {
+		return nil, &TxRejectError{Kind: ErrPoolConflict, TxID: txID, Input: -1,
+			Detail: "replaces " + strconv.Itoa(len(replaced)) + " transactions, more than " + strconv.Itoa(maxReplacements)}
+	}
+	for i, in := range tx.GetInputs() {
+		if replaced[in.GetOutpoint().GetTxID()] {
+			return nil, &TxRejectError{Kind: ErrPoolConflict, TxID: txID, Input: i,
+				Detail: "spends an output of a transaction it replaces"}
+		}
+	}
+	return replaced, nil
+}
+
+// checkTransaction 按接收策略检查交易，通过后将交易的改动记入叠加视图。调用方需要持有 p.mutex。
+// 参数:
+// - tx: 待检查的交易。
+// - replaced: 该交易将要替换的交易池条目，已经从交易池中移除；没有替换时为空。
+// 返回值:
+// 检查通过时返回交易手续费，否则返回 *TxRejectError，此时叠加视图保持不变。
+func (p *TransactionPool) checkTransaction(tx *data.Transaction, replaced []*poolEntry) (int, error) {
+	txID := tx.TxID()
+	reject := func(kind error, input int, detail string) (int, error) {
+		return 0, &TxRejectError{Kind: kind, TxID: txID, Input: input, Detail: detail}
+	}
+	if tx.IsCoinbase() {
+		return reject(ErrBadCoinbase, -1, "coinbase transactions are only valid in blocks")
+	}
+	if len(tx.GetInputs()) == 0 {
+		return reject(ErrMissingInputs, -1, "no inputs")
+	}
+
+	fee, err := p.network.GetBlockchain().checkPoolTransaction(tx, p.delta, func(inputs []*data.UTXO) error {
+		return p.checkPolicy(tx, inputs, replaced)
+	})
+	if err != nil {
+		var rejectErr *TxRejectError
+		if errors.As(err, &rejectErr) {
+			return 0, err
+		}
+		// 接收策略之外再按共识规则验证，与区块中的交易保持同样的约束
+		return reject(err, -1, "")
+	}
+	return fee, nil
+}
+
+// checkPolicy 检查交易输入引用的输出、锁定脚本、输出金额与手续费，每个输入的签名只在这里验证一次。
+// 参数:
+// - tx: 待检查的交易。
+// - inputs: 叠加交易池改动之后每个输入引用的输出，不存在或已被花费时为 nil。
+// - replaced: 该交易将要替换的交易池条目。
+// 返回值:
+// 检查通过时返回 nil，否则返回 *TxRejectError。
+func (p *TransactionPool) checkPolicy(tx *data.Transaction, inputs []*data.UTXO, replaced []*poolEntry) error {
+	txID := tx.TxID()
+	reject := func(kind error, input int, detail string) error {
+		return &TxRejectError{Kind: kind, TxID: txID, Input: input, Detail: detail}
+	}
+	inAmount := 0
+	spent := make(map[data.Outpoint]bool)
+	for i, in := range tx.GetInputs() {
+		outpoint := in.GetOutpoint()
+		if spent[outpoint] {
+			return reject(ErrPoolConflict, i, "output "+outpoint.String()+" is spent twice")
+		}
+		if spender, ok := p.spentBy[outpoint]; ok {
+			return reject(ErrPoolConflict, i, "output "+outpoint.String()+" is spent by "+spender)
+		}
+		utxo := inputs[i]
+		if utxo == nil {
+			return reject(ErrMissingInputs, i, "output "+outpoint.String()+" does not exist or is spent")
+		}
+		if !tx.UnlockInput(i, utxo) {
+			return reject(ErrUnlockFailed, i, "")
+		}
+		spent[outpoint] = true
+		inAmount += utxo.GetAmount()
+	}
+
+	outAmount := 0
+	for i, out := range tx.GetOutUTXOs() {
+		if out.GetAmount() <= 0 {
+			return reject(ErrNonPositiveOutput, -1, "output "+strconv.Itoa(i))
+		}
+		outAmount += out.GetAmount()
+	}
+	if outAmount > inAmount {
+		return reject(ErrValueCreated, -1, "outputs "+strconv.Itoa(outAmount)+" exceed inputs "+strconv.Itoa(inAmount))
+	}
+	fee := inAmount - outAmount
+	if minFee := MinRelayFee(tx.Size()); fee < minFee {
+		return reject(ErrFeeTooLow, -1, "fee "+strconv.Itoa(fee)+" is below "+strconv.Itoa(minFee))
+	}
+	if len(replaced) > 0 {
+		replacedFee := 0
+		for _, entry := range replaced {
+			replacedFee += entry.fee
+		}
+		if fee <= replacedFee {
+			return reject(ErrReplacementFee, -1, "fee "+strconv.Itoa(fee)+" does not exceed replaced fees "+strconv.Itoa(replacedFee))
+		}
+		if minFee := MinRelayFee(tx.Size()); fee-replacedFee < minFee {
+			return reject(ErrReplacementFee, -1, "additional fee "+strconv.Itoa(fee-replacedFee)+" is below "+strconv.Itoa(minFee))
+		}
+	}
+	return nil
+}
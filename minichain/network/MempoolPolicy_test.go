@@ -0,0 +1,215 @@
+package network
+
+import (
+	"Go-Minichain/data"
+	"errors"
+	"testing"
+)
+
+// spendOutputs 构造一笔由 from 花费指定输出的已签名交易，依次向 to 支付 amounts 中的金额，扣除手续费后的余额找零给 from。
+// 与 newPayment 不同，输入由调用方指定，因此可以构造与池中交易冲突的交易。
+// 参数:
+// - utxos: 交易花费的输出，必须属于 from。
+// - fee: 交易手续费。
+// - replaceable: 交易是否允许被替换。
+// - amounts: 支付给 to 的各个输出的金额。
+func spendOutputs(t *testing.T, n *NetWork, from data.Account, to data.Account, utxos []*data.UTXO, fee int,
+	replaceable bool, amounts ...int) data.Transaction {
+	t.Helper()
+	inputs := make([]*data.TxInput, 0, len(utxos))
+	change := -fee
+	for _, utxo := range utxos {
+		inputs = append(inputs, data.NewTxInput(utxo.GetOutpoint(), from.GetPublicKey()))
+		change += utxo.GetAmount()
+	}
+	outputs := make([]*data.UTXO, 0, len(amounts)+1)
+	for _, amount := range amounts {
+		outputs = append(outputs, data.NewUTXO(amount, to.GetPublicKey()))
+		change -= amount
+	}
+	if change < 0 {
+		t.Fatalf("outputs %v and fee %d exceed the inputs", amounts, fee)
+	}
+	if change > 0 {
+		outputs = append(outputs, data.NewUTXO(change, from.GetPublicKey()))
+	}
+	tx := data.NewTransaction(inputs, outputs)
+	tx.SetTimestamp(int(n.Now().Unix()))
+	tx.SetReplaceable(replaceable)
+	tx.Sign(from.GetPrivateKey())
+	return *tx
+}
+
+// assertRejected 检查交易被拒绝的原因，并且交易池中仍然恰好是 pooled 中的交易。
+func assertRejected(t *testing.T, n *NetWork, tx data.Transaction, kind error, pooled ...data.Transaction) {
+	t.Helper()
+	if err := n.AcceptTransaction(tx); !errors.Is(err, kind) {
+		t.Fatalf("transaction returned %v, want %v", err, kind)
+	}
+	assertPooled(t, n.txPool, tx, false)
+	for _, p := range pooled {
+		assertPooled(t, n.txPool, p, true)
+	}
+	if count := n.txPool.Count(); count != len(pooled) {
+		t.Fatalf("pool holds %d transactions, want %d", count, len(pooled))
+	}
+	checkPoolInvariants(t, n.txPool)
+}
+
+func TestReplacementRequiresOptIn(t *testing.T) {
+	n := newTestNetWork(t, 1)
+	accounts := n.GetAccounts()
+	utxos := n.GetSpendableUTXOs(accounts[0].GetWalletAddress())
+	original := spendOutputs(t, n, accounts[0], accounts[9], utxos, 100, false, 100)
+	if err := n.AcceptTransaction(original); err != nil {
+		t.Fatal(err)
+	}
+	// 原交易没有声明允许替换，手续费再高的冲突交易也被拒绝
+	replacement := spendOutputs(t, n, accounts[0], accounts[8], utxos, 5000, true, 100)
+	assertRejected(t, n, replacement, ErrPoolConflict, original)
+}
+
+func TestReplacementFeeRules(t *testing.T) {
+	// 最低手续费为每字节 1，便于检查替换交易高出的手续费是否足以支付其自身的大小
+	useConfig(t, "-minRelayFeeRate=1000")
+	n := newTestNetWork(t, 1)
+	accounts := n.GetAccounts()
+	utxos := n.GetSpendableUTXOs(accounts[0].GetWalletAddress())
+	original := spendOutputs(t, n, accounts[0], accounts[9], utxos, 1000, true, 100)
+	if err := n.AcceptTransaction(original); err != nil {
+		t.Fatal(err)
+	}
+	// 子交易花费原交易的找零，原交易被替换时子交易随之被替换
+	child := mustPayment(t, n, accounts[0], accounts[9], 100, 1000)
+	if err := n.AcceptTransaction(child); err != nil {
+		t.Fatal(err)
+	}
+	other := mustPayment(t, n, accounts[1], accounts[9], 100, 1000)
+	if err := n.AcceptTransaction(other); err != nil {
+		t.Fatal(err)
+	}
+
+	// 手续费没有超过原交易及其后代的手续费之和
+	replacedFee := 2000
+	equal := spendOutputs(t, n, accounts[0], accounts[8], utxos, replacedFee, true, 100)
+	assertRejected(t, n, equal, ErrReplacementFee, original, child, other)
+	// 超过了手续费之和，但高出的部分不足以支付替换交易自身的最低手续费
+	barely := spendOutputs(t, n, accounts[0], accounts[8], utxos, replacedFee+1, true, 100)
+	assertRejected(t, n, barely, ErrReplacementFee, original, child, other)
+
+	replacement := spendOutputs(t, n, accounts[0], accounts[8], utxos, replacedFee+MinRelayFee(equal.Size()), true, 100)
+	if err := n.AcceptTransaction(replacement); err != nil {
+		t.Fatal(err)
+	}
+	assertPooled(t, n.txPool, original, false)
+	assertPooled(t, n.txPool, child, false)
+	assertPooled(t, n.txPool, replacement, true)
+	assertPooled(t, n.txPool, other, true)
+	checkPoolInvariants(t, n.txPool)
+}
+
+func TestReplacementEvictedByFullPoolRestoresOriginal(t *testing.T) {
+	n := newTestNetWork(t, 1)
+	accounts := n.GetAccounts()
+	utxos := n.GetSpendableUTXOs(accounts[0].GetWalletAddress())
+	original := spendOutputs(t, n, accounts[0], accounts[9], utxos, 100, true, 100)
+	other := mustPayment(t, n, accounts[1], accounts[9], 100, 9000)
+	for _, tx := range []data.Transaction{original, other} {
+		if err := n.AcceptTransaction(tx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	n.txPool.maxSize = n.txPool.Size()
+	removed := n.events.Subscribe(8, EventTxRemoved)
+	defer removed.Unsubscribe()
+
+	// 替换交易满足手续费规则，但更多的输出使交易池超过字节数上限，而它的手续费率是池中最低的
+	replacement := spendOutputs(t, n, accounts[0], accounts[8], utxos, 200, true, 100, 100, 100)
+	assertRejected(t, n, replacement, ErrPoolFull, original, other)
+	if spendable := n.GetSpendableUTXOs(accounts[0].GetWalletAddress()); len(spendable) != 1 ||
+		spendable[0].GetOutpoint().GetTxID() != original.TxID() {
+		t.Fatalf("spendable outputs %v, want only the change of the restored original", spendable)
+	}
+	select {
+	case event := <-removed.Events():
+		t.Fatalf("rejected replacement published %+v", event)
+	default:
+	}
+}
+
+// poolState 记录交易池中的交易与叠加视图，用于检查被拒绝的交易没有改变交易池。
+func poolState(n *NetWork) string {
+	state := ""
+	for _, entry := range n.txPool.snapshot() {
+		state += entry.txID + ";"
+	}
+	for _, account := range n.GetAccounts() {
+		for _, utxo := range n.GetSpendableUTXOs(account.GetWalletAddress()) {
+			state += utxo.GetOutpoint().String() + ";"
+		}
+	}
+	return state
+}
+
+func TestPoolRejectionKinds(t *testing.T) {
+	n := newTestNetWork(t, 1)
+	accounts := n.GetAccounts()
+	pooledInputs := n.GetSpendableUTXOs(accounts[1].GetWalletAddress())
+	pooled := spendOutputs(t, n, accounts[1], accounts[9], pooledInputs, 100, false, 100)
+	if err := n.AcceptTransaction(pooled); err != nil {
+		t.Fatal(err)
+	}
+	utxos := func(i int) []*data.UTXO {
+		return n.GetSpendableUTXOs(accounts[i].GetWalletAddress())
+	}
+	total := utxos(2)[0].GetAmount()
+
+	for _, tc := range []struct {
+		name string
+		tx   func() data.Transaction
+		kind error
+	}{
+		{"already in pool", func() data.Transaction { return pooled }, ErrTxInPool},
+		{"missing inputs", func() data.Transaction {
+			// 从未提交的交易的输出不存在
+			unknown := spendOutputs(t, n, accounts[2], accounts[3], utxos(2), 100, false, 100)
+			return spendOutputs(t, n, accounts[3], accounts[4], unknown.GetOutUTXOs()[:1], 10, false, 50)
+		}, ErrMissingInputs},
+		{"conflict with pool", func() data.Transaction {
+			return spendOutputs(t, n, accounts[1], accounts[8], pooledInputs, 500, false, 100)
+		}, ErrPoolConflict},
+		{"output spent twice", func() data.Transaction {
+			return spendOutputs(t, n, accounts[2], accounts[8], append(utxos(2), utxos(2)...), 100, false, total)
+		}, ErrPoolConflict},
+		{"unlock failed", func() data.Transaction {
+			// accounts[3] 的签名无法解锁 accounts[2] 的输出
+			return spendOutputs(t, n, accounts[3], accounts[8], utxos(2), 100, false, 100)
+		}, ErrUnlockFailed},
+		{"non-positive output", func() data.Transaction {
+			return spendOutputs(t, n, accounts[2], accounts[8], utxos(2), 100, false, 100, 0)
+		}, ErrNonPositiveOutput},
+		{"value created", func() data.Transaction {
+			return spendOutputs(t, n, accounts[2], accounts[8], utxos(2), -1, false, total+1)
+		}, ErrValueCreated},
+		{"fee too low", func() data.Transaction {
+			return spendOutputs(t, n, accounts[2], accounts[8], utxos(2), 0, false, 100)
+		}, ErrFeeTooLow},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			tx := tc.tx()
+			before := poolState(n)
+			err := n.AcceptTransaction(tx)
+			var rejectErr *TxRejectError
+			if !errors.As(err, &rejectErr) || !errors.Is(err, tc.kind) || rejectErr.Kind != tc.kind {
+				t.Fatalf("returned %v, want a *TxRejectError of kind %v", err, tc.kind)
+			}
+			if rejectErr.TxID != tx.TxID() {
+				t.Fatalf("rejection names %s, want %s", rejectErr.TxID, tx.TxID())
+			}
+			if after := poolState(n); after != before {
+				t.Fatalf("rejected transaction changed the pool from %s to %s", before, after)
+			}
+			checkPoolInvariants(t, n.txPool)
+		})
+	}
+}
@@ -5,52 +5,220 @@ import (
 	"Go-Minichain/data"
 	"Go-Minichain/spv"
 	"Go-Minichain/utils"
+	"context"
 	"fmt"
-	"math/rand"
-	"os"
-	"strings"
+	"sync"
+	"time"
 )
 
 /**
  * 矿工线程
  *
  * 该线程的主要工作就是不断的进行交易打包、Merkle树根哈希值计算、构造区块，
- * 然后尝试使用不同的随机字段（nonce）进行区块的哈希值计算以生成新的区块添加到区块中
+ * 然后由多个工作协程尝试不同的随机字段（nonce）计算区块头的哈希值，以生成新的区块添加到区块链中。
+ * 主链的最新区块发生变化时，正在进行的挖矿会被取消。
  *
  */
 
 // MinerNode 定义了一个矿工节点的结构体。
 // 字段说明：
 // - network: 网络对象，用于与区块链网络交互。
+// - account: 矿工账户，接收 coinbase 交易发放的挖矿奖励与交易手续费。
+// - jobParent: 正在挖的区块的前一个区块哈希，没有进行中的挖矿时为空。
+// - cancelJob: 取消正在进行的挖矿。
+// - stats: 累计的挖矿统计信息。
+// - threads: 挖矿工作协程个数。
+// - mutex: 保护 jobParent、cancelJob 与 stats 的互斥锁。
 type MinerNode struct {
-	network *NetWork
+	network   *NetWork
+	account   *data.Account
+	jobParent string
+	cancelJob context.CancelFunc
+	stats     MinerStats
+	threads   int
+	mutex     sync.Mutex
 }
 
 // NewMinerNode 创建一个新的矿工节点实例。
 // 参数:
 // - network: 网络对象，用于初始化矿工节点。
+// - account: 矿工账户。
 // 返回值:
 // 返回一个指向新创建的矿工节点实例的指针。
-func NewMinerNode(network *NetWork) *MinerNode {
-	return &MinerNode{network: network}
-}
-
-// Run 启动矿工节点的工作流程。
-// 该方法会不断检查交易池是否已满，如果已满则打包交易、生成区块并广播到网络中。
-func (m *MinerNode) Run() {
-	for i := 0; i < 3; {
-		if m.network.CheckTransactionIsFull() {
-			transactions := m.network.GetAllTransactions()
-			if !m.Check(transactions) {
-				fmt.Println("The transaction is not in the blockchain")
-				os.Exit(0)
+func NewMinerNode(network *NetWork, account *data.Account) *MinerNode {
+	return &MinerNode{network: network, account: account, threads: config.MiniChainConfig.GetMinerThreads()}
+}
+
+// GetAccount 返回矿工账户。
+func (m *MinerNode) GetAccount() *data.Account {
+	return m.account
+}
+
+// GetStats 返回累计的挖矿统计信息。
+func (m *MinerNode) GetStats() MinerStats {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.stats
+}
+
+// NotifyNewTip 通知矿工主链的最新区块已经变化，如果正在挖的区块不是以该区块为前一个区块，则取消挖矿。
+// 参数:
+// - hash: 新的最新区块哈希。
+func (m *MinerNode) NotifyNewTip(hash string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if m.cancelJob != nil && m.jobParent != hash {
+		m.cancelJob()
+	}
+}
+
+// startJob 登记一次挖矿，使 NotifyNewTip 可以取消它。登记前最新区块已经变化时立即取消。
+func (m *MinerNode) startJob(parent string, cancel context.CancelFunc) {
+	m.mutex.Lock()
+	m.jobParent = parent
+	m.cancelJob = cancel
+	m.mutex.Unlock()
+	if m.network.GetNewestBlock().Hash() != parent {
+		cancel()
+	}
+}
+
+// finishJob 结束一次挖矿并累计统计信息。
+func (m *MinerNode) finishJob(stats MinerStats) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.jobParent = ""
+	m.cancelJob = nil
+	m.stats.add(stats)
+}
+
+// syncRetryInterval 同步期间矿工暂停挖矿，每隔这段时间检查一次同步是否完成。
+const syncRetryInterval = time.Second
+
+// Run 启动矿工节点的工作流程，直到 ctx 被取消。
+// 矿工等待交易池的新交易通知或出块间隔计时器，而不是轮询交易池：交易池中的交易足以填满一个区块，或者距离上次出块已经过了
+// config 中的 targetBlockInterval 秒时，按手续费率选择交易（见 BuildBlockTemplate）、生成区块并广播到网络中。
+// 区块的第一笔交易为向矿工发放奖励的 coinbase 交易，因此每个区块最多打包 MaxTransactionCount-1 笔普通交易。
+// 区块同步期间（见 Sync.go）暂停挖矿。
+// 交易在区块被连接到主链后才会从交易池中移除；无法打包的交易会被移出交易池。
+// 参数:
+// - ctx: 停止矿工的上下文，取消时正在进行的挖矿也会被中止。
+func (m *MinerNode) Run(ctx context.Context) {
+	interval := time.Duration(config.MiniChainConfig.GetTargetBlockInterval()) * time.Second
+	timer := time.NewTimer(interval)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			fmt.Println("MinerNode is stopped")
+			return
+		case <-m.network.txPool.Added():
+			if !m.templateReady() {
+				continue
+			}
+		case <-timer.C:
+		}
+
+		next := interval
+		if m.network.IsSyncing() {
+			// 同步完成之前挖出的区块很可能被其他节点已有的区块取代，稍后再试
+			next = syncRetryInterval
+		} else {
+			m.mineNext(ctx)
+		}
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
 			}
-			blockBody := m.GetBlockBody(transactions)
-			m.Mine(blockBody)
-			i++
-			fmt.Println("The sum of all amount", m.network.GetTotalAmount())
+		}
+		timer.Reset(next)
+	}
+}
+
+// mineNext 按区块模板打包交易并挖出紧接最新区块之后的区块。
+// 最新区块只读取一次，区块模板、coinbase 交易的高度与区块头的前一个区块哈希都以它为准；
+// 之后主链发生变化时，startJob 会立即取消这次挖矿。
+// 返回值:
+// 与 Mine 相同。
+func (m *MinerNode) mineNext(ctx context.Context) error {
+	tip := m.network.GetBlockchain().GetTip()
+	transactions, fees := m.selectTransactions(tip.Height + 1)
+	coinbase := m.newCoinbaseTransaction(tip.Height+1, fees)
+	blockBody := m.GetBlockBody(append([]data.Transaction{*coinbase}, transactions...))
+	err := m.mine(ctx, tip, blockBody)
+	if err == nil {
+		amount, supplyErr := m.network.GetTotalAmount()
+		if supplyErr != nil {
+			fmt.Println("Supply audit failed: " + supplyErr.Error())
+		} else {
+			fmt.Println("The sum of all amount", amount)
 		}
 	}
+	return err
+}
+
+// templateReady 判断交易池中的交易是否足以填满一个区块（为 coinbase 预留一个位置），或者交易池已满。
+func (m *MinerNode) templateReady() bool {
+	pool := m.network.txPool
+	return pool.Count() >= config.MiniChainConfig.GetMaxTransactionCount()-1 ||
+		pool.Size() >= config.MiniChainConfig.GetMaxBlockSize() || pool.IsFull()
+}
+
+// selectTransactions 从区块模板中选出可以打包的交易并计算手续费之和。
+// 每笔交易只通过 ValidateTransaction 验证一次，其中已经包含签名检查；
+// 签名无效或基于已确认 UTXO 集合验证失败的交易会被移出交易池，依赖它们的交易也不会被打包。
+// 参数:
+// - height: 要打包的区块高度。
+// 返回值:
+// 返回按打包顺序排列的有效交易以及它们的手续费之和。
+func (m *MinerNode) selectTransactions(height int) ([]data.Transaction, int) {
+	blockchain := m.network.GetBlockchain()
+	delta := NewUTXODelta()
+	valid := make([]data.Transaction, 0)
+	invalid := make([]string, 0)
+	fees := 0
+	for _, tx := range m.BuildBlockTemplate(height) {
+		fee, err := blockchain.ValidateTransaction(&tx, delta)
+		if err != nil {
+			invalid = append(invalid, tx.TxID())
+			continue
+		}
+		valid = append(valid, tx)
+		fees += fee
+	}
+	if len(invalid) > 0 {
+		evicted := m.network.txPool.Evict(invalid...)
+		fmt.Println("Evict", evicted, "invalid transactions from TransactionPool")
+	}
+	return valid, fees
+}
+
+// GetCoinbaseTransaction 生成紧接最新区块之后的区块中的 coinbase 交易，
+// 向矿工账户发放该高度的挖矿奖励与交易手续费之和。
+// 参数:
+// - transactions: 将被打包进区块的交易，不包含 coinbase 交易。
+// 返回值:
+// 返回 coinbase 交易；有交易未通过验证时返回对应的错误类别。
+func (m *MinerNode) GetCoinbaseTransaction(transactions []data.Transaction) (*data.Transaction, error) {
+	fees, err := m.network.GetBlockchain().ComputeFees(transactions)
+	if err != nil {
+		return nil, err
+	}
+	height := m.network.GetBlockchain().GetHeight() + 1
+	return m.newCoinbaseTransaction(height, fees), nil
+}
+
+// newCoinbaseTransaction 生成指定高度的 coinbase 交易，向矿工账户发放挖矿奖励与手续费之和。
+func (m *MinerNode) newCoinbaseTransaction(height int, fees int) *data.Transaction {
+	reward := BlockSubsidy(height) + fees
+	outUTXOs := make([]*data.UTXO, 0)
+	if reward > 0 {
+		outUTXOs = append(outUTXOs, data.NewUTXO(reward, m.account.GetPublicKey()))
+	}
+	coinbase := data.NewCoinbaseTransaction(height, outUTXOs)
+	coinbase.SetTimestamp(int(m.network.Now().Unix()))
+	return coinbase
 }
 
 // GetBlockBody 根据交易列表生成区块体。
@@ -62,66 +230,75 @@ func (m *MinerNode) GetBlockBody(transactions []data.Transaction) data.BlockBody
 	if transactions == nil || len(transactions) > config.MiniChainConfig.GetMaxTransactionCount() {
 		panic("transactions can not be nil or be more than config.MaxTransactionCount")
 	}
-	hashes := make([]string, 0)
-	for _, tx := range transactions {
-		hashes = append(hashes, utils.GetSha256Digest(tx.ToString()))
-	}
-
-	for len(hashes) > 1 {
-		var newLevel []string
-		for i := 0; i < len(hashes); i += 2 {
-			if i+1 == len(hashes) {
-				newLevel = append(newLevel, utils.GetSha256Digest(hashes[i]+hashes[i]))
-			} else {
-				newLevel = append(newLevel, utils.GetSha256Digest(hashes[i]+hashes[i+1]))
-			}
-		}
-		hashes = newLevel
-	}
-	return *data.NewBlockBody(hashes[0], transactions)
+	return *data.NewBlockBody(data.ComputeMerkleRootHash(transactions), transactions)
 }
 
-// Mine 尝试挖矿，生成新的区块。
+// Mine 尝试挖矿，生成紧接最新区块之后的新区块；区块需要通过区块链的验证才会被添加并广播。
+// 哈希计算分配给 threads（默认为 config 中的 minerThreads）个工作协程，ctx 被取消或主链的最新区块变化时挖矿立即停止。
 // 参数:
+// - ctx: 取消挖矿的上下文。
 // - blockBody: 区块体对象，包含交易信息和 Merkle 树根哈希。
-func (m *MinerNode) Mine(blockBody data.BlockBody) {
-	block := m.GetBlock(blockBody)
-	for {
-		blockHash := utils.GetSha256Digest(block.ToString())
-		if strings.HasPrefix(blockHash, utils.HashPrefixTarget()) {
-			header := block.GetBlockHeader()
-			fmt.Println("Mined a new Block! Previous Block Hash is: " + header.GetPreBlockHash())
-			fmt.Println("And the hash of this Block is : " + utils.GetSha256Digest(block.ToString()) +
-				", you will see the hash value in next Block's preBlockHash field.")
-			fmt.Println()
-			m.network.AddNewBlock(*block)
-			m.BroadCast(*block)
-			break
-		} else {
-			nonce := rand.Int63()
-			block.SetNonce(int64(nonce))
-		}
+// 返回值:
+// 区块被挖出并加入区块链时返回 nil；挖矿被取消时返回 context.Canceled 等错误，区块未通过验证时返回验证错误。
+func (m *MinerNode) Mine(ctx context.Context, blockBody data.BlockBody) error {
+	return m.mine(ctx, m.network.GetBlockchain().GetTip(), blockBody)
+}
+
+// mine 挖出紧接 tip 之后的区块，见 Mine。
+func (m *MinerNode) mine(ctx context.Context, tip ChainTip, blockBody data.BlockBody) error {
+	block := m.newBlock(tip, blockBody)
+	header := block.GetBlockHeader()
+	jobCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	m.startJob(header.GetPreBlockHash(), cancel)
+	solved, stats, err := solveHeader(jobCtx, header, m.threads)
+	m.finishJob(stats)
+	fmt.Printf("Tried %d hashes in %.2fs, hashrate %.0f H/s\n", stats.Hashes, stats.Elapsed.Seconds(), stats.HashRate())
+	if err != nil {
+		fmt.Println("Mining is aborted: " + err.Error())
+		fmt.Println()
+		return err
+	}
+
+	block = data.NewBlock(solved, blockBody)
+	blockHash := block.Hash()
+	if err := m.network.AddNewBlock(*block); err != nil {
+		// 区块未通过验证时直接丢弃，不广播给其他节点，并重新验证交易池中的交易
+		fmt.Println("The mined Block is rejected: " + err.Error())
+		fmt.Println()
+		m.network.txPool.Update(nil, nil)
+		return err
 	}
+	fmt.Println("Mined a new Block! Previous Block Hash is: " + solved.GetPreBlockHash())
+	fmt.Println("And the hash of this Block is : " + blockHash +
+		", you will see the hash value in next Block's preBlockHash field.")
+	fmt.Println()
+	return nil
 }
 
-// GetBlock 根据区块体生成一个完整的区块对象。
+// GetBlock 根据区块体生成一个紧接最新区块之后的完整区块对象。
 // 参数:
 // - blockBody: 区块体对象。
 // 返回值:
 // 返回一个包含区块头和区块体的完整区块对象。
 func (m *MinerNode) GetBlock(blockBody data.BlockBody) *data.Block {
-	lastBlock := m.network.GetNewestBlock()
-	if lastBlock == nil {
-		return nil
-	} else {
-		preBlockHash := utils.GetSha256Digest(lastBlock.ToString())
-		header := data.NewBlockHeader(
-			preBlockHash,
-			blockBody.GetMerkleRootHash(),
-			rand.Int63(),
-		)
-		return data.NewBlock(*header, blockBody)
+	return m.newBlock(m.network.GetBlockchain().GetTip(), blockBody)
+}
+
+// newBlock 根据区块体生成紧接 tip 之后的区块，目标值与时间下限同样取自 tip。
+func (m *MinerNode) newBlock(tip ChainTip, blockBody data.BlockBody) *data.Block {
+	header := data.NewBlockHeader(
+		tip.Hash,
+		blockBody.GetMerkleRootHash(),
+		m.network.rand.Int63(),
+	)
+	// 目标值按难度调整规则设置；时间戳取自网络的时钟，且必须大于 median-time-past，出块很快时可能略晚于当前时间
+	header.SetBits(tip.Bits)
+	header.SetTimestamp(m.network.Now().Unix())
+	if header.GetTimestamp() <= tip.MedianTimePast {
+		header.SetTimestamp(tip.MedianTimePast + 1)
 	}
+	return data.NewBlock(*header, blockBody)
 }
 
 // Check 验证交易的有效性。
@@ -130,12 +307,11 @@ func (m *MinerNode) GetBlock(blockBody data.BlockBody) *data.Block {
 // 返回值:
 // 返回布尔值，表示交易是否通过验证。
 func (m *MinerNode) Check(transactions []data.Transaction) bool {
-	for _, transactions := range transactions {
-		data := data.UTXO2Bytes(transactions.GetInUTXOs(), transactions.GetOutUTXOs())
-		sign := transactions.GeSendSign()
-		publicKey := transactions.GetSendPublicKey()
-		if !utils.Verify(data, sign, &publicKey) {
-			return false
+	for _, transaction := range transactions {
+		for i := range transaction.GetInputs() {
+			if !transaction.VerifyInput(i) {
+				return false
+			}
 		}
 	}
 	return true
@@ -155,7 +331,7 @@ func (m *MinerNode) GetProof(txHash string) spv.Proof {
 		blockBody := block.GetBlockBody()
 		for _, tx := range blockBody.GetTransctions() {
 			// 计算当前交易的哈希值并与传入的 txHash 进行比对，找到匹配的交易。
-			if utils.GetSha256Digest(tx.ToString()) == txHash {
+			if tx.TxID() == txHash {
 				flag = true
 				proofBlock = block
 				break // 找到交易后立即跳出循环
@@ -176,7 +352,7 @@ func (m *MinerNode) GetProof(txHash string) spv.Proof {
 	pathTxHash := txHash
 	blockBody := proofBlock.GetBlockBody()
 	for _, transaction := range blockBody.GetTransctions() {
-		hashList = append(hashList, utils.GetSha256Digest(transaction.ToString()))
+		hashList = append(hashList, transaction.TxID())
 	}
 	for {
 		if len(hashList) == 1 {
@@ -215,6 +391,9 @@ func (m *MinerNode) GetProof(txHash string) spv.Proof {
 // - block: 新生成的区块。
 func (m *MinerNode) BroadCast(block data.Block) {
 	spvPeers := m.network.GetSPVPeers()
+	if len(spvPeers) == 0 {
+		return
+	}
 	for _, spvPeer := range spvPeers {
 		spvPeer.Accept(block.GetBlockHeader())
 	}
@@ -0,0 +1,66 @@
+package network
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// runMiner 在后台运行矿工，测试结束时停止并等待其退出。
+func runMiner(t *testing.T, n *NetWork) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		n.miner.Run(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+}
+
+func TestMinerMinesWhenPoolFillsTemplate(t *testing.T) {
+	// 每个区块除 coinbase 外放两笔交易；出块间隔足够长，计时器不会在测试期间触发
+	useConfig(t, "-maxTransactionCount=3", "-targetBlockInterval=3600")
+	n := newTestNetWork(t, 1)
+	runMiner(t, n)
+	accounts := n.GetAccounts()
+
+	first := mustPayment(t, n, accounts[0], accounts[9], 100, 10)
+	if err := n.AcceptTransaction(first); err != nil {
+		t.Fatal(err)
+	}
+	// 一笔交易还不足以填满区块，矿工收到通知后继续等待
+	time.Sleep(200 * time.Millisecond)
+	if height := n.blockchain.GetHeight(); height != 0 {
+		t.Fatalf("miner produced height %d before the template was full", height)
+	}
+
+	second := mustPayment(t, n, accounts[1], accounts[9], 100, 10)
+	if err := n.AcceptTransaction(second); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "a block triggered by the pool", func() bool { return n.blockchain.GetHeight() == 1 })
+	block := n.GetNewestBlock()
+	body := block.GetBlockBody()
+	if transactions := body.GetTransctions(); len(transactions) != 3 {
+		t.Fatalf("block holds %d transactions, want the coinbase and both payments", len(transactions))
+	}
+	waitFor(t, "the pool to drain", n.txPool.IsEmpty)
+}
+
+func TestMinerMinesOnTimer(t *testing.T) {
+	useConfig(t, "-targetBlockInterval=1")
+	n := newTestNetWork(t, 1)
+	runMiner(t, n)
+
+	// 交易池为空，出块间隔到达后矿工挖出只包含 coinbase 交易的区块
+	waitFor(t, "a block triggered by the timer", func() bool { return n.blockchain.GetHeight() >= 1 })
+	block := n.GetNewestBlock()
+	body := block.GetBlockBody()
+	if transactions := body.GetTransctions(); len(transactions) != 1 || !transactions[0].IsCoinbase() {
+		t.Fatalf("timer block holds %d transactions, want only the coinbase", len(transactions))
+	}
+}
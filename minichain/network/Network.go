@@ -4,7 +4,14 @@ import (
 	"Go-Minichain/config"
 	"Go-Minichain/data"
 	"Go-Minichain/spv"
+	"Go-Minichain/store"
+	"Go-Minichain/workload"
+	"context"
 	"fmt"
+	"math/rand"
+	"strconv"
+	"sync"
+	"time"
 )
 
 // NetWork 定义了一个区块链网络的结构体。
@@ -14,49 +21,209 @@ import (
 // - blockchain: 区块链对象，用于管理区块和 UTXO。
 // - miner: 矿工节点，负责挖矿和生成新区块。
 // - spvPeer: SPV 节点列表，用于轻量级客户端验证。
+// - generator: 交易负载生成器，向交易池提交模拟交易。
+// - seed: 随机数种子，交易负载与区块头的随机字段都由它决定。
+// - clock: 提供当前时间的时钟。
+// - rand: 由种子初始化的随机数生成器，只在初始化区块链与矿工协程中使用。
+// - simulated: 是否为模拟运行，见 NewSimulatedNetWork。
+// - relay: 与其他节点之间的区块与交易转发，模拟运行时为 nil。
+// - blockMutex: 保证区块逐个加入区块链，使 SPV 节点按顺序收到区块头。
+// - ready: 区块链初始化完成、节点间通信启动之后关闭的通道。
+// - events: 区块链与交易池发布事件的事件总线。
 type NetWork struct {
 	accounts   []data.Account
 	txPool     *TransactionPool
 	blockchain *BlockChain
-	miner      MinerNode
+	miner      *MinerNode
 	spvPeer    []*SPVPeer
+	generator  *workload.Generator
+	seed       int64
+	clock      Clock
+	rand       *rand.Rand
+	simulated  bool
+	relay      *Relay
+	blockMutex sync.Mutex
+	ready      chan struct{}
+	events     *EventBus
 }
 
-// NewNetWork 创建一个新的区块链网络实例。
+// NewNetWork 创建一个新的区块链网络实例，使用本地时间，账户密钥随机生成或从数据目录中加载，
+// 随机数种子为 config 中的 workloadSeed，为 0 时使用当前时间。
 // 返回值:
 // 返回一个指向新创建的区块链网络实例的指针。
 func NewNetWork() *NetWork {
-	network := new(NetWork)
+	seed := config.MiniChainConfig.GetWorkloadSeed()
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	return newNetWork(seed, SystemClock{}, false)
+}
+
+// newNetWork 创建区块链网络实例。
+// 参数:
+// - seed: 随机数种子。
+// - clock: 提供当前时间的时钟。
+// - simulated: 是否为模拟运行，模拟运行时账户密钥由种子派生，区块只保存在内存中。
+// 返回值:
+// 返回一个指向新创建的区块链网络实例的指针。
+func newNetWork(seed int64, clock Clock, simulated bool) *NetWork {
+	network := &NetWork{seed: seed, clock: clock, rand: rand.New(rand.NewSource(seed)), simulated: simulated,
+		ready: make(chan struct{}), events: NewEventBus(clock)}
+	dataDir := config.MiniChainConfig.GetDataDir()
+	if simulated {
+		dataDir = ""
+	}
 	fmt.Println("Accounts and SPVPeers config...")
-	accounts := make([]data.Account, config.MiniChainConfig.GetAccountNumber())
-	peers := make([]*SPVPeer, config.MiniChainConfig.GetAccountNumber())
-	for i := range accounts {
-		accounts[i] = *data.NewAccount()
-		peers[i] = NewSPVPeer(accounts[i], network)
+	var accounts []data.Account
+	if simulated {
+		accounts = deriveAccounts(seed, config.MiniChainConfig.GetAccountNumber())
+	} else if genesisSeed := config.MiniChainConfig.GetGenesisSeed(); genesisSeed != 0 {
+		// 多个节点使用相同的 genesisSeed 时得到相同的账户与创世块
+		accounts = deriveAccounts(genesisSeed, config.MiniChainConfig.GetAccountNumber())
+	} else {
+		accounts = loadAccounts(dataDir)
+	}
+	// 关闭 SPV 时不创建 SPV 节点，同步与广播区块头都不再进行
+	peers := make([]*SPVPeer, 0, len(accounts))
+	if config.MiniChainConfig.IsSPVEnabled() {
+		for i := range accounts {
+			peers = append(peers, NewSPVPeer(accounts[i], network))
+		}
 	}
 	network.accounts = accounts
 	network.spvPeer = peers
 	fmt.Println("TransactionPool config...")
-	pool := NewTransactionPool(config.MiniChainConfig.GetMaxTransactionCount(), network)
+	pool := NewTransactionPool(config.MiniChainConfig.GetMaxPoolTransactions(), config.MiniChainConfig.GetMaxPoolSize(),
+		time.Duration(config.MiniChainConfig.GetPoolExpiry())*time.Second, network)
 	fmt.Println("Blockchain config...")
-	blockchain := NewBlockChain(network)
+	blockStore, err := store.Open(dataDir)
+	if err != nil {
+		panic("Open block store error: " + err.Error())
+	}
+	blockchain := NewBlockChain(network, blockStore)
 	fmt.Println("MinerNode config...")
-	miner := NewMinerNode(network)
+	var minerAccount *data.Account
+	if simulated {
+		minerAccount = deriveAccount(seed, "miner", 0)
+	} else {
+		minerAccount = loadMinerAccount(dataDir)
+	}
+	miner := NewMinerNode(network, minerAccount)
+	fmt.Println("Workload config...")
+	strategy, err := workload.ByName(config.MiniChainConfig.GetWorkload())
+	if err != nil {
+		panic("Workload config error: " + err.Error())
+	}
+	fmt.Println("Workload", strategy.Name(), "with seed", seed)
+	network.generator = workload.NewGenerator(network, strategy, seed)
 	fmt.Println("Network Config Finished...")
 	fmt.Println("Network Start...")
 	network.txPool = pool
 	network.blockchain = blockchain
-	network.miner = *miner
+	network.miner = miner
+	if !simulated {
+		network.relay = NewRelay(network)
+	}
 	return network
 }
 
+// loadAccounts 加载系统账户。
+// 数据目录中已有账户时直接复用，保证重启后仍能花费链上的 UTXO；
+// 否则生成新的账户并写入数据目录。
+// 参数:
+// - dataDir: 数据目录，为空时不做持久化。
+// 返回值:
+// 返回账户列表。
+func loadAccounts(dataDir string) []data.Account {
+	nbAccount := config.MiniChainConfig.GetAccountNumber()
+	if dataDir != "" {
+		accounts, err := store.LoadAccounts(dataDir)
+		if err != nil {
+			panic("Load accounts error: " + err.Error())
+		}
+		if len(accounts) > 0 {
+			if len(accounts) != nbAccount {
+				panic("The data directory was created with " + strconv.Itoa(len(accounts)) +
+					" accounts, but the config requires " + strconv.Itoa(nbAccount))
+			}
+			return accounts
+		}
+	}
+	accounts := make([]data.Account, nbAccount)
+	for i := range accounts {
+		accounts[i] = *data.NewAccount()
+	}
+	if dataDir != "" {
+		if err := store.SaveAccounts(dataDir, accounts); err != nil {
+			panic("Save accounts error: " + err.Error())
+		}
+	}
+	return accounts
+}
+
+// loadMinerAccount 加载矿工账户，数据目录中没有矿工账户时生成新的账户并写入数据目录。
+// 参数:
+// - dataDir: 数据目录，为空时不做持久化。
+// 返回值:
+// 返回矿工账户。
+func loadMinerAccount(dataDir string) *data.Account {
+	if dataDir != "" {
+		account, err := store.LoadMinerAccount(dataDir)
+		if err != nil {
+			panic("Load miner account error: " + err.Error())
+		}
+		if account != nil {
+			return account
+		}
+	}
+	account := data.NewAccount()
+	if dataDir != "" {
+		if err := store.SaveMinerAccount(dataDir, *account); err != nil {
+			panic("Save miner account error: " + err.Error())
+		}
+	}
+	return account
+}
+
 // Start 启动区块链网络。
-// 该方法会初始化创世块、广播创世块，并启动交易池和矿工节点。
-func (n *NetWork) Start() {
+// 该方法会初始化（或从存储中恢复）区块链、向 SPV 节点同步区块头，启动节点间通信，
+// 并启动交易负载生成器和矿工节点（config 中关闭挖矿时不启动矿工），直到 ctx 被取消后断开全部连接、关闭区块存储并返回。
+// 参数:
+// - ctx: 停止网络的上下文，通常在收到中断信号时取消。
+func (n *NetWork) Start(ctx context.Context) {
 	n.blockchain.SetUp()
-	n.BroadCast(*n.GetNewestBlock())
-	n.txPool.Start()
-	n.miner.Run()
+	n.SyncSPVPeers()
+	if n.relay != nil {
+		if err := n.relay.Start(ctx); err != nil {
+			panic("Start P2P server error: " + err.Error())
+		}
+	}
+	close(n.ready)
+	go n.generator.Run(ctx, n)
+	if config.MiniChainConfig.IsMiningEnabled() {
+		n.miner.Run(ctx)
+	} else {
+		<-ctx.Done()
+	}
+	if n.relay != nil {
+		n.relay.Wait()
+	}
+	if err := n.blockchain.Close(); err != nil {
+		fmt.Println("Close block store error: " + err.Error())
+	}
+	fmt.Println("Network Stopped...")
+}
+
+// SyncSPVPeers 将区块链中已有的全部区块头同步到所有 SPV 节点。
+func (n *NetWork) SyncSPVPeers() {
+	blocks := n.GetBlocks()
+	headers := make([]data.BlockHeader, len(blocks))
+	for i, block := range blocks {
+		headers[i] = block.GetBlockHeader()
+	}
+	for _, spvPeer := range n.spvPeer {
+		spvPeer.SyncHeaders(headers)
+	}
 }
 
 // GetTransactionsInLatestBlock 获取最新区块中与指定钱包地址相关的所有交易。
@@ -70,8 +237,8 @@ func (n *NetWork) GetTransactionsInLatestBlock(address string) []data.Transactio
 	blockBody := block.GetBlockBody()
 	for _, tx := range blockBody.GetTransctions() {
 		have := false
-		for _, utxo := range tx.GetInUTXOs() {
-			if utxo.GetWalletAddress() == address {
+		for _, in := range tx.GetInputs() {
+			if in.GetWalletAddress() == address {
 				txs = append(txs, tx)
 				have = true
 				break
@@ -106,16 +273,123 @@ func (n *NetWork) GetAllTransactions() []data.Transaction {
 
 // GetTotalAmount 获取区块链中所有账户的总金额。
 // 返回值:
-// 返回整数类型的总金额。
-func (n *NetWork) GetTotalAmount() int {
+// 返回整数类型的总金额；总金额超过预期发行的货币总量时同时返回错误，见 BlockChain.GetAllAmount。
+func (n *NetWork) GetTotalAmount() (int, error) {
 	return n.blockchain.GetAllAmount()
 }
 
-// AddNewBlock 将新区块添加到区块链中。
+// AddNewBlock 验证新区块并将其添加到区块链中，区块可能由本节点的矿工挖出，也可能来自其他节点。
+// 新区块延长主链时将区块头广播给 SPV 节点；引发链重组时重新向 SPV 节点同步主链上的区块头。
+// 主链的最新区块变化后，向其他节点通告新的最新区块；同步期间不通告，以免向其他节点逐个通告大量历史区块。
 // 参数:
 // - block: 要添加的新区块。
-func (n *NetWork) AddNewBlock(block data.Block) {
-	n.blockchain.AddNewBlock(block)
+// 返回值:
+// 区块未通过验证或写入存储失败时返回错误。
+func (n *NetWork) AddNewBlock(block data.Block) error {
+	n.blockMutex.Lock()
+	defer n.blockMutex.Unlock()
+	previous := n.GetNewestBlock().Hash()
+	if err := n.blockchain.AddNewBlock(block); err != nil {
+		return err
+	}
+	newest := n.GetNewestBlock()
+	if newest.Hash() == previous {
+		// 新区块位于侧链上，主链没有变化
+		return nil
+	}
+	header := newest.GetBlockHeader()
+	if header.GetPreBlockHash() == previous {
+		n.BroadCast(*newest)
+	} else {
+		n.SyncSPVPeers()
+	}
+	// 最新区块变化后，矿工正在挖的区块已经过时
+	n.miner.NotifyNewTip(newest.Hash())
+	if n.relay != nil && !n.IsSyncing() {
+		n.relay.RelayBlock(newest.Hash())
+	}
+	return nil
+}
+
+// AcceptTransaction 将交易加入交易池，并向其他节点转发。交易负载生成器通过它提交交易。
+// 参数:
+// - transaction: 要加入交易池的交易。
+// 返回值:
+// 交易被拒绝时返回 *TxRejectError。
+func (n *NetWork) AcceptTransaction(transaction data.Transaction) error {
+	if err := n.txPool.AcceptTransaction(transaction); err != nil {
+		return err
+	}
+	if n.relay != nil {
+		n.relay.RelayTransaction(transaction.TxID(), nil)
+	}
+	return nil
+}
+
+// WaitForSpace 等待区块同步完成且交易池有空间接收新的交易，ctx 被取消时返回 false。
+// 同步期间账户的 UTXO 还不是最新的，此时生成的交易大多会被拒绝。
+func (n *NetWork) WaitForSpace(ctx context.Context) bool {
+	for n.IsSyncing() {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(syncRetryInterval):
+		}
+	}
+	return n.txPool.WaitForSpace(ctx)
+}
+
+// genesisNonce 返回创世块头的随机数：config 中指定了 genesisSeed 时直接使用它，使各节点生成相同的创世块。
+func (n *NetWork) genesisNonce() int64 {
+	if seed := config.MiniChainConfig.GetGenesisSeed(); seed != 0 && !n.simulated {
+		return seed
+	}
+	return n.rand.Int63()
+}
+
+// genesisTime 返回创世块与创世交易的时间：config 中指定了 genesisSeed 时为固定的 SimulationEpoch。
+func (n *NetWork) genesisTime() time.Time {
+	if config.MiniChainConfig.GetGenesisSeed() != 0 && !n.simulated {
+		return SimulationEpoch
+	}
+	return n.Now()
+}
+
+// IsSyncing 判断是否还有已经收到区块头、但尚未下载的区块，即区块头链比主链更长。
+func (n *NetWork) IsSyncing() bool {
+	return n.blockchain.GetHeaderHeight() > n.blockchain.GetHeight()
+}
+
+// GetSyncStatus 返回区块同步的状态，模拟运行时只有高度信息。
+func (n *NetWork) GetSyncStatus() SyncStatus {
+	if n.relay == nil {
+		height := n.blockchain.GetHeight()
+		return SyncStatus{Height: height, HeaderHeight: height}
+	}
+	return n.relay.sync.GetStatus()
+}
+
+// Ready 返回区块链初始化完成、节点间通信启动之后关闭的通道。
+// 从存储中恢复区块需要重新验证全部区块，在此之前区块链与交易池还不能查询。
+func (n *NetWork) Ready() <-chan struct{} {
+	return n.ready
+}
+
+// GetEventBus 获取事件总线，订阅后可以收到区块连接与移除、交易进入与离开交易池的事件。
+func (n *NetWork) GetEventBus() *EventBus {
+	return n.events
+}
+
+// GetTransactionPool 获取交易池。
+// 返回值:
+// 返回指向交易池的指针。
+func (n *NetWork) GetTransactionPool() *TransactionPool {
+	return n.txPool
+}
+
+// GetRelay 获取区块与交易转发服务，模拟运行时为 nil。
+func (n *NetWork) GetRelay() *Relay {
+	return n.relay
 }
 
 // GetNewestBlock 获取区块链中的最新区块。
@@ -141,21 +415,54 @@ func (n *NetWork) GetAccount(i int) data.Account {
 	return n.accounts[i]
 }
 
-// GetTrueUTXOs 获取指定钱包地址的有效 UTXO 列表。
+// GetTrueUTXOs 获取指定钱包地址已确认的 UTXO 列表。
 // 参数:
 // - address: 钱包地址。
 // 返回值:
-// 返回一个包含有效 UTXO 的列表。
+// 返回一个包含已确认 UTXO 的列表。
 func (n *NetWork) GetTrueUTXOs(address string) []*data.UTXO {
 	return n.blockchain.GetTrueUTXOs(address)
 }
 
-// ProcessTransactionUTXO 处理交易中的未花费交易输出（UTXO）。
+// GetSpendableUTXOs 获取指定钱包地址当前可以花费的 UTXO 列表，
+// 即已确认的 UTXO 叠加交易池中未确认交易的花费与新产生的输出之后的结果。
+// 参数:
+// - address: 钱包地址。
+// 返回值:
+// 返回一个包含可花费 UTXO 的列表。
+func (n *NetWork) GetSpendableUTXOs(address string) []*data.UTXO {
+	return n.txPool.GetSpendableUTXOs(address)
+}
+
+// Now 返回网络时钟的当前时间。
+func (n *NetWork) Now() time.Time {
+	return n.clock.Now()
+}
+
+// GetSeed 返回网络的随机数种子。
+func (n *NetWork) GetSeed() int64 {
+	return n.seed
+}
+
+// GetClock 返回网络的时钟。
+func (n *NetWork) GetClock() Clock {
+	return n.clock
+}
+
+// MinRelayFee 返回指定大小的交易进入交易池需要支付的最低手续费。
 // 参数:
-// - inUTXO: 表示交易中作为输入的 UTXO 列表。
-// - outUTXO: 表示交易中作为输出的 UTXO 列表。
-func (n *NetWork) ProcessTransactionUTXO(inUTXO []*data.UTXO, outUTXO []*data.UTXO) {
-	n.blockchain.ProcessTransactionUTXO(inUTXO, outUTXO)
+// - size: 交易规范编码的字节数。
+// 返回值:
+// 返回最低手续费。
+func (n *NetWork) MinRelayFee(size int) int {
+	return MinRelayFee(size)
+}
+
+// GetGenerator 获取交易负载生成器。
+// 返回值:
+// 返回指向交易负载生成器的指针。
+func (n *NetWork) GetGenerator() *workload.Generator {
+	return n.generator
 }
 
 // GetBlocks 获取区块链中的所有区块。
@@ -0,0 +1,105 @@
+package network
+
+import (
+	"Go-Minichain/config"
+	"Go-Minichain/data"
+	"context"
+	"os"
+	"testing"
+)
+
+// testConfigArgs 测试使用的配置：最低的难度、较少的账户，不创建 SPV 节点，挖矿只使用一个工作协程。
+var testConfigArgs = []string{
+	"-difficulty=1",
+	"-nbAccount=10",
+	"-spvEnabled=false",
+	"-minerThreads=1",
+	"-dataDir=",
+}
+
+// noEnv 不读取任何环境变量，使测试不受运行环境影响。
+func noEnv(string) (string, bool) {
+	return "", false
+}
+
+func TestMain(m *testing.M) {
+	c, err := config.Load("network.test", testConfigArgs, noEnv)
+	if err != nil {
+		panic("test config: " + err.Error())
+	}
+	config.MiniChainConfig = c
+	os.Exit(m.Run())
+}
+
+// useConfig 在测试配置的基础上修改部分配置项，测试结束后恢复原来的配置。
+// 配置是全局的，使用它的测试不能并行运行。
+// 参数:
+// - overrides: 与命令行参数格式相同的配置项，例如 -maxPoolTransactions=4。
+func useConfig(tb testing.TB, overrides ...string) {
+	tb.Helper()
+	c, err := config.Load("network.test", append(append([]string(nil), testConfigArgs...), overrides...), noEnv)
+	if err != nil {
+		tb.Fatalf("test config: %v", err)
+	}
+	previous := config.MiniChainConfig
+	config.MiniChainConfig = c
+	tb.Cleanup(func() {
+		config.MiniChainConfig = previous
+	})
+}
+
+// newTestNetWork 创建一个以 SimulationEpoch 为起点的模拟网络，并生成创世块。
+func newTestNetWork(tb testing.TB, seed int64) *NetWork {
+	tb.Helper()
+	n := NewSimulatedNetWork(seed, NewSimulatedClock(SimulationEpoch))
+	n.blockchain.SetUp()
+	return n
+}
+
+// mineBlock 在指定的父区块之后挖出一个只包含 coinbase 交易的区块，不将其加入区块链。
+// 参数:
+// - parentHash: 父区块哈希，可以位于侧链上。
+// - reward: coinbase 交易发放的金额，超过挖矿奖励时区块在连接到主链时才会被判定为无效。
+// - salt: coinbase 交易的时间戳，使不同分支上同一高度的区块互不相同。
+func mineBlock(tb testing.TB, n *NetWork, parentHash string, reward int, salt int) data.Block {
+	tb.Helper()
+	c := n.blockchain
+	c.mutex.Lock()
+	parent, ok := c.index[parentHash]
+	if !ok {
+		c.mutex.Unlock()
+		tb.Fatalf("unknown parent %s", parentHash)
+	}
+	height := parent.height + 1
+	bits := nextBits(parent)
+	medianTime := parent.medianTimePast()
+	c.mutex.Unlock()
+
+	coinbase := data.NewCoinbaseTransaction(height, []*data.UTXO{data.NewUTXO(reward, n.miner.account.GetPublicKey())})
+	coinbase.SetTimestamp(salt)
+	transactions := []data.Transaction{*coinbase}
+	body := data.NewBlockBody(data.ComputeMerkleRootHash(transactions), transactions)
+	header := data.NewBlockHeader(parentHash, body.GetMerkleRootHash(), 0)
+	header.SetBits(bits)
+	header.SetTimestamp(medianTime + 1)
+	solved, _, err := solveHeader(context.Background(), *header, 1)
+	if err != nil {
+		tb.Fatalf("mine block: %v", err)
+	}
+	return *data.NewBlock(solved, *body)
+}
+
+// mineBranch 从指定的父区块开始挖出 count 个连续的区块并依次加入区块链，返回这些区块。
+func mineBranch(tb testing.TB, n *NetWork, parentHash string, count int, salt int) []data.Block {
+	tb.Helper()
+	blocks := make([]data.Block, 0, count)
+	for i := 0; i < count; i++ {
+		block := mineBlock(tb, n, parentHash, BlockSubsidy(1), salt)
+		if err := n.AddNewBlock(block); err != nil {
+			tb.Fatalf("add block %d: %v", i, err)
+		}
+		blocks = append(blocks, block)
+		parentHash = block.Hash()
+	}
+	return blocks
+}
@@ -0,0 +1,107 @@
+package network
+
+import (
+	"Go-Minichain/data"
+	"Go-Minichain/utils"
+	"context"
+	"errors"
+	"math"
+	"math/big"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+/**
+ * 多线程工作量证明
+ *
+ * 挖矿只对区块头的规范编码做哈希。nonce 的取值空间被均匀划分给多个工作协程，
+ * 每个协程在自己的区间内依次尝试，任意一个协程找到不大于目标值的哈希后其余协程立即停止。
+ * 调用方通过 context.Context 取消挖矿，例如主链的最新区块已经变化、当前区块已无意义时。
+ */
+
+// checkInterval 工作协程每尝试多少次哈希检查一次是否需要停止。
+const checkInterval = 1024
+
+// ErrNonceExhausted 全部 nonce 都已尝试但没有找到满足目标值的哈希。
+var ErrNonceExhausted = errors.New("nonce space exhausted")
+
+// MinerStats 挖矿的统计信息。
+// 字段说明：
+// - Hashes: 尝试的哈希次数。
+// - Elapsed: 挖矿用时。
+type MinerStats struct {
+	Hashes  uint64
+	Elapsed time.Duration
+}
+
+// HashRate 返回每秒的哈希次数。
+func (s MinerStats) HashRate() float64 {
+	if s.Elapsed <= 0 {
+		return 0
+	}
+	return float64(s.Hashes) / s.Elapsed.Seconds()
+}
+
+// add 累加另一段挖矿的统计信息。
+func (s *MinerStats) add(other MinerStats) {
+	s.Hashes += other.Hashes
+	s.Elapsed += other.Elapsed
+}
+
+// solveHeader 使用 threads 个工作协程寻找使区块头哈希不大于其目标值的 nonce。
+// 参数:
+// - ctx: 取消挖矿的上下文。
+// - header: 区块头，其 nonce 会被替换。
+// - threads: 工作协程个数。
+// 返回值:
+// 返回设置了满足条件的 nonce 的区块头以及统计信息；被取消时返回 ctx.Err()，nonce 全部尝试过时返回 ErrNonceExhausted。
+func solveHeader(ctx context.Context, header data.BlockHeader, threads int) (data.BlockHeader, MinerStats, error) {
+	if threads < 1 {
+		threads = 1
+	}
+	start := time.Now()
+	workerCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	target := header.GetTarget()
+	span := int64(math.MaxInt64 / uint64(threads))
+	found := make(chan data.BlockHeader, threads)
+	var hashes uint64
+	var wg sync.WaitGroup
+	for i := 0; i < threads; i++ {
+		wg.Add(1)
+		go func(first int64) {
+			defer wg.Done()
+			h := header
+			hashInt := new(big.Int)
+			var count uint64
+			for nonce := first; nonce < first+span; nonce++ {
+				if count%checkInterval == 0 && workerCtx.Err() != nil {
+					break
+				}
+				h.SetNonce(nonce)
+				hashInt.SetBytes(utils.Sha256Digest(h.Encode()))
+				count++
+				if hashInt.Cmp(target) <= 0 {
+					found <- h
+					cancel()
+					break
+				}
+			}
+			atomic.AddUint64(&hashes, count)
+		}(int64(i) * span)
+	}
+	wg.Wait()
+
+	stats := MinerStats{Hashes: hashes, Elapsed: time.Since(start)}
+	select {
+	case solved := <-found:
+		return solved, stats, nil
+	default:
+	}
+	if err := ctx.Err(); err != nil {
+		return header, stats, err
+	}
+	return header, stats, ErrNonceExhausted
+}
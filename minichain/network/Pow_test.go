@@ -0,0 +1,87 @@
+package network
+
+import (
+	"Go-Minichain/data"
+	"Go-Minichain/utils"
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+)
+
+// powHeader 返回一个使用 zeros 个十六进制前导 0 的目标值的区块头。
+func powHeader(zeros int) data.BlockHeader {
+	header := data.NewBlockHeader(utils.GetSha256Digest("parent"), utils.GetSha256Digest("root"), 0)
+	header.SetTimestamp(SimulationEpoch.Unix())
+	header.SetBits(utils.BigToCompact(utils.LeadingZerosTarget(zeros)))
+	return *header
+}
+
+func TestSolveHeaderFindsValidNonce(t *testing.T) {
+	header := powHeader(2)
+	solved, stats, err := solveHeader(context.Background(), header, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !utils.CheckProofOfWork(solved.Hash(), solved.GetTarget()) {
+		t.Fatalf("nonce %d gives hash %s above the target", solved.GetNonce(), solved.Hash())
+	}
+	// 只有 nonce 被修改
+	solved.SetNonce(header.GetNonce())
+	if solved.Hash() != header.Hash() {
+		t.Fatal("solving changed fields other than the nonce")
+	}
+	if stats.Hashes == 0 || stats.HashRate() <= 0 {
+		t.Fatalf("stats %+v report no hashes or a zero hashrate", stats)
+	}
+}
+
+func TestSolveHeaderStopsWhenCancelled(t *testing.T) {
+	// 目标值为 1，实际上不可能找到满足条件的哈希
+	header := powHeader(0)
+	header.SetBits(utils.BigToCompact(big.NewInt(1)))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	type result struct {
+		stats MinerStats
+		err   error
+	}
+	done := make(chan result, 1)
+	go func() {
+		_, stats, err := solveHeader(ctx, header, 4)
+		done <- result{stats, err}
+	}()
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+
+	// solveHeader 等待全部工作协程退出后才返回
+	select {
+	case r := <-done:
+		if !errors.Is(r.err, context.Canceled) {
+			t.Fatalf("cancelled mining returned %v, want %v", r.err, context.Canceled)
+		}
+		if r.stats.Hashes == 0 || r.stats.HashRate() <= 0 {
+			t.Fatalf("stats %+v report no hashes or a zero hashrate", r.stats)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("workers did not stop after cancellation")
+	}
+
+	// 上下文已经取消时工作协程不尝试任何哈希
+	_, stats, err := solveHeader(ctx, header, 4)
+	if !errors.Is(err, context.Canceled) || stats.Hashes != 0 {
+		t.Fatalf("mining with a cancelled context returned %v after %d hashes", err, stats.Hashes)
+	}
+}
+
+func TestMinerStats(t *testing.T) {
+	if rate := (MinerStats{Hashes: 100}).HashRate(); rate != 0 {
+		t.Fatalf("hashrate without elapsed time %v, want 0", rate)
+	}
+	stats := MinerStats{Hashes: 100, Elapsed: time.Second}
+	stats.add(MinerStats{Hashes: 300, Elapsed: time.Second})
+	if stats.Hashes != 400 || stats.HashRate() != 200 {
+		t.Fatalf("accumulated stats %+v with hashrate %v, want 400 hashes at 200/s", stats, stats.HashRate())
+	}
+}
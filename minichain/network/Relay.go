@@ -0,0 +1,211 @@
+package network
+
+import (
+	"Go-Minichain/config"
+	"Go-Minichain/data"
+	"Go-Minichain/p2p"
+	"context"
+	"sync"
+	"time"
+)
+
+/**
+ * 区块与交易转发
+ *
+ * Relay 实现 p2p.Handler，把其他节点发来的消息交给区块链与交易池处理，并把本节点新接受的区块与交易转发出去：
+ * - 新区块成为主链的最新区块、新交易进入交易池之后，向其他节点发送 inv；
+ * - 交易的通告直接用 getdata 请求，同一笔交易在 requestTimeout 之内只向一个节点请求一次；
+ * - 区块头、区块以及区块的通告交给 Syncer 处理，先下载并验证区块头，再下载区块（见 Sync.go）；
+ * - 回复其他节点的 getheaders 与 getdata 请求。
+ */
+
+// requestTimeout 等待请求的区块或交易的最长时间，超过后可以再次请求。
+const requestTimeout = 30 * time.Second
+
+// Relay 在节点之间转发区块与交易。
+// 字段说明：
+// - network: 所属的区块链网络。
+// - server: 节点间通信服务。
+// - sync: 区块同步服务。
+// - requested: 已经请求但尚未收到的交易哈希，以及请求的时间。
+// - mutex: 保护 requested 的互斥锁。
+type Relay struct {
+	network   *NetWork
+	server    *p2p.Server
+	sync      *Syncer
+	requested map[string]time.Time
+	mutex     sync.Mutex
+}
+
+// NewRelay 创建区块与交易转发服务，监听地址与最大连接数来自 config。
+// 参数:
+// - network: 所属的区块链网络。
+// 返回值:
+// 返回一个指向新创建的转发服务的指针。
+func NewRelay(network *NetWork) *Relay {
+	relay := &Relay{network: network, sync: NewSyncer(network), requested: make(map[string]time.Time)}
+	relay.server = p2p.NewServer(config.MiniChainConfig.GetP2PListen(), config.MiniChainConfig.GetMaxPeers(), relay)
+	return relay
+}
+
+// Start 开始监听、连接 config 中指定的节点并启动区块同步，ctx 被取消时断开全部连接。
+// 返回值:
+// 无法监听时返回错误。
+func (r *Relay) Start(ctx context.Context) error {
+	if err := r.server.Start(ctx); err != nil {
+		return err
+	}
+	go r.sync.Run(ctx)
+	for _, addr := range config.MiniChainConfig.GetPeers() {
+		r.server.Connect(ctx, addr)
+	}
+	return nil
+}
+
+// Wait 等待全部连接关闭，应在 Start 的 ctx 被取消之后调用。
+func (r *Relay) Wait() {
+	r.server.Wait()
+}
+
+// GetServer 获取节点间通信服务。
+func (r *Relay) GetServer() *p2p.Server {
+	return r.server
+}
+
+// GetSyncer 获取区块同步服务。
+func (r *Relay) GetSyncer() *Syncer {
+	return r.sync
+}
+
+// RelayBlock 向全部节点通告新区块。
+func (r *Relay) RelayBlock(hash string) {
+	r.server.Broadcast(&p2p.MsgInv{Items: []p2p.InvVect{{Type: p2p.InvBlock, Hash: hash}}}, nil)
+}
+
+// RelayTransaction 向除 except 之外的全部节点通告新交易。
+func (r *Relay) RelayTransaction(txID string, except *p2p.Peer) {
+	r.server.Broadcast(&p2p.MsgInv{Items: []p2p.InvVect{{Type: p2p.InvTx, Hash: txID}}}, except)
+}
+
+// LocalVersion 返回创世块哈希与主链高度。
+func (r *Relay) LocalVersion() (string, int) {
+	blocks := r.network.GetBlocks()
+	return blocks[0].Hash(), len(blocks) - 1
+}
+
+// PeerConnected 将新连接的节点加入区块同步。
+func (r *Relay) PeerConnected(p *p2p.Peer) {
+	r.sync.AddPeer(p)
+}
+
+// PeerDisconnected 将断开的节点移出区块同步，向该节点请求的交易超时后可以向其他节点重新请求。
+func (r *Relay) PeerDisconnected(p *p2p.Peer) {
+	r.sync.RemovePeer(p)
+}
+
+// HandleMessage 处理其他节点发来的消息。
+func (r *Relay) HandleMessage(p *p2p.Peer, msg p2p.Message) {
+	switch m := msg.(type) {
+	case *p2p.MsgInv:
+		r.handleInv(p, m)
+	case *p2p.MsgGetData:
+		r.handleGetData(p, m)
+	case *p2p.MsgNotFound:
+		blocks := make([]string, 0)
+		for _, item := range m.Items {
+			if item.Type == p2p.InvBlock {
+				blocks = append(blocks, item.Hash)
+			} else {
+				r.forget(item.Hash)
+			}
+		}
+		r.sync.HandleNotFound(blocks)
+	case *p2p.MsgBlock:
+		r.sync.HandleBlock(p, m.Block)
+	case *p2p.MsgTx:
+		r.handleTx(p, m.Transaction)
+	case *p2p.MsgGetHeaders:
+		p.Send(&p2p.MsgHeaders{Headers: r.network.blockchain.GetHeadersAfter(m.Locator, m.Stop, p2p.MaxHeaders)})
+	case *p2p.MsgHeaders:
+		r.sync.HandleHeaders(p, m.Headers)
+	}
+}
+
+// handleInv 请求通告中本节点没有的交易，区块的通告交给区块同步处理。
+func (r *Relay) handleInv(p *p2p.Peer, inv *p2p.MsgInv) {
+	wanted := make([]p2p.InvVect, 0)
+	blocks := make([]string, 0)
+	for _, item := range inv.Items {
+		switch item.Type {
+		case p2p.InvBlock:
+			blocks = append(blocks, item.Hash)
+		case p2p.InvTx:
+			if !r.network.txPool.Has(item.Hash) && r.request(item.Hash) {
+				wanted = append(wanted, item)
+			}
+		}
+	}
+	if len(blocks) > 0 {
+		r.sync.HandleBlockInv(p, blocks)
+	}
+	if len(wanted) > 0 {
+		p.Send(&p2p.MsgGetData{Items: wanted})
+	}
+}
+
+// handleGetData 发送对方请求的区块与交易，本节点没有的条目通过 notfound 告知对方。
+func (r *Relay) handleGetData(p *p2p.Peer, getData *p2p.MsgGetData) {
+	missing := make([]p2p.InvVect, 0)
+	for _, item := range getData.Items {
+		switch item.Type {
+		case p2p.InvBlock:
+			if block, ok := r.network.blockchain.GetBlock(item.Hash); ok {
+				p.SendWait(&p2p.MsgBlock{Block: block})
+				continue
+			}
+		case p2p.InvTx:
+			if tx, ok := r.network.txPool.Get(item.Hash); ok {
+				p.SendWait(&p2p.MsgTx{Transaction: tx})
+				continue
+			}
+		}
+		missing = append(missing, item)
+	}
+	if len(missing) > 0 {
+		p.Send(&p2p.MsgNotFound{Items: missing})
+	}
+}
+
+// handleTx 将收到的交易加入交易池，并转发给其他节点。
+// 各节点的交易可能互相冲突，被交易池拒绝是正常情况，因此不输出日志。
+func (r *Relay) handleTx(p *p2p.Peer, tx data.Transaction) {
+	txID := tx.TxID()
+	r.forget(txID)
+	if err := r.network.txPool.AcceptTransaction(tx); err == nil {
+		r.RelayTransaction(txID, p)
+	}
+}
+
+// request 登记一次交易请求，同一哈希已经在 requestTimeout 之内请求过时返回 false。
+func (r *Relay) request(hash string) bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	now := time.Now()
+	if at, ok := r.requested[hash]; ok && now.Sub(at) < requestTimeout {
+		return false
+	}
+	for h, at := range r.requested {
+		if now.Sub(at) >= requestTimeout {
+			delete(r.requested, h)
+		}
+	}
+	r.requested[hash] = now
+	return true
+}
+
+// forget 移除已经收到或对方没有的请求。
+func (r *Relay) forget(hash string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	delete(r.requested, hash)
+}
@@ -0,0 +1,157 @@
+package network
+
+import (
+	"Go-Minichain/config"
+	"Go-Minichain/p2p"
+	"Go-Minichain/store"
+	"context"
+	"testing"
+	"time"
+)
+
+// relayTestArgs 多个节点通过本机回环地址互相连接的测试配置：节点共享由 genesisSeed 派生的账户与创世块，
+// 监听系统分配的端口，不运行矿工，区块由测试挖出；关闭难度调整，使连续挖出的区块保持最低难度。
+var relayTestArgs = []string{
+	"-genesisSeed=7",
+	"-p2pListen=127.0.0.1:0",
+	"-mining=false",
+	"-retargetInterval=0",
+}
+
+// waitTimeout 等待区块或交易在节点之间传播的最长时间。
+const waitTimeout = 10 * time.Second
+
+// testNode 测试中运行的一个节点，只启动区块链与节点间通信，不启动矿工与交易负载生成器。
+// 字段说明：
+// - network: 节点的区块链网络。
+// - ctx / cancel: 节点的上下文，取消后断开全部连接。
+// - stopped: 节点是否已经停止。
+type testNode struct {
+	network *NetWork
+	ctx     context.Context
+	cancel  context.CancelFunc
+	stopped bool
+}
+
+// startTestNode 创建并启动一个节点，测试结束时自动停止。配置是全局的，调用前应先用 useConfig 设置 relayTestArgs。
+// 参数:
+// - dataDir: 区块存储目录，为空时区块只保存在内存中；节点之间共享配置，因此不通过 config 指定。
+// - wrap: 不为 nil 时用它包装节点的消息处理者，用于检查节点收到的消息。
+// 返回值:
+// 返回已经开始监听的节点。
+func startTestNode(tb testing.TB, dataDir string, wrap func(r *Relay) p2p.Handler) *testNode {
+	tb.Helper()
+	n := NewNetWork()
+	if dataDir != "" {
+		blockStore, err := store.Open(dataDir)
+		if err != nil {
+			tb.Fatalf("open block store: %v", err)
+		}
+		n.blockchain = NewBlockChain(n, blockStore)
+	}
+	if wrap != nil {
+		n.relay.server = p2p.NewServer(config.MiniChainConfig.GetP2PListen(), config.MiniChainConfig.GetMaxPeers(), wrap(n.relay))
+	}
+	n.blockchain.SetUp()
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := n.relay.Start(ctx); err != nil {
+		cancel()
+		tb.Fatalf("start relay: %v", err)
+	}
+	close(n.ready)
+	node := &testNode{network: n, ctx: ctx, cancel: cancel}
+	tb.Cleanup(node.stop)
+	return node
+}
+
+// connect 使节点主动连接另一个节点。
+func (node *testNode) connect(other *testNode) {
+	node.network.relay.server.Connect(node.ctx, other.network.relay.server.GetListenAddr())
+}
+
+// stop 断开节点的全部连接并关闭区块存储，可以重复调用。
+func (node *testNode) stop() {
+	if node.stopped {
+		return
+	}
+	node.stopped = true
+	node.cancel()
+	node.network.relay.Wait()
+	node.network.blockchain.Close()
+}
+
+// peerCount 返回节点握手完成的连接个数。
+func (node *testNode) peerCount() int {
+	return len(node.network.relay.server.GetPeers())
+}
+
+// waitFor 每隔一小段时间检查一次条件，超过 waitTimeout 仍不满足时终止测试。
+// 参数:
+// - what: 等待的内容，用于失败时的说明。
+// - cond: 等待的条件。
+func waitFor(tb testing.TB, what string, cond func() bool) {
+	tb.Helper()
+	deadline := time.Now().Add(waitTimeout)
+	for !cond() {
+		if time.Now().After(deadline) {
+			tb.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// waitForTip 等待节点的主链延伸到指定节点的最新区块。
+func waitForTip(tb testing.TB, node *testNode, source *testNode) {
+	tb.Helper()
+	tip := source.network.blockchain.GetTip()
+	waitFor(tb, "block "+tip.Hash, func() bool {
+		return node.network.blockchain.GetTip().Hash == tip.Hash
+	})
+}
+
+func TestRelayBlocksAndTransactions(t *testing.T) {
+	useConfig(t, relayTestArgs...)
+	a := startTestNode(t, "", nil)
+	b := startTestNode(t, "", nil)
+	c := startTestNode(t, "", nil)
+	// 节点连成一条线，a 与 c 之间的区块与交易都需要经过 b 转发
+	b.connect(a)
+	c.connect(b)
+	waitFor(t, "connections", func() bool {
+		return a.peerCount() == 1 && b.peerCount() == 2 && c.peerCount() == 1
+	})
+
+	genesis := *a.network.GetNewestBlock()
+	mineBranch(t, a.network, genesis.Hash(), 3, 1)
+	waitForTip(t, b, a)
+	waitForTip(t, c, a)
+	if height := c.network.blockchain.GetHeight(); height != 3 {
+		t.Fatalf("relayed chain has height %d, want 3", height)
+	}
+
+	// 交易从 c 提交，经 b 转发到 a
+	accounts := c.network.GetAccounts()
+	tx := mustPayment(t, c.network, accounts[0], accounts[1], 100, 10)
+	if err := c.network.AcceptTransaction(tx); err != nil {
+		t.Fatal(err)
+	}
+	for _, node := range []*testNode{a, b} {
+		pool := node.network.txPool
+		waitFor(t, "transaction "+tx.TxID(), func() bool { return pool.Has(tx.TxID()) })
+	}
+
+	// a 挖出包含该交易的区块后，三个节点的交易池都移除了它
+	if err := a.network.miner.mineNext(a.ctx); err != nil {
+		t.Fatal(err)
+	}
+	waitForTip(t, c, a)
+	block := a.network.GetNewestBlock()
+	body := block.GetBlockBody()
+	if transactions := body.GetTransctions(); len(transactions) != 2 || transactions[1].TxID() != tx.TxID() {
+		t.Fatalf("mined block has %d transactions, want the coinbase and %s", len(transactions), tx.TxID())
+	}
+	for _, node := range []*testNode{a, b, c} {
+		pool := node.network.txPool
+		waitFor(t, "confirmed transaction to leave the pool", func() bool { return !pool.Has(tx.TxID()) })
+	}
+}
@@ -0,0 +1,59 @@
+package network
+
+import "Go-Minichain/config"
+
+/**
+ * 挖矿奖励
+ *
+ * 除创世块外，每个区块的第一笔交易必须是 coinbase 交易，向矿工发放该高度的挖矿奖励以及区块中全部交易的手续费。
+ * 挖矿奖励从 config 中的 blockSubsidy 开始，每经过 halvingInterval 个区块减半，
+ * 因此任意高度下已发行的货币总量都是确定的，可以用来审计 UTXO 集合。
+ */
+
+// BlockSubsidy 返回指定高度区块的挖矿奖励，创世块没有挖矿奖励。
+// 参数:
+// - height: 区块高度。
+// 返回值:
+// 返回挖矿奖励。
+func BlockSubsidy(height int) int {
+	if height <= 0 {
+		return 0
+	}
+	interval := config.MiniChainConfig.GetHalvingInterval()
+	if interval <= 0 {
+		return config.MiniChainConfig.GetBlockSubsidy()
+	}
+	halvings := (height - 1) / interval
+	if halvings >= 63 {
+		return 0
+	}
+	return config.MiniChainConfig.GetBlockSubsidy() >> uint(halvings)
+}
+
+// ExpectedSupply 返回主链高度为 height 时预期发行的货币总量，
+// 即创世块发行的初始余额加上高度 1 到 height 的全部挖矿奖励。
+// 参数:
+// - genesisAmount: 创世块发行的初始余额，取自创世块的输出而不是当前配置，配置在创建区块链之后可能被修改。
+// - height: 主链高度。
+// 返回值:
+// 返回预期发行的货币总量。
+func ExpectedSupply(genesisAmount int, height int) int {
+	supply := genesisAmount
+	interval := config.MiniChainConfig.GetHalvingInterval()
+	if interval <= 0 {
+		return supply + height*BlockSubsidy(1)
+	}
+	// 按减半周期累加，同一周期内每个区块的奖励相同
+	for start := 1; start <= height; start += interval {
+		end := start + interval - 1
+		if end > height {
+			end = height
+		}
+		subsidy := BlockSubsidy(start)
+		if subsidy == 0 {
+			break
+		}
+		supply += (end - start + 1) * subsidy
+	}
+	return supply
+}
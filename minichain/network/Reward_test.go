@@ -0,0 +1,91 @@
+package network
+
+import (
+	"Go-Minichain/data"
+	"errors"
+	"testing"
+)
+
+func TestBlockSubsidyHalving(t *testing.T) {
+	useConfig(t, "-blockSubsidy=50", "-halvingInterval=3")
+	for height, want := range map[int]int{0: 0, 1: 50, 3: 50, 4: 25, 6: 25, 7: 12, 10: 6, 13: 3, 16: 1, 19: 0, 1000: 0} {
+		if got := BlockSubsidy(height); got != want {
+			t.Errorf("subsidy at height %d is %d, want %d", height, got, want)
+		}
+	}
+	// 预期发行总量等于逐个区块累加的挖矿奖励
+	supply := 1000
+	for height := 0; height <= 30; height++ {
+		supply += BlockSubsidy(height)
+		if got := ExpectedSupply(1000, height); got != supply {
+			t.Fatalf("expected supply at height %d is %d, want %d", height, got, supply)
+		}
+	}
+
+	useConfig(t, "-blockSubsidy=50", "-halvingInterval=0")
+	if BlockSubsidy(1000) != 50 || ExpectedSupply(1000, 10) != 1500 {
+		t.Fatalf("without halving: subsidy %d, supply %d; want 50 and 1500", BlockSubsidy(1000), ExpectedSupply(1000, 10))
+	}
+}
+
+func TestCoinbaseLimitedToSubsidyAndFees(t *testing.T) {
+	// 每个区块奖励减半，高度 2 的区块只能领取 25
+	useConfig(t, "-blockSubsidy=50", "-halvingInterval=1")
+	n := newTestNetWork(t, 1)
+	first := mineBranch(t, n, n.GetNewestBlock().Hash(), 1, 0)[0]
+
+	greedy := mineBlock(t, n, first.Hash(), BlockSubsidy(1), 0)
+	if err := n.AddNewBlock(greedy); !errors.Is(err, ErrBadCoinbase) {
+		t.Fatalf("coinbase claiming the previous subsidy returned %v, want %v", err, ErrBadCoinbase)
+	}
+	if newest := n.GetNewestBlock(); newest.Hash() != first.Hash() {
+		t.Fatal("block with an invalid coinbase extended the main chain")
+	}
+
+	// 手续费可以计入 coinbase，超过挖矿奖励与手续费之和的部分不行
+	accounts := n.GetAccounts()
+	utxos := n.GetSpendableUTXOs(accounts[0].GetWalletAddress())
+	payment := spendOutputs(t, n, accounts[0], accounts[1], utxos, 100, false, 100)
+	limit := BlockSubsidy(2) + 100
+	block := buildBlock(t, n, limit+1, []data.Transaction{payment}, nil)
+	body := block.GetBlockBody()
+	assertInvalid(t, n, block, ErrBadCoinbase, body.GetTransctions()[0].TxID())
+	if err := n.AddNewBlock(buildBlock(t, n, limit, []data.Transaction{payment}, nil)); err != nil {
+		t.Fatalf("coinbase claiming subsidy and fees rejected: %v", err)
+	}
+}
+
+func TestSupplyAudit(t *testing.T) {
+	useConfig(t, "-blockSubsidy=50", "-halvingInterval=2")
+	n := newTestNetWork(t, 1)
+	genesis, err := n.GetTotalAmount()
+	if err != nil {
+		t.Fatal(err)
+	}
+	parent := n.GetNewestBlock().Hash()
+	for height := 1; height <= 5; height++ {
+		block := mineBlock(t, n, parent, BlockSubsidy(height), 0)
+		if err := n.AddNewBlock(block); err != nil {
+			t.Fatal(err)
+		}
+		parent = block.Hash()
+	}
+	if supply, err := n.GetTotalAmount(); err != nil || supply != ExpectedSupply(genesis, 5) {
+		t.Fatalf("supply %d (%v), want %d", supply, err, ExpectedSupply(genesis, 5))
+	}
+
+	// 矿工可以少领奖励，供应量低于预期不是错误
+	underpaid := mineBlock(t, n, parent, 1, 0)
+	if err := n.AddNewBlock(underpaid); err != nil {
+		t.Fatal(err)
+	}
+	if supply, err := n.GetTotalAmount(); err != nil || supply != ExpectedSupply(genesis, 5)+1 {
+		t.Fatalf("supply %d (%v), want %d", supply, err, ExpectedSupply(genesis, 5)+1)
+	}
+
+	// 凭空出现在 UTXO 集合中的货币使审计失败
+	n.blockchain.UTXOs.Add(data.NewUTXO(ExpectedSupply(genesis, 6), n.miner.account.GetPublicKey()))
+	if _, err := n.GetTotalAmount(); err == nil {
+		t.Fatal("supply above the expected amount passed the audit")
+	}
+}
@@ -0,0 +1,79 @@
+package network
+
+import (
+	"Go-Minichain/config"
+	"Go-Minichain/data"
+	"context"
+	"time"
+)
+
+/**
+ * 确定性模拟
+ *
+ * 模拟运行的网络使用显式的种子与时钟：账户与矿工的密钥由种子派生，交易负载与区块头的随机字段来自种子初始化的随机数生成器，
+ * 时间戳来自注入的时钟，签名不依赖随机源（见 utils.Signature），区块只保存在内存中。
+ * Simulate 在同一个协程中交替生成交易与挖矿，挖矿只使用一个工作协程，因此相同种子、相同配置的两次运行
+ * 得到逐字节相同的区块链，可以对整个网络的行为编写回归测试。
+ */
+
+// maxSimulationSteps 模拟运行中每个区块之前最多生成交易的次数。
+const maxSimulationSteps = 100
+
+// NewSimulatedNetWork 创建一个用于确定性模拟的区块链网络实例。
+// 参数:
+// - seed: 随机数种子，决定账户密钥、交易负载与区块头的随机字段。
+// - clock: 提供当前时间的时钟，通常为 SimulatedClock，Simulate 会在每个区块之前使其前进一个出块间隔。
+// 返回值:
+// 返回一个指向新创建的区块链网络实例的指针。
+func NewSimulatedNetWork(seed int64, clock Clock) *NetWork {
+	network := newNetWork(seed, clock, true)
+	// 多个工作协程中哪一个先找到 nonce 取决于调度，模拟时只使用一个
+	network.miner.threads = 1
+	return network
+}
+
+// deriveAccounts 根据种子派生指定个数的账户。
+func deriveAccounts(seed int64, count int) []data.Account {
+	accounts := make([]data.Account, count)
+	for i := range accounts {
+		accounts[i] = *deriveAccount(seed, "account", i)
+	}
+	return accounts
+}
+
+// deriveAccount 根据种子、用途与序号派生账户，不同用途或序号得到互不相关的密钥。
+func deriveAccount(seed int64, label string, index int) *data.Account {
+	e := data.NewEncoder()
+	e.WriteString(label)
+	e.WriteInt64(seed)
+	e.WriteInt(index)
+	return data.NewAccountFromSeed(e.Bytes())
+}
+
+// Simulate 在当前协程中依次生成 blocks 个区块，区块链尚未初始化时先初始化，之后 Ready 返回的通道被关闭。
+// 每个区块之前交易负载生成器不断提交交易，直到交易池中的交易足以填满一个区块或达到 maxSimulationSteps 次，
+// 然后时钟前进 config 中的 targetBlockInterval 秒，矿工打包交易并挖出区块。
+// 参数:
+// - blocks: 生成的区块个数。
+// 返回值:
+// 挖出的区块未通过验证时返回对应错误。
+func (n *NetWork) Simulate(blocks int) error {
+	if n.blockchain.tip == nil {
+		n.blockchain.SetUp()
+		n.SyncSPVPeers()
+		close(n.ready)
+	}
+	interval := time.Duration(config.MiniChainConfig.GetTargetBlockInterval()) * time.Second
+	for i := 0; i < blocks; i++ {
+		for step := 0; step < maxSimulationSteps && !n.miner.templateReady(); step++ {
+			n.generator.Step(n.txPool)
+		}
+		if clock, ok := n.clock.(*SimulatedClock); ok {
+			clock.Advance(interval)
+		}
+		if err := n.miner.mineNext(context.Background()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package network
+
+import (
+	"bytes"
+	"testing"
+)
+
+// simulateChain 以指定种子运行一次模拟，返回主链上全部区块的规范编码以及非 coinbase 交易的个数。
+func simulateChain(t *testing.T, seed int64, blocks int) ([]byte, int) {
+	t.Helper()
+	n := NewSimulatedNetWork(seed, NewSimulatedClock(SimulationEpoch))
+	if err := n.Simulate(blocks); err != nil {
+		t.Fatalf("simulate with seed %d: %v", seed, err)
+	}
+	var encoded bytes.Buffer
+	transactions := 0
+	for i, block := range n.GetBlocks() {
+		encoded.Write(block.Encode())
+		if i > 0 {
+			body := block.GetBlockBody()
+			transactions += len(body.GetTransctions()) - 1
+		}
+	}
+	if height := n.blockchain.GetHeight(); height != blocks {
+		t.Fatalf("simulation with seed %d reached height %d, want %d", seed, height, blocks)
+	}
+	return encoded.Bytes(), transactions
+}
+
+func TestSimulationIsDeterministic(t *testing.T) {
+	// 签名验证占据了模拟的大部分时间，使用较小的区块与混合负载覆盖尽可能多的交易类型
+	useConfig(t, "-workload=mixed", "-maxTransactionCount=6")
+	first, transactions := simulateChain(t, 42, 6)
+	if transactions == 0 {
+		t.Fatal("simulation did not include any workload transactions")
+	}
+	second, _ := simulateChain(t, 42, 6)
+	if !bytes.Equal(first, second) {
+		t.Fatal("two simulations with the same seed produced different chains")
+	}
+	other, _ := simulateChain(t, 43, 6)
+	if bytes.Equal(first, other) {
+		t.Fatal("simulations with different seeds produced the same chain")
+	}
+}
@@ -0,0 +1,390 @@
+package network
+
+import (
+	"Go-Minichain/data"
+	"Go-Minichain/p2p"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+/**
+ * 区块同步
+ *
+ * Syncer 以 headers-first 的方式让节点追上其他节点的主链：
+ * 1. 连接建立或收到新区块的通告时，用区块头链的定位器请求区块头，验证通过的区块头加入区块头链（见 HeaderChain.go）；
+ * 2. 按高度顺序取出区块头链最长分支上尚未下载的区块，分配给高度足够的多个节点并行下载，每个节点同时最多请求 maxBlocksInFlight 个；
+ * 3. 收到的区块先暂存起来，由 Run 所在的协程按顺序加入区块链，乱序到达的区块等到前一个区块连接之后再加入，
+ *    这样验证区块期间不会阻塞读取其他节点发来的消息，也不会误判对方超时；
+ * 4. 超过 blockStallTimeout 没有收到的区块重新分配给其他节点，对方断开时它负责的区块同样重新分配。
+ * 已连接的区块写入区块存储，节点重启后从存储中的最新区块继续同步，不会重新下载。
+ * 同步期间每隔 progressInterval 输出一次进度。
+ */
+
+const (
+	// maxBlocksInFlight 每个节点同时请求的区块个数上限。
+	maxBlocksInFlight = 16
+	// downloadWindow 只下载主链最新区块之后这么多个区块以内的区块，限制暂存的乱序区块个数。
+	downloadWindow = 1024
+	// blockStallTimeout 等待一个区块的最长时间，超过后向其他节点请求。
+	blockStallTimeout = 15 * time.Second
+	// syncTickInterval 检查超时请求的间隔。
+	syncTickInterval = time.Second
+	// progressInterval 输出同步进度的间隔。
+	progressInterval = 5 * time.Second
+)
+
+// SyncStatus 区块同步的状态。
+// 字段说明：
+// - Height: 主链的高度。
+// - HeaderHeight: 区块头链最长分支的高度。
+// - Peers: 参与同步的节点个数。
+// - InFlight: 已经请求但尚未收到的区块个数。
+// - Pending: 已经收到但前一个区块尚未连接的区块个数。
+// - Syncing: 是否还有区块需要下载。
+type SyncStatus struct {
+	Height       int
+	HeaderHeight int
+	Peers        int
+	InFlight     int
+	Pending      int
+	Syncing      bool
+}
+
+// syncPeer 参与同步的一个节点。
+// 字段说明：
+// - height: 已知该节点主链的高度，来自握手以及该节点发来的区块头。
+// - inFlight: 向该节点请求但尚未收到的区块个数。
+type syncPeer struct {
+	height   int
+	inFlight int
+}
+
+// pendingBlock 已经收到但尚未加入区块链的区块。
+// 字段说明：
+// - block: 区块本身。
+// - peer: 发来该区块的节点。
+type pendingBlock struct {
+	block data.Block
+	peer  *p2p.Peer
+}
+
+// blockRequest 一个已经请求但尚未收到的区块。
+// 字段说明：
+// - peer: 负责发送该区块的节点。
+// - requestedAt: 请求的时间。
+type blockRequest struct {
+	peer        *p2p.Peer
+	requestedAt time.Time
+}
+
+// Syncer 从其他节点下载区块头与区块。
+// 字段说明：
+// - network: 所属的区块链网络。
+// - peers: 参与同步的节点。
+// - inFlight: 已经请求但尚未收到的区块，按区块哈希索引。
+// - pending: 已经收到但尚未加入区块链的区块，按区块哈希索引。
+// - children: pending 中的区块按前一个区块哈希的索引。
+// - ready: 有新的区块暂存时发出通知。
+// - lastReport / lastReportHeight: 上一次输出进度的时间与当时的主链高度，用于计算下载速度。
+// - mutex: 保护以上字段的互斥锁。
+type Syncer struct {
+	network          *NetWork
+	peers            map[*p2p.Peer]*syncPeer
+	inFlight         map[string]*blockRequest
+	pending          map[string]pendingBlock
+	children         map[string][]string
+	ready            chan struct{}
+	lastReport       time.Time
+	lastReportHeight int
+	mutex            sync.Mutex
+}
+
+// NewSyncer 创建区块同步服务。
+// 参数:
+// - network: 所属的区块链网络。
+// 返回值:
+// 返回一个指向新创建的区块同步服务的指针。
+func NewSyncer(network *NetWork) *Syncer {
+	return &Syncer{
+		network:  network,
+		peers:    make(map[*p2p.Peer]*syncPeer),
+		inFlight: make(map[string]*blockRequest),
+		pending:  make(map[string]pendingBlock),
+		children: make(map[string][]string),
+		ready:    make(chan struct{}, 1),
+	}
+}
+
+// Run 将暂存的区块加入区块链，并定期重新分配超时的请求、输出同步进度，直到 ctx 被取消。
+func (s *Syncer) Run(ctx context.Context) {
+	ticker := time.NewTicker(syncTickInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.ready:
+			s.processPending()
+		case <-ticker.C:
+			s.releaseStalled()
+			s.schedule()
+			s.reportProgress()
+		}
+	}
+}
+
+// GetStatus 返回区块同步的状态。
+func (s *Syncer) GetStatus() SyncStatus {
+	blockchain := s.network.blockchain
+	status := SyncStatus{Height: blockchain.GetHeight(), HeaderHeight: blockchain.GetHeaderHeight()}
+	status.Syncing = status.HeaderHeight > status.Height
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	status.Peers = len(s.peers)
+	status.InFlight = len(s.inFlight)
+	status.Pending = len(s.pending)
+	return status
+}
+
+// AddPeer 登记新连接的节点，并向它请求区块头。
+func (s *Syncer) AddPeer(p *p2p.Peer) {
+	s.mutex.Lock()
+	s.peers[p] = &syncPeer{height: p.GetHeight()}
+	s.mutex.Unlock()
+	s.requestHeaders(p)
+}
+
+// RemovePeer 移除断开的节点，它负责的区块重新分配给其他节点。
+func (s *Syncer) RemovePeer(p *p2p.Peer) {
+	s.mutex.Lock()
+	delete(s.peers, p)
+	for hash, request := range s.inFlight {
+		if request.peer == p {
+			delete(s.inFlight, hash)
+		}
+	}
+	s.mutex.Unlock()
+	s.schedule()
+}
+
+// HandleBlockInv 处理区块通告：有未知的区块时向对方请求区块头，验证区块头之后再下载区块。
+func (s *Syncer) HandleBlockInv(p *p2p.Peer, hashes []string) {
+	for _, hash := range hashes {
+		if !s.network.blockchain.HasHeader(hash) {
+			s.requestHeaders(p)
+			return
+		}
+	}
+}
+
+// HandleHeaders 将对方发来的区块头加入区块头链，并开始下载其中的区块。
+// 区块头未通过验证时断开连接；区块头个数达到上限时继续请求之后的区块头。
+func (s *Syncer) HandleHeaders(p *p2p.Peer, headers []data.BlockHeader) {
+	if len(headers) == 0 {
+		return
+	}
+	blockchain := s.network.blockchain
+	_, err := blockchain.AcceptHeaders(headers)
+	if err != nil {
+		if errors.Is(err, ErrBadParent) && !blockchain.HasHeader(headers[0].GetPreBlockHash()) {
+			// 区块头无法与本节点已知的区块头连接，用定位器重新请求
+			s.requestHeaders(p)
+			return
+		}
+		fmt.Println("Disconnect", p, "after invalid headers:", err)
+		p.Disconnect()
+		return
+	}
+	last := headers[len(headers)-1]
+	lastHash := last.Hash()
+	if height, ok := blockchain.GetHeaderHeightOf(lastHash); ok {
+		s.mutex.Lock()
+		if peer, ok := s.peers[p]; ok && height > peer.height {
+			peer.height = height
+		}
+		s.mutex.Unlock()
+	}
+	if len(headers) == p2p.MaxHeaders {
+		p.Send(&p2p.MsgGetHeaders{Locator: []string{lastHash}})
+	}
+	s.schedule()
+}
+
+// HandleBlock 暂存对方发来的区块，由 Run 所在的协程加入区块链。
+// 区块头未知且前一个区块也未知的区块是未经请求的孤块，此时向对方请求缺少的区块头。
+func (s *Syncer) HandleBlock(p *p2p.Peer, block data.Block) {
+	hash := block.Hash()
+	header := block.GetBlockHeader()
+	prev := header.GetPreBlockHash()
+	blockchain := s.network.blockchain
+	s.release(hash)
+	if !blockchain.HasBlock(prev) && !blockchain.HasHeader(hash) {
+		s.requestHeaders(p)
+		return
+	}
+	s.mutex.Lock()
+	if _, ok := s.pending[hash]; !ok {
+		s.pending[hash] = pendingBlock{block: block, peer: p}
+		s.children[prev] = append(s.children[prev], hash)
+	}
+	s.mutex.Unlock()
+	select {
+	case s.ready <- struct{}{}:
+	default:
+	}
+	s.schedule()
+}
+
+// HandleNotFound 对方没有请求的区块时，将这些区块重新分配给其他节点。
+func (s *Syncer) HandleNotFound(hashes []string) {
+	for _, hash := range hashes {
+		s.release(hash)
+	}
+	s.schedule()
+}
+
+// processPending 将前一个区块已经在区块链中的暂存区块依次加入区块链，直到没有可以加入的区块。
+// 区块未通过验证时丢弃暂存的后代区块。
+func (s *Syncer) processPending() {
+	blockchain := s.network.blockchain
+	for {
+		s.mutex.Lock()
+		queue := make([]pendingBlock, 0)
+		for prev, hashes := range s.children {
+			if !blockchain.HasBlock(prev) {
+				continue
+			}
+			for _, hash := range hashes {
+				queue = append(queue, s.pending[hash])
+				delete(s.pending, hash)
+			}
+			delete(s.children, prev)
+		}
+		s.mutex.Unlock()
+		if len(queue) == 0 {
+			return
+		}
+		for _, next := range queue {
+			if err := s.network.AddNewBlock(next.block); err != nil && !errors.Is(err, ErrDuplicateBlock) {
+				fmt.Println("Reject Block from", next.peer, ":", err)
+				s.mutex.Lock()
+				s.dropPendingLocked(next.block.Hash())
+				s.mutex.Unlock()
+			}
+		}
+		// 同步期间这里可能连续运行很久，同样需要重新分配超时的请求并输出进度
+		s.releaseStalled()
+		s.schedule()
+		s.reportProgress()
+	}
+}
+
+// dropPendingLocked 丢弃暂存的指定区块的全部后代区块，调用方需要持有 s.mutex。
+func (s *Syncer) dropPendingLocked(hash string) {
+	for _, child := range s.children[hash] {
+		delete(s.pending, child)
+		s.dropPendingLocked(child)
+	}
+	delete(s.children, hash)
+}
+
+// schedule 将区块头链最长分支上尚未下载的区块分配给高度足够且请求未满的节点，每次选择请求最少的节点。
+func (s *Syncer) schedule() {
+	missing := s.network.blockchain.missingBlocks(downloadWindow)
+	if len(missing) == 0 {
+		return
+	}
+	batches := make(map[*p2p.Peer][]p2p.InvVect)
+	s.mutex.Lock()
+	now := time.Now()
+	for _, location := range missing {
+		if _, ok := s.inFlight[location.hash]; ok {
+			continue
+		}
+		if _, ok := s.pending[location.hash]; ok {
+			continue
+		}
+		var best *p2p.Peer
+		for p, peer := range s.peers {
+			if peer.height >= location.height && peer.inFlight < maxBlocksInFlight &&
+				(best == nil || peer.inFlight < s.peers[best].inFlight) {
+				best = p
+			}
+		}
+		if best == nil {
+			continue
+		}
+		s.peers[best].inFlight++
+		s.inFlight[location.hash] = &blockRequest{peer: best, requestedAt: now}
+		batches[best] = append(batches[best], p2p.InvVect{Type: p2p.InvBlock, Hash: location.hash})
+	}
+	s.mutex.Unlock()
+	for p, items := range batches {
+		p.Send(&p2p.MsgGetData{Items: items})
+	}
+}
+
+// release 移除一个区块请求，使它可以重新分配。
+func (s *Syncer) release(hash string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.releaseLocked(hash)
+}
+
+// releaseLocked 与 release 相同，调用方需要持有 s.mutex。
+func (s *Syncer) releaseLocked(hash string) {
+	request, ok := s.inFlight[hash]
+	if !ok {
+		return
+	}
+	delete(s.inFlight, hash)
+	if peer, ok := s.peers[request.peer]; ok {
+		peer.inFlight--
+	}
+}
+
+// releaseStalled 移除超时的区块请求，使它们可以分配给其他节点。
+func (s *Syncer) releaseStalled() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	now := time.Now()
+	for hash, request := range s.inFlight {
+		if now.Sub(request.requestedAt) > blockStallTimeout {
+			fmt.Println("Block", hash, "from", request.peer, "timed out")
+			s.releaseLocked(hash)
+		}
+	}
+}
+
+// reportProgress 同步期间每隔 progressInterval 输出一次主链高度、区块头链高度与下载速度。
+func (s *Syncer) reportProgress() {
+	status := s.GetStatus()
+	now := time.Now()
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if !status.Syncing {
+		s.lastReport = time.Time{}
+		return
+	}
+	if s.lastReport.IsZero() {
+		s.lastReport, s.lastReportHeight = now, status.Height
+		return
+	}
+	elapsed := now.Sub(s.lastReport)
+	if elapsed < progressInterval {
+		return
+	}
+	rate := float64(status.Height-s.lastReportHeight) / elapsed.Seconds()
+	fmt.Printf("Sync progress: block %d of %d (%.1f%%), %.1f blocks/s, %d in flight from %d peers, %d pending\n",
+		status.Height, status.HeaderHeight, 100*float64(status.Height)/float64(status.HeaderHeight), rate,
+		status.InFlight, status.Peers, status.Pending)
+	s.lastReport, s.lastReportHeight = now, status.Height
+}
+
+// requestHeaders 用区块头链的定位器向对方请求之后的区块头。
+func (s *Syncer) requestHeaders(p *p2p.Peer) {
+	p.Send(&p2p.MsgGetHeaders{Locator: s.network.blockchain.GetHeaderLocator()})
+}
@@ -0,0 +1,105 @@
+package network
+
+import (
+	"Go-Minichain/data"
+	"Go-Minichain/p2p"
+	"sync"
+	"testing"
+)
+
+// headersFirstChecker 包装节点的消息处理者，记录节点收到的区块头与区块，并检查每个区块到达时其区块头是否已经被接受。
+// 字段说明：
+// - Relay: 被包装的转发服务，消息检查之后交给它处理。
+// - headers: 收到的区块头个数。
+// - blocks: 收到的区块哈希。
+// - violations: 区块头尚未接受时就收到的区块。
+// - mutex: 保护以上字段的互斥锁。
+type headersFirstChecker struct {
+	*Relay
+	headers    int
+	blocks     map[string]bool
+	violations []string
+	mutex      sync.Mutex
+}
+
+// newHeadersFirstChecker 返回 startTestNode 的 wrap 参数，被包装的消息处理者保存在 *checker 中。
+func newHeadersFirstChecker(checker **headersFirstChecker) func(r *Relay) p2p.Handler {
+	return func(r *Relay) p2p.Handler {
+		*checker = &headersFirstChecker{Relay: r, blocks: make(map[string]bool)}
+		return *checker
+	}
+}
+
+// HandleMessage 检查收到的区块头与区块，再交给转发服务处理。
+func (c *headersFirstChecker) HandleMessage(p *p2p.Peer, msg p2p.Message) {
+	switch m := msg.(type) {
+	case *p2p.MsgHeaders:
+		c.mutex.Lock()
+		c.headers += len(m.Headers)
+		c.mutex.Unlock()
+	case *p2p.MsgBlock:
+		hash := m.Block.Hash()
+		known := c.network.blockchain.HasHeader(hash)
+		c.mutex.Lock()
+		c.blocks[hash] = true
+		if !known {
+			c.violations = append(c.violations, hash)
+		}
+		c.mutex.Unlock()
+	}
+	c.Relay.HandleMessage(p, msg)
+}
+
+// check 检查节点收到的区块头个数至少为 headers，收到的区块恰好是 want，且每个区块都在其区块头之后到达。
+func (c *headersFirstChecker) check(tb testing.TB, headers int, want []data.Block) {
+	tb.Helper()
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if len(c.violations) > 0 {
+		tb.Fatalf("blocks %v arrived before their headers", c.violations)
+	}
+	if c.headers < headers {
+		tb.Fatalf("received %d headers, want at least %d", c.headers, headers)
+	}
+	if len(c.blocks) != len(want) {
+		tb.Fatalf("downloaded %d blocks, want %d", len(c.blocks), len(want))
+	}
+	for _, block := range want {
+		if !c.blocks[block.Hash()] {
+			tb.Fatalf("block %s was not downloaded", block.Hash())
+		}
+	}
+}
+
+func TestSyncHeadersFirstAndResumeAfterRestart(t *testing.T) {
+	useConfig(t, relayTestArgs...)
+	a := startTestNode(t, "", nil)
+	b := startTestNode(t, "", nil)
+	b.connect(a)
+	waitFor(t, "connection", func() bool { return a.peerCount() == 1 })
+	genesis := *a.network.GetNewestBlock()
+	first := mineBranch(t, a.network, genesis.Hash(), 20, 1)
+	waitForTip(t, b, a)
+
+	// 新节点同时连接 a 与 b，先下载全部区块头，再从两个节点下载区块
+	dataDir := t.TempDir()
+	var checker *headersFirstChecker
+	fresh := startTestNode(t, dataDir, newHeadersFirstChecker(&checker))
+	fresh.connect(a)
+	fresh.connect(b)
+	waitForTip(t, fresh, a)
+	checker.check(t, len(first), first)
+	fresh.stop()
+
+	// 节点停止期间 a 继续出块，重启后从存储中恢复已有的区块，只下载新的区块
+	second := mineBranch(t, a.network, first[len(first)-1].Hash(), 10, 1)
+	waitForTip(t, b, a)
+	restarted := startTestNode(t, dataDir, newHeadersFirstChecker(&checker))
+	if height := restarted.network.blockchain.GetHeight(); height != len(first) {
+		t.Fatalf("restarted node recovered height %d from the block store, want %d", height, len(first))
+	}
+	restarted.connect(a)
+	restarted.connect(b)
+	waitForTip(t, restarted, a)
+	checker.check(t, len(second), second)
+}
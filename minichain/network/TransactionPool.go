@@ -2,135 +2,637 @@ package network
 
 import (
 	"Go-Minichain/data"
-	"Go-Minichain/utils"
-	"crypto/elliptic"
-	"math/rand"
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
 )
 
 /**
  * 交易池
+ *
+ * 交易池中的交易尚未被确认，不会修改区块链中已确认的 UTXO 集合。
+ * 交易池在已确认的 UTXO 集合之上维护一层叠加视图：记录池中交易花费的输出与新产生的输出，
+ * 新交易基于该视图选择输入，从而可以花费池中其他交易的找零。新交易需要通过接收策略的检查（见 AcceptTransaction）。
+ * 主链发生变化时，交易池移除已被确认的交易，放回被回滚区块中的交易，并按顺序重新验证，
+ * 不再有效的交易会被直接丢弃。
+ *
+ * 交易的手续费为输入金额之和减去输出金额之和，在交易加入交易池时计算；
+ * 手续费率为手续费除以交易规范编码的字节数，矿工按手续费率从高到低选择交易（见 MinerNode.BuildBlockTemplate）。
+ *
+ * 交易池的交易个数与交易编码的总字节数都有上限，超过上限时移除后代交易包手续费率最低的交易（见 trim）；
+ * 在交易池中停留超过 poolExpiry 秒的交易会过期。移除一笔交易时，花费其输出的池中交易会被一并移除。
+ * 交易池的全部状态由互斥锁保护，生成交易的协程、矿工与其他调用方可以并发访问。
  */
 
+// poolEntry 交易池中的一笔交易及其手续费信息。
+// 字段说明：
+// - tx: 交易本身。
+// - txID: 交易标识。
+// - fee: 交易手续费。
+// - size: 交易规范编码的字节数。
+// - parents: 交易花费的输出所属的、仍在交易池中的交易标识，这些交易必须先于该交易被打包。
+// - addedAt: 交易加入交易池的时间，用于判断交易是否过期。
+type poolEntry struct {
+	tx      data.Transaction
+	txID    string
+	fee     int
+	size    int
+	parents []string
+	addedAt time.Time
+}
+
+// higherFeeRate 判断该交易的手续费率是否高于另一笔交易，以交叉相乘代替除法避免精度损失。
+func (e *poolEntry) higherFeeRate(other *poolEntry) bool {
+	return e.fee*other.size > other.fee*e.size
+}
+
+// TransactionPool 定义了交易池的结构体。
+// 字段说明：
+// - entries: 按加入顺序排列的未确认交易，池中交易花费的其他池中交易总是排在其前面。
+// - byID: 以交易标识索引的未确认交易。
+// - totalSize: 池中全部交易规范编码的字节数之和。
+// - capacity: 交易池最多容纳的交易个数。
+// - maxSize: 池中交易编码的总字节数上限。
+// - expiry: 交易在池中停留的最长时间，不大于 0 时交易不会过期。
+// - network: 网络对象，用于访问区块链。
+// - delta: 池中交易对已确认 UTXO 集合的改动，即花费的已确认输出与尚未被花费的新输出。
+// - spentBy: 池中交易花费的输出及花费它的交易标识，用于发现冲突的交易。
+// - added: 有新交易加入交易池时发出通知，缓冲区为 1，多次通知会被合并。
+// - space: 交易池有空余位置时发出通知，缓冲区为 1，多次通知会被合并。
+// - mutex: 保护 entries、byID、totalSize、delta 与 spentBy 的互斥锁。
 type TransactionPool struct {
-	transactions []data.Transaction
-	capacity     int
-	network      *NetWork
+	entries   []*poolEntry
+	byID      map[string]*poolEntry
+	totalSize int
+	capacity  int
+	maxSize   int
+	expiry    time.Duration
+	network   *NetWork
+	delta     *UTXODelta
+	spentBy   map[data.Outpoint]string
+	added     chan struct{}
+	space     chan struct{}
+	mutex     sync.Mutex
 }
 
-func NewTransactionPool(c int, network *NetWork) *TransactionPool {
+// NewTransactionPool 创建一个交易池。
+// 参数:
+// - c: 交易池最多容纳的交易个数。
+// - maxSize: 池中交易编码的总字节数上限。
+// - expiry: 交易在池中停留的最长时间，不大于 0 时交易不会过期。
+// - network: 网络对象，用于访问区块链。
+// 返回值:
+// 返回一个指向新创建的交易池的指针。
+func NewTransactionPool(c int, maxSize int, expiry time.Duration, network *NetWork) *TransactionPool {
 	p := new(TransactionPool)
 	p.capacity = c
-	p.transactions = make([]data.Transaction, 0)
+	p.maxSize = maxSize
+	p.expiry = expiry
+	p.entries = make([]*poolEntry, 0)
+	p.byID = make(map[string]*poolEntry)
 	p.network = network
+	p.delta = NewUTXODelta()
+	p.spentBy = make(map[data.Outpoint]string)
+	p.added = make(chan struct{}, 1)
+	p.space = make(chan struct{}, 1)
 	return p
 }
-func (p *TransactionPool) Put(transaction data.Transaction) {
-	p.transactions = append(p.transactions, transaction)
+
+// signal 向缓冲区为 1 的通知通道发送通知，已有未被接收的通知时直接返回。
+func signal(ch chan struct{}) {
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+}
+
+// Added 返回新交易加入交易池时收到通知的通道，矿工据此判断是否可以打包区块，而不必轮询交易池。
+func (p *TransactionPool) Added() <-chan struct{} {
+	return p.added
+}
+
+// AcceptTransaction 以当前的叠加视图为基础按接收策略检查交易，通过后将其加入交易池。
+// 交易与池中允许被替换的交易冲突时，如果满足替换规则（见 replacementSet），则替换这些交易及其后代。
+// 加入后交易池超过上限时，依次移除后代交易包手续费率最低的交易及其后代，直到回到上限以内；
+// 新交易自身因此被移除时，被替换与被移除的交易全部放回，交易被拒绝。
+// 参数:
+// - transaction: 新交易。
+// 返回值:
+// 交易被拒绝时返回 *TxRejectError，可以使用 errors.Is 判断 ErrTxInPool、ErrMissingInputs、ErrPoolConflict、
+// ErrUnlockFailed、ErrNonPositiveOutput、ErrValueCreated、ErrFeeTooLow、ErrReplacementFee 或 ErrPoolFull 等原因。
+// 交易被拒绝时交易池中的交易保持不变，只有已经过期的交易会被移除。
+func (p *TransactionPool) AcceptTransaction(transaction data.Transaction) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.publishRemoved(p.expire(p.network.Now()), RemoveExpired)
+	txID := transaction.TxID()
+	if _, ok := p.byID[txID]; ok {
+		return &TxRejectError{Kind: ErrTxInPool, TxID: txID, Input: -1}
+	}
+	replaced, err := p.replacementSet(&transaction)
+	if err != nil {
+		return err
+	}
+	// 先移除被替换的交易，使新交易可以花费它们花费的输出；新交易被拒绝时再放回
+	removed := p.removeEntries(replaced)
+	fee, err := p.checkTransaction(&transaction, removed)
+	if err != nil {
+		p.restoreEntries(removed)
+		return err
+	}
+	p.addEntry(transaction, fee, p.network.Now())
+	trimmed := p.trim()
+	evicted := make([]*poolEntry, 0, len(trimmed))
+	for _, entry := range trimmed {
+		if entry.txID != txID {
+			evicted = append(evicted, entry)
+		}
+	}
+	entry, ok := p.byID[txID]
+	if !ok {
+		// 被移除的交易不会是被替换交易的后代，先放回它们，被替换的交易可能花费它们的输出
+		p.restoreEntries(evicted)
+		p.restoreEntries(removed)
+		return &TxRejectError{Kind: ErrPoolFull, TxID: txID, Input: -1, Detail: "fee rate is too low to enter the full pool"}
+	}
+	if len(removed) > 0 {
+		fmt.Println("TransactionPool replaced", len(removed), "transactions with", txID)
+	}
+	p.publishRemoved(removed, RemoveReplaced)
+	p.publishRemoved(evicted, RemoveEvicted)
+	p.publishAccepted(entry, "")
+	signal(p.added)
+	return nil
+}
+
+// addEntry 将已通过验证的交易记入交易池。调用方需要持有 p.mutex。
+func (p *TransactionPool) addEntry(transaction data.Transaction, fee int, addedAt time.Time) {
+	entry := &poolEntry{tx: transaction, txID: transaction.TxID(), fee: fee, size: transaction.Size(), addedAt: addedAt}
+	for _, in := range transaction.GetInputs() {
+		p.spentBy[in.GetOutpoint()] = entry.txID
+		parentID := in.GetOutpoint().GetTxID()
+		if _, ok := p.byID[parentID]; ok && !containsString(entry.parents, parentID) {
+			entry.parents = append(entry.parents, parentID)
+		}
+	}
+	p.entries = append(p.entries, entry)
+	p.byID[entry.txID] = entry
+	p.totalSize += entry.size
+}
+
+// addDescendants 将花费指定交易输出的全部池中后代交易加入集合。调用方需要持有 p.mutex。
+func (p *TransactionPool) addDescendants(txIDs map[string]bool) {
+	// entries 中父交易总是排在子交易前面，顺序遍历一次即可找到全部后代
+	for _, entry := range p.entries {
+		for _, parentID := range entry.parents {
+			if txIDs[parentID] {
+				txIDs[entry.txID] = true
+				break
+			}
+		}
+	}
+}
+
+// removeEntries 移除指定的交易以及花费其输出的全部池中交易，并撤销它们对叠加视图的改动。
+// 其余交易的输入不受影响，因此不需要重新验证。调用方需要持有 p.mutex。
+// 参数:
+// - txIDs: 要移除的交易标识，会被扩充为包含全部后代交易。
+// 返回值:
+// 按加入顺序返回被移除的交易池条目。
+func (p *TransactionPool) removeEntries(txIDs map[string]bool) []*poolEntry {
+	p.addDescendants(txIDs)
+	// 从后往前撤销改动：子交易恢复父交易的输出之后，父交易再将其移除
+	removed := 0
+	for i := len(p.entries) - 1; i >= 0; i-- {
+		entry := p.entries[i]
+		if !txIDs[entry.txID] {
+			continue
+		}
+		for _, out := range entry.tx.GetOutUTXOs() {
+			p.delta.discard(out.GetOutpoint())
+		}
+		for _, in := range entry.tx.GetInputs() {
+			outpoint := in.GetOutpoint()
+			var utxo *data.UTXO
+			if parent, ok := p.byID[outpoint.GetTxID()]; ok {
+				utxo = parent.tx.GetOutUTXOs()[outpoint.GetIndex()]
+			}
+			p.delta.restore(outpoint, utxo)
+			delete(p.spentBy, outpoint)
+		}
+		removed++
+	}
+	if removed == 0 {
+		return nil
+	}
+	entries := make([]*poolEntry, 0, len(p.entries)-removed)
+	removedEntries := make([]*poolEntry, 0, removed)
+	for _, entry := range p.entries {
+		if txIDs[entry.txID] {
+			delete(p.byID, entry.txID)
+			p.totalSize -= entry.size
+			removedEntries = append(removedEntries, entry)
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	p.entries = entries
+	signal(p.space)
+	return removedEntries
+}
+
+// restoreEntries 将 removeEntries 移除的交易按原来的顺序放回交易池，交易加入交易池的时间保持不变。
+// 调用方需要持有 p.mutex。
+func (p *TransactionPool) restoreEntries(entries []*poolEntry) {
+	for _, entry := range entries {
+		tx := entry.tx
+		fee, err := p.network.GetBlockchain().ValidateTransaction(&tx, p.delta)
+		if err != nil {
+			continue
+		}
+		p.addEntry(tx, fee, entry.addedAt)
+	}
 }
+
+// trim 交易池超过交易个数或总字节数上限时，依次移除后代交易包手续费率最低的交易及其后代。调用方需要持有 p.mutex。
+// 后代交易包由一笔交易及其全部池中后代组成，移除一笔交易时其后代必须一并移除，因此按整个交易包的手续费率比较，
+// 子交易为父交易支付的手续费（child-pays-for-parent）可以让父交易留在交易池中，与 BuildBlockTemplate 的选择方式一致。
+// 交易包只计算并排序一次，先选出全部需要移除的交易，再一次性移除。
+// 返回值:
+// 按加入顺序返回被移除的交易池条目。
+func (p *TransactionPool) trim() []*poolEntry {
+	count, size := len(p.entries), p.totalSize
+	if count <= p.capacity && size <= p.maxSize {
+		return nil
+	}
+	children := make(map[string][]*poolEntry)
+	for _, entry := range p.entries {
+		for _, parentID := range entry.parents {
+			children[parentID] = append(children[parentID], entry)
+		}
+	}
+	packages := make(map[string]*txPackage, len(p.entries))
+	for _, entry := range p.entries {
+		packages[entry.txID] = descendantPackage(entry, children)
+	}
+	// 按后代交易包的手续费率从低到高排序，手续费率相同时较晚加入的交易排在前面
+	candidates := make([]*poolEntry, len(p.entries))
+	for i, entry := range p.entries {
+		candidates[len(candidates)-1-i] = entry
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return packages[candidates[j].txID].higherFeeRate(packages[candidates[i].txID])
+	})
+	trimmed := make(map[string]bool)
+	for _, lowest := range candidates {
+		if count <= p.capacity && size <= p.maxSize {
+			break
+		}
+		stack := []*poolEntry{lowest}
+		for len(stack) > 0 {
+			entry := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if trimmed[entry.txID] {
+				continue
+			}
+			trimmed[entry.txID] = true
+			count--
+			size -= entry.size
+			stack = append(stack, children[entry.txID]...)
+		}
+	}
+	return p.removeEntries(trimmed)
+}
+
+// descendantPackage 计算一笔交易与其全部池中后代交易的手续费之和与大小之和，返回的交易包不记录包内交易。
+// 参数:
+// - entry: 交易包中的第一笔交易。
+// - children: 交易标识到花费其输出的池中交易的映射。
+func descendantPackage(entry *poolEntry, children map[string][]*poolEntry) *txPackage {
+	pkg := new(txPackage)
+	visited := map[string]bool{entry.txID: true}
+	stack := []*poolEntry{entry}
+	for len(stack) > 0 {
+		current := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		pkg.fee += current.fee
+		pkg.size += current.size
+		for _, child := range children[current.txID] {
+			if !visited[child.txID] {
+				visited[child.txID] = true
+				stack = append(stack, child)
+			}
+		}
+	}
+	return pkg
+}
+
+// expire 移除在交易池中停留超过 expiry 的交易及其后代。调用方需要持有 p.mutex。
+// 返回值:
+// 返回被移除的交易池条目。
+func (p *TransactionPool) expire(now time.Time) []*poolEntry {
+	if p.expiry <= 0 {
+		return nil
+	}
+	expired := make(map[string]bool)
+	for _, entry := range p.entries {
+		if now.Sub(entry.addedAt) > p.expiry {
+			expired[entry.txID] = true
+		}
+	}
+	if len(expired) == 0 {
+		return nil
+	}
+	removed := p.removeEntries(expired)
+	fmt.Println("TransactionPool expired", len(removed), "transactions")
+	return removed
+}
+
+// publishAccepted 发布交易进入交易池的事件。调用方需要持有 p.mutex。
+func (p *TransactionPool) publishAccepted(entry *poolEntry, reason string) {
+	p.network.events.Publish(Event{Type: EventTxAccepted, TxID: entry.txID, Fee: entry.fee, Size: entry.size, Reason: reason})
+}
+
+// publishRemoved 按顺序发布交易离开交易池的事件。调用方需要持有 p.mutex。
+func (p *TransactionPool) publishRemoved(entries []*poolEntry, reason string) {
+	for _, entry := range entries {
+		p.network.events.Publish(Event{Type: EventTxRemoved, TxID: entry.txID, Fee: entry.fee, Size: entry.size, Reason: reason})
+	}
+}
+
+// containsString 判断字符串列表中是否包含指定字符串。
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// GetAll 按加入顺序返回交易池中全部交易的副本。
+// 交易会一直保留在交易池中，直到包含它们的区块被连接到主链。
 func (p *TransactionPool) GetAll() []data.Transaction {
-	transactions := p.transactions
-	p.clear()
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	transactions := make([]data.Transaction, len(p.entries))
+	for i, entry := range p.entries {
+		transactions[i] = entry.tx
+	}
 	return transactions
 }
-func (p *TransactionPool) clear() {
-	p.transactions = make([]data.Transaction, 0)
+
+// GetAllByFeeRate 按手续费率从高到低返回交易池中全部交易的副本，手续费率相同的交易按加入顺序排列。
+// 注意该顺序不考虑交易之间的依赖关系，打包区块时应使用 MinerNode.BuildBlockTemplate。
+func (p *TransactionPool) GetAllByFeeRate() []data.Transaction {
+	entries := p.entriesByFeeRate()
+	transactions := make([]data.Transaction, len(entries))
+	for i, entry := range entries {
+		transactions[i] = entry.tx
+	}
+	return transactions
 }
-func (p *TransactionPool) IsFull() bool {
-	return len(p.transactions) >= p.capacity
+
+// GetFee 返回交易池中指定交易的手续费，交易不在交易池中时第二个返回值为 false。
+func (p *TransactionPool) GetFee(txID string) (int, bool) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	entry, ok := p.byID[txID]
+	if !ok {
+		return 0, false
+	}
+	return entry.fee, true
 }
-func (p *TransactionPool) IsEmpty() bool {
-	return len(p.transactions) == 0
+
+// Get 返回交易池中指定哈希的交易，交易不在交易池中时第二个返回值为 false。
+func (p *TransactionPool) Get(txID string) (data.Transaction, bool) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	entry, ok := p.byID[txID]
+	if !ok {
+		return data.Transaction{}, false
+	}
+	return entry.tx, true
 }
-func (p *TransactionPool) GetCapacity() int {
-	return p.capacity
+
+// Has 判断交易池中是否有指定哈希的交易。
+func (p *TransactionPool) Has(txID string) bool {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	_, ok := p.byID[txID]
+	return ok
+}
+
+// snapshot 按加入顺序返回交易池条目的副本。
+func (p *TransactionPool) snapshot() []*poolEntry {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return append([]*poolEntry(nil), p.entries...)
+}
+
+// entriesByFeeRate 返回按手续费率从高到低排序的交易池条目副本。
+func (p *TransactionPool) entriesByFeeRate() []*poolEntry {
+	entries := p.snapshot()
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].higherFeeRate(entries[j])
+	})
+	return entries
 }
 
-// GetNewTransaction 生成一个新的交易对象，随机选择两个账户进行交易。
-// 该方法会确保交易的有效性，包括检查账户余额、解锁UTXO、签名交易等操作，
-// 并将交易的输入和输出更新到区块链的UTXO池中。
-//
+// Update 根据主链的变化同步交易池。
+// 被回滚区块中除 coinbase 以外的交易重新放回交易池的最前面，已被确认的交易从交易池中移除，
+// 之后所有交易按顺序基于新的已确认 UTXO 集合重新验证，无效的交易被丢弃。
 // 参数:
-// - p: 指向 TransactionPool 的指针，包含区块链和交易池的相关信息。
-//
-// 返回值:
-// - *Transaction: 指向新创建的交易对象的指针。
-func (p *TransactionPool) GetNewTransaction() *data.Transaction {
-	accounts := p.network.GetAccounts() // 获取区块链中的所有账户
-	var transaction *data.Transaction
-
-	for {
-		// 随机选择两个不同的账户作为交易的发送方和接收方
-		aAccount := accounts[rand.Intn(len(accounts))]
-		bAccount := accounts[rand.Intn(len(accounts))]
-
-		if aAccount == bAccount {
-			continue // 如果选中了相同的账户，则重新选择
-		}
-
-		// 获取发送方和接收方的钱包地址
-		aWalletAddress := aAccount.GetWalletAddress()
-		bWalletAddress := bAccount.GetWalletAddress()
-
-		// 获取发送方的有效未花费交易输出（UTXO）并计算其总金额
-		aTrueUTXOs := p.network.GetTrueUTXOs(aWalletAddress)
-		aAmount := aAccount.GetAmount(aTrueUTXOs)
-
-		if aAmount == 0 {
-			continue // 如果发送方没有可用余额，则重新选择账户
-		}
-
-		// 随机生成交易金额，并初始化输入和输出UTXO列表
-		txAmount := rand.Intn(aAmount) + 1
-		inUTXOs := make([]*data.UTXO, 0)
-		outUTXOs := make([]*data.UTXO, 0)
-
-		// 使用发送方的私钥对其公钥进行签名，用于解锁UTXO
-		publicKey := aAccount.GetPublicKey()
-		publicKeyBytes := elliptic.Marshal(publicKey, publicKey.X, publicKey.Y)
-		aUnLockSign := utils.Signature(publicKeyBytes, aAccount.GetPrivateKey())
-
-		// 解锁发送方的UTXO，累积足够的输入金额以满足交易需求
-		inAmount := 0
-		for _, utxo := range aTrueUTXOs {
-			if utxo.UnlockScript(aUnLockSign, aAccount.GetPublicKey()) {
-				inAmount += utxo.GetAmount()
-				inUTXOs = append(inUTXOs, utxo)
-				if inAmount >= txAmount {
-					break
-				}
+// - disconnected: 从主链上移除的区块。
+// - connected: 新连接到主链上的区块。
+func (p *TransactionPool) Update(disconnected []data.Block, connected []data.Block) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	now := p.network.Now()
+	candidates := make([]*poolEntry, 0)
+	for i := len(disconnected) - 1; i >= 0; i-- {
+		blockBody := disconnected[i].GetBlockBody()
+		for _, tx := range blockBody.GetTransctions() {
+			// coinbase 交易只在其所属区块中有效，不会放回交易池
+			if !tx.IsCoinbase() {
+				candidates = append(candidates, &poolEntry{tx: tx, addedAt: now})
 			}
 		}
+	}
+	candidates = append(candidates, p.entries...)
 
-		if inAmount < txAmount {
-			continue // 如果累积的输入金额不足，则重新选择账户
+	confirmed := make(map[string]bool)
+	for _, block := range connected {
+		blockBody := block.GetBlockBody()
+		for _, tx := range blockBody.GetTransctions() {
+			confirmed[tx.TxID()] = true
 		}
+	}
 
-		// 创建接收方的输出UTXO
-		outUTXOs = append(outUTXOs, data.NewUTXO(bWalletAddress, txAmount, bAccount.GetPublicKey()))
+	remaining := make([]*poolEntry, 0, len(candidates))
+	for _, candidate := range candidates {
+		if !confirmed[candidate.tx.TxID()] {
+			remaining = append(remaining, candidate)
+		}
+	}
+	previous := p.entries
+	p.revalidate(remaining)
+	expired := p.expire(now)
+	trimmed := p.trim()
+	p.publishUpdate(previous, confirmed, expired, trimmed)
+}
 
-		// 如果输入金额大于交易金额，则创建找零的输出UTXO
-		if inAmount > txAmount {
-			outUTXOs = append(outUTXOs, data.NewUTXO(aWalletAddress, inAmount-txAmount, aAccount.GetPublicKey()))
+// publishUpdate 比较主链变化前后的交易池，为离开交易池的原有交易发布移除事件，
+// 为从被回滚区块放回交易池的交易发布加入事件。调用方需要持有 p.mutex。
+// 参数:
+// - previous: 主链变化前交易池中的条目。
+// - confirmed: 新连接到主链上的区块中的交易标识。
+// - expired / trimmed: 重新验证之后因过期、超过上限被移除的条目。
+func (p *TransactionPool) publishUpdate(previous []*poolEntry, confirmed map[string]bool, expired []*poolEntry,
+	trimmed []*poolEntry) {
+	reasons := make(map[string]string, len(expired)+len(trimmed))
+	for _, entry := range expired {
+		reasons[entry.txID] = RemoveExpired
+	}
+	for _, entry := range trimmed {
+		reasons[entry.txID] = RemoveEvicted
+	}
+	wasPooled := make(map[string]bool, len(previous))
+	for _, entry := range previous {
+		wasPooled[entry.txID] = true
+		if _, ok := p.byID[entry.txID]; ok {
+			continue
+		}
+		reason := RemoveConflict
+		if confirmed[entry.txID] {
+			reason = RemoveConfirmed
+		} else if r, ok := reasons[entry.txID]; ok {
+			reason = r
+		}
+		p.publishRemoved([]*poolEntry{entry}, reason)
+	}
+	for _, entry := range p.entries {
+		if !wasPooled[entry.txID] {
+			p.publishAccepted(entry, AcceptReorg)
 		}
+	}
+}
 
-		// 将输入和输出UTXO序列化，并使用发送方的私钥对交易数据进行签名
-		sign := utils.Signature(data.UTXO2Bytes(inUTXOs, outUTXOs), aAccount.GetPrivateKey())
+// revalidate 清空叠加视图后按顺序重新验证交易，只保留仍然有效且不重复的交易，交易加入交易池的时间保持不变。
+// 调用方需要持有 p.mutex。
+func (p *TransactionPool) revalidate(candidates []*poolEntry) {
+	p.entries = make([]*poolEntry, 0, len(candidates))
+	p.byID = make(map[string]*poolEntry)
+	p.totalSize = 0
+	p.delta = NewUTXODelta()
+	p.spentBy = make(map[data.Outpoint]string)
+	dropped := 0
+	for _, candidate := range candidates {
+		tx := candidate.tx
+		if _, ok := p.byID[tx.TxID()]; ok {
+			continue
+		}
+		fee, err := p.network.GetBlockchain().ValidateTransaction(&tx, p.delta)
+		if err != nil {
+			dropped++
+			continue
+		}
+		p.addEntry(tx, fee, candidate.addedAt)
+	}
+	if dropped > 0 {
+		fmt.Println("TransactionPool dropped", dropped, "transactions that are no longer valid")
+	}
+	if len(p.entries) < p.capacity && p.totalSize < p.maxSize {
+		signal(p.space)
+	}
+}
 
-		// 创建交易对象，并更新区块链的UTXO池
-		transaction = data.NewTransaction(inUTXOs, outUTXOs, sign, aAccount.GetPublicKey())
-		p.network.ProcessTransactionUTXO(inUTXOs, outUTXOs)
-		break
+// Evict 从交易池中移除指定的交易，依赖这些交易输出的池中交易随之失效，也会被一并移除。
+// 矿工发现交易池中有无法打包的交易时调用该方法，而不是终止进程。
+// 参数:
+// - txIDs: 要移除的交易标识。
+// 返回值:
+// 返回实际移除的交易个数，包括一并失效的交易。
+func (p *TransactionPool) Evict(txIDs ...string) int {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	evicted := make(map[string]bool, len(txIDs))
+	for _, txID := range txIDs {
+		evicted[txID] = true
 	}
-	return transaction
+	removed := p.removeEntries(evicted)
+	p.publishRemoved(removed, RemoveEvicted)
+	return len(removed)
 }
 
-func (p *TransactionPool) Start() {
-	go func() {
-		for {
-			if !p.IsFull() {
-				transaction := p.GetNewTransaction()
-				p.Put(*transaction)
-			}
+// GetSpendableUTXOs 返回指定钱包地址在叠加视图中可以花费的 UTXO，按 Outpoint 排序。
+// 参数:
+// - walletAddress: 钱包地址。
+// 返回值:
+// 返回 UTXO 列表。
+func (p *TransactionPool) GetSpendableUTXOs(walletAddress string) []*data.UTXO {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return p.network.GetBlockchain().getUTXOs(p.delta, walletAddress)
+}
+
+// IsFull 判断交易池的交易个数或交易编码的总字节数是否已经达到上限。
+func (p *TransactionPool) IsFull() bool {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return len(p.entries) >= p.capacity || p.totalSize >= p.maxSize
+}
+
+// Count 返回交易池中的交易个数。
+func (p *TransactionPool) Count() int {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return len(p.entries)
+}
+
+// Size 返回交易池中全部交易规范编码的字节数之和。
+func (p *TransactionPool) Size() int {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return p.totalSize
+}
+
+// IsEmpty 判断交易池中是否没有任何交易。
+// 返回值:
+// 交易池为空时返回 true。
+func (p *TransactionPool) IsEmpty() bool {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return len(p.entries) == 0
+}
+
+// GetCapacity 返回交易池最多容纳的交易个数。
+func (p *TransactionPool) GetCapacity() int {
+	return p.capacity
+}
+
+// GetMaxSize 返回交易池中交易编码的总字节数上限。
+func (p *TransactionPool) GetMaxSize() int {
+	return p.maxSize
+}
+
+// WaitForSpace 等待交易池有空余位置，交易池未满时立即返回，供交易负载生成器在提交交易前调用。
+// 参数:
+// - ctx: 停止等待的上下文。
+// 返回值:
+// ctx 被取消时返回 false。
+func (p *TransactionPool) WaitForSpace(ctx context.Context) bool {
+	for ctx.Err() == nil {
+		if !p.IsFull() {
+			return true
 		}
-	}()
+		select {
+		case <-ctx.Done():
+		case <-p.space:
+		}
+	}
+	return false
 }
@@ -0,0 +1,344 @@
+package network
+
+import (
+	"Go-Minichain/data"
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newPayment 构造一笔由 from 付款给 to 的已签名交易，从 from 在交易池视图中可以花费的 UTXO 里依次选择输入，
+// 多余的金额找零给 from。
+// 返回值:
+// 返回交易；可以花费的金额不足时第二个返回值为 false。
+func newPayment(n *NetWork, from data.Account, to data.Account, amount int, fee int) (data.Transaction, bool) {
+	inputs := make([]*data.TxInput, 0)
+	total := 0
+	for _, utxo := range n.GetSpendableUTXOs(from.GetWalletAddress()) {
+		inputs = append(inputs, data.NewTxInput(utxo.GetOutpoint(), from.GetPublicKey()))
+		total += utxo.GetAmount()
+		if total >= amount+fee {
+			break
+		}
+	}
+	if total < amount+fee {
+		return data.Transaction{}, false
+	}
+	outputs := []*data.UTXO{data.NewUTXO(amount, to.GetPublicKey())}
+	if change := total - amount - fee; change > 0 {
+		outputs = append(outputs, data.NewUTXO(change, from.GetPublicKey()))
+	}
+	tx := data.NewTransaction(inputs, outputs)
+	tx.SetTimestamp(int(n.Now().Unix()))
+	tx.Sign(from.GetPrivateKey())
+	return *tx, true
+}
+
+// mustPayment 与 newPayment 相同，金额不足时终止测试。
+func mustPayment(t *testing.T, n *NetWork, from data.Account, to data.Account, amount int, fee int) data.Transaction {
+	t.Helper()
+	tx, ok := newPayment(n, from, to, amount, fee)
+	if !ok {
+		t.Fatalf("account %s can not pay %d", from.GetWalletAddress(), amount+fee)
+	}
+	return tx
+}
+
+// assertPooled 检查交易是否在交易池中。
+func assertPooled(t *testing.T, p *TransactionPool, tx data.Transaction, pooled bool) {
+	t.Helper()
+	if p.Has(tx.TxID()) != pooled {
+		t.Fatalf("transaction %s in pool: %v, want %v", tx.TxID(), !pooled, pooled)
+	}
+}
+
+// checkPoolInvariants 检查交易池的内部状态：不超过上限、没有重复的交易、总字节数与条目一致、父交易排在子交易之前。
+func checkPoolInvariants(t *testing.T, p *TransactionPool) {
+	t.Helper()
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if len(p.entries) > p.capacity || p.totalSize > p.maxSize {
+		t.Fatalf("pool holds %d transactions and %d bytes, limits are %d and %d", len(p.entries), p.totalSize, p.capacity, p.maxSize)
+	}
+	if len(p.byID) != len(p.entries) {
+		t.Fatalf("pool index has %d transactions, entries has %d", len(p.byID), len(p.entries))
+	}
+	seen := make(map[string]bool, len(p.entries))
+	size := 0
+	for _, entry := range p.entries {
+		if seen[entry.txID] {
+			t.Fatalf("transaction %s is pooled twice", entry.txID)
+		}
+		for _, parentID := range entry.parents {
+			if !seen[parentID] {
+				t.Fatalf("transaction %s is pooled before its parent %s", entry.txID, parentID)
+			}
+		}
+		seen[entry.txID] = true
+		size += entry.size
+	}
+	if size != p.totalSize {
+		t.Fatalf("pool size is %d, entries add up to %d", p.totalSize, size)
+	}
+}
+
+func TestPoolRejectsDuplicateTxID(t *testing.T) {
+	n := newTestNetWork(t, 1)
+	accounts := n.GetAccounts()
+	tx := mustPayment(t, n, accounts[0], accounts[1], 100, 10)
+	if err := n.AcceptTransaction(tx); err != nil {
+		t.Fatal(err)
+	}
+	if err := n.AcceptTransaction(tx); !errors.Is(err, ErrTxInPool) {
+		t.Fatalf("duplicate transaction returned %v, want %v", err, ErrTxInPool)
+	}
+	if count := n.txPool.Count(); count != 1 {
+		t.Fatalf("pool holds %d transactions, want 1", count)
+	}
+}
+
+func TestPoolCountBoundEvictsLowestFeeRate(t *testing.T) {
+	n := newTestNetWork(t, 1)
+	n.txPool.capacity = 3
+	accounts := n.GetAccounts()
+	low := mustPayment(t, n, accounts[0], accounts[9], 100, 100)
+	middle := mustPayment(t, n, accounts[1], accounts[9], 100, 200)
+	high := mustPayment(t, n, accounts[2], accounts[9], 100, 300)
+	for _, tx := range []data.Transaction{middle, low, high} {
+		if err := n.AcceptTransaction(tx); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 交易池已满时，手续费率更高的交易取代手续费率最低的交易
+	higher := mustPayment(t, n, accounts[3], accounts[9], 100, 400)
+	if err := n.AcceptTransaction(higher); err != nil {
+		t.Fatal(err)
+	}
+	assertPooled(t, n.txPool, low, false)
+	for _, tx := range []data.Transaction{middle, high, higher} {
+		assertPooled(t, n.txPool, tx, true)
+	}
+
+	// 手续费率不高于池中最低手续费率的交易被拒绝，交易池保持不变
+	lowest := mustPayment(t, n, accounts[4], accounts[9], 100, 50)
+	if err := n.AcceptTransaction(lowest); !errors.Is(err, ErrPoolFull) {
+		t.Fatalf("low fee transaction returned %v, want %v", err, ErrPoolFull)
+	}
+	assertPooled(t, n.txPool, lowest, false)
+	if count := n.txPool.Count(); count != 3 {
+		t.Fatalf("pool holds %d transactions, want 3", count)
+	}
+	checkPoolInvariants(t, n.txPool)
+}
+
+func TestPoolSizeBoundEvictsDescendants(t *testing.T) {
+	n := newTestNetWork(t, 1)
+	accounts := n.GetAccounts()
+	parent := mustPayment(t, n, accounts[0], accounts[9], 100, 100)
+	if err := n.AcceptTransaction(parent); err != nil {
+		t.Fatal(err)
+	}
+	// 子交易花费父交易的找零，父交易被移除时子交易随之失效
+	child := mustPayment(t, n, accounts[0], accounts[9], 100, 1000)
+	if err := n.AcceptTransaction(child); err != nil {
+		t.Fatal(err)
+	}
+	packageSize := n.txPool.Size()
+
+	// 父交易自身的手续费率低于新交易，但子交易为它支付了手续费，交易包的手续费率更高，新交易被拒绝
+	other := mustPayment(t, n, accounts[1], accounts[9], 100, 300)
+	n.txPool.maxSize = packageSize + other.Size() - 1
+	if err := n.AcceptTransaction(other); !errors.Is(err, ErrPoolFull) {
+		t.Fatalf("transaction below the package fee rate returned %v, want %v", err, ErrPoolFull)
+	}
+	assertPooled(t, n.txPool, parent, true)
+	assertPooled(t, n.txPool, child, true)
+	checkPoolInvariants(t, n.txPool)
+
+	// 手续费率高于整个交易包的交易使父交易连同子交易一起被移除
+	higher := mustPayment(t, n, accounts[1], accounts[9], 100, 2000)
+	if err := n.AcceptTransaction(higher); err != nil {
+		t.Fatal(err)
+	}
+	assertPooled(t, n.txPool, parent, false)
+	assertPooled(t, n.txPool, child, false)
+	assertPooled(t, n.txPool, higher, true)
+	if size := n.txPool.Size(); size != higher.Size() {
+		t.Fatalf("pool size is %d, want %d", size, higher.Size())
+	}
+	checkPoolInvariants(t, n.txPool)
+}
+
+func TestPoolExpiry(t *testing.T) {
+	n := newTestNetWork(t, 1)
+	clock := n.clock.(*SimulatedClock)
+	accounts := n.GetAccounts()
+	old := mustPayment(t, n, accounts[0], accounts[9], 100, 100)
+	if err := n.AcceptTransaction(old); err != nil {
+		t.Fatal(err)
+	}
+	clock.Advance(n.txPool.expiry)
+	recent := mustPayment(t, n, accounts[1], accounts[9], 100, 100)
+	if err := n.AcceptTransaction(recent); err != nil {
+		t.Fatal(err)
+	}
+	// 恰好停留 expiry 的交易尚未过期
+	assertPooled(t, n.txPool, old, true)
+
+	clock.Advance(time.Second)
+	n.txPool.Update(nil, nil)
+	assertPooled(t, n.txPool, old, false)
+	assertPooled(t, n.txPool, recent, true)
+}
+
+func TestPoolConcurrentProducersAndMiners(t *testing.T) {
+	n := newTestNetWork(t, 1)
+	n.txPool.capacity = 8
+	n.txPool.maxSize = 8 * 400
+	accounts := n.GetAccounts()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var wg sync.WaitGroup
+	// 每个生产者使用自己的账户不断付款，交易之间可能因为驱逐或打包而失效，被拒绝的交易直接忽略
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(from data.Account, to data.Account) {
+			defer wg.Done()
+			for k := 0; k < 30; k++ {
+				if tx, ok := newPayment(n, from, to, 10, 50+k); ok {
+					n.AcceptTransaction(tx)
+				}
+			}
+		}(accounts[i], accounts[len(accounts)-1-i])
+	}
+	// 模板选择与交易池同步和交易生产同时进行
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for k := 0; k < 20; k++ {
+				height := n.blockchain.GetHeight() + 1
+				n.miner.BuildBlockTemplate(height)
+				n.txPool.GetAllByFeeRate()
+				n.txPool.Update(nil, nil)
+			}
+		}()
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for k := 0; k < 5; k++ {
+			if err := n.miner.mineNext(ctx); err != nil && !errors.Is(err, context.Canceled) {
+				t.Errorf("mine block: %v", err)
+			}
+		}
+	}()
+	wg.Wait()
+
+	checkPoolInvariants(t, n.txPool)
+	if _, err := n.GetTotalAmount(); err != nil {
+		t.Fatal(err)
+	}
+	// 交易池中剩余的交易仍然可以被打包
+	if err := n.miner.mineNext(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := n.blockchain.ComputeFees(n.txPool.GetAll()); err != nil {
+		t.Fatalf("pool is inconsistent with the chain after mining: %v", err)
+	}
+}
+
+// outpoints 返回 UTXO 列表中每个输出的位置，按字典序排列。
+func outpoints(utxos []*data.UTXO) []string {
+	result := make([]string, len(utxos))
+	for i, utxo := range utxos {
+		result[i] = utxo.GetOutpoint().String()
+	}
+	sort.Strings(result)
+	return result
+}
+
+// checkPoolView 检查每个账户在交易池视图中可以花费的输出，恰好是已确认的输出去掉池中交易花费的输出、加上池中交易新产生的输出。
+func checkPoolView(t *testing.T, n *NetWork) {
+	t.Helper()
+	entries := n.txPool.snapshot()
+	for _, account := range n.GetAccounts() {
+		address := account.GetWalletAddress()
+		expected := make(map[string]bool)
+		for _, utxo := range n.GetTrueUTXOs(address) {
+			expected[utxo.GetOutpoint().String()] = true
+		}
+		for _, entry := range entries {
+			for _, out := range entry.tx.GetOutUTXOs() {
+				if out.GetWalletAddress() == address {
+					expected[out.GetOutpoint().String()] = true
+				}
+			}
+		}
+		for _, entry := range entries {
+			for _, in := range entry.tx.GetInputs() {
+				delete(expected, in.GetOutpoint().String())
+			}
+		}
+		want := make([]string, 0, len(expected))
+		for outpoint := range expected {
+			want = append(want, outpoint)
+		}
+		sort.Strings(want)
+		if got := outpoints(n.GetSpendableUTXOs(address)); !reflect.DeepEqual(got, want) {
+			t.Fatalf("%s can spend %v, want %v", address, got, want)
+		}
+	}
+}
+
+func TestPoolViewAcrossConfirmAndReorg(t *testing.T) {
+	n := newTestNetWork(t, 1)
+	genesis := n.GetNewestBlock().Hash()
+	accounts := n.GetAccounts()
+	payer := accounts[0].GetWalletAddress()
+	funding := n.GetTrueUTXOs(payer)
+	payment := spendOutputs(t, n, accounts[0], accounts[9], funding, 100, false, 300)
+	if err := n.AcceptTransaction(payment); err != nil {
+		t.Fatal(err)
+	}
+	outputs := payment.GetOutUTXOs()
+
+	// 交易池视图中资金已被花费、找零可以花费，已确认的集合仍是原来的输出
+	assertViews := func(stage string, confirmed []*data.UTXO, spendable []*data.UTXO) {
+		t.Helper()
+		if got, want := outpoints(n.GetTrueUTXOs(payer)), outpoints(confirmed); !reflect.DeepEqual(got, want) {
+			t.Fatalf("%s: payer has confirmed %v, want %v", stage, got, want)
+		}
+		if got, want := outpoints(n.GetSpendableUTXOs(payer)), outpoints(spendable); !reflect.DeepEqual(got, want) {
+			t.Fatalf("%s: payer can spend %v, want %v", stage, got, want)
+		}
+		checkPoolView(t, n)
+	}
+	assertViews("pooled", funding, outputs[1:])
+	if _, ok := n.blockchain.UTXOs.Get(outputs[0].GetOutpoint()); ok {
+		t.Fatal("unconfirmed payment is in the confirmed set")
+	}
+
+	// 交易被确认后两个视图一致
+	block := buildBlock(t, n, BlockSubsidy(1)+100, []data.Transaction{payment}, nil)
+	if err := n.AddNewBlock(block); err != nil {
+		t.Fatal(err)
+	}
+	assertPooled(t, n.txPool, payment, false)
+	assertViews("confirmed", outputs[1:], outputs[1:])
+
+	// 不包含该交易的分支成为主链后交易回到交易池，已确认的集合恢复原来的输出
+	mineBranch(t, n, genesis, 2, 7)
+	if n.blockchain.GetHeight() != 2 {
+		t.Fatal("heavier branch did not become the main chain")
+	}
+	assertPooled(t, n.txPool, payment, true)
+	assertViews("reorganized", funding, outputs[1:])
+	checkPoolInvariants(t, n.txPool)
+}
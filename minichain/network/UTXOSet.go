@@ -0,0 +1,179 @@
+package network
+
+import (
+	"Go-Minichain/data"
+	"sort"
+)
+
+/**
+ * 已确认的 UTXO 集合
+ *
+ * 只有区块被连接到主链时才会向集合中添加或移除 UTXO，区块在链重组中被回滚时做相反的改动，
+ * 交易池中尚未确认的交易不会修改该集合，而是在其之上叠加自己的改动（见 UTXODelta 与 TransactionPool）。
+ *
+ * 每个 UTXO 由其位置（Outpoint，即产生它的交易标识与输出序号）唯一标识，
+ * 集合以 Outpoint 为键保存 UTXO，并按钱包地址建立二级索引，
+ * 查找、添加与花费都是常数时间，按地址查询只需访问该地址自己的 UTXO。已花费的输出会被直接删除。
+ */
+
+// UTXOSet 以 Outpoint 为键的未花费输出集合。
+// 字段说明：
+// - utxos: 全部未花费输出。
+// - byAddress: 按钱包地址索引的未花费输出，某个地址没有 UTXO 时删除其条目。
+type UTXOSet struct {
+	utxos     map[data.Outpoint]*data.UTXO
+	byAddress map[string]map[data.Outpoint]*data.UTXO
+}
+
+// NewUTXOSet 创建一个空的 UTXO 集合。
+func NewUTXOSet() *UTXOSet {
+	return &UTXOSet{
+		utxos:     make(map[data.Outpoint]*data.UTXO),
+		byAddress: make(map[string]map[data.Outpoint]*data.UTXO),
+	}
+}
+
+// Add 向集合中添加一个 UTXO，位置相同的 UTXO 会被覆盖。
+func (s *UTXOSet) Add(utxo *data.UTXO) {
+	outpoint := utxo.GetOutpoint()
+	s.Remove(outpoint)
+	s.utxos[outpoint] = utxo
+	address := utxo.GetWalletAddress()
+	if s.byAddress[address] == nil {
+		s.byAddress[address] = make(map[data.Outpoint]*data.UTXO)
+	}
+	s.byAddress[address][outpoint] = utxo
+}
+
+// Remove 从集合中移除指定位置的 UTXO。
+// 返回值:
+// 集合中不存在该 UTXO 时返回 false。
+func (s *UTXOSet) Remove(outpoint data.Outpoint) bool {
+	utxo, ok := s.utxos[outpoint]
+	if !ok {
+		return false
+	}
+	delete(s.utxos, outpoint)
+	address := utxo.GetWalletAddress()
+	delete(s.byAddress[address], outpoint)
+	if len(s.byAddress[address]) == 0 {
+		delete(s.byAddress, address)
+	}
+	return true
+}
+
+// Get 返回指定位置的 UTXO，不存在时第二个返回值为 false。
+func (s *UTXOSet) Get(outpoint data.Outpoint) (*data.UTXO, bool) {
+	utxo, ok := s.utxos[outpoint]
+	return utxo, ok
+}
+
+// Len 返回集合中未花费输出的个数。
+func (s *UTXOSet) Len() int {
+	return len(s.utxos)
+}
+
+// GetUTXOs 返回属于指定钱包地址的全部 UTXO，按 Outpoint 排序。
+// 参数:
+// - walletAddress: 钱包地址。
+// 返回值:
+// 返回 UTXO 列表。
+func (s *UTXOSet) GetUTXOs(walletAddress string) []*data.UTXO {
+	utxos := make([]*data.UTXO, 0, len(s.byAddress[walletAddress]))
+	for _, utxo := range s.byAddress[walletAddress] {
+		utxos = append(utxos, utxo)
+	}
+	sortUTXOs(utxos)
+	return utxos
+}
+
+// GetTotalAmount 返回集合中全部 UTXO 的金额之和。
+func (s *UTXOSet) GetTotalAmount() int {
+	amount := 0
+	for _, utxo := range s.utxos {
+		amount += utxo.GetAmount()
+	}
+	return amount
+}
+
+// sortUTXOs 按 Outpoint 对 UTXO 排序，使按地址返回的结果与 map 的遍历顺序无关。
+func sortUTXOs(utxos []*data.UTXO) {
+	sort.Slice(utxos, func(i, j int) bool {
+		return utxos[i].GetOutpoint().Less(utxos[j].GetOutpoint())
+	})
+}
+
+// UTXODelta 记录一组尚未写入 UTXOSet 的交易对集合的改动，
+// 区块验证时用于记录同一区块中排在前面的交易，交易池用于记录池中的未确认交易。
+// 字段说明：
+// - spent: 被花费的已确认输出。
+// - created: 新产生且尚未被花费的输出。
+type UTXODelta struct {
+	spent   map[data.Outpoint]bool
+	created map[data.Outpoint]*data.UTXO
+}
+
+// NewUTXODelta 创建一个空的改动记录。
+func NewUTXODelta() *UTXODelta {
+	return &UTXODelta{
+		spent:   make(map[data.Outpoint]bool),
+		created: make(map[data.Outpoint]*data.UTXO),
+	}
+}
+
+// lookup 在叠加改动后的集合中查找指定位置的 UTXO。
+func (d *UTXODelta) lookup(set *UTXOSet, outpoint data.Outpoint) (*data.UTXO, bool) {
+	if utxo, ok := d.created[outpoint]; ok {
+		return utxo, true
+	}
+	if d.spent[outpoint] {
+		return nil, false
+	}
+	return set.Get(outpoint)
+}
+
+// spend 记录花费指定位置的输出。
+func (d *UTXODelta) spend(outpoint data.Outpoint) {
+	if _, ok := d.created[outpoint]; ok {
+		delete(d.created, outpoint)
+		return
+	}
+	d.spent[outpoint] = true
+}
+
+// create 记录新产生的输出。
+func (d *UTXODelta) create(utxo *data.UTXO) {
+	d.created[utxo.GetOutpoint()] = utxo
+}
+
+// restore 撤销对指定位置输出的花费。
+// utxo 不为 nil 时表示该输出由仍在改动记录中的交易产生，重新记为新产生的输出；否则它是已确认的输出。
+func (d *UTXODelta) restore(outpoint data.Outpoint, utxo *data.UTXO) {
+	if utxo != nil {
+		d.created[outpoint] = utxo
+		return
+	}
+	delete(d.spent, outpoint)
+}
+
+// discard 撤销新产生的输出。
+func (d *UTXODelta) discard(outpoint data.Outpoint) {
+	delete(d.created, outpoint)
+}
+
+// getUTXOs 返回叠加改动后属于指定钱包地址的全部 UTXO，按 Outpoint 排序。
+func (d *UTXODelta) getUTXOs(set *UTXOSet, walletAddress string) []*data.UTXO {
+	utxos := make([]*data.UTXO, 0)
+	for outpoint, utxo := range set.byAddress[walletAddress] {
+		if !d.spent[outpoint] {
+			utxos = append(utxos, utxo)
+		}
+	}
+	for _, utxo := range d.created {
+		if utxo.GetWalletAddress() == walletAddress {
+			utxos = append(utxos, utxo)
+		}
+	}
+	sortUTXOs(utxos)
+	return utxos
+}
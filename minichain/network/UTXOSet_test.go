@@ -0,0 +1,126 @@
+package network
+
+import (
+	"Go-Minichain/data"
+	"Go-Minichain/store"
+	"strconv"
+	"testing"
+)
+
+// benchmarkAddresses 基准测试中接收输出的账户个数。
+const benchmarkAddresses = 100
+
+// buildBenchmarkChain 构造一条包含 blocks 个区块的主链：每个区块有一笔 coinbase 交易，
+// 以及一笔花费前一个区块 coinbase 输出、付款给三个账户的交易，UTXO 集合随高度线性增长。
+// 区块只用于更新 UTXO 集合，不经过验证，交易也不需要签名。
+func buildBenchmarkChain(tb testing.TB, blocks int) (*BlockChain, []data.Account) {
+	tb.Helper()
+	accounts := deriveAccounts(1, benchmarkAddresses)
+	chain := NewBlockChain(nil, store.NewMemoryStore())
+	var parent *blockNode
+	var previous *data.Transaction
+	for height := 0; height < blocks; height++ {
+		miner := accounts[height%len(accounts)]
+		coinbase := data.NewCoinbaseTransaction(height, []*data.UTXO{data.NewUTXO(50, miner.GetPublicKey())})
+		transactions := []data.Transaction{*coinbase}
+		if previous != nil {
+			owner := accounts[(height-1)%len(accounts)]
+			inputs := []*data.TxInput{data.NewTxInput(previous.GetOutUTXOs()[0].GetOutpoint(), owner.GetPublicKey())}
+			outputs := make([]*data.UTXO, 3)
+			for k := range outputs {
+				outputs[k] = data.NewUTXO(10, accounts[(height*3+k)%len(accounts)].GetPublicKey())
+			}
+			transactions = append(transactions, *data.NewTransaction(inputs, outputs))
+		}
+		previous = coinbase
+
+		prevHash := ""
+		if parent != nil {
+			prevHash = parent.hash
+		}
+		body := data.NewBlockBody(data.ComputeMerkleRootHash(transactions), transactions)
+		header := data.NewBlockHeader(prevHash, body.GetMerkleRootHash(), int64(height))
+		block := data.NewBlock(*header, *body)
+		node := newBlockNode(*block, block.Hash(), parent)
+		chain.index[node.hash] = node
+		chain.connectNode(node)
+		parent = node
+	}
+	return chain, accounts
+}
+
+// benchmarkHeights 基准测试的主链长度，用于比较查询耗时是否随区块增多而增长。
+var benchmarkHeights = []int{1000, 2000, 5000}
+
+// BenchmarkUTXOSetGetUTXOs 按地址查询只访问该地址自己的 UTXO，耗时与该地址的 UTXO 个数成正比，而与集合大小无关。
+func BenchmarkUTXOSetGetUTXOs(b *testing.B) {
+	for _, blocks := range benchmarkHeights {
+		b.Run("blocks="+strconv.Itoa(blocks), func(b *testing.B) {
+			chain, accounts := buildBenchmarkChain(b, blocks)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				chain.UTXOs.GetUTXOs(accounts[i%len(accounts)].GetWalletAddress())
+			}
+		})
+	}
+}
+
+func BenchmarkUTXOSetGet(b *testing.B) {
+	for _, blocks := range benchmarkHeights {
+		b.Run("blocks="+strconv.Itoa(blocks), func(b *testing.B) {
+			chain, _ := buildBenchmarkChain(b, blocks)
+			outpoints := make([]data.Outpoint, 0, chain.UTXOs.Len())
+			for outpoint := range chain.UTXOs.utxos {
+				outpoints = append(outpoints, outpoint)
+			}
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, ok := chain.UTXOs.Get(outpoints[i%len(outpoints)]); !ok {
+					b.Fatal("missing UTXO")
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkUTXOSetSpendAndRestore(b *testing.B) {
+	for _, blocks := range benchmarkHeights {
+		b.Run("blocks="+strconv.Itoa(blocks), func(b *testing.B) {
+			chain, _ := buildBenchmarkChain(b, blocks)
+			utxos := make([]*data.UTXO, 0, chain.UTXOs.Len())
+			for _, utxo := range chain.UTXOs.utxos {
+				utxos = append(utxos, utxo)
+			}
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				utxo := utxos[i%len(utxos)]
+				chain.UTXOs.Remove(utxo.GetOutpoint())
+				chain.UTXOs.Add(utxo)
+			}
+		})
+	}
+}
+
+func TestBenchmarkChainUTXOs(t *testing.T) {
+	blocks := 50
+	chain, accounts := buildBenchmarkChain(t, blocks)
+	// 每个区块新增一个 coinbase 输出，除创世块外每个区块再花费一个输出、新增三个输出
+	if want := blocks + (blocks-1)*2; chain.UTXOs.Len() != want {
+		t.Fatalf("UTXO set has %d outputs, want %d", chain.UTXOs.Len(), want)
+	}
+	total := 0
+	for _, account := range accounts {
+		for _, utxo := range chain.UTXOs.GetUTXOs(account.GetWalletAddress()) {
+			if utxo.GetWalletAddress() != account.GetWalletAddress() {
+				t.Fatalf("UTXO %s indexed under %s", utxo.GetOutpoint().String(), account.GetWalletAddress())
+			}
+			total++
+		}
+	}
+	if total != chain.UTXOs.Len() {
+		t.Fatalf("address index holds %d outputs, want %d", total, chain.UTXOs.Len())
+	}
+}
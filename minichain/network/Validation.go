@@ -0,0 +1,387 @@
+package network
+
+import (
+	"Go-Minichain/config"
+	"Go-Minichain/data"
+	"Go-Minichain/utils"
+	"bytes"
+	"errors"
+	"strconv"
+)
+
+/**
+ * 区块验证
+ *
+ * 无论区块来自本地矿工还是其他节点，都必须通过这里的检查才能加入区块链：
+ * 只依赖父区块所在分支的难度、时间戳、工作量证明与 Merkle 根哈希在收到区块时立即检查；
+ * 交易的签名、重复花费、金额守恒以及 coinbase 交易的奖励需要基于父区块之后的 UTXO 集合，在区块被连接到主链时检查。
+ * 验证失败时返回 *BlockValidationError，其中的 Kind 为下列错误类别之一，
+ * 调用方可以使用 errors.Is 判断具体原因。
+ */
+
+var (
+	// ErrBadParent 区块的前一个区块未知或未通过验证，或者与期望的前一个区块不一致。
+	ErrBadParent = errors.New("bad parent")
+	// ErrDuplicateBlock 区块已经存在于区块树中。
+	ErrDuplicateBlock = errors.New("duplicate block")
+	// ErrInsufficientWork 区块哈希大于区块头中的目标值。
+	ErrInsufficientWork = errors.New("insufficient work")
+	// ErrBadDifficulty 区块头中的目标值与难度调整规则计算出的目标值不符。
+	ErrBadDifficulty = errors.New("bad difficulty")
+	// ErrBadTimestamp 区块时间戳不大于 median-time-past，或超过本地时间太多。
+	ErrBadTimestamp = errors.New("bad timestamp")
+	// ErrBadMerkleRoot 区块头或区块体中的 Merkle 根哈希与交易列表不符。
+	ErrBadMerkleRoot = errors.New("bad merkle root")
+	// ErrInvalidSignature 交易输入的签名无效，或签名公钥与被引用输出的锁定公钥哈希不匹配。
+	ErrInvalidSignature = errors.New("invalid signature")
+	// ErrDoubleSpend 交易输入引用的输出不存在、已被花费或内容不符，在同一区块中被重复花费，
+	// 或交易产生的输出与尚未花费的输出位置重复。
+	ErrDoubleSpend = errors.New("double spend")
+	// ErrValueCreated 交易输出金额之和大于输入金额之和，或存在非正数金额的输出。
+	ErrValueCreated = errors.New("value created out of thin air")
+	// ErrBlockTooLarge 区块中的交易个数或交易编码的总字节数超过上限。
+	ErrBlockTooLarge = errors.New("block too large")
+	// ErrBadCoinbase 区块的第一笔交易不是 coinbase 交易，coinbase 交易出现在其他位置，
+	// 其记录的高度与区块高度不符，或发放的金额超过挖矿奖励与手续费之和。
+	ErrBadCoinbase = errors.New("bad coinbase")
+)
+
+// BlockValidationError 描述区块未通过验证的原因。
+// 字段说明：
+// - Kind: 错误类别，为上面定义的 Err* 之一。
+// - BlockHash: 未通过验证的区块哈希。
+// - TxID: 出错交易的标识，与具体交易无关时为空。
+// - Detail: 便于排查问题的补充说明。
+type BlockValidationError struct {
+	Kind      error
+	BlockHash string
+	TxID      string
+	Detail    string
+}
+
+func (e *BlockValidationError) Error() string {
+	msg := "invalid block " + e.BlockHash + ": " + e.Kind.Error()
+	if e.TxID != "" {
+		msg += " in transaction " + e.TxID
+	}
+	if e.Detail != "" {
+		msg += " (" + e.Detail + ")"
+	}
+	return msg
+}
+
+// Unwrap 返回错误类别，使 errors.Is(err, ErrDoubleSpend) 等判断可以生效。
+func (e *BlockValidationError) Unwrap() error {
+	return e.Kind
+}
+
+// ValidateBlock 验证区块能否连接到当前最新区块之后，验证过程不会修改区块链状态。
+// 参数:
+// - block: 待验证的区块。
+// 返回值:
+// 验证通过时返回 nil，否则返回 *BlockValidationError。
+func (c *BlockChain) ValidateBlock(block data.Block) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	expectedParent := ""
+	if c.tip != nil {
+		expectedParent = c.tip.hash
+	}
+	header := block.GetBlockHeader()
+	if header.GetPreBlockHash() != expectedParent {
+		return &BlockValidationError{Kind: ErrBadParent, BlockHash: block.Hash(), Detail: "expected previous hash " + expectedParent}
+	}
+	if err := c.checkBlock(block, c.tip); err != nil {
+		return err
+	}
+	return c.validateTransactions(block, c.tip == nil)
+}
+
+// checkBlock 检查只依赖父区块所在分支的部分：难度、时间戳、工作量证明、区块大小以及 Merkle 根哈希。
+// 参数:
+// - block: 待检查的区块。
+// - parent: 父区块节点，创世块为 nil；创世块由系统直接生成，不需要工作量证明。
+// 返回值:
+// 检查通过时返回 nil，否则返回 *BlockValidationError。
+func (c *BlockChain) checkBlock(block data.Block, parent *blockNode) error {
+	hash := block.Hash()
+	header := block.GetBlockHeader()
+	body := block.GetBlockBody()
+	fail := func(kind error, detail string) error {
+		return &BlockValidationError{Kind: kind, BlockHash: hash, Detail: detail}
+	}
+	genesis := parent == nil
+
+	if !genesis {
+		if err := c.checkHeader(header, hash, parent); err != nil {
+			return err
+		}
+	}
+
+	// 创世块按账户数量发行初始余额，不受区块大小限制
+	if !genesis {
+		transactions := body.GetTransctions()
+		if len(transactions) > config.MiniChainConfig.GetMaxTransactionCount() {
+			return fail(ErrBlockTooLarge, strconv.Itoa(len(transactions))+" transactions")
+		}
+		size := 0
+		for i := range transactions {
+			size += transactions[i].Size()
+		}
+		if size > config.MiniChainConfig.GetMaxBlockSize() {
+			return fail(ErrBlockTooLarge, strconv.Itoa(size)+" bytes")
+		}
+	}
+
+	merkleRootHash := data.ComputeMerkleRootHash(body.GetTransctions())
+	if body.GetMerkleRootHash() != merkleRootHash || header.GetMerkleRootHash() != merkleRootHash {
+		return fail(ErrBadMerkleRoot, "expected "+merkleRootHash)
+	}
+	return nil
+}
+
+// checkHeader 检查区块头的难度、工作量证明与时间戳，区块头链与完整区块使用相同的检查。
+// 参数:
+// - header: 待检查的区块头。
+// - hash: 区块头的哈希。
+// - parent: 父区块节点，不能为 nil。
+// 返回值:
+// 检查通过时返回 nil，否则返回 *BlockValidationError。
+func (c *BlockChain) checkHeader(header data.BlockHeader, hash string, parent *blockNode) error {
+	fail := func(kind error, detail string) error {
+		return &BlockValidationError{Kind: kind, BlockHash: hash, Detail: detail}
+	}
+	if expected := nextBits(parent); header.GetBits() != expected {
+		return fail(ErrBadDifficulty, "bits "+strconv.FormatUint(uint64(header.GetBits()), 16)+" does not match "+
+			strconv.FormatUint(uint64(expected), 16))
+	}
+	if !utils.CheckProofOfWork(hash, header.GetTarget()) {
+		return fail(ErrInsufficientWork, "hash is above the target")
+	}
+	if medianTime := parent.medianTimePast(); header.GetTimestamp() <= medianTime {
+		return fail(ErrBadTimestamp, "timestamp is not after median time past "+strconv.FormatInt(medianTime, 10))
+	}
+	if header.GetTimestamp() > c.network.Now().Unix()+maxFutureBlockTime {
+		return fail(ErrBadTimestamp, "timestamp is too far in the future")
+	}
+	return nil
+}
+
+// validateTransactions 以当前已确认的 UTXO 集合为基础验证区块中的每一笔交易，
+// 即假设该区块紧接在当前最新区块之后。调用方需要持有 c.mutex。
+// 参数:
+// - block: 待验证的区块。
+// - genesis: 是否为创世块，创世交易凭空发行初始余额，没有输入，也没有 coinbase 交易。
+// 返回值:
+// 验证通过时返回 nil，否则返回 *BlockValidationError。
+func (c *BlockChain) validateTransactions(block data.Block, genesis bool) error {
+	body := block.GetBlockBody()
+	transactions := body.GetTransctions()
+	fail := func(kind error, tx *data.Transaction, detail string) error {
+		return &BlockValidationError{Kind: kind, BlockHash: block.Hash(), TxID: tx.TxID(), Detail: detail}
+	}
+	if genesis {
+		for i := range transactions {
+			if len(transactions[i].GetInputs()) != 0 {
+				return fail(ErrDoubleSpend, &transactions[i], "genesis transaction can not spend outputs")
+			}
+		}
+		return nil
+	}
+
+	if len(transactions) == 0 || !transactions[0].IsCoinbase() {
+		return &BlockValidationError{Kind: ErrBadCoinbase, BlockHash: block.Hash(), Detail: "the first transaction must be coinbase"}
+	}
+	// 本区块内已验证交易对 UTXO 集合的改动，
+	// 使得区块内的交易可以花费同一区块中排在前面的交易的输出，同时能够检测区块内部的重复花费
+	delta := NewUTXODelta()
+	fees := 0
+	for i := 1; i < len(transactions); i++ {
+		tx := &transactions[i]
+		fee, err := c.validateTransaction(tx, delta)
+		if err != nil {
+			return fail(err, tx, "")
+		}
+		fees += fee
+	}
+	if err := c.validateCoinbase(&transactions[0], c.tip.height+1, fees, delta); err != nil {
+		return fail(ErrBadCoinbase, &transactions[0], err.Error())
+	}
+	return nil
+}
+
+// validateCoinbase 检查 coinbase 交易记录的高度以及发放的金额。调用方需要持有 c.mutex。
+// 参数:
+// - tx: coinbase 交易。
+// - height: 区块高度。
+// - fees: 区块中其他交易的手续费之和。
+// - delta: 区块中其他交易对 UTXO 集合的改动，用于检查输出位置是否重复。
+// 返回值:
+// 检查通过时返回 nil，否则返回描述具体原因的错误。
+func (c *BlockChain) validateCoinbase(tx *data.Transaction, height int, fees int, delta *UTXODelta) error {
+	fail := errors.New
+	if index := tx.GetInputs()[0].GetOutpoint().GetIndex(); index != height {
+		return fail("coinbase height " + strconv.Itoa(index) + " does not match block height " + strconv.Itoa(height))
+	}
+	reward := 0
+	for _, out := range tx.GetOutUTXOs() {
+		if out.GetAmount() <= 0 {
+			return fail("non-positive output")
+		}
+		if _, ok := delta.lookup(c.UTXOs, out.GetOutpoint()); ok {
+			return fail("duplicate output " + out.GetOutpoint().String())
+		}
+		reward += out.GetAmount()
+	}
+	if limit := BlockSubsidy(height) + fees; reward > limit {
+		return fail("reward " + strconv.Itoa(reward) + " exceeds subsidy and fees " + strconv.Itoa(limit))
+	}
+	return nil
+}
+
+// validateTransaction 验证一笔非创世交易的输入签名、输入引用的输出以及金额守恒。
+// 参数:
+// - tx: 待验证的交易。
+// - delta: 本区块中此前交易对 UTXO 集合的改动，验证通过后会记入本交易的输入与输出。
+// 返回值:
+// 验证通过时返回交易手续费（输入金额之和减去输出金额之和），否则返回对应的错误类别，此时 delta 保持不变。
+func (c *BlockChain) validateTransaction(tx *data.Transaction, delta *UTXODelta) (int, error) {
+	return c.applyTransaction(tx, delta, true)
+}
+
+// applyTransaction 验证交易并记入 delta，见 validateTransaction。调用方需要持有 c.mutex。
+// 参数:
+// - verifySignatures: 为 false 时不检查输入的公钥与签名，调用方需要已经用被引用输出的锁定脚本检查过每个输入。
+func (c *BlockChain) applyTransaction(tx *data.Transaction, delta *UTXODelta, verifySignatures bool) (int, error) {
+	if tx.IsCoinbase() {
+		return 0, ErrBadCoinbase
+	}
+	if len(tx.GetInputs()) == 0 {
+		return 0, ErrValueCreated
+	}
+
+	inAmount := 0
+	spent := make(map[data.Outpoint]bool)
+	for i, in := range tx.GetInputs() {
+		// 输入引用的输出必须未被花费，同一交易不能重复引用同一个输出
+		outpoint := in.GetOutpoint()
+		utxo, ok := delta.lookup(c.UTXOs, outpoint)
+		if !ok || spent[outpoint] {
+			return 0, ErrDoubleSpend
+		}
+		// 签名公钥必须与输出的锁定公钥哈希一致，且签名覆盖整笔交易
+		if verifySignatures && (!bytes.Equal(utxo.GetPublicKeyHash(), data.PublicKeyHash(in.GetPublicKey())) || !tx.VerifyInput(i)) {
+			return 0, ErrInvalidSignature
+		}
+		spent[outpoint] = true
+		inAmount += utxo.GetAmount()
+	}
+
+	outAmount := 0
+	for _, out := range tx.GetOutUTXOs() {
+		if out.GetAmount() <= 0 {
+			return 0, ErrValueCreated
+		}
+		outAmount += out.GetAmount()
+	}
+	if outAmount > inAmount {
+		return 0, ErrValueCreated
+	}
+	for _, out := range tx.GetOutUTXOs() {
+		if _, ok := delta.lookup(c.UTXOs, out.GetOutpoint()); ok {
+			return 0, ErrDoubleSpend
+		}
+	}
+
+	for _, in := range tx.GetInputs() {
+		delta.spend(in.GetOutpoint())
+	}
+	for _, out := range tx.GetOutUTXOs() {
+		delta.create(out)
+	}
+	return inAmount - outAmount, nil
+}
+
+// ValidateTransaction 验证一笔尚未确认的交易，供交易池在接收交易或重新验证交易时使用。
+// 交易的输入必须存在于已确认的 UTXO 集合叠加 delta 之后的结果中。
+// 参数:
+// - tx: 待验证的交易。
+// - delta: 交易池中此前交易对已确认 UTXO 集合的改动，验证通过后会记入本交易的改动。
+// 返回值:
+// 验证通过时返回交易手续费，否则返回 ErrInvalidSignature、ErrDoubleSpend、ErrValueCreated 或 ErrBadCoinbase。
+func (c *BlockChain) ValidateTransaction(tx *data.Transaction, delta *UTXODelta) (int, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.validateTransaction(tx, delta)
+}
+
+// checkPoolTransaction 在同一次加锁中查找交易输入引用的输出、交给交易池的接收策略检查，
+// 通过后按共识规则验证交易并记入 delta。接收策略已经用锁定脚本检查过每个输入，这里不再重复验证签名。
+// 参数:
+// - tx: 待检查的交易。
+// - delta: 交易池中的交易对已确认 UTXO 集合的改动。
+// - policy: 接收策略，参数为叠加 delta 之后每个输入引用的输出，不存在或已被花费时为 nil。
+// 返回值:
+// 验证通过时返回交易手续费；policy 返回的错误原样返回，否则返回共识规则的错误类别，此时 delta 保持不变。
+func (c *BlockChain) checkPoolTransaction(tx *data.Transaction, delta *UTXODelta, policy func(inputs []*data.UTXO) error) (int, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	inputs := make([]*data.UTXO, len(tx.GetInputs()))
+	for i, in := range tx.GetInputs() {
+		if utxo, ok := delta.lookup(c.UTXOs, in.GetOutpoint()); ok {
+			inputs[i] = utxo
+		}
+	}
+	if err := policy(inputs); err != nil {
+		return 0, err
+	}
+	return c.applyTransaction(tx, delta, false)
+}
+
+// connectBlock 将已验证区块中的交易应用到已确认的 UTXO 集合上。
+// 交易输入只保存被花费输出的位置，因此返回按顺序被花费的输出，供回滚区块时恢复。
+// 调用方需要持有 c.mutex。
+func (c *BlockChain) connectBlock(block data.Block) []*data.UTXO {
+	spent := make([]*data.UTXO, 0)
+	blockBody := block.GetBlockBody()
+	for _, tx := range blockBody.GetTransctions() {
+		for _, in := range spentInputs(&tx) {
+			utxo, _ := c.UTXOs.Get(in.GetOutpoint())
+			spent = append(spent, utxo)
+			c.UTXOs.Remove(in.GetOutpoint())
+		}
+		for _, out := range tx.GetOutUTXOs() {
+			c.UTXOs.Add(out)
+		}
+	}
+	return spent
+}
+
+// disconnectBlock 撤销 connectBlock 对已确认 UTXO 集合的改动，用于链重组时回滚区块。
+// 按交易的逆序先移除交易产生的输出，再恢复交易花费的输出。调用方需要持有 c.mutex。
+// 参数:
+// - block: 被回滚的区块。
+// - spent: 连接该区块时 connectBlock 返回的被花费输出。
+func (c *BlockChain) disconnectBlock(block data.Block, spent []*data.UTXO) {
+	blockBody := block.GetBlockBody()
+	transactions := blockBody.GetTransctions()
+	k := len(spent)
+	for i := len(transactions) - 1; i >= 0; i-- {
+		for _, out := range transactions[i].GetOutUTXOs() {
+			c.UTXOs.Remove(out.GetOutpoint())
+		}
+		n := len(spentInputs(&transactions[i]))
+		k -= n
+		for _, utxo := range spent[k : k+n] {
+			c.UTXOs.Add(utxo)
+		}
+	}
+}
+
+// spentInputs 返回交易中花费已有输出的输入，coinbase 交易的输入不引用任何输出。
+func spentInputs(tx *data.Transaction) []*data.TxInput {
+	if tx.IsCoinbase() {
+		return nil
+	}
+	return tx.GetInputs()
+}
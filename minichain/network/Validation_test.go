@@ -0,0 +1,128 @@
+package network
+
+import (
+	"Go-Minichain/data"
+	"Go-Minichain/utils"
+	"context"
+	"errors"
+	"testing"
+)
+
+// nextHeader 返回一个连接在 preBlockHash 之后的区块头，目标值与时间戳满足当前最新区块之后的要求。
+func nextHeader(n *NetWork, preBlockHash string, merkleRootHash string) *data.BlockHeader {
+	header := data.NewBlockHeader(preBlockHash, merkleRootHash, 0)
+	header.SetBits(n.blockchain.NextBits())
+	header.SetTimestamp(n.blockchain.GetMedianTimePast() + 1)
+	return header
+}
+
+// solveBlock 为区块头找到满足工作量证明的随机数，返回由它与 body 组成的区块。
+func solveBlock(t *testing.T, header *data.BlockHeader, body *data.BlockBody) data.Block {
+	t.Helper()
+	solved, _, err := solveHeader(context.Background(), *header, 1)
+	if err != nil {
+		t.Fatalf("mine block: %v", err)
+	}
+	return *data.NewBlock(solved, *body)
+}
+
+// buildBlock 构造一个连接在当前最新区块之后、包含指定交易的区块，coinbase 交易发放 reward。
+// edit 在挖矿之前修改区块头，例如设置无效的时间戳。
+func buildBlock(t *testing.T, n *NetWork, reward int, transactions []data.Transaction, edit func(*data.BlockHeader)) data.Block {
+	t.Helper()
+	tip := n.blockchain.GetTip()
+	coinbase := data.NewCoinbaseTransaction(tip.Height+1, []*data.UTXO{data.NewUTXO(reward, n.miner.account.GetPublicKey())})
+	transactions = append([]data.Transaction{*coinbase}, transactions...)
+	body := data.NewBlockBody(data.ComputeMerkleRootHash(transactions), transactions)
+	header := nextHeader(n, tip.Hash, body.GetMerkleRootHash())
+	if edit != nil {
+		edit(header)
+	}
+	return solveBlock(t, header, body)
+}
+
+// assertInvalid 检查区块未通过验证，错误类别为 kind，且指向 txID 标识的交易（与具体交易无关时为空）。
+func assertInvalid(t *testing.T, n *NetWork, block data.Block, kind error, txID string) {
+	t.Helper()
+	err := n.blockchain.ValidateBlock(block)
+	var validationErr *BlockValidationError
+	if !errors.As(err, &validationErr) || !errors.Is(err, kind) {
+		t.Fatalf("block returned %v, want a *BlockValidationError of kind %v", err, kind)
+	}
+	if validationErr.BlockHash != block.Hash() || validationErr.TxID != txID {
+		t.Fatalf("error names block %s and transaction %q, want %s and %q",
+			validationErr.BlockHash, validationErr.TxID, block.Hash(), txID)
+	}
+}
+
+func TestValidateBlockHeader(t *testing.T) {
+	n := newTestNetWork(t, 1)
+	subsidy := BlockSubsidy(1)
+	if err := n.blockchain.ValidateBlock(buildBlock(t, n, subsidy, nil, nil)); err != nil {
+		t.Fatalf("valid block rejected: %v", err)
+	}
+
+	t.Run("bad proof of work", func(t *testing.T) {
+		block := buildBlock(t, n, subsidy, nil, nil)
+		header := block.GetBlockHeader()
+		for utils.CheckProofOfWork(header.Hash(), header.GetTarget()) {
+			header.SetNonce(header.GetNonce() + 1)
+		}
+		assertInvalid(t, n, *data.NewBlock(header, block.GetBlockBody()), ErrInsufficientWork, "")
+	})
+	t.Run("bad previous hash", func(t *testing.T) {
+		block := buildBlock(t, n, subsidy, nil, func(header *data.BlockHeader) {
+			*header = *nextHeader(n, utils.GetSha256Digest("unknown"), header.GetMerkleRootHash())
+		})
+		assertInvalid(t, n, block, ErrBadParent, "")
+	})
+	t.Run("bad merkle root", func(t *testing.T) {
+		// 区块体与交易列表一致，区块头中的 Merkle 根哈希不一致
+		block := buildBlock(t, n, subsidy, nil, func(header *data.BlockHeader) {
+			*header = *nextHeader(n, header.GetPreBlockHash(), utils.GetSha256Digest("other"))
+		})
+		assertInvalid(t, n, block, ErrBadMerkleRoot, "")
+	})
+	t.Run("timestamp too far in the future", func(t *testing.T) {
+		block := buildBlock(t, n, subsidy, nil, func(header *data.BlockHeader) {
+			header.SetTimestamp(n.Now().Unix() + maxFutureBlockTime + 1)
+		})
+		assertInvalid(t, n, block, ErrBadTimestamp, "")
+	})
+	t.Run("timestamp not after median time past", func(t *testing.T) {
+		block := buildBlock(t, n, subsidy, nil, func(header *data.BlockHeader) {
+			header.SetTimestamp(n.blockchain.GetMedianTimePast())
+		})
+		assertInvalid(t, n, block, ErrBadTimestamp, "")
+	})
+}
+
+func TestValidateBlockTransactions(t *testing.T) {
+	n := newTestNetWork(t, 1)
+	accounts := n.GetAccounts()
+	subsidy := BlockSubsidy(1)
+	utxos := n.GetSpendableUTXOs(accounts[0].GetWalletAddress())
+	first := spendOutputs(t, n, accounts[0], accounts[1], utxos, 100, false, 100)
+	second := spendOutputs(t, n, accounts[0], accounts[2], utxos, 100, false, 200)
+
+	t.Run("double spend within the block", func(t *testing.T) {
+		block := buildBlock(t, n, subsidy, []data.Transaction{first, second}, nil)
+		assertInvalid(t, n, block, ErrDoubleSpend, second.TxID())
+	})
+	t.Run("coinbase above subsidy and fees", func(t *testing.T) {
+		block := buildBlock(t, n, subsidy+100+1, []data.Transaction{first}, nil)
+		body := block.GetBlockBody()
+		assertInvalid(t, n, block, ErrBadCoinbase, body.GetTransctions()[0].TxID())
+	})
+	t.Run("missing coinbase", func(t *testing.T) {
+		transactions := []data.Transaction{first}
+		body := data.NewBlockBody(data.ComputeMerkleRootHash(transactions), transactions)
+		block := solveBlock(t, nextHeader(n, n.blockchain.GetTip().Hash, body.GetMerkleRootHash()), body)
+		assertInvalid(t, n, block, ErrBadCoinbase, "")
+	})
+
+	// 手续费计入 coinbase 时区块有效
+	if err := n.blockchain.ValidateBlock(buildBlock(t, n, subsidy+100, []data.Transaction{first}, nil)); err != nil {
+		t.Fatalf("block claiming subsidy and fees rejected: %v", err)
+	}
+}
@@ -42,6 +42,13 @@ func (p *SPVPeer) Accept(header data.BlockHeader) {
 	}
 }
 
+// SyncHeaders 直接使用已确认的区块头替换本地存储，用于节点启动时的初始同步。
+// 参数:
+// - headers: 从创世块开始的全部区块头。
+func (p *SPVPeer) SyncHeaders(headers []data.BlockHeader) {
+	p.headers = append([]data.BlockHeader{}, headers...)
+}
+
 // Verify 验证指定交易的有效性。
 // 通过获取交易的 SPV 证明，并根据 Merkle 路径重新计算哈希值，验证其是否与区块头中的 Merkle 根哈希一致。
 // 参数:
@@ -49,7 +56,7 @@ func (p *SPVPeer) Accept(header data.BlockHeader) {
 // 返回值:
 // 返回布尔值，表示交易是否通过验证。
 func (p *SPVPeer) Verify(transaction data.Transaction) bool {
-	txHash := utils.GetSha256Digest(transaction.ToString())
+	txHash := transaction.TxID()
 	proof := p.network.GetProof(txHash)
 	hash := proof.GetTxHash()
 
@@ -0,0 +1,447 @@
+package p2p
+
+import (
+	"Go-Minichain/data"
+	"Go-Minichain/utils"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+/**
+ * 节点间消息
+ *
+ * 每条消息由 13 字节的消息头与消息体组成：
+ * - 4 字节大端的网络标识 Magic，用于拒绝不属于本网络的连接；
+ * - 1 字节的消息类型（Command）；
+ * - 4 字节大端的消息体长度；
+ * - 4 字节的校验和，即消息体 SHA-256 摘要的前 4 个字节。
+ * 消息体使用 data 包的规范二进制编码，以编码版本号开头；区块、区块头与交易直接嵌入它们自己的规范编码。
+ * 协议版本在握手时通过 version 消息交换，低于 MinProtocolVersion 的节点会被断开。
+ */
+
+const (
+	// Magic 网络标识。
+	Magic uint32 = 0x4D434831
+	// ProtocolVersion 本节点使用的协议版本。
+	ProtocolVersion uint32 = 1
+	// MinProtocolVersion 可以接受的最低协议版本。
+	MinProtocolVersion uint32 = 1
+	// MaxPayloadSize 消息体的最大字节数。
+	MaxPayloadSize = 8 << 20
+	// MaxInvItems inv、getdata 与 notfound 消息中最多包含的条目个数。
+	MaxInvItems = 1000
+	// MaxHeaders headers 消息中最多包含的区块头个数。
+	MaxHeaders = 2000
+	// MaxLocatorHashes getheaders 消息中区块定位器最多包含的哈希个数。
+	MaxLocatorHashes = 64
+
+	headerSize = 13
+)
+
+var (
+	// ErrBadMagic 消息头中的网络标识与本网络不符。
+	ErrBadMagic = errors.New("bad network magic")
+	// ErrBadChecksum 消息体的校验和不符。
+	ErrBadChecksum = errors.New("bad checksum")
+	// ErrPayloadTooLarge 消息体或其中的列表超过上限。
+	ErrPayloadTooLarge = errors.New("payload too large")
+	// ErrUnknownCommand 消息类型无法识别。
+	ErrUnknownCommand = errors.New("unknown command")
+)
+
+// Command 消息类型。
+type Command uint8
+
+const (
+	CmdVersion Command = iota + 1
+	CmdVerAck
+	CmdPing
+	CmdPong
+	CmdInv
+	CmdGetData
+	CmdNotFound
+	CmdBlock
+	CmdTx
+	CmdGetHeaders
+	CmdHeaders
+)
+
+var commandNames = map[Command]string{
+	CmdVersion:    "version",
+	CmdVerAck:     "verack",
+	CmdPing:       "ping",
+	CmdPong:       "pong",
+	CmdInv:        "inv",
+	CmdGetData:    "getdata",
+	CmdNotFound:   "notfound",
+	CmdBlock:      "block",
+	CmdTx:         "tx",
+	CmdGetHeaders: "getheaders",
+	CmdHeaders:    "headers",
+}
+
+func (c Command) String() string {
+	if name, ok := commandNames[c]; ok {
+		return name
+	}
+	return "command(" + strconv.Itoa(int(c)) + ")"
+}
+
+// Message 节点间传递的消息。
+type Message interface {
+	// Command 返回消息类型。
+	Command() Command
+	encodeTo(e *data.Encoder)
+	decodeFrom(d *data.Decoder) error
+}
+
+// newMessage 根据消息类型创建空消息，用于解码。
+func newMessage(command Command) (Message, error) {
+	switch command {
+	case CmdVersion:
+		return &MsgVersion{}, nil
+	case CmdVerAck:
+		return &MsgVerAck{}, nil
+	case CmdPing:
+		return &MsgPing{}, nil
+	case CmdPong:
+		return &MsgPong{}, nil
+	case CmdInv:
+		return &MsgInv{}, nil
+	case CmdGetData:
+		return &MsgGetData{}, nil
+	case CmdNotFound:
+		return &MsgNotFound{}, nil
+	case CmdBlock:
+		return &MsgBlock{}, nil
+	case CmdTx:
+		return &MsgTx{}, nil
+	case CmdGetHeaders:
+		return &MsgGetHeaders{}, nil
+	case CmdHeaders:
+		return &MsgHeaders{}, nil
+	}
+	return nil, fmt.Errorf("%w: %d", ErrUnknownCommand, command)
+}
+
+// WriteMessage 将消息编码后写入 w。
+// 参数:
+// - w: 写入的目标，通常为网络连接。
+// - msg: 消息。
+// 返回值:
+// 返回写入的字节数以及可能出现的错误。
+func WriteMessage(w io.Writer, msg Message) (int, error) {
+	e := data.NewEncoder()
+	msg.encodeTo(e)
+	payload := e.Bytes()
+	if len(payload) > MaxPayloadSize {
+		return 0, fmt.Errorf("%w: %s of %d bytes", ErrPayloadTooLarge, msg.Command(), len(payload))
+	}
+	frame := make([]byte, headerSize+len(payload))
+	binary.BigEndian.PutUint32(frame[0:4], Magic)
+	frame[4] = byte(msg.Command())
+	binary.BigEndian.PutUint32(frame[5:9], uint32(len(payload)))
+	copy(frame[9:13], utils.Sha256Digest(payload)[:4])
+	copy(frame[headerSize:], payload)
+	return w.Write(frame)
+}
+
+// ReadMessage 从 r 中读取并解码一条消息。
+// 参数:
+// - r: 读取的来源，通常为网络连接。
+// 返回值:
+// 返回解码得到的消息、读取的字节数以及可能出现的错误。
+func ReadMessage(r io.Reader) (Message, int, error) {
+	header := make([]byte, headerSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, 0, err
+	}
+	if binary.BigEndian.Uint32(header[0:4]) != Magic {
+		return nil, headerSize, ErrBadMagic
+	}
+	length := binary.BigEndian.Uint32(header[5:9])
+	if length > MaxPayloadSize {
+		return nil, headerSize, fmt.Errorf("%w: %d bytes", ErrPayloadTooLarge, length)
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return nil, headerSize, err
+	}
+	n := headerSize + int(length)
+	if !bytes.Equal(utils.Sha256Digest(payload)[:4], header[9:13]) {
+		return nil, n, ErrBadChecksum
+	}
+	msg, err := newMessage(Command(header[4]))
+	if err != nil {
+		return nil, n, err
+	}
+	d := data.NewDecoder(payload)
+	if err := msg.decodeFrom(d); err != nil {
+		return nil, n, fmt.Errorf("decode %s: %w", msg.Command(), err)
+	}
+	if err := d.Finish(); err != nil {
+		return nil, n, fmt.Errorf("decode %s: %w", msg.Command(), err)
+	}
+	return msg, n, nil
+}
+
+// MsgVersion 握手时首先发送的消息，描述本节点。
+// 字段说明：
+// - Version: 协议版本。
+// - Nonce: 本节点的随机标识，用于发现连接到了自己。
+// - UserAgent: 节点软件的名称与版本。
+// - Genesis: 创世块哈希，不同创世块的节点不属于同一个网络。
+// - Height: 主链的高度。
+// - ListenAddr: 本节点的监听地址，没有监听时为空。
+// - Timestamp: 发送时的 Unix 时间（秒）。
+type MsgVersion struct {
+	Version    uint32
+	Nonce      int64
+	UserAgent  string
+	Genesis    string
+	Height     int
+	ListenAddr string
+	Timestamp  int64
+}
+
+func (m *MsgVersion) Command() Command { return CmdVersion }
+
+func (m *MsgVersion) encodeTo(e *data.Encoder) {
+	e.WriteUint32(m.Version)
+	e.WriteInt64(m.Nonce)
+	e.WriteString(m.UserAgent)
+	e.WriteString(m.Genesis)
+	e.WriteInt(m.Height)
+	e.WriteString(m.ListenAddr)
+	e.WriteInt64(m.Timestamp)
+}
+
+func (m *MsgVersion) decodeFrom(d *data.Decoder) error {
+	m.Version = d.ReadUint32()
+	m.Nonce = d.ReadInt64()
+	m.UserAgent = d.ReadString()
+	m.Genesis = d.ReadString()
+	m.Height = d.ReadInt()
+	m.ListenAddr = d.ReadString()
+	m.Timestamp = d.ReadInt64()
+	return d.Err()
+}
+
+// MsgVerAck 确认收到对方的 version 消息，握手双方都收到 verack 后连接建立。
+type MsgVerAck struct{}
+
+func (m *MsgVerAck) Command() Command                 { return CmdVerAck }
+func (m *MsgVerAck) encodeTo(e *data.Encoder)         {}
+func (m *MsgVerAck) decodeFrom(d *data.Decoder) error { return d.Err() }
+
+// MsgPing 检查连接是否存活，对方需要以携带相同 Nonce 的 pong 回复。
+type MsgPing struct {
+	Nonce int64
+}
+
+func (m *MsgPing) Command() Command         { return CmdPing }
+func (m *MsgPing) encodeTo(e *data.Encoder) { e.WriteInt64(m.Nonce) }
+func (m *MsgPing) decodeFrom(d *data.Decoder) error {
+	m.Nonce = d.ReadInt64()
+	return d.Err()
+}
+
+// MsgPong 对 ping 的回复。
+type MsgPong struct {
+	Nonce int64
+}
+
+func (m *MsgPong) Command() Command         { return CmdPong }
+func (m *MsgPong) encodeTo(e *data.Encoder) { e.WriteInt64(m.Nonce) }
+func (m *MsgPong) decodeFrom(d *data.Decoder) error {
+	m.Nonce = d.ReadInt64()
+	return d.Err()
+}
+
+// InvType 库存条目的类型。
+type InvType uint8
+
+const (
+	InvBlock InvType = iota + 1
+	InvTx
+)
+
+func (t InvType) String() string {
+	switch t {
+	case InvBlock:
+		return "block"
+	case InvTx:
+		return "tx"
+	}
+	return "inv(" + strconv.Itoa(int(t)) + ")"
+}
+
+// InvVect 库存条目，以类型与哈希（区块哈希或交易标识）指明一个区块或一笔交易。
+type InvVect struct {
+	Type InvType
+	Hash string
+}
+
+func encodeInvList(e *data.Encoder, items []InvVect) {
+	e.WriteUint32(uint32(len(items)))
+	for _, item := range items {
+		e.WriteUint8(uint8(item.Type))
+		e.WriteString(item.Hash)
+	}
+}
+
+func decodeInvList(d *data.Decoder) ([]InvVect, error) {
+	n := d.ReadCount(5)
+	if n > MaxInvItems {
+		return nil, fmt.Errorf("%w: %d inventory items", ErrPayloadTooLarge, n)
+	}
+	items := make([]InvVect, 0, n)
+	for i := 0; i < n && d.Err() == nil; i++ {
+		items = append(items, InvVect{Type: InvType(d.ReadUint8()), Hash: d.ReadString()})
+	}
+	return items, d.Err()
+}
+
+// MsgInv 通告本节点拥有的区块或交易，对方需要时以 getdata 请求。
+type MsgInv struct {
+	Items []InvVect
+}
+
+func (m *MsgInv) Command() Command         { return CmdInv }
+func (m *MsgInv) encodeTo(e *data.Encoder) { encodeInvList(e, m.Items) }
+func (m *MsgInv) decodeFrom(d *data.Decoder) (err error) {
+	m.Items, err = decodeInvList(d)
+	return err
+}
+
+// MsgGetData 请求 inv 中通告的区块或交易，对方以 block、tx 消息回复，没有的条目以 notfound 回复。
+type MsgGetData struct {
+	Items []InvVect
+}
+
+func (m *MsgGetData) Command() Command         { return CmdGetData }
+func (m *MsgGetData) encodeTo(e *data.Encoder) { encodeInvList(e, m.Items) }
+func (m *MsgGetData) decodeFrom(d *data.Decoder) (err error) {
+	m.Items, err = decodeInvList(d)
+	return err
+}
+
+// MsgNotFound 回复 getdata 中本节点没有的条目。
+type MsgNotFound struct {
+	Items []InvVect
+}
+
+func (m *MsgNotFound) Command() Command         { return CmdNotFound }
+func (m *MsgNotFound) encodeTo(e *data.Encoder) { encodeInvList(e, m.Items) }
+func (m *MsgNotFound) decodeFrom(d *data.Decoder) (err error) {
+	m.Items, err = decodeInvList(d)
+	return err
+}
+
+// MsgBlock 传递一个完整的区块。
+type MsgBlock struct {
+	Block data.Block
+}
+
+func (m *MsgBlock) Command() Command         { return CmdBlock }
+func (m *MsgBlock) encodeTo(e *data.Encoder) { e.WriteBytes(m.Block.Encode()) }
+func (m *MsgBlock) decodeFrom(d *data.Decoder) error {
+	raw := d.ReadBytes()
+	if err := d.Err(); err != nil {
+		return err
+	}
+	block, err := data.DecodeBlock(raw)
+	if err != nil {
+		return err
+	}
+	m.Block = *block
+	return nil
+}
+
+// MsgTx 传递一笔交易。
+type MsgTx struct {
+	Transaction data.Transaction
+}
+
+func (m *MsgTx) Command() Command         { return CmdTx }
+func (m *MsgTx) encodeTo(e *data.Encoder) { e.WriteBytes(m.Transaction.Encode()) }
+func (m *MsgTx) decodeFrom(d *data.Decoder) error {
+	raw := d.ReadBytes()
+	if err := d.Err(); err != nil {
+		return err
+	}
+	tx, err := data.DecodeTransaction(raw)
+	if err != nil {
+		return err
+	}
+	m.Transaction = *tx
+	return nil
+}
+
+// MsgGetHeaders 请求区块头。Locator 为请求方主链上从新到旧的区块哈希（见 network.BlockChain.GetLocator），
+// 对方从其中第一个位于自己主链上的区块之后开始，最多回复 MaxHeaders 个区块头，遇到 Stop 时停止。
+type MsgGetHeaders struct {
+	Locator []string
+	Stop    string
+}
+
+func (m *MsgGetHeaders) Command() Command { return CmdGetHeaders }
+
+func (m *MsgGetHeaders) encodeTo(e *data.Encoder) {
+	e.WriteUint32(uint32(len(m.Locator)))
+	for _, hash := range m.Locator {
+		e.WriteString(hash)
+	}
+	e.WriteString(m.Stop)
+}
+
+func (m *MsgGetHeaders) decodeFrom(d *data.Decoder) error {
+	n := d.ReadCount(4)
+	if n > MaxLocatorHashes {
+		return fmt.Errorf("%w: %d locator hashes", ErrPayloadTooLarge, n)
+	}
+	m.Locator = make([]string, 0, n)
+	for i := 0; i < n && d.Err() == nil; i++ {
+		m.Locator = append(m.Locator, d.ReadString())
+	}
+	m.Stop = d.ReadString()
+	return d.Err()
+}
+
+// MsgHeaders 回复 getheaders，按从旧到新的顺序包含连续的区块头。
+type MsgHeaders struct {
+	Headers []data.BlockHeader
+}
+
+func (m *MsgHeaders) Command() Command { return CmdHeaders }
+
+func (m *MsgHeaders) encodeTo(e *data.Encoder) {
+	e.WriteUint32(uint32(len(m.Headers)))
+	for i := range m.Headers {
+		e.WriteBytes(m.Headers[i].Encode())
+	}
+}
+
+func (m *MsgHeaders) decodeFrom(d *data.Decoder) error {
+	n := d.ReadCount(4)
+	if n > MaxHeaders {
+		return fmt.Errorf("%w: %d headers", ErrPayloadTooLarge, n)
+	}
+	m.Headers = make([]data.BlockHeader, 0, n)
+	for i := 0; i < n && d.Err() == nil; i++ {
+		raw := d.ReadBytes()
+		if d.Err() != nil {
+			break
+		}
+		header, err := data.DecodeBlockHeader(raw)
+		if err != nil {
+			return err
+		}
+		m.Headers = append(m.Headers, *header)
+	}
+	return d.Err()
+}
@@ -0,0 +1,325 @@
+package p2p
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+/**
+ * 对等节点连接
+ *
+ * 连接建立后双方先交换 version 与 verack 完成握手，之后由一个协程读取消息并交给 Handler 处理，
+ * 另一个协程从发送队列中取出消息写入连接。同一节点发来的消息按顺序处理。
+ * 每隔 pingInterval 发送一次 ping，超过 pingTimeout 没有收到对应的 pong，或者超过 idleTimeout 没有收到任何消息时断开连接。
+ */
+
+const (
+	// handshakeTimeout 完成握手的最长时间。
+	handshakeTimeout = 10 * time.Second
+	// writeTimeout 写入一条消息的最长时间。
+	writeTimeout = 30 * time.Second
+	// pingInterval 发送 ping 的间隔。
+	pingInterval = 30 * time.Second
+	// pingTimeout 等待 pong 的最长时间。
+	pingTimeout = 20 * time.Second
+	// idleTimeout 没有收到任何消息时断开连接的时间。
+	idleTimeout = 2 * pingInterval
+	// sendQueueSize 发送队列的长度，队列已满说明对方读取过慢，此时断开连接。
+	sendQueueSize = 256
+)
+
+var (
+	// ErrHandshake 握手失败。
+	ErrHandshake = errors.New("handshake failed")
+	// ErrSelfConnection 连接到了本节点自己。
+	ErrSelfConnection = errors.New("connected to self")
+	// ErrGenesisMismatch 对方节点的创世块与本节点不同。
+	ErrGenesisMismatch = errors.New("genesis block mismatch")
+)
+
+// PeerInfo 对等节点的连接信息。
+// 字段说明：
+// - Addr: 对方的网络地址。
+// - Inbound: 是否为对方发起的连接。
+// - Version: 对方的协议版本。
+// - UserAgent: 对方的节点软件名称。
+// - ListenAddr: 对方的监听地址。
+// - StartHeight: 握手时对方的主链高度。
+// - ConnectedAt: 连接建立的时间。
+// - BytesSent / BytesReceived: 发送与接收的字节数。
+// - PingTime: 最近一次 ping 的往返时间，尚未测量时为 0。
+type PeerInfo struct {
+	Addr          string
+	Inbound       bool
+	Version       uint32
+	UserAgent     string
+	ListenAddr    string
+	StartHeight   int
+	ConnectedAt   time.Time
+	BytesSent     uint64
+	BytesReceived uint64
+	PingTime      time.Duration
+}
+
+// Peer 与一个对等节点的连接。
+// 字段说明：
+// - conn: 网络连接。
+// - inbound: 是否为对方发起的连接。
+// - remote: 对方在握手时发送的 version 消息。
+// - connectedAt: 握手完成的时间。
+// - send: 发送队列。
+// - quit: 连接关闭时关闭的通道。
+// - closeOnce: 保证连接只关闭一次。
+// - bytesSent / bytesReceived: 发送与接收的字节数。
+// - pingNonce / pingSent / pingTime: 尚未收到回复的 ping 的随机数与发送时间，以及最近一次的往返时间。
+// - mutex: 保护 ping 相关字段的互斥锁。
+type Peer struct {
+	conn          net.Conn
+	inbound       bool
+	remote        MsgVersion
+	connectedAt   time.Time
+	send          chan Message
+	quit          chan struct{}
+	closeOnce     sync.Once
+	bytesSent     uint64
+	bytesReceived uint64
+	pingNonce     int64
+	pingSent      time.Time
+	pingTime      time.Duration
+	mutex         sync.Mutex
+}
+
+func newPeer(conn net.Conn, inbound bool) *Peer {
+	return &Peer{
+		conn:    conn,
+		inbound: inbound,
+		send:    make(chan Message, sendQueueSize),
+		quit:    make(chan struct{}),
+	}
+}
+
+// Addr 返回对方的网络地址。
+func (p *Peer) Addr() string {
+	return p.conn.RemoteAddr().String()
+}
+
+// IsInbound 判断是否为对方发起的连接。
+func (p *Peer) IsInbound() bool {
+	return p.inbound
+}
+
+// GetHeight 返回握手时对方的主链高度。
+func (p *Peer) GetHeight() int {
+	return p.remote.Height
+}
+
+// String 返回便于输出日志的节点描述。
+func (p *Peer) String() string {
+	direction := "outbound"
+	if p.inbound {
+		direction = "inbound"
+	}
+	return "peer " + p.Addr() + " (" + direction + ")"
+}
+
+// Info 返回连接信息。
+func (p *Peer) Info() PeerInfo {
+	p.mutex.Lock()
+	pingTime := p.pingTime
+	p.mutex.Unlock()
+	return PeerInfo{
+		Addr:          p.Addr(),
+		Inbound:       p.inbound,
+		Version:       p.remote.Version,
+		UserAgent:     p.remote.UserAgent,
+		ListenAddr:    p.remote.ListenAddr,
+		StartHeight:   p.remote.Height,
+		ConnectedAt:   p.connectedAt,
+		BytesSent:     atomic.LoadUint64(&p.bytesSent),
+		BytesReceived: atomic.LoadUint64(&p.bytesReceived),
+		PingTime:      pingTime,
+	}
+}
+
+// Send 将消息放入发送队列，不会阻塞。连接已关闭时返回 false；发送队列已满时断开连接并返回 false。
+func (p *Peer) Send(msg Message) bool {
+	select {
+	case <-p.quit:
+		return false
+	default:
+	}
+	select {
+	case p.send <- msg:
+		return true
+	default:
+		fmt.Println("Disconnect", p, "because its send queue is full")
+		p.Disconnect()
+		return false
+	}
+}
+
+// SendWait 将消息放入发送队列，队列已满时等待。连接已关闭时返回 false。
+// 用于回复 getdata 等可能一次发送大量消息的请求，等待期间不再读取该节点的消息，使对方按自己的读取速度接收。
+func (p *Peer) SendWait(msg Message) bool {
+	select {
+	case p.send <- msg:
+		return true
+	case <-p.quit:
+		return false
+	}
+}
+
+// Disconnect 关闭连接。
+func (p *Peer) Disconnect() {
+	p.closeOnce.Do(func() {
+		close(p.quit)
+		p.conn.Close()
+	})
+}
+
+// Done 返回连接关闭时关闭的通道。
+func (p *Peer) Done() <-chan struct{} {
+	return p.quit
+}
+
+// writeMessage 直接向连接写入一条消息，只在握手阶段与写协程中调用。
+func (p *Peer) writeMessage(msg Message) error {
+	p.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	n, err := WriteMessage(p.conn, msg)
+	atomic.AddUint64(&p.bytesSent, uint64(n))
+	return err
+}
+
+// readMessage 读取一条消息，超过 timeout 没有读到时返回错误。
+func (p *Peer) readMessage(timeout time.Duration) (Message, error) {
+	p.conn.SetReadDeadline(time.Now().Add(timeout))
+	msg, n, err := ReadMessage(p.conn)
+	atomic.AddUint64(&p.bytesReceived, uint64(n))
+	return msg, err
+}
+
+// handshake 交换 version 与 verack 消息，并检查对方的协议版本、创世块以及是否连接到了自己。
+func (p *Peer) handshake(local MsgVersion) error {
+	if err := p.writeMessage(&local); err != nil {
+		return err
+	}
+	msg, err := p.readMessage(handshakeTimeout)
+	if err != nil {
+		return err
+	}
+	remote, ok := msg.(*MsgVersion)
+	if !ok {
+		return fmt.Errorf("%w: expected version, got %s", ErrHandshake, msg.Command())
+	}
+	switch {
+	case remote.Nonce == local.Nonce:
+		return ErrSelfConnection
+	case remote.Version < MinProtocolVersion:
+		return fmt.Errorf("%w: protocol version %d is below %d", ErrHandshake, remote.Version, MinProtocolVersion)
+	case remote.Genesis != local.Genesis:
+		return fmt.Errorf("%w: %s", ErrGenesisMismatch, remote.Genesis)
+	}
+	p.remote = *remote
+	if err := p.writeMessage(&MsgVerAck{}); err != nil {
+		return err
+	}
+	msg, err = p.readMessage(handshakeTimeout)
+	if err != nil {
+		return err
+	}
+	if _, ok := msg.(*MsgVerAck); !ok {
+		return fmt.Errorf("%w: expected verack, got %s", ErrHandshake, msg.Command())
+	}
+	p.connectedAt = time.Now()
+	return nil
+}
+
+// run 启动写协程与 ping 协程，并在当前协程中读取消息，直到连接关闭。
+func (p *Peer) run(handler Handler) {
+	go p.writeLoop()
+	go p.pingLoop()
+	defer p.Disconnect()
+	for {
+		msg, err := p.readMessage(idleTimeout)
+		if err != nil {
+			select {
+			case <-p.quit:
+			default:
+				fmt.Println("Disconnect", p, "after read error:", err)
+			}
+			return
+		}
+		switch m := msg.(type) {
+		case *MsgPing:
+			p.Send(&MsgPong{Nonce: m.Nonce})
+		case *MsgPong:
+			p.handlePong(m)
+		case *MsgVersion, *MsgVerAck:
+			fmt.Println("Disconnect", p, "after duplicate", msg.Command())
+			return
+		default:
+			handler.HandleMessage(p, msg)
+		}
+	}
+}
+
+// writeLoop 依次发送队列中的消息，直到连接关闭。
+func (p *Peer) writeLoop() {
+	for {
+		select {
+		case <-p.quit:
+			return
+		case msg := <-p.send:
+			if err := p.writeMessage(msg); err != nil {
+				fmt.Println("Disconnect", p, "after write error:", err)
+				p.Disconnect()
+				return
+			}
+		}
+	}
+}
+
+// pingLoop 定期发送 ping，上一个 ping 超时未回复时断开连接。
+func (p *Peer) pingLoop() {
+	ticker := time.NewTicker(pingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.quit:
+			return
+		case <-ticker.C:
+		}
+		p.mutex.Lock()
+		if p.pingNonce != 0 {
+			expired := time.Since(p.pingSent) > pingTimeout
+			p.mutex.Unlock()
+			if expired {
+				fmt.Println("Disconnect", p, "after ping timeout")
+				p.Disconnect()
+				return
+			}
+			continue
+		}
+		// 随机数为 0 表示没有等待回复的 ping
+		p.pingNonce = rand.Int63() | 1
+		p.pingSent = time.Now()
+		nonce := p.pingNonce
+		p.mutex.Unlock()
+		p.Send(&MsgPing{Nonce: nonce})
+	}
+}
+
+// handlePong 记录 ping 的往返时间。
+func (p *Peer) handlePong(pong *MsgPong) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if pong.Nonce == p.pingNonce && p.pingNonce != 0 {
+		p.pingTime = time.Since(p.pingSent)
+		p.pingNonce = 0
+	}
+}
@@ -0,0 +1,255 @@
+package p2p
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"sync"
+	"time"
+)
+
+/**
+ * 节点间通信服务
+ *
+ * Server 监听其他节点发起的连接，并主动连接配置中指定的节点，连接断开后按指数退避重新连接。
+ * 握手完成的连接交给 Handler 处理，p2p 包只负责消息的收发与连接管理，不依赖区块链的实现。
+ */
+
+const (
+	// dialTimeout 建立 TCP 连接的最长时间。
+	dialTimeout = 5 * time.Second
+	// minRetryInterval / maxRetryInterval 重新连接的最短与最长等待时间。
+	minRetryInterval = time.Second
+	maxRetryInterval = time.Minute
+)
+
+// UserAgent 本节点软件的名称与版本，在握手时发送给对方。
+const UserAgent = "/minichain:0.1/"
+
+// Handler 处理握手完成的连接上收到的消息，方法可能被多个连接的协程并发调用。
+type Handler interface {
+	// LocalVersion 返回握手时发送给对方的创世块哈希与主链高度。
+	LocalVersion() (genesis string, height int)
+	// PeerConnected 在握手完成后调用。
+	PeerConnected(p *Peer)
+	// HandleMessage 处理除 version、verack、ping 与 pong 之外的消息，同一连接上的消息按收到的顺序处理。
+	HandleMessage(p *Peer, msg Message)
+	// PeerDisconnected 在连接关闭后调用。
+	PeerDisconnected(p *Peer)
+}
+
+// Server 节点间通信服务。
+// 字段说明：
+// - listenAddr: 监听地址，为空时不接受其他节点发起的连接。
+// - maxPeers: 最多接受的连接个数（包括主动发起的连接）。
+// - handler: 消息处理者。
+// - nonce: 本节点的随机标识，用于发现连接到了自己。
+// - listener: 监听器。
+// - peers: 握手完成的连接。
+// - mutex: 保护 listener 与 peers 的互斥锁。
+// - wg: 等待全部连接协程结束。
+type Server struct {
+	listenAddr string
+	maxPeers   int
+	handler    Handler
+	nonce      int64
+	listener   net.Listener
+	peers      map[*Peer]bool
+	mutex      sync.Mutex
+	wg         sync.WaitGroup
+}
+
+// NewServer 创建节点间通信服务。
+// 参数:
+// - listenAddr: 监听地址，为空时不监听。
+// - maxPeers: 最多的连接个数。
+// - handler: 消息处理者。
+// 返回值:
+// 返回一个指向新创建的服务的指针。
+func NewServer(listenAddr string, maxPeers int, handler Handler) *Server {
+	return &Server{
+		listenAddr: listenAddr,
+		maxPeers:   maxPeers,
+		handler:    handler,
+		nonce:      rand.Int63(),
+		peers:      make(map[*Peer]bool),
+	}
+}
+
+// Start 开始监听，ctx 被取消时停止监听并断开全部连接。
+// 返回值:
+// 无法监听时返回错误。
+func (s *Server) Start(ctx context.Context) error {
+	if s.listenAddr != "" {
+		listener, err := net.Listen("tcp", s.listenAddr)
+		if err != nil {
+			return err
+		}
+		s.mutex.Lock()
+		s.listener = listener
+		s.mutex.Unlock()
+		fmt.Println("P2P server is listening on " + listener.Addr().String())
+		s.wg.Add(1)
+		go s.acceptLoop(ctx, listener)
+	}
+	go func() {
+		<-ctx.Done()
+		s.mutex.Lock()
+		if s.listener != nil {
+			s.listener.Close()
+		}
+		for p := range s.peers {
+			p.Disconnect()
+		}
+		s.mutex.Unlock()
+	}()
+	return nil
+}
+
+// GetListenAddr 返回实际的监听地址，没有监听时为空。监听端口为 0 时可以由此得到系统分配的端口。
+func (s *Server) GetListenAddr() string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.listener == nil {
+		return ""
+	}
+	return s.listener.Addr().String()
+}
+
+// acceptLoop 接受其他节点发起的连接，直到监听器被关闭。
+func (s *Server) acceptLoop(ctx context.Context, listener net.Listener) {
+	defer s.wg.Done()
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() == nil {
+				fmt.Println("P2P server stopped accepting connections:", err)
+			}
+			return
+		}
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.handle(ctx, conn, true)
+		}()
+	}
+}
+
+// Connect 在后台保持与指定地址的连接：连接失败或断开后按指数退避重新连接，直到 ctx 被取消。
+// 参数:
+// - ctx: 停止重新连接的上下文。
+// - addr: 对方的监听地址。
+func (s *Server) Connect(ctx context.Context, addr string) {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		retry := minRetryInterval
+		for ctx.Err() == nil {
+			dialer := net.Dialer{Timeout: dialTimeout}
+			conn, err := dialer.DialContext(ctx, "tcp", addr)
+			if err == nil {
+				err = s.handle(ctx, conn, false)
+				if err == nil {
+					retry = minRetryInterval
+				}
+			}
+			if errors.Is(err, ErrSelfConnection) {
+				fmt.Println("Stop connecting to " + addr + ", which is this node itself")
+				return
+			}
+			if err != nil && ctx.Err() == nil {
+				fmt.Println("Connect to "+addr+" failed:", err, "retry in", retry)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(retry):
+			}
+			if retry *= 2; retry > maxRetryInterval {
+				retry = maxRetryInterval
+			}
+		}
+	}()
+}
+
+// handle 完成握手并处理连接上的消息，直到连接关闭。
+// 返回值:
+// 握手成功时在连接关闭后返回 nil，否则返回握手失败的原因。
+func (s *Server) handle(ctx context.Context, conn net.Conn, inbound bool) error {
+	p := newPeer(conn, inbound)
+	if inbound && len(s.GetPeers()) >= s.maxPeers {
+		conn.Close()
+		return errors.New("too many peers")
+	}
+	genesis, height := s.handler.LocalVersion()
+	local := MsgVersion{
+		Version:    ProtocolVersion,
+		Nonce:      s.nonce,
+		UserAgent:  UserAgent,
+		Genesis:    genesis,
+		Height:     height,
+		ListenAddr: s.GetListenAddr(),
+		Timestamp:  time.Now().Unix(),
+	}
+	if err := p.handshake(local); err != nil {
+		conn.Close()
+		if inbound {
+			fmt.Println("Handshake with", p, "failed:", err)
+		}
+		return err
+	}
+
+	s.mutex.Lock()
+	if ctx.Err() != nil {
+		s.mutex.Unlock()
+		conn.Close()
+		return ctx.Err()
+	}
+	s.peers[p] = true
+	s.mutex.Unlock()
+	fmt.Println("Connected to", p, p.remote.UserAgent, "at height", p.remote.Height)
+	s.handler.PeerConnected(p)
+
+	p.run(s.handler)
+
+	s.mutex.Lock()
+	delete(s.peers, p)
+	s.mutex.Unlock()
+	fmt.Println("Disconnected from", p)
+	s.handler.PeerDisconnected(p)
+	return nil
+}
+
+// GetPeers 返回全部握手完成的连接。
+func (s *Server) GetPeers() []*Peer {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	peers := make([]*Peer, 0, len(s.peers))
+	for p := range s.peers {
+		peers = append(peers, p)
+	}
+	return peers
+}
+
+// Broadcast 向除 except 之外的全部连接发送消息。
+// 参数:
+// - msg: 消息。
+// - except: 不需要发送的连接，通常为消息的来源，可以为 nil。
+// 返回值:
+// 返回发送到的连接个数。
+func (s *Server) Broadcast(msg Message, except *Peer) int {
+	count := 0
+	for _, p := range s.GetPeers() {
+		if p != except && p.Send(msg) {
+			count++
+		}
+	}
+	return count
+}
+
+// Wait 等待监听与全部连接的协程结束，应在 Start 的 ctx 被取消之后调用。
+func (s *Server) Wait() {
+	s.wg.Wait()
+}
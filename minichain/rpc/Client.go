@@ -0,0 +1,192 @@
+package rpc
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Client JSON-RPC 客户端，供命令行客户端与其他 Go 程序调用节点的方法。
+// 字段说明：
+// - url: 服务地址。
+// - token: 请求携带的令牌，为空时不携带。
+// - httpClient: HTTP 客户端。
+// - nextID: 下一个请求的标识。
+type Client struct {
+	url        string
+	token      string
+	httpClient *http.Client
+	nextID     int64
+}
+
+// NewClient 创建 JSON-RPC 客户端。
+// 参数:
+// - addr: 服务地址，可以是 "127.0.0.1:9332" 这样的主机与端口，也可以是完整的 URL。
+// - timeout: 单个请求的超时时间。
+// 返回值:
+// 返回一个指向新创建的客户端的指针。
+func NewClient(addr string, timeout time.Duration) *Client {
+	url := addr
+	if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
+		url = "http://" + url
+	}
+	return &Client{url: url, httpClient: &http.Client{Timeout: timeout}}
+}
+
+// GetURL 返回服务地址。
+func (c *Client) GetURL() string {
+	return c.url
+}
+
+// SetToken 设置请求携带的令牌，与节点配置的 rpcToken 相同。
+func (c *Client) SetToken(token string) {
+	c.token = token
+}
+
+// newRequest 创建发往节点的 HTTP 请求，设置了令牌时携带 Authorization 头。
+func (c *Client) newRequest(ctx context.Context, method string, url string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return nil, err
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	return req, nil
+}
+
+// checkStatus 将非 200 的 HTTP 状态转换为错误，令牌缺失或错误时给出提示。
+func checkStatus(resp *http.Response) error {
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return nil
+	case http.StatusUnauthorized:
+		return errors.New("the node rejected the request: missing or invalid RPC token")
+	}
+	var message bytes.Buffer
+	message.ReadFrom(resp.Body)
+	if text := strings.TrimSpace(message.String()); text != "" {
+		return fmt.Errorf("unexpected HTTP status %s: %s", resp.Status, text)
+	}
+	return fmt.Errorf("unexpected HTTP status %s", resp.Status)
+}
+
+// Call 调用一个方法并将结果解析到 result 中。
+// 参数:
+// - method: 方法名。
+// - params: 按位置传递的参数，可以为 nil。
+// - result: 指向结果的指针，传入 *json.RawMessage 时保留原始结果，为 nil 时忽略结果。
+// 返回值:
+// 节点返回错误时返回 *Error，无法连接或响应无法解析时返回其他错误。
+func (c *Client) Call(method string, params []interface{}, result interface{}) error {
+	if params == nil {
+		params = []interface{}{}
+	}
+	rawParams, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	id := atomic.AddInt64(&c.nextID, 1)
+	body, err := json.Marshal(Request{JSONRPC: "2.0", ID: json.RawMessage(strconv.FormatInt(id, 10)), Method: method, Params: rawParams})
+	if err != nil {
+		return err
+	}
+	req, err := c.newRequest(context.Background(), http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	httpResp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer httpResp.Body.Close()
+	if err := checkStatus(httpResp); err != nil {
+		return err
+	}
+	var resp struct {
+		Result json.RawMessage `json:"result"`
+		Error  *Error          `json:"error"`
+	}
+	if err := json.NewDecoder(httpResp.Body).Decode(&resp); err != nil {
+		return fmt.Errorf("decode response error: %w", err)
+	}
+	if resp.Error != nil {
+		return resp.Error
+	}
+	if result == nil {
+		return nil
+	}
+	return json.Unmarshal(resp.Result, result)
+}
+
+// SubscribeEvents 连接节点的事件流（GET /events），依次将收到的事件交给 handle。
+// 事件流没有超时限制，直到 ctx 被取消、连接断开或 handle 返回错误时才返回。
+// 参数:
+// - ctx: 控制订阅时长的上下文。
+// - types: 订阅的事件类型，为空时订阅全部类型。
+// - handle: 处理一个事件，返回错误时停止订阅。
+// 返回值:
+// ctx 被取消时返回 ctx.Err()；handle 返回错误时原样返回；节点因客户端过慢关闭事件流或连接断开时返回说明原因的错误。
+func (c *Client) SubscribeEvents(ctx context.Context, types []string, handle func(EventResult) error) error {
+	url := strings.TrimSuffix(c.url, "/") + "/events"
+	if len(types) > 0 {
+		url += "?types=" + strings.Join(types, ",")
+	}
+	req, err := c.newRequest(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	httpResp, err := (&http.Client{Transport: c.httpClient.Transport}).Do(req)
+	if err != nil {
+		return err
+	}
+	defer httpResp.Body.Close()
+	if err := checkStatus(httpResp); err != nil {
+		return err
+	}
+	scanner := bufio.NewScanner(httpResp.Body)
+	var eventType, payload string
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			// 空行结束一条消息
+			if payload == "" {
+				continue
+			}
+			if eventType == "error" {
+				return errors.New("event stream closed by the node: " + payload)
+			}
+			var event EventResult
+			if err := json.Unmarshal([]byte(payload), &event); err != nil {
+				return fmt.Errorf("decode event error: %w", err)
+			}
+			if err := handle(event); err != nil {
+				return err
+			}
+			eventType, payload = "", ""
+		case strings.HasPrefix(line, "event:"):
+			eventType = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			payload += strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		}
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return errors.New("event stream closed by the node")
+}
@@ -0,0 +1,110 @@
+package rpc
+
+import (
+	"Go-Minichain/network"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+/**
+ * 事件流
+ *
+ * GET /events 以 Server-Sent Events 格式推送节点的事件总线（见 network.EventBus）中的事件，
+ * 钱包、区块浏览器等外部工具无需轮询即可得知区块连接、链重组以及交易进入和离开交易池。
+ * 每个事件为一条消息：id 为事件序号，event 为事件类型，data 为 EventResult 的 JSON。
+ * 查询参数 types 以逗号分隔指定订阅的事件类型，缺省时订阅全部类型。
+ * 客户端接收过慢导致事件被丢弃时，服务端发送一条 error 事件后关闭连接，客户端应重新连接并重新查询当前状态。
+ */
+
+const (
+	// eventBuffer 每个事件流缓冲的事件个数。
+	eventBuffer = 256
+	// heartbeatInterval 没有事件时发送注释行的间隔，使代理与客户端不会因空闲而断开连接。
+	heartbeatInterval = 15 * time.Second
+)
+
+// eventTypes 可以订阅的事件类型。
+var eventTypes = []network.EventType{network.EventBlockConnected, network.EventBlockDisconnected,
+	network.EventTxAccepted, network.EventTxRemoved}
+
+// parseEventTypes 解析查询参数 types，为空时返回 nil，即订阅全部类型。
+func parseEventTypes(value string) ([]network.EventType, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+	types := make([]network.EventType, 0)
+	for _, name := range strings.Split(value, ",") {
+		name = strings.TrimSpace(name)
+		found := false
+		for _, t := range eventTypes {
+			if string(t) == name {
+				types = append(types, t)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown event type %q", name)
+		}
+	}
+	return types, nil
+}
+
+// handleEvents 订阅事件总线并持续写出事件，直到客户端断开、订阅者过慢或服务停止。
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		http.Error(w, "the event stream must use GET", http.StatusMethodNotAllowed)
+		return
+	}
+	types, err := parseEventTypes(r.URL.Query().Get("types"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+	sub := s.network.GetEventBus().Subscribe(eventBuffer, types...)
+	defer sub.Unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+		case event, ok := <-sub.Events():
+			if !ok {
+				if err := sub.Err(); err != nil {
+					fmt.Fprintf(w, "event: error\ndata: %s\n\n", err.Error())
+					flusher.Flush()
+				}
+				return
+			}
+			payload, err := json.Marshal(newEventResult(event))
+			if err != nil {
+				return
+			}
+			if _, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.Sequence, event.Type, payload); err != nil {
+				return
+			}
+		}
+		flusher.Flush()
+	}
+}
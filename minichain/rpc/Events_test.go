@@ -0,0 +1,66 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// errDone 在收到期望的事件后停止订阅。
+var errDone = errors.New("done")
+
+func TestEventStreamDeliversBlocks(t *testing.T) {
+	s, n := startSimulatedServer(t)
+	client := NewClient(s.GetListenAddr(), time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	received := make(chan EventResult, 1)
+	result := make(chan error, 1)
+	go func() {
+		result <- client.SubscribeEvents(ctx, []string{"blockconnected"}, func(event EventResult) error {
+			received <- event
+			return errDone
+		})
+	}()
+	// 订阅建立后再挖出区块，否则事件在订阅之前已经发布
+	for n.GetEventBus().GetSubscriberCount() == 0 {
+		select {
+		case err := <-result:
+			t.Fatalf("subscription ended before the block was mined: %v", err)
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	if err := n.Simulate(1); err != nil {
+		t.Fatal(err)
+	}
+	tip := n.GetBlockchain().GetTip()
+
+	select {
+	case event := <-received:
+		if event.Type != "blockconnected" || event.BlockHash != tip.Hash || event.Height == nil || *event.Height != tip.Height {
+			t.Fatalf("received %+v, want blockconnected for %s at height %d", event, tip.Hash, tip.Height)
+		}
+		if event.Sequence == 0 {
+			t.Fatal("event has no sequence number")
+		}
+	case err := <-result:
+		t.Fatalf("subscription ended without an event: %v", err)
+	case <-ctx.Done():
+		t.Fatal("no block event was received")
+	}
+	if err := <-result; err != errDone {
+		t.Fatalf("subscription returned %v, want the handler's error", err)
+	}
+}
+
+func TestEventStreamRejectsUnknownTypes(t *testing.T) {
+	s, _ := startSimulatedServer(t)
+	client := NewClient(s.GetListenAddr(), time.Second)
+	err := client.SubscribeEvents(context.Background(), []string{"blockmined"}, func(EventResult) error { return nil })
+	if err == nil || !strings.Contains(err.Error(), "blockmined") {
+		t.Fatalf("subscription returned %v, want an error naming the unknown type", err)
+	}
+}
@@ -0,0 +1,351 @@
+package rpc
+
+import (
+	"Go-Minichain/config"
+	"Go-Minichain/data"
+	"Go-Minichain/network"
+	"Go-Minichain/spv"
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+/**
+ * JSON-RPC 方法
+ *
+ * 查询区块链：
+ * - getblockcount: 主链的高度。
+ * - getbestblockhash: 主链最新区块的哈希。
+ * - getblockchaininfo: 主链与区块同步的状态。
+ * - getblock(block, verbose=true): 按哈希或高度查询区块，verbose 为 false 时返回区块的原始编码。
+ * - gettransaction(txid): 在交易池与主链中查询交易。
+ * - getproof(txid): 主链中交易的 Merkle 证明。
+ * 查询账户：
+ * - getbalance(address): 钱包地址已确认与可以花费的金额。
+ * - listunspent(address, includemempool=false): 钱包地址的 UTXO，includemempool 为 true 时叠加交易池中的未确认交易。
+ * 交易池：
+ * - getmempoolinfo: 交易池的状态。
+ * - getrawmempool: 交易池中按手续费率从高到低排列的交易标识。
+ * - sendrawtransaction(hex): 提交交易的原始编码，交易进入交易池后转发给其他节点。
+ * 节点：
+ * - getpeerinfo: 连接的节点。
+ * - stop: 停止节点。
+ */
+
+// method 一个 RPC 方法。
+// 字段说明：
+// - params: 参数名，按位置传递参数时依次对应。
+// - anytime: 节点初始化完成之前是否也可以调用。
+// - handler: 处理函数，args 与 params 一一对应，未提供的参数为 nil。
+type method struct {
+	params  []string
+	anytime bool
+	handler func(s *Server, args []json.RawMessage) (interface{}, *Error)
+}
+
+// methods 按名称索引的全部方法。
+var methods = map[string]method{
+	"getblockcount":      {handler: getBlockCount},
+	"getbestblockhash":   {handler: getBestBlockHash},
+	"getblockchaininfo":  {handler: getBlockchainInfo},
+	"getblock":           {params: []string{"block", "verbose"}, handler: getBlock},
+	"gettransaction":     {params: []string{"txid"}, handler: getTransaction},
+	"getproof":           {params: []string{"txid"}, handler: getProof},
+	"getbalance":         {params: []string{"address"}, handler: getBalance},
+	"listunspent":        {params: []string{"address", "includemempool"}, handler: listUnspent},
+	"getmempoolinfo":     {handler: getMempoolInfo},
+	"getrawmempool":      {handler: getRawMempool},
+	"sendrawtransaction": {params: []string{"hex"}, handler: sendRawTransaction},
+	"getpeerinfo":        {handler: getPeerInfo},
+	"stop":               {anytime: true, handler: stop},
+}
+
+func getBlockCount(s *Server, _ []json.RawMessage) (interface{}, *Error) {
+	return s.network.GetBlockchain().GetHeight(), nil
+}
+
+func getBestBlockHash(s *Server, _ []json.RawMessage) (interface{}, *Error) {
+	return s.network.GetNewestBlock().Hash(), nil
+}
+
+func getBlockchainInfo(s *Server, _ []json.RawMessage) (interface{}, *Error) {
+	chain := s.network.GetBlockchain()
+	status := s.network.GetSyncStatus()
+	return BlockchainInfoResult{
+		Height:        status.Height,
+		Headers:       status.HeaderHeight,
+		BestBlockHash: s.network.GetNewestBlock().Hash(),
+		Bits:          chain.NextBits(),
+		TotalWork:     chain.GetTotalWork().String(),
+		MedianTime:    chain.GetMedianTimePast(),
+		Syncing:       status.Syncing,
+		InFlight:      status.InFlight,
+		Pending:       status.Pending,
+		Peers:         status.Peers,
+	}, nil
+}
+
+// getBlock 按哈希（字符串）或主链上的高度（整数）查询区块。
+func getBlock(s *Server, args []json.RawMessage) (interface{}, *Error) {
+	if args[0] == nil {
+		return nil, invalidParams("missing parameter block")
+	}
+	verbose, err := optionalBool(args[1], "verbose", true)
+	if err != nil {
+		return nil, err
+	}
+	chain := s.network.GetBlockchain()
+	var info network.BlockInfo
+	var found bool
+	if args[0][0] == '"' {
+		hash, err := requiredString(args[0], "block")
+		if err != nil {
+			return nil, err
+		}
+		info, found = chain.GetBlockInfo(strings.ToUpper(hash))
+	} else {
+		var height int
+		if json.Unmarshal(args[0], &height) != nil {
+			return nil, invalidParams("block must be a hash or a height")
+		}
+		info, found = chain.GetBlockInfoAt(height)
+	}
+	if !found {
+		return nil, &Error{Code: ErrCodeNotFound, Message: "block not found"}
+	}
+	if !verbose {
+		return hex.EncodeToString(info.Block.Encode()), nil
+	}
+	return newBlockResult(info), nil
+}
+
+// getTransaction 先在交易池中查询交易，再从最新区块开始向前在主链中查询。
+func getTransaction(s *Server, args []json.RawMessage) (interface{}, *Error) {
+	txID, err := requiredString(args[0], "txid")
+	if err != nil {
+		return nil, err
+	}
+	txID = strings.ToUpper(txID)
+	pool := s.network.GetTransactionPool()
+	if tx, ok := pool.Get(txID); ok {
+		result := newTransactionResult(tx)
+		if fee, ok := pool.GetFee(txID); ok {
+			result.Fee = &fee
+		}
+		return result, nil
+	}
+	tx, info, ok := s.network.GetBlockchain().FindTransaction(txID)
+	if !ok {
+		return nil, &Error{Code: ErrCodeNotFound, Message: "transaction not found in the pool or the main chain"}
+	}
+	result := newTransactionResult(tx)
+	result.BlockHash = info.Block.Hash()
+	result.Height = info.Height
+	result.Confirmations = info.GetConfirmations()
+	return result, nil
+}
+
+func getProof(s *Server, args []json.RawMessage) (interface{}, *Error) {
+	txID, err := requiredString(args[0], "txid")
+	if err != nil {
+		return nil, err
+	}
+	txID = strings.ToUpper(txID)
+	// 先确认交易在主链中，GetProof 找不到交易时会输出日志
+	_, info, ok := s.network.GetBlockchain().FindTransaction(txID)
+	if !ok {
+		return nil, &Error{Code: ErrCodeNotFound, Message: "transaction not found in the main chain"}
+	}
+	proof := s.network.GetProof(txID)
+	if proof.GetTxHash() == "" {
+		return nil, &Error{Code: ErrCodeNotFound, Message: "transaction not found in the main chain"}
+	}
+	path := make([]ProofNodeResult, 0, len(proof.GetPath()))
+	for _, node := range proof.GetPath() {
+		orientation := "right"
+		if node.GetOrientation() == spv.LEFT {
+			orientation = "left"
+		}
+		path = append(path, ProofNodeResult{Hash: node.GetTxHash(), Orientation: orientation})
+	}
+	return ProofResult{
+		TxHash:     proof.GetTxHash(),
+		MerkleRoot: proof.GetMerkleRootHash(),
+		Height:     proof.GetHeight(),
+		BlockHash:  info.Block.Hash(),
+		Path:       path,
+	}, nil
+}
+
+func getBalance(s *Server, args []json.RawMessage) (interface{}, *Error) {
+	address, err := requiredString(args[0], "address")
+	if err != nil {
+		return nil, err
+	}
+	return BalanceResult{
+		Address:   address,
+		Confirmed: sumAmount(s.network.GetTrueUTXOs(address)),
+		Spendable: sumAmount(s.network.GetSpendableUTXOs(address)),
+	}, nil
+}
+
+func listUnspent(s *Server, args []json.RawMessage) (interface{}, *Error) {
+	address, err := requiredString(args[0], "address")
+	if err != nil {
+		return nil, err
+	}
+	includeMempool, err := optionalBool(args[1], "includemempool", false)
+	if err != nil {
+		return nil, err
+	}
+	if includeMempool {
+		return newUnspentResults(s.network.GetSpendableUTXOs(address)), nil
+	}
+	return newUnspentResults(s.network.GetTrueUTXOs(address)), nil
+}
+
+func getMempoolInfo(s *Server, _ []json.RawMessage) (interface{}, *Error) {
+	pool := s.network.GetTransactionPool()
+	fees := 0
+	for _, tx := range pool.GetAll() {
+		if fee, ok := pool.GetFee(tx.TxID()); ok {
+			fees += fee
+		}
+	}
+	return MempoolInfoResult{
+		Size:            pool.Count(),
+		Bytes:           pool.Size(),
+		MaxCount:        pool.GetCapacity(),
+		MaxBytes:        pool.GetMaxSize(),
+		MinRelayFeeRate: config.MiniChainConfig.GetMinRelayFeeRate(),
+		Full:            pool.IsFull(),
+		Fees:            fees,
+	}, nil
+}
+
+func getRawMempool(s *Server, _ []json.RawMessage) (interface{}, *Error) {
+	txs := s.network.GetTransactionPool().GetAllByFeeRate()
+	ids := make([]string, len(txs))
+	for i := range txs {
+		ids[i] = txs[i].TxID()
+	}
+	return ids, nil
+}
+
+// sendRawTransaction 解析交易的原始编码并提交到交易池，交易被拒绝时错误的 data 中给出原因类别。
+func sendRawTransaction(s *Server, args []json.RawMessage) (interface{}, *Error) {
+	rawHex, err := requiredString(args[0], "hex")
+	if err != nil {
+		return nil, err
+	}
+	raw, decodeErr := hex.DecodeString(strings.TrimSpace(rawHex))
+	if decodeErr != nil {
+		return nil, &Error{Code: ErrCodeDeserialization, Message: "invalid hex: " + decodeErr.Error()}
+	}
+	tx, decodeErr := data.DecodeTransaction(raw)
+	if decodeErr != nil {
+		return nil, &Error{Code: ErrCodeDeserialization, Message: "decode transaction error: " + decodeErr.Error()}
+	}
+	if acceptErr := s.network.AcceptTransaction(*tx); acceptErr != nil {
+		var rejectErr *network.TxRejectError
+		if errors.As(acceptErr, &rejectErr) {
+			return nil, &Error{Code: ErrCodeRejected, Message: rejectErr.Error(),
+				Data: map[string]interface{}{"kind": rejectErr.Kind.Error(), "input": rejectErr.Input}}
+		}
+		return nil, &Error{Code: ErrCodeInternal, Message: acceptErr.Error()}
+	}
+	return tx.TxID(), nil
+}
+
+func getPeerInfo(s *Server, _ []json.RawMessage) (interface{}, *Error) {
+	peers := make([]PeerResult, 0)
+	if relay := s.network.GetRelay(); relay != nil {
+		for _, p := range relay.GetServer().GetPeers() {
+			peers = append(peers, newPeerResult(p.Info()))
+		}
+	}
+	return peers, nil
+}
+
+// stop 停止节点，响应在节点开始停止之前返回。
+func stop(s *Server, _ []json.RawMessage) (interface{}, *Error) {
+	if s.stop == nil {
+		return nil, &Error{Code: ErrCodeInternal, Message: "stop is not supported"}
+	}
+	go s.stop()
+	return "minichain stopping", nil
+}
+
+// parseParams 将参数数组或参数对象转换为与参数名一一对应的列表，未提供的参数与 null 参数为 nil。
+func parseParams(raw json.RawMessage, names []string) ([]json.RawMessage, *Error) {
+	args := make([]json.RawMessage, len(names))
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 || bytes.Equal(raw, []byte("null")) {
+		return args, nil
+	}
+	switch raw[0] {
+	case '[':
+		var list []json.RawMessage
+		if err := json.Unmarshal(raw, &list); err != nil {
+			return nil, invalidParams("params: " + err.Error())
+		}
+		if len(list) > len(names) {
+			return nil, invalidParams("too many parameters")
+		}
+		copy(args, list)
+	case '{':
+		var named map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &named); err != nil {
+			return nil, invalidParams("params: " + err.Error())
+		}
+		for i, name := range names {
+			args[i] = named[name]
+			delete(named, name)
+		}
+		for name := range named {
+			return nil, invalidParams("unknown parameter " + name)
+		}
+	default:
+		return nil, invalidParams("params must be an array or an object")
+	}
+	for i := range args {
+		args[i] = bytes.TrimSpace(args[i])
+		if len(args[i]) == 0 || bytes.Equal(args[i], []byte("null")) {
+			args[i] = nil
+		}
+	}
+	return args, nil
+}
+
+// requiredString 解析必须提供的非空字符串参数。
+func requiredString(arg json.RawMessage, name string) (string, *Error) {
+	if arg == nil {
+		return "", invalidParams("missing parameter " + name)
+	}
+	var value string
+	if json.Unmarshal(arg, &value) != nil {
+		return "", invalidParams(name + " must be a string")
+	}
+	if value == "" {
+		return "", invalidParams(name + " must not be empty")
+	}
+	return value, nil
+}
+
+// optionalBool 解析可选的布尔参数，未提供时返回默认值。
+func optionalBool(arg json.RawMessage, name string, def bool) (bool, *Error) {
+	if arg == nil {
+		return def, nil
+	}
+	var value bool
+	if json.Unmarshal(arg, &value) != nil {
+		return false, invalidParams(name + " must be a boolean")
+	}
+	return value, nil
+}
+
+func invalidParams(message string) *Error {
+	return &Error{Code: ErrCodeInvalidParams, Message: "invalid params: " + message}
+}
@@ -0,0 +1,296 @@
+package rpc
+
+import (
+	"Go-Minichain/network"
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/**
+ * JSON-RPC 服务
+ *
+ * Server 在 HTTP 上提供 JSON-RPC 2.0 接口，外部工具无需链接 Go 代码即可查询并控制节点。
+ * 请求以 POST 发送到根路径，可以是单个请求对象，也可以是请求数组（批量请求）；
+ * 参数可以按位置以数组传递，也可以按名称以对象传递，方法与参数见 Methods.go。
+ * 不带 id 的请求为通知，服务端执行但不返回结果。
+ * GET /events 以 Server-Sent Events 格式推送区块与交易池的事件，见 Events.go。
+ * 配置了令牌时，全部请求都需要在 Authorization 头中以 "Bearer <令牌>" 的形式携带令牌，否则返回 401；
+ * 没有令牌时任何能连接到监听地址的程序都可以调用 stop 等方法，因此 config 只允许此时监听本机回环地址。
+ * 为了防止操作者打开的网页借助浏览器调用节点：
+ * - 携带 Origin 头的请求（浏览器发出的跨源请求）一律返回 403；
+ * - 没有令牌时 Host 头必须是本机回环地址，否则返回 403，防止 DNS 重绑定把外部域名解析到本机；
+ * - JSON-RPC 请求的 Content-Type 必须是 application/json，否则返回 415，浏览器无法不经预检就发出这样的请求。
+ */
+
+const (
+	// maxRequestSize 请求体的最大字节数。
+	maxRequestSize = 4 << 20
+	// shutdownTimeout 停止服务时等待正在处理的请求完成的最长时间。
+	shutdownTimeout = 5 * time.Second
+)
+
+// JSON-RPC 2.0 规定的错误码，以及节点自定义的错误码。
+const (
+	ErrCodeParse          = -32700
+	ErrCodeInvalidRequest = -32600
+	ErrCodeMethodNotFound = -32601
+	ErrCodeInvalidParams  = -32602
+	ErrCodeInternal       = -32603
+	// ErrCodeNotFound 请求的区块或交易不存在。
+	ErrCodeNotFound = -5
+	// ErrCodeDeserialization 交易的原始编码无法解析。
+	ErrCodeDeserialization = -22
+	// ErrCodeRejected 交易被交易池拒绝。
+	ErrCodeRejected = -26
+	// ErrCodeWarmingUp 节点仍在从存储中恢复区块。
+	ErrCodeWarmingUp = -28
+)
+
+// Error JSON-RPC 错误对象。
+// 字段说明：
+// - Code: 错误码。
+// - Message: 错误说明。
+// - Data: 补充信息，例如交易被拒绝的原因类别。
+type Error struct {
+	Code    int         `json:"code"`
+	Message string      `json:"message"`
+	Data    interface{} `json:"data,omitempty"`
+}
+
+func (e *Error) Error() string {
+	return e.Message + " (code " + strconv.Itoa(e.Code) + ")"
+}
+
+// Request JSON-RPC 请求。
+// 字段说明：
+// - JSONRPC: 协议版本，固定为 "2.0"。
+// - ID: 请求标识，原样返回；缺失时请求为通知。
+// - Method: 方法名。
+// - Params: 参数数组或参数对象。
+type Request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+// Response JSON-RPC 响应，Result 与 Error 只有一个不为空。
+type Response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *Error          `json:"error,omitempty"`
+}
+
+// Server JSON-RPC 服务。
+// 字段说明：
+// - network: 提供数据的区块链网络。
+// - addr: 监听地址。
+// - token: 请求需要携带的令牌，为空时不检查。
+// - stop: stop 方法调用的函数，用于停止节点。
+// - listener: 监听器。
+// - httpServer: HTTP 服务。
+type Server struct {
+	network    *network.NetWork
+	addr       string
+	token      string
+	stop       func()
+	listener   net.Listener
+	httpServer *http.Server
+}
+
+// NewServer 创建 JSON-RPC 服务。
+// 参数:
+// - n: 提供数据的区块链网络。
+// - addr: 监听地址，例如 "127.0.0.1:9332"。
+// - token: 请求需要携带的令牌，为空时不检查，此时 addr 应为本机回环地址。
+// - stop: stop 方法调用的函数，通常是取消节点上下文的函数。
+// 返回值:
+// 返回一个指向新创建的服务的指针。
+func NewServer(n *network.NetWork, addr string, token string, stop func()) *Server {
+	s := &Server{network: n, addr: addr, token: token, stop: stop}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", s.handleHTTP)
+	mux.HandleFunc("/events", s.handleEvents)
+	s.httpServer = &http.Server{Handler: s.guard(mux), ReadHeaderTimeout: 10 * time.Second}
+	return s
+}
+
+// Start 开始监听并在后台处理请求，ctx 被取消时停止服务。
+// 返回值:
+// 无法监听时返回错误。
+func (s *Server) Start(ctx context.Context) error {
+	listener, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		return err
+	}
+	s.listener = listener
+	// 请求的上下文派生自 ctx，停止服务时事件流随之结束，Shutdown 不必等待超时
+	s.httpServer.BaseContext = func(net.Listener) context.Context { return ctx }
+	fmt.Println("JSON-RPC server listening on " + listener.Addr().String())
+	go func() {
+		if err := s.httpServer.Serve(listener); err != nil && err != http.ErrServerClosed {
+			fmt.Println("JSON-RPC server error: " + err.Error())
+		}
+	}()
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		s.httpServer.Shutdown(shutdownCtx)
+	}()
+	return nil
+}
+
+// GetListenAddr 返回实际的监听地址，未开始监听时为空。
+func (s *Server) GetListenAddr() string {
+	if s.listener == nil {
+		return ""
+	}
+	return s.listener.Addr().String()
+}
+
+// guard 在交给 next 处理之前拒绝跨源请求、没有令牌时 Host 不是本机回环地址的请求，以及令牌不符的请求。
+func (s *Server) guard(next http.Handler) http.Handler {
+	expected := []byte("Bearer " + s.token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Origin") != "" {
+			http.Error(w, "cross-origin requests are not allowed", http.StatusForbidden)
+			return
+		}
+		if s.token == "" {
+			if !isLoopbackHost(r.Host) {
+				http.Error(w, "the node must be addressed by a loopback host when no RPC token is configured", http.StatusForbidden)
+				return
+			}
+		} else if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), expected) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="minichain"`)
+			http.Error(w, "missing or invalid RPC token", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// isLoopbackHost 判断 Host 头（可以带端口）是否为 localhost 或本机回环 IP 地址。
+func isLoopbackHost(host string) bool {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// handleHTTP 解析 HTTP 请求体中的单个或批量请求并写回响应。
+func (s *Server) handleHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "JSON-RPC requests must use POST", http.StatusMethodNotAllowed)
+		return
+	}
+	if mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err != nil || mediaType != "application/json" {
+		http.Error(w, "JSON-RPC requests must have Content-Type application/json", http.StatusUnsupportedMediaType)
+		return
+	}
+	var body bytes.Buffer
+	if _, err := body.ReadFrom(http.MaxBytesReader(w, r.Body, maxRequestSize)); err != nil {
+		http.Error(w, "request body too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+	raw := bytes.TrimSpace(body.Bytes())
+	var reply interface{}
+	if len(raw) > 0 && raw[0] == '[' {
+		var batch []json.RawMessage
+		if err := json.Unmarshal(raw, &batch); err != nil {
+			reply = errorResponse(nil, &Error{Code: ErrCodeParse, Message: "parse error: " + err.Error()})
+		} else if len(batch) == 0 {
+			reply = errorResponse(nil, &Error{Code: ErrCodeInvalidRequest, Message: "empty batch"})
+		} else {
+			responses := make([]*Response, 0, len(batch))
+			for _, item := range batch {
+				if resp := s.handleRaw(item); resp != nil {
+					responses = append(responses, resp)
+				}
+			}
+			if len(responses) > 0 {
+				reply = responses
+			}
+		}
+	} else if resp := s.handleRaw(raw); resp != nil {
+		reply = resp
+	}
+	if reply == nil {
+		// 全部为通知时不返回内容
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(reply)
+}
+
+// handleRaw 处理一个请求对象，请求为通知时返回 nil。
+func (s *Server) handleRaw(raw json.RawMessage) *Response {
+	var req Request
+	if err := json.Unmarshal(raw, &req); err != nil {
+		if _, ok := err.(*json.SyntaxError); ok {
+			return errorResponse(nil, &Error{Code: ErrCodeParse, Message: "parse error: " + err.Error()})
+		}
+		return errorResponse(nil, &Error{Code: ErrCodeInvalidRequest, Message: "invalid request: " + err.Error()})
+	}
+	if req.JSONRPC != "2.0" || req.Method == "" {
+		return errorResponse(req.ID, &Error{Code: ErrCodeInvalidRequest, Message: "invalid request: jsonrpc must be \"2.0\" and method is required"})
+	}
+	result, rpcErr := s.call(req.Method, req.Params)
+	if req.ID == nil {
+		return nil
+	}
+	if rpcErr != nil {
+		return errorResponse(req.ID, rpcErr)
+	}
+	return &Response{JSONRPC: "2.0", ID: req.ID, Result: result}
+}
+
+// call 查找并调用方法，节点尚未完成初始化时返回 ErrCodeWarmingUp。
+func (s *Server) call(name string, params json.RawMessage) (interface{}, *Error) {
+	m, ok := methods[name]
+	if !ok {
+		return nil, &Error{Code: ErrCodeMethodNotFound, Message: "method not found: " + name}
+	}
+	select {
+	case <-s.network.Ready():
+	default:
+		if !m.anytime {
+			return nil, &Error{Code: ErrCodeWarmingUp, Message: "loading block chain, try again later"}
+		}
+	}
+	args, err := parseParams(params, m.params)
+	if err != nil {
+		return nil, err
+	}
+	return m.handler(s, args)
+}
+
+// errorResponse 生成错误响应，无法得到请求标识时 id 为 null。
+func errorResponse(id json.RawMessage, err *Error) *Response {
+	if id == nil {
+		id = json.RawMessage("null")
+	}
+	return &Response{JSONRPC: "2.0", ID: id, Error: err}
+}
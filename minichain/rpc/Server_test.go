@@ -0,0 +1,300 @@
+package rpc
+
+import (
+	"Go-Minichain/config"
+	"Go-Minichain/data"
+	"Go-Minichain/network"
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMain(m *testing.M) {
+	// 最低的难度、较少的账户、每个区块只打包一笔交易，使模拟网络可以很快生成几个区块
+	c, err := config.Load("rpc.test", []string{"-difficulty=1", "-nbAccount=10", "-spvEnabled=false",
+		"-minerThreads=1", "-dataDir=", "-maxTransactionCount=2"}, func(string) (string, bool) { return "", false })
+	if err != nil {
+		panic("test config: " + err.Error())
+	}
+	config.MiniChainConfig = c
+	os.Exit(m.Run())
+}
+
+// startServer 启动一个监听系统分配端口的服务，测试结束时停止。
+// 参数:
+// - n: 提供数据的区块链网络，只调用不存在的方法时可以为 nil。
+// - token: 请求需要携带的令牌。
+func startServer(t *testing.T, n *network.NetWork, token string) *Server {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	s := NewServer(n, "127.0.0.1:0", token, cancel)
+	if err := s.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+// startSimulatedServer 模拟生成两个区块，并为该网络启动不要求令牌的服务。
+func startSimulatedServer(t *testing.T) (*Server, *network.NetWork) {
+	t.Helper()
+	n := network.NewSimulatedNetWork(1, network.NewSimulatedClock(network.SimulationEpoch))
+	if err := n.Simulate(2); err != nil {
+		t.Fatal(err)
+	}
+	return startServer(t, n, ""), n
+}
+
+// post 以 application/json 发送请求体，返回 HTTP 状态码与响应体。
+func post(t *testing.T, s *Server, body string, header http.Header) (int, []byte) {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodPost, "http://"+s.GetListenAddr(), strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var reply bytes.Buffer
+	reply.ReadFrom(resp.Body)
+	return resp.StatusCode, reply.Bytes()
+}
+
+// decodeResponse 解析单个响应。
+func decodeResponse(t *testing.T, raw []byte) Response {
+	t.Helper()
+	var resp Response
+	if err := json.Unmarshal(raw, &resp); err != nil {
+		t.Fatalf("decode response %s: %v", raw, err)
+	}
+	return resp
+}
+
+// newPayment 构造一笔由 from 付款给 to 的已签名交易，使用 from 在交易池视图中的第一个 UTXO，多余的金额找零。
+func newPayment(t *testing.T, n *network.NetWork, from data.Account, to data.Account) *data.Transaction {
+	t.Helper()
+	utxos := n.GetSpendableUTXOs(from.GetWalletAddress())
+	if len(utxos) == 0 {
+		t.Fatalf("account %s has nothing to spend", from.GetWalletAddress())
+	}
+	inputs := []*data.TxInput{data.NewTxInput(utxos[0].GetOutpoint(), from.GetPublicKey())}
+	outputs := []*data.UTXO{data.NewUTXO(100, to.GetPublicKey()), data.NewUTXO(utxos[0].GetAmount()-110, from.GetPublicKey())}
+	tx := data.NewTransaction(inputs, outputs)
+	tx.SetTimestamp(int(n.Now().Unix()))
+	tx.Sign(from.GetPrivateKey())
+	return tx
+}
+
+func TestServerRequiresToken(t *testing.T) {
+	s := startServer(t, nil, "secret")
+	for _, token := range []string{"", "wrong"} {
+		client := NewClient(s.GetListenAddr(), time.Second)
+		client.SetToken(token)
+		err := client.Call("nosuchmethod", nil, nil)
+		var rpcErr *Error
+		if err == nil || errors.As(err, &rpcErr) || !strings.Contains(err.Error(), "RPC token") {
+			t.Fatalf("request with token %q returned %v, want it rejected before reaching the method", token, err)
+		}
+	}
+
+	// 事件流同样需要令牌
+	resp, err := http.Get("http://" + s.GetListenAddr() + "/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("event stream without a token returned %s, want 401", resp.Status)
+	}
+
+	client := NewClient(s.GetListenAddr(), time.Second)
+	client.SetToken("secret")
+	var rpcErr *Error
+	if err := client.Call("nosuchmethod", nil, nil); !errors.As(err, &rpcErr) || rpcErr.Code != ErrCodeMethodNotFound {
+		t.Fatalf("request with the right token returned %v, want method not found", err)
+	}
+}
+
+func TestServerRejectsBrowserRequests(t *testing.T) {
+	s := startServer(t, nil, "")
+	body := `{"jsonrpc":"2.0","id":1,"method":"stop"}`
+	for _, tc := range []struct {
+		name   string
+		header http.Header
+		status int
+	}{
+		{"cross-origin", http.Header{"Origin": {"http://example.com"}}, http.StatusForbidden},
+		{"form content type", http.Header{"Content-Type": {"text/plain"}}, http.StatusUnsupportedMediaType},
+		{"missing content type", http.Header{"Content-Type": {""}}, http.StatusUnsupportedMediaType},
+	} {
+		if status, reply := post(t, s, body, tc.header); status != tc.status {
+			t.Errorf("%s: status %d (%s), want %d", tc.name, status, reply, tc.status)
+		}
+	}
+
+	// DNS 重绑定：浏览器发出的同源请求中 Host 为攻击者的域名
+	req, _ := http.NewRequest(http.MethodGet, "http://"+s.GetListenAddr()+"/events", nil)
+	req.Host = "attacker.example:9332"
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("request for a non-loopback host returned %s, want 403", resp.Status)
+	}
+
+	// 命令行客户端的请求不受影响
+	var rpcErr *Error
+	if err := NewClient(s.GetListenAddr(), time.Second).Call("nosuchmethod", nil, nil); !errors.As(err, &rpcErr) {
+		t.Fatalf("client request returned %v, want a JSON-RPC error", err)
+	}
+}
+
+func TestGetBlockByHashAndHeight(t *testing.T) {
+	s, n := startSimulatedServer(t)
+	client := NewClient(s.GetListenAddr(), 5*time.Second)
+	want := n.GetBlocks()[1]
+
+	var byHeight, byHash BlockResult
+	if err := client.Call("getblock", []interface{}{1}, &byHeight); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Call("getblock", []interface{}{strings.ToLower(want.Hash())}, &byHash); err != nil {
+		t.Fatal(err)
+	}
+	if byHeight.Hash != want.Hash() || byHash.Hash != want.Hash() || byHash.Height != 1 {
+		t.Fatalf("getblock returned %s at %d and %s, want %s at 1", byHeight.Hash, byHash.Height, byHash.Hash, want.Hash())
+	}
+
+	// verbose 为 false 时返回区块的原始编码，按名称传递参数
+	_, reply := post(t, s, `{"jsonrpc":"2.0","id":1,"method":"getblock","params":{"block":1,"verbose":false}}`, nil)
+	resp := decodeResponse(t, reply)
+	rawHex, ok := resp.Result.(string)
+	if !ok {
+		t.Fatalf("getblock with verbose false returned %s", reply)
+	}
+	raw, err := hex.DecodeString(rawHex)
+	if err != nil || !bytes.Equal(raw, want.Encode()) {
+		t.Fatalf("raw block does not match the encoded block: %v", err)
+	}
+
+	var rpcErr *Error
+	for _, block := range []interface{}{99, strings.Repeat("0", 64)} {
+		if err := client.Call("getblock", []interface{}{block}, nil); !errors.As(err, &rpcErr) || rpcErr.Code != ErrCodeNotFound {
+			t.Fatalf("getblock %v returned %v, want not found", block, err)
+		}
+	}
+	if err := client.Call("getblock", []interface{}{true}, nil); !errors.As(err, &rpcErr) || rpcErr.Code != ErrCodeInvalidParams {
+		t.Fatalf("getblock with a boolean returned %v, want invalid params", err)
+	}
+}
+
+func TestSendRawTransactionRejection(t *testing.T) {
+	s, n := startSimulatedServer(t)
+	client := NewClient(s.GetListenAddr(), 5*time.Second)
+	accounts := n.GetAccounts()
+	tx := newPayment(t, n, accounts[0], accounts[1])
+	rawHex := hex.EncodeToString(tx.Encode())
+
+	var txID string
+	if err := client.Call("sendrawtransaction", []interface{}{rawHex}, &txID); err != nil {
+		t.Fatal(err)
+	}
+	if txID != tx.TxID() || !n.GetTransactionPool().Has(txID) {
+		t.Fatalf("sendrawtransaction returned %s, want %s in the pool", txID, tx.TxID())
+	}
+
+	// 再次提交同一笔交易被交易池拒绝，data.kind 给出拒绝原因的类别
+	var rpcErr *Error
+	if err := client.Call("sendrawtransaction", []interface{}{rawHex}, nil); !errors.As(err, &rpcErr) || rpcErr.Code != ErrCodeRejected {
+		t.Fatalf("duplicate transaction returned %v, want code %d", err, ErrCodeRejected)
+	}
+	detail, ok := rpcErr.Data.(map[string]interface{})
+	if !ok || detail["kind"] != network.ErrTxInPool.Error() {
+		t.Fatalf("rejection data is %v, want kind %q", rpcErr.Data, network.ErrTxInPool.Error())
+	}
+
+	for _, bad := range []string{"zz", "00"} {
+		if err := client.Call("sendrawtransaction", []interface{}{bad}, nil); !errors.As(err, &rpcErr) || rpcErr.Code != ErrCodeDeserialization {
+			t.Fatalf("sendrawtransaction %q returned %v, want code %d", bad, err, ErrCodeDeserialization)
+		}
+	}
+}
+
+func TestBatchAndNotifications(t *testing.T) {
+	s, n := startSimulatedServer(t)
+	status, reply := post(t, s, `[
+		{"jsonrpc":"2.0","id":1,"method":"getblockcount"},
+		{"jsonrpc":"2.0","method":"getblockcount"},
+		{"jsonrpc":"2.0","id":"b","method":"nosuchmethod"},
+		{"jsonrpc":"2.0","id":3,"method":"getbestblockhash"}
+	]`, nil)
+	if status != http.StatusOK {
+		t.Fatalf("batch returned status %d", status)
+	}
+	var responses []Response
+	if err := json.Unmarshal(reply, &responses); err != nil {
+		t.Fatal(err)
+	}
+	// 通知没有响应，其余响应按请求的顺序返回
+	if len(responses) != 3 {
+		t.Fatalf("batch returned %d responses, want 3: %s", len(responses), reply)
+	}
+	if string(responses[0].ID) != "1" || responses[0].Result != float64(2) {
+		t.Fatalf("first response is %s %v, want id 1 with height 2", responses[0].ID, responses[0].Result)
+	}
+	if string(responses[1].ID) != `"b"` || responses[1].Error == nil || responses[1].Error.Code != ErrCodeMethodNotFound {
+		t.Fatalf("second response is %s %v, want method not found", responses[1].ID, responses[1].Error)
+	}
+	if string(responses[2].ID) != "3" || responses[2].Result != n.GetNewestBlock().Hash() {
+		t.Fatalf("third response is %s %v, want the newest block hash", responses[2].ID, responses[2].Result)
+	}
+
+	// 全部为通知时不返回内容，通知同样会被执行
+	status, reply = post(t, s, `[{"jsonrpc":"2.0","method":"getblockcount"},{"jsonrpc":"2.0","method":"stop"}]`, nil)
+	if status != http.StatusNoContent || len(reply) != 0 {
+		t.Fatalf("notifications returned status %d with %q, want 204 without a body", status, reply)
+	}
+}
+
+func TestParseAndInvalidRequestErrors(t *testing.T) {
+	s := startServer(t, nil, "")
+	for _, tc := range []struct {
+		body string
+		code int
+	}{
+		{`{"jsonrpc":"2.0","id":1,"method":`, ErrCodeParse},
+		{`[{"jsonrpc":"2.0"`, ErrCodeParse},
+		{`[]`, ErrCodeInvalidRequest},
+		{`{"jsonrpc":"1.0","id":1,"method":"getblockcount"}`, ErrCodeInvalidRequest},
+		{`{"jsonrpc":"2.0","id":1}`, ErrCodeInvalidRequest},
+		{`{"jsonrpc":"2.0","id":1,"method":7}`, ErrCodeInvalidRequest},
+	} {
+		status, reply := post(t, s, tc.body, nil)
+		if status != http.StatusOK {
+			t.Fatalf("%s: status %d", tc.body, status)
+		}
+		resp := decodeResponse(t, reply)
+		if resp.Error == nil || resp.Error.Code != tc.code {
+			t.Fatalf("%s: response %s, want code %d", tc.body, reply, tc.code)
+		}
+		// 无法得到请求标识时 id 为 null
+		if tc.code == ErrCodeParse && string(resp.ID) != "null" {
+			t.Fatalf("%s: id is %s, want null", tc.body, resp.ID)
+		}
+	}
+}
@@ -0,0 +1,328 @@
+package rpc
+
+import (
+	"Go-Minichain/data"
+	"Go-Minichain/network"
+	"Go-Minichain/p2p"
+	"encoding/hex"
+)
+
+/**
+ * RPC 方法的返回结果
+ *
+ * 结果以 JSON 对象返回，字段名与 data 包中的结构对应，哈希与交易标识为大写十六进制字符串，
+ * 原始编码（hex 字段）为 data 包规范编码的小写十六进制字符串，可以直接传给 sendrawtransaction。
+ * 命令行客户端（见 minichain-cli）使用相同的结构解析结果。
+ */
+
+// BlockResult getblock 的返回结果。
+// 字段说明：
+// - Hash: 区块哈希。
+// - Height: 区块高度。
+// - Confirmations: 确认数，主链上的最新区块为 1，侧链上的区块为 -1。
+// - Version / PreviousBlockHash / MerkleRoot / Time / Bits / Nonce: 区块头字段。
+// - TxCount: 区块中的交易个数。
+// - Tx: 区块中交易的标识，第一笔为 coinbase 交易。
+// - Size: 区块规范编码的字节数。
+type BlockResult struct {
+	Hash              string   `json:"hash"`
+	Height            int      `json:"height"`
+	Confirmations     int      `json:"confirmations"`
+	Version           int      `json:"version"`
+	PreviousBlockHash string   `json:"previousblockhash"`
+	MerkleRoot        string   `json:"merkleroot"`
+	Time              int64    `json:"time"`
+	Bits              uint32   `json:"bits"`
+	Nonce             int64    `json:"nonce"`
+	TxCount           int      `json:"txcount"`
+	Tx                []string `json:"tx"`
+	Size              int      `json:"size"`
+}
+
+// InputResult 交易输入。
+// 字段说明：
+// - TxID / Vout: 被花费的输出所在的交易标识与输出序号。
+// - Address: 输入公钥对应的钱包地址。
+type InputResult struct {
+	TxID    string `json:"txid"`
+	Vout    int    `json:"vout"`
+	Address string `json:"address"`
+}
+
+// OutputResult 交易输出。
+// 字段说明：
+// - N: 输出序号。
+// - Address: 收款的钱包地址。
+// - Amount: 金额。
+type OutputResult struct {
+	N       int    `json:"n"`
+	Address string `json:"address"`
+	Amount  int    `json:"amount"`
+}
+
+// TransactionResult gettransaction 的返回结果。
+// 字段说明：
+// - TxID: 交易标识。
+// - Size: 交易规范编码的字节数。
+// - Time: 交易的时间戳。
+// - Coinbase: 是否为 coinbase 交易。
+// - Replaceable: 是否允许被手续费更高的交易替换。
+// - Vin / Vout: 交易的输入与输出，coinbase 交易没有列出的输入。
+// - Fee: 手续费，只有交易池中的交易才有。
+// - BlockHash / Height: 交易所在区块的哈希与高度，交易在交易池中时为空与 -1。
+// - Confirmations: 确认数，交易在交易池中时为 0。
+// - Hex: 交易的规范编码。
+type TransactionResult struct {
+	TxID          string         `json:"txid"`
+	Size          int            `json:"size"`
+	Time          int            `json:"time"`
+	Coinbase      bool           `json:"coinbase"`
+	Replaceable   bool           `json:"replaceable"`
+	Vin           []InputResult  `json:"vin"`
+	Vout          []OutputResult `json:"vout"`
+	Fee           *int           `json:"fee,omitempty"`
+	BlockHash     string         `json:"blockhash"`
+	Height        int            `json:"height"`
+	Confirmations int            `json:"confirmations"`
+	Hex           string         `json:"hex"`
+}
+
+// BalanceResult getbalance 的返回结果。
+// 字段说明：
+// - Address: 钱包地址。
+// - Confirmed: 已确认的 UTXO 的金额之和。
+// - Spendable: 叠加交易池中未确认交易之后可以花费的金额之和。
+type BalanceResult struct {
+	Address   string `json:"address"`
+	Confirmed int    `json:"confirmed"`
+	Spendable int    `json:"spendable"`
+}
+
+// UnspentResult listunspent 返回的一个 UTXO。
+// 字段说明：
+// - TxID / Vout: 输出所在的交易标识与输出序号。
+// - Address: 钱包地址。
+// - Amount: 金额。
+type UnspentResult struct {
+	TxID    string `json:"txid"`
+	Vout    int    `json:"vout"`
+	Address string `json:"address"`
+	Amount  int    `json:"amount"`
+}
+
+// MempoolInfoResult getmempoolinfo 的返回结果。
+// 字段说明：
+// - Size: 交易池中的交易个数。
+// - Bytes: 交易池中交易编码的总字节数。
+// - MaxCount / MaxBytes: 交易个数与总字节数的上限。
+// - MinRelayFeeRate: 最低手续费率（每 1000 字节）。
+// - Full: 交易池是否已满。
+// - Fees: 交易池中全部交易的手续费之和。
+type MempoolInfoResult struct {
+	Size            int  `json:"size"`
+	Bytes           int  `json:"bytes"`
+	MaxCount        int  `json:"maxcount"`
+	MaxBytes        int  `json:"maxbytes"`
+	MinRelayFeeRate int  `json:"minrelayfeerate"`
+	Full            bool `json:"full"`
+	Fees            int  `json:"fees"`
+}
+
+// ProofNodeResult Merkle 路径上的一个节点。
+// 字段说明：
+// - Hash: 节点哈希。
+// - Orientation: 节点相对于路径哈希的位置，"left" 或 "right"。
+type ProofNodeResult struct {
+	Hash        string `json:"hash"`
+	Orientation string `json:"orientation"`
+}
+
+// ProofResult getproof 的返回结果。
+// 字段说明：
+// - TxHash: 交易标识。
+// - MerkleRoot: 交易所在区块的 Merkle 根哈希。
+// - Height: 交易所在区块的高度。
+// - BlockHash: 交易所在区块的哈希。
+// - Path: 从交易到 Merkle 根的路径。
+type ProofResult struct {
+	TxHash     string            `json:"txhash"`
+	MerkleRoot string            `json:"merkleroot"`
+	Height     int               `json:"height"`
+	BlockHash  string            `json:"blockhash"`
+	Path       []ProofNodeResult `json:"path"`
+}
+
+// PeerResult getpeerinfo 返回的一个连接。
+// 字段说明：
+// - Addr: 对方的网络地址。
+// - Inbound: 是否为对方发起的连接。
+// - Version / UserAgent: 对方的协议版本与软件名称。
+// - ListenAddr: 对方的监听地址。
+// - StartHeight: 握手时对方主链的高度。
+// - ConnTime: 握手完成的时间（Unix 秒）。
+// - BytesSent / BytesRecv: 发送与接收的字节数。
+// - PingTime: 最近一次 ping 的往返时间（秒）。
+type PeerResult struct {
+	Addr        string  `json:"addr"`
+	Inbound     bool    `json:"inbound"`
+	Version     uint32  `json:"version"`
+	UserAgent   string  `json:"useragent"`
+	ListenAddr  string  `json:"listenaddr"`
+	StartHeight int     `json:"startheight"`
+	ConnTime    int64   `json:"conntime"`
+	BytesSent   uint64  `json:"bytessent"`
+	BytesRecv   uint64  `json:"bytesrecv"`
+	PingTime    float64 `json:"pingtime"`
+}
+
+// BlockchainInfoResult getblockchaininfo 的返回结果。
+// 字段说明：
+// - Height: 主链的高度。
+// - Headers: 区块头链最长分支的高度。
+// - BestBlockHash: 主链最新区块的哈希。
+// - Bits: 下一个区块的难度目标。
+// - TotalWork: 主链的累计工作量（十进制字符串）。
+// - MedianTime: 主链最新区块的过去中位时间。
+// - Syncing: 是否还有区块需要下载。
+// - InFlight / Pending: 同步中已经请求与已经收到但尚未连接的区块个数。
+// - Peers: 连接的节点个数。
+type BlockchainInfoResult struct {
+	Height        int    `json:"height"`
+	Headers       int    `json:"headers"`
+	BestBlockHash string `json:"bestblockhash"`
+	Bits          uint32 `json:"bits"`
+	TotalWork     string `json:"totalwork"`
+	MedianTime    int64  `json:"mediantime"`
+	Syncing       bool   `json:"syncing"`
+	InFlight      int    `json:"inflight"`
+	Pending       int    `json:"pending"`
+	Peers         int    `json:"peers"`
+}
+
+// EventResult 事件流（/events）中一个事件的内容。
+// 字段说明：
+// - Sequence: 事件序号，从 1 开始递增。
+// - Type: 事件类型，见 network.EventType。
+// - Time: 事件发布的时间（Unix 秒）。
+// - BlockHash / Height: 区块事件中的区块哈希与高度。
+// - TxID: 交易事件中的交易标识。
+// - Fee / Size: 交易的手续费与规范编码的字节数。
+// - Reason: 交易离开交易池的原因，或交易因链重组重新进入交易池时的 "reorg"。
+type EventResult struct {
+	Sequence  uint64 `json:"sequence"`
+	Type      string `json:"type"`
+	Time      int64  `json:"time"`
+	BlockHash string `json:"blockhash,omitempty"`
+	Height    *int   `json:"height,omitempty"`
+	TxID      string `json:"txid,omitempty"`
+	Fee       *int   `json:"fee,omitempty"`
+	Size      int    `json:"size,omitempty"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+// newBlockResult 根据区块信息生成 getblock 的返回结果。
+func newBlockResult(info network.BlockInfo) BlockResult {
+	block := info.Block
+	header := block.GetBlockHeader()
+	body := block.GetBlockBody()
+	txs := body.GetTransctions()
+	ids := make([]string, len(txs))
+	for i := range txs {
+		ids[i] = txs[i].TxID()
+	}
+	return BlockResult{
+		Hash:              block.Hash(),
+		Height:            info.Height,
+		Confirmations:     info.GetConfirmations(),
+		Version:           header.GetVersion(),
+		PreviousBlockHash: header.GetPreBlockHash(),
+		MerkleRoot:        header.GetMerkleRootHash(),
+		Time:              header.GetTimestamp(),
+		Bits:              header.GetBits(),
+		Nonce:             header.GetNonce(),
+		TxCount:           len(txs),
+		Tx:                ids,
+		Size:              len(block.Encode()),
+	}
+}
+
+// newTransactionResult 生成 gettransaction 的返回结果，区块与手续费信息由调用方填写。
+func newTransactionResult(tx data.Transaction) TransactionResult {
+	// coinbase 交易的输入不引用任何输出，不列出
+	vin := make([]InputResult, 0, len(tx.GetInputs()))
+	for _, in := range tx.GetInputs() {
+		if tx.IsCoinbase() {
+			break
+		}
+		outpoint := in.GetOutpoint()
+		vin = append(vin, InputResult{TxID: outpoint.GetTxID(), Vout: outpoint.GetIndex(), Address: in.GetWalletAddress()})
+	}
+	vout := make([]OutputResult, 0, len(tx.GetOutUTXOs()))
+	for i, utxo := range tx.GetOutUTXOs() {
+		vout = append(vout, OutputResult{N: i, Address: utxo.GetWalletAddress(), Amount: utxo.GetAmount()})
+	}
+	return TransactionResult{
+		TxID:        tx.TxID(),
+		Size:        tx.Size(),
+		Time:        tx.GetTimeStamp(),
+		Coinbase:    tx.IsCoinbase(),
+		Replaceable: tx.IsReplaceable(),
+		Vin:         vin,
+		Vout:        vout,
+		Height:      -1,
+		Hex:         hex.EncodeToString(tx.Encode()),
+	}
+}
+
+// newUnspentResults 生成 listunspent 的返回结果。
+func newUnspentResults(utxos []*data.UTXO) []UnspentResult {
+	results := make([]UnspentResult, len(utxos))
+	for i, utxo := range utxos {
+		outpoint := utxo.GetOutpoint()
+		results[i] = UnspentResult{TxID: outpoint.GetTxID(), Vout: outpoint.GetIndex(), Address: utxo.GetWalletAddress(), Amount: utxo.GetAmount()}
+	}
+	return results
+}
+
+// newPeerResult 生成 getpeerinfo 返回的一个连接。
+func newPeerResult(info p2p.PeerInfo) PeerResult {
+	return PeerResult{
+		Addr:        info.Addr,
+		Inbound:     info.Inbound,
+		Version:     info.Version,
+		UserAgent:   info.UserAgent,
+		ListenAddr:  info.ListenAddr,
+		StartHeight: info.StartHeight,
+		ConnTime:    info.ConnectedAt.Unix(),
+		BytesSent:   info.BytesSent,
+		BytesRecv:   info.BytesReceived,
+		PingTime:    info.PingTime.Seconds(),
+	}
+}
+
+// newEventResult 生成事件流中的一个事件，区块事件只包含区块字段，交易事件只包含交易字段。
+func newEventResult(event network.Event) EventResult {
+	result := EventResult{Sequence: event.Sequence, Type: string(event.Type), Time: event.Time.Unix()}
+	switch event.Type {
+	case network.EventBlockConnected, network.EventBlockDisconnected:
+		height := event.Height
+		result.BlockHash = event.BlockHash
+		result.Height = &height
+	default:
+		fee := event.Fee
+		result.TxID = event.TxID
+		result.Fee = &fee
+		result.Size = event.Size
+		result.Reason = event.Reason
+	}
+	return result
+}
+
+// sumAmount 返回 UTXO 的金额之和。
+func sumAmount(utxos []*data.UTXO) int {
+	total := 0
+	for _, utxo := range utxos {
+		total += utxo.GetAmount()
+	}
+	return total
+}
@@ -0,0 +1,95 @@
+package store
+
+import (
+	"Go-Minichain/data"
+	"Go-Minichain/utils"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+// LoadAccounts 从数据目录中读取已保存的账户。
+// 账户文件不存在时返回空列表，表示这是一个全新的数据目录。
+// 参数:
+// - dir: 数据目录。
+// 返回值:
+// 返回账户列表以及可能出现的错误。
+func LoadAccounts(dir string) ([]data.Account, error) {
+	return readAccounts(filepath.Join(dir, accountFileName))
+}
+
+// SaveAccounts 将账户私钥以十六进制形式写入数据目录。
+// 参数:
+// - dir: 数据目录。
+// - accounts: 需要保存的账户列表。
+// 返回值:
+// 返回可能出现的错误。
+func SaveAccounts(dir string, accounts []data.Account) error {
+	return writeAccounts(dir, accountFileName, accounts)
+}
+
+// LoadMinerAccount 从数据目录中读取矿工账户。
+// 参数:
+// - dir: 数据目录。
+// 返回值:
+// 返回矿工账户，文件不存在时为 nil，以及可能出现的错误。
+func LoadMinerAccount(dir string) (*data.Account, error) {
+	accounts, err := readAccounts(filepath.Join(dir, minerFileName))
+	if err != nil || len(accounts) == 0 {
+		return nil, err
+	}
+	return &accounts[0], nil
+}
+
+// SaveMinerAccount 将矿工账户私钥写入数据目录，使重启后的矿工仍能花费此前获得的挖矿奖励。
+// 参数:
+// - dir: 数据目录。
+// - account: 矿工账户。
+// 返回值:
+// 返回可能出现的错误。
+func SaveMinerAccount(dir string, account data.Account) error {
+	return writeAccounts(dir, minerFileName, []data.Account{account})
+}
+
+// readAccounts 读取以十六进制私钥数组保存的账户文件，文件不存在时返回空列表。
+func readAccounts(path string) ([]data.Account, error) {
+	raw, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return []data.Account{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]string, 0)
+	if err := json.Unmarshal(raw, &keys); err != nil {
+		return nil, err
+	}
+	accounts := make([]data.Account, len(keys))
+	for i, key := range keys {
+		d, err := hex.DecodeString(key)
+		if err != nil {
+			return nil, err
+		}
+		privateKey, publicKey := utils.Secp256k1FromBytes(d)
+		accounts[i] = data.Account{PublicKey: publicKey, PrivateKey: privateKey}
+	}
+	return accounts, nil
+}
+
+// writeAccounts 将账户私钥以十六进制数组写入数据目录中的指定文件。
+func writeAccounts(dir string, fileName string, accounts []data.Account) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	keys := make([]string, len(accounts))
+	for i, account := range accounts {
+		keys[i] = hex.EncodeToString(account.GetPrivateKey().D.Bytes())
+	}
+	raw, err := json.MarshalIndent(keys, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, fileName), raw, 0o600)
+}
@@ -0,0 +1,45 @@
+package store
+
+import (
+	"Go-Minichain/data"
+	"os"
+	"path/filepath"
+)
+
+/**
+ * 区块存储后端
+ *
+ * BlockChain 通过该接口读写区块，从而可以在内存存储与磁盘存储之间切换，
+ * 使用磁盘存储时节点重启后能够重新加载已有的区块链并从最新区块继续挖矿
+ */
+
+// BlockStore 定义了区块存储后端需要实现的方法。
+type BlockStore interface {
+	// Append 按顺序追加一个新区块。
+	Append(block data.Block) error
+	// Load 按写入顺序返回已存储的全部区块。
+	Load() ([]data.Block, error)
+	// Close 释放存储后端占用的资源。
+	Close() error
+}
+
+const (
+	blockFileName   = "blocks.dat"
+	accountFileName = "accounts.json"
+	minerFileName   = "miner.json"
+)
+
+// Open 根据数据目录打开区块存储后端。
+// 参数:
+// - dir: 数据目录，为空时使用内存存储。
+// 返回值:
+// 返回区块存储后端以及可能出现的错误。
+func Open(dir string) (BlockStore, error) {
+	if dir == "" {
+		return NewMemoryStore(), nil
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return NewFileStore(filepath.Join(dir, blockFileName))
+}
@@ -0,0 +1,131 @@
+package store
+
+import (
+	"Go-Minichain/data"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// FileStore 是一个只追加写入的文件存储。
+// 每个区块按规范二进制编码后以一条记录写入文件末尾，记录格式为：4字节大端长度 + 区块编码。
+// 加载时若发现文件末尾存在因异常退出而写了一半的记录，会将其截断丢弃，之前的区块照常加载；
+// 损坏的记录之后仍有数据时不做任何修改，返回 ErrCorruptRecord，由运维人员决定如何处理。
+type FileStore struct {
+	file  *os.File
+	mutex sync.Mutex
+}
+
+// ErrCorruptRecord 表示区块文件中间的记录已损坏，其后还有数据，不能当作写了一半的末尾截断。
+var ErrCorruptRecord = errors.New("corrupt record in block file")
+
+// NewFileStore 打开（不存在时创建）指定路径的区块文件。
+// 参数:
+// - path: 区块文件路径。
+// 返回值:
+// 返回文件存储实例以及可能出现的错误。
+func NewFileStore(path string) (*FileStore, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return &FileStore{file: file}, nil
+}
+
+// Append 将区块编码后追加到文件末尾，并同步到磁盘。
+func (s *FileStore) Append(block data.Block) error {
+	payload := block.Encode()
+	record := make([]byte, 4+len(payload))
+	binary.BigEndian.PutUint32(record, uint32(len(payload)))
+	copy(record[4:], payload)
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if _, err := s.file.Seek(0, io.SeekEnd); err != nil {
+		return err
+	}
+	if _, err := s.file.Write(record); err != nil {
+		return err
+	}
+	return s.file.Sync()
+}
+
+// Load 从文件头开始依次读取全部区块。
+func (s *FileStore) Load() ([]data.Block, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	info, err := s.file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.file.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	blocks := make([]data.Block, 0)
+	offset := int64(0)
+	lengthBuf := make([]byte, 4)
+	for {
+		if _, err := io.ReadFull(s.file, lengthBuf); err != nil {
+			if errors.Is(err, io.EOF) {
+				return blocks, nil
+			}
+			return blocks, s.truncate(offset, err)
+		}
+		// 先检查长度再分配内存，损坏的长度字段不会导致分配过大的缓冲区
+		length := int64(binary.BigEndian.Uint32(lengthBuf))
+		remaining := info.Size() - offset - 4
+		if length > remaining {
+			return blocks, s.truncateTornTail(offset, length, remaining)
+		}
+		payload := make([]byte, length)
+		if _, err := io.ReadFull(s.file, payload); err != nil {
+			return blocks, err
+		}
+		block, err := data.DecodeBlock(payload)
+		if err != nil {
+			if length < remaining {
+				return blocks, fmt.Errorf("%w at offset %d: %v", ErrCorruptRecord, offset, err)
+			}
+			return blocks, s.file.Truncate(offset)
+		}
+		blocks = append(blocks, *block)
+		offset += 4 + length
+	}
+}
+
+// truncate 丢弃从 offset 开始的不完整记录。
+func (s *FileStore) truncate(offset int64, cause error) error {
+	if !errors.Is(cause, io.ErrUnexpectedEOF) {
+		return cause
+	}
+	return s.file.Truncate(offset)
+}
+
+// truncateTornTail 处理长度超出文件剩余部分的记录。
+// 写了一半的记录只包含区块编码的前一部分，解码时数据提前结束；
+// 若剩余数据能解码出完整的区块或出现其他错误，说明损坏的是长度字段而不是末尾，不做截断。
+// 参数:
+// - offset: 记录的起始位置。
+// - length: 长度字段记录的区块编码长度。
+// - remaining: 长度字段之后剩余的字节数。
+func (s *FileStore) truncateTornTail(offset, length, remaining int64) error {
+	rest := make([]byte, remaining)
+	if _, err := io.ReadFull(s.file, rest); err != nil {
+		return err
+	}
+	if _, err := data.DecodeBlock(rest); !errors.Is(err, data.ErrUnexpectedEnd) {
+		return fmt.Errorf("%w at offset %d: length %d exceeds the %d remaining bytes", ErrCorruptRecord, offset,
+			length, remaining)
+	}
+	return s.file.Truncate(offset)
+}
+
+// Close 关闭区块文件。
+func (s *FileStore) Close() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.file.Close()
+}
@@ -0,0 +1,150 @@
+package store
+
+import (
+	"Go-Minichain/data"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// testBlock 返回一个以 preBlockHash 为前一个区块、不包含交易的区块。
+func testBlock(preBlockHash string, nonce int64) data.Block {
+	body := data.NewBlockBody(data.ComputeMerkleRootHash(nil), nil)
+	header := data.NewBlockHeader(preBlockHash, body.GetMerkleRootHash(), nonce)
+	return *data.NewBlock(*header, *body)
+}
+
+// appendBlocks 在新的区块文件中写入两个区块，返回文件路径与写入的区块。
+func appendBlocks(t *testing.T) (string, []data.Block) {
+	path := filepath.Join(t.TempDir(), blockFileName)
+	s, err := NewFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := testBlock("", 1)
+	blocks := []data.Block{first, testBlock(first.Hash(), 2)}
+	for _, block := range blocks {
+		if err := s.Append(block); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return path, blocks
+}
+
+// appendRaw 向文件末尾追加原始字节。
+func appendRaw(t *testing.T, path string, raw []byte) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if _, err := file.Write(raw); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// loadAfterCorruption 重新打开区块文件并加载，检查只恢复了完整的区块且损坏的末尾被截断。
+func loadAfterCorruption(t *testing.T, path string, blocks []data.Block, validSize int64) {
+	s, err := NewFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	loaded, err := s.Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if len(loaded) != len(blocks) {
+		t.Fatalf("loaded %d blocks, want %d", len(loaded), len(blocks))
+	}
+	for i := range blocks {
+		if loaded[i].Hash() != blocks[i].Hash() {
+			t.Fatalf("block %d: hash %s, want %s", i, loaded[i].Hash(), blocks[i].Hash())
+		}
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size() != validSize {
+		t.Fatalf("file size %d after load, want %d", info.Size(), validSize)
+	}
+}
+
+func TestFileStoreTruncatesTornRecord(t *testing.T) {
+	path, blocks := appendBlocks(t)
+	info, _ := os.Stat(path)
+	// 异常退出时最后一条记录只写入了长度与区块编码的前一半
+	torn := testBlock(blocks[1].Hash(), 3)
+	payload := torn.Encode()
+	header := make([]byte, 4)
+	binary.BigEndian.PutUint32(header, uint32(len(payload)))
+	appendRaw(t, path, append(header, payload[:len(payload)/2]...))
+	loadAfterCorruption(t, path, blocks, info.Size())
+}
+
+func TestFileStoreTruncatesUndecodableRecord(t *testing.T) {
+	path, blocks := appendBlocks(t)
+	info, _ := os.Stat(path)
+	header := make([]byte, 4)
+	binary.BigEndian.PutUint32(header, 3)
+	appendRaw(t, path, append(header, 1, 2, 3))
+	loadAfterCorruption(t, path, blocks, info.Size())
+}
+
+func TestFileStoreTruncatesPartialRecord(t *testing.T) {
+	path, blocks := appendBlocks(t)
+	info, _ := os.Stat(path)
+	appendRaw(t, path, []byte{0, 0})
+	loadAfterCorruption(t, path, blocks, info.Size())
+}
+
+// corruptFirstRecord 用 edit 修改区块文件中第一条记录的长度字段与区块编码。
+func corruptFirstRecord(t *testing.T, path string, edit func(record []byte)) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edit(raw)
+	if err := os.WriteFile(path, raw, 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFileStoreRejectsMidFileCorruption(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		edit func(record []byte)
+	}{
+		{"undecodable payload", func(record []byte) { record[4] = 0xFF }},
+		{"length too long", func(record []byte) {
+			binary.BigEndian.PutUint32(record, binary.BigEndian.Uint32(record)+1)
+		}},
+		{"length past end of file", func(record []byte) { binary.BigEndian.PutUint32(record, 0xFFFFFFFF) }},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			path, _ := appendBlocks(t)
+			corruptFirstRecord(t, path, tc.edit)
+			before, _ := os.ReadFile(path)
+
+			s, err := NewFileStore(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer s.Close()
+			// 损坏的记录之后还有完整的区块，不能截断文件丢弃它们
+			if _, err := s.Load(); !errors.Is(err, ErrCorruptRecord) {
+				t.Fatalf("Load returned %v, want %v", err, ErrCorruptRecord)
+			}
+			if after, _ := os.ReadFile(path); !bytes.Equal(after, before) {
+				t.Fatalf("Load modified the block file: %d bytes, want %d", len(after), len(before))
+			}
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package store
+
+import (
+	"Go-Minichain/data"
+	"sync"
+)
+
+// MemoryStore 将区块保存在内存中，进程退出后数据即丢失。
+type MemoryStore struct {
+	blocks []data.Block
+	mutex  sync.Mutex
+}
+
+// NewMemoryStore 创建一个新的内存区块存储。
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{blocks: make([]data.Block, 0)}
+}
+
+// Append 追加一个新区块。
+func (s *MemoryStore) Append(block data.Block) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.blocks = append(s.blocks, block)
+	return nil
+}
+
+// Load 返回已存储的全部区块的副本。
+func (s *MemoryStore) Load() ([]data.Block, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	blocks := make([]data.Block, len(s.blocks))
+	copy(blocks, s.blocks)
+	return blocks, nil
+}
+
+// Close 内存存储无需释放资源。
+func (s *MemoryStore) Close() error {
+	return nil
+}
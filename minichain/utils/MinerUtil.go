@@ -1,7 +1,7 @@
 package utils
 
 import (
-	"Go-Minichain/config"
+	"math/big"
 	"math/rand"
 )
 
@@ -13,10 +13,85 @@ func RandomString(length int) string {
 	}
 	return string(b)
 }
-func HashPrefixTarget() string {
-	result := ""
-	for i := 0; i < config.MiniChainConfig.GetDifficulty(); i++ {
-		result += "0"
+
+/**
+ * 工作量证明的目标值
+ *
+ * 区块哈希视为 256 位无符号整数，不大于目标值时满足工作量证明。
+ * 区块头中以紧凑格式（bits）保存目标值：最高字节为目标值的字节数（指数），低 3 字节为目标值的最高 3 个字节（尾数），
+ * 尾数的最高位为符号位，目标值总是正数。
+ */
+
+// CompactToBig 将紧凑格式转换为目标值。
+// 参数:
+// - compact: 紧凑格式的目标值。
+// 返回值:
+// 返回目标值。
+func CompactToBig(compact uint32) *big.Int {
+	mantissa := compact & 0x007fffff
+	negative := compact&0x00800000 != 0
+	exponent := uint(compact >> 24)
+
+	var n *big.Int
+	if exponent <= 3 {
+		mantissa >>= 8 * (3 - exponent)
+		n = big.NewInt(int64(mantissa))
+	} else {
+		n = big.NewInt(int64(mantissa))
+		n.Lsh(n, 8*(exponent-3))
+	}
+	if negative {
+		n.Neg(n)
+	}
+	return n
+}
+
+// BigToCompact 将目标值转换为紧凑格式，只保留最高 3 个字节的精度。
+// 参数:
+// - n: 目标值。
+// 返回值:
+// 返回紧凑格式的目标值。
+func BigToCompact(n *big.Int) uint32 {
+	if n.Sign() == 0 {
+		return 0
+	}
+	abs := new(big.Int).Abs(n)
+	exponent := uint(len(abs.Bytes()))
+	var mantissa uint32
+	if exponent <= 3 {
+		mantissa = uint32(abs.Uint64()) << (8 * (3 - exponent))
+	} else {
+		mantissa = uint32(new(big.Int).Rsh(abs, 8*(exponent-3)).Uint64())
+	}
+	// 尾数的最高位是符号位，被占用时将尾数右移一个字节并增加指数
+	if mantissa&0x00800000 != 0 {
+		mantissa >>= 8
+		exponent++
 	}
-	return result
+	compact := uint32(exponent<<24) | mantissa
+	if n.Sign() < 0 {
+		compact |= 0x00800000
+	}
+	return compact
+}
+
+// LeadingZerosTarget 返回要求区块哈希十六进制表示至少以 zeros 个 0 开头的目标值，即 2^(256-4*zeros)-1。
+func LeadingZerosTarget(zeros int) *big.Int {
+	target := new(big.Int).Lsh(big.NewInt(1), uint(256-4*zeros))
+	return target.Sub(target, big.NewInt(1))
+}
+
+// HashToBig 将十六进制表示的区块哈希转换为 256 位无符号整数，格式错误时返回 nil。
+func HashToBig(hash string) *big.Int {
+	n, ok := new(big.Int).SetString(hash, 16)
+	if !ok {
+		return nil
+	}
+	return n
+}
+
+// CheckProofOfWork 判断十六进制表示的区块哈希是否不大于目标值。
+func CheckProofOfWork(hash string, target *big.Int) bool {
+	n := HashToBig(hash)
+	return n != nil && n.Cmp(target) <= 0
 }
@@ -0,0 +1,121 @@
+package utils
+
+import (
+	"math/big"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// bigHex 将十六进制字符串转换为整数，前缀 - 表示负数。
+func bigHex(t *testing.T, s string) *big.Int {
+	t.Helper()
+	n, ok := new(big.Int).SetString(s, 16)
+	if !ok {
+		t.Fatalf("bad hex %s", s)
+	}
+	return n
+}
+
+func TestCompactRoundTrip(t *testing.T) {
+	for _, tc := range []struct {
+		compact uint32
+		target  string
+	}{
+		{0x00000000, "0"},
+		{0x01120000, "12"},
+		{0x02123400, "1234"},
+		{0x03123456, "123456"},
+		{0x04123456, "12345600"},
+		{0x04923456, "-12345600"},
+		// 最高位为 1 的尾数会被当作符号位，因此右移一个字节并增加指数
+		{0x02008000, "80"},
+		{0x05009234, "92340000"},
+		{0x1d00ffff, "ffff" + strings.Repeat("0", 52)},
+		{0x1f0fffff, "fffff" + strings.Repeat("0", 56)},
+	} {
+		target := bigHex(t, tc.target)
+		if got := CompactToBig(tc.compact); got.Cmp(target) != 0 {
+			t.Errorf("CompactToBig(%08x) = %x, want %x", tc.compact, got, target)
+		}
+		if got := BigToCompact(target); got != tc.compact {
+			t.Errorf("BigToCompact(%x) = %08x, want %08x", target, got, tc.compact)
+		}
+	}
+
+	// 非规范的紧凑格式解码后再编码得到规范形式
+	for compact, canonical := range map[uint32]uint32{
+		0x01123456: 0x01120000, // 指数不大于 3 时丢弃超出的字节
+		0x01003456: 0x00000000,
+		0x00800000: 0x00000000, // 尾数为 0 时忽略符号位
+		0x04003456: 0x03345600,
+	} {
+		if got := BigToCompact(CompactToBig(compact)); got != canonical {
+			t.Errorf("%08x re-encodes to %08x, want %08x", compact, got, canonical)
+		}
+	}
+
+	// 编码只保留最高 3 个字节的精度，目标值被截断而不是进位
+	target := bigHex(t, "123456789a")
+	if got := CompactToBig(BigToCompact(target)); got.Cmp(bigHex(t, "1234560000")) != 0 {
+		t.Errorf("%x round-trips to %x, want it truncated to 1234560000", target, got)
+	}
+}
+
+func TestCompactOverflowAndNegative(t *testing.T) {
+	// 指数超过 32 字节时目标值超过任何 256 位哈希
+	maxHash := LeadingZerosTarget(0)
+	overflow := CompactToBig(0xff123456)
+	if overflow.Cmp(maxHash) <= 0 {
+		t.Fatalf("target of ff123456 is %x, want it above every 256-bit hash", overflow)
+	}
+	if got := BigToCompact(overflow); got != 0xff123456 {
+		t.Fatalf("overflowing target re-encodes to %08x", got)
+	}
+
+	// 带符号位的目标值为负数，没有哈希满足工作量证明
+	negative := CompactToBig(0x1f8fffff)
+	if negative.Sign() >= 0 {
+		t.Fatalf("target of 1f8fffff is %x, want a negative number", negative)
+	}
+	if CheckProofOfWork(strings.Repeat("0", 64), negative) {
+		t.Fatal("a zero hash satisfies a negative target")
+	}
+}
+
+func TestLeadingZerosTargetMatchesHashPrefix(t *testing.T) {
+	for zeros := 1; zeros <= 3; zeros++ {
+		target := LeadingZerosTarget(zeros)
+		prefix := strings.Repeat("0", zeros)
+		if highest := prefix + strings.Repeat("F", 64-zeros); !CheckProofOfWork(highest, target) {
+			t.Fatalf("%d zeros: %s does not satisfy the target", zeros, highest)
+		}
+		if lowestAbove := strings.Repeat("0", zeros-1) + "1" + strings.Repeat("0", 64-zeros); CheckProofOfWork(lowestAbove, target) {
+			t.Fatalf("%d zeros: %s satisfies the target", zeros, lowestAbove)
+		}
+
+		// 区块头中的目标值经过紧凑格式编码，尾数最高位为 1 时只保留最高 2 个字节，比十六进制前缀的要求略严格
+		compact := CompactToBig(BigToCompact(target))
+		gap := new(big.Int).Sub(target, compact)
+		if gap.Sign() < 0 || gap.Cmp(new(big.Int).Rsh(target, 16)) > 0 {
+			t.Fatalf("%d zeros: compact target %x is not within 2^-16 below %x", zeros, compact, target)
+		}
+		boundary := compact.Text(16)
+		boundary = strings.Repeat("0", 64-len(boundary)) + boundary
+		above := new(big.Int).Add(compact, big.NewInt(1)).Text(16)
+		above = strings.Repeat("0", 64-len(above)) + above
+		if !CheckProofOfWork(boundary, compact) || CheckProofOfWork(above, compact) {
+			t.Fatalf("%d zeros: hashes next to the compact target %s are misclassified", zeros, boundary)
+		}
+		// 除去两者之间可以忽略的差距，紧凑格式的目标值与十六进制前缀的判断一致
+		for i := 0; i < 2000; i++ {
+			hash := GetSha256Digest(strconv.Itoa(i))
+			if CheckProofOfWork(hash, compact) != strings.HasPrefix(hash, prefix) {
+				t.Fatalf("%d zeros: hash %s disagrees with the hex prefix rule", zeros, hash)
+			}
+		}
+	}
+	if CheckProofOfWork("not a hash", LeadingZerosTarget(0)) {
+		t.Fatal("a malformed hash satisfies the proof of work")
+	}
+}
@@ -25,3 +25,8 @@ func Sha256Digest(data []byte) []byte {
 	}
 	return hash.Sum(nil)
 }
+
+// GetBytesSha256Digest 计算字节序列的 SHA-256 哈希值，返回大写十六进制字符串。
+func GetBytesSha256Digest(data []byte) string {
+	return strings.ToUpper(hex.EncodeToString(Sha256Digest(data)))
+}
@@ -2,9 +2,13 @@ package utils
 
 import (
 	"crypto/ecdsa"
+	"crypto/elliptic"
 	"crypto/rand"
-	"fmt"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"github.com/dustinxie/ecc"
+	"math/big"
 )
 
 /**
@@ -14,7 +18,7 @@ import (
  */
 
 func Byte2HexString(data []byte) string {
-	return fmt.Sprint(data)
+	return hex.EncodeToString(data)
 }
 
 /**
@@ -46,25 +50,106 @@ func Secp256k1Generate() (*ecdsa.PrivateKey, ecdsa.PublicKey) {
 	return privateKey, privateKey.PublicKey
 }
 
+/**
+ * 根据种子确定性地派生secp256k1密钥对，相同的种子总是得到相同的密钥，用于可复现的模拟运行
+ * 私钥标量为种子的SHA-256摘要对曲线阶减一取模后加一，保证落在 [1, N-1] 内
+ * @param seed 种子字节序列
+ * @return
+ */
+
+func Secp256k1Derive(seed []byte) (*ecdsa.PrivateKey, ecdsa.PublicKey) {
+	n := new(big.Int).Sub(ecc.P256k1().Params().N, big.NewInt(1))
+	d := new(big.Int).SetBytes(Sha256Digest(seed))
+	d.Mod(d, n).Add(d, big.NewInt(1))
+	return Secp256k1FromBytes(d.Bytes())
+}
+
+/**
+ * 根据私钥标量恢复secp256k1密钥对，用于从磁盘重新加载账户
+ * @param d 私钥标量的大端字节序列
+ * @return
+ */
+
+func Secp256k1FromBytes(d []byte) (*ecdsa.PrivateKey, ecdsa.PublicKey) {
+	p256k1 := ecc.P256k1()
+	privateKey := new(ecdsa.PrivateKey)
+	privateKey.Curve = p256k1
+	privateKey.D = new(big.Int).SetBytes(d)
+	privateKey.PublicKey.X, privateKey.PublicKey.Y = p256k1.ScalarBaseMult(d)
+	return privateKey, privateKey.PublicKey
+}
+
+/**
+ * 公钥序列化为未压缩格式的字节序列
+ * @param publicKey
+ * @return
+ */
+
+func MarshalPublicKey(publicKey ecdsa.PublicKey) []byte {
+	return elliptic.Marshal(publicKey, publicKey.X, publicKey.Y)
+}
+
+/**
+ * 从未压缩格式的字节序列解析secp256k1公钥
+ * @param data
+ * @return
+ */
+
+func UnmarshalPublicKey(data []byte) (ecdsa.PublicKey, error) {
+	p256k1 := ecc.P256k1()
+	x, y := elliptic.Unmarshal(p256k1, data)
+	if x == nil {
+		return ecdsa.PublicKey{}, errors.New("invalid secp256k1 public key")
+	}
+	return ecdsa.PublicKey{Curve: p256k1, X: x, Y: y}, nil
+}
+
+/**
+ * zeroReader 不断返回零字节的随机源
+ */
+
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = 0
+	}
+	return len(p), nil
+}
+
 /**
  * 私钥签名
+ * 先对数据做SHA-256摘要再签名，ECDSA只会使用与曲线阶等长的前32字节，直接签名原始数据会忽略其余部分
+ * 签名使用的随机数由私钥与摘要经SHA-512派生（随机源固定为零字节），同一私钥对同一数据的签名总是相同，
+ * 因此交易标识可以复现，也不会因为随机源质量差而泄露私钥
+ * (r, N-s) 同样是有效的签名，为了让签名只有一种合法形式，s 总是取 [1, N/2] 内的值（low-S）
  * @param data 签名数据
  * @param privateKey 签名私钥
- * @return 签名后的比特数据
+ * @return 签名后的比特数据，依次为32字节的r与s
  */
 
 func Signature(data []byte, privateKey *ecdsa.PrivateKey) []byte {
 	// sign message
-	//hash := sha256.Sum256(data)
-	sig, err := ecc.SignBytes(privateKey, data[:], ecc.Normal)
+	hash := sha256.Sum256(data)
+	r, s, _, err := ecc.Sign(zeroReader{}, privateKey, hash[:])
 	if err != nil {
 		panic("Signature Message Error...")
 	}
+	params := privateKey.Curve.Params()
+	if s.Cmp(new(big.Int).Rsh(params.N, 1)) > 0 {
+		s.Sub(params.N, s)
+	}
+	size := (params.BitSize + 7) / 8
+	sig := make([]byte, 2*size)
+	r.FillBytes(sig[:size])
+	s.FillBytes(sig[size:])
 	return sig
 }
 
 /**
  * 公钥验签
+ * 只接受 64 字节的 r 与 s 且 s 不超过 N/2 的签名，第三方无法在不使签名失效的情况下改写签名，
+ * 交易标识（包含签名）因此不可被篡改
  * @param data  签名数据
  * @param publicKey 验签公钥
  * @param sign 签名数据
@@ -72,5 +157,6 @@ func Signature(data []byte, privateKey *ecdsa.PrivateKey) []byte {
  */
 
 func Verify(data []byte, sign []byte, publicKey *ecdsa.PublicKey) bool {
-	return ecc.VerifyBytes(publicKey, data[:], sign, ecc.Normal)
+	hash := sha256.Sum256(data)
+	return ecc.VerifyBytes(publicKey, hash[:], sign, ecc.LowerS)
 }
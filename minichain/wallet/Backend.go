@@ -0,0 +1,69 @@
+package wallet
+
+import (
+	"Go-Minichain/data"
+)
+
+// Coin 钱包可以花费的一个输出。
+// 字段说明：
+// - Outpoint: 输出的位置。
+// - Address: 输出所属的钱包地址，花费时使用该地址对应的私钥签名。
+// - Amount: 输出的金额。
+type Coin struct {
+	Outpoint data.Outpoint
+	Address  string
+	Amount   int
+}
+
+// Backend 定义了钱包读取 UTXO 集合与提交交易的一方，可以是同一进程中的节点，也可以是通过 JSON-RPC 访问的节点。
+type Backend interface {
+	// ListUnspent 返回地址当前可以花费的输出：已确认的 UTXO 与交易池中未确认交易的找零，
+	// 不包括已经被交易池中的交易花费的输出。
+	ListUnspent(address string) ([]Coin, error)
+	// GetFeeRate 返回节点接收交易要求的最低手续费率，单位为每 1000 字节的手续费。
+	GetFeeRate() (int, error)
+	// Broadcast 将签名后的交易提交给节点。
+	Broadcast(tx *data.Transaction) error
+}
+
+// Ledger 定义了同一进程中的节点需要提供的方法，network.NetWork 满足该接口。
+type Ledger interface {
+	// GetSpendableUTXOs 返回指定钱包地址当前可以花费的 UTXO，包括交易池中未确认交易的找零。
+	GetSpendableUTXOs(address string) []*data.UTXO
+	// MinRelayFee 返回指定大小的交易需要支付的最低手续费。
+	MinRelayFee(size int) int
+	// AcceptTransaction 将交易提交给交易池，交易被拒绝时返回错误。
+	AcceptTransaction(transaction data.Transaction) error
+}
+
+// ledgerBackend 以同一进程中的节点作为钱包的 Backend。
+type ledgerBackend struct {
+	ledger Ledger
+}
+
+// NewLedgerBackend 以同一进程中的节点作为钱包的 Backend。
+// 参数:
+// - ledger: 节点，通常为 network.NetWork。
+// 返回值:
+// 返回读取该节点 UTXO 集合并向其交易池提交交易的 Backend。
+func NewLedgerBackend(ledger Ledger) Backend {
+	return &ledgerBackend{ledger: ledger}
+}
+
+func (b *ledgerBackend) ListUnspent(address string) ([]Coin, error) {
+	utxos := b.ledger.GetSpendableUTXOs(address)
+	coins := make([]Coin, len(utxos))
+	for i, utxo := range utxos {
+		coins[i] = Coin{Outpoint: utxo.GetOutpoint(), Address: utxo.GetWalletAddress(), Amount: utxo.GetAmount()}
+	}
+	return coins, nil
+}
+
+// GetFeeRate 最低手续费按 1000 字节向上取整计算，1000 字节交易的最低手续费即为手续费率。
+func (b *ledgerBackend) GetFeeRate() (int, error) {
+	return b.ledger.MinRelayFee(1000), nil
+}
+
+func (b *ledgerBackend) Broadcast(tx *data.Transaction) error {
+	return b.ledger.AcceptTransaction(*tx)
+}
@@ -0,0 +1,187 @@
+package wallet
+
+import (
+	"Go-Minichain/data"
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// signatureSize 签名的字节数，utils.Signature 生成的 r 与 s 各 32 字节。
+const signatureSize = 64
+
+var (
+	// ErrNoPayments 没有指定付款。
+	ErrNoPayments = errors.New("no payments")
+	// ErrInvalidAmount 付款金额不是正数。
+	ErrInvalidAmount = errors.New("payment amount must be positive")
+)
+
+// Payment 一笔付款。
+// 字段说明：
+// - Address: 收款方的钱包地址。
+// - Amount: 付款金额。
+type Payment struct {
+	Address string
+	Amount  int
+}
+
+// SendOptions 构造交易的选项，零值即为默认选项。
+// 字段说明：
+// - FeeRate: 每 1000 字节的手续费，低于节点要求的最低手续费率时使用节点的最低手续费率。
+// - Selector: 选币策略，为 nil 时使用 DefaultSelector。
+// - ChangeAddress: 找零地址，为空时使用钱包的默认地址。
+// - From: 只花费这些地址的输出，为空时花费钱包中全部地址的输出。
+// - Replaceable: 交易是否允许在确认之前被支付更高手续费的交易替换。
+type SendOptions struct {
+	FeeRate       int
+	Selector      CoinSelector
+	ChangeAddress string
+	From          []string
+	Replaceable   bool
+}
+
+// CreateTransaction 为付款选择输入、添加找零输出并签名交易，不提交给节点。
+// 参数:
+// - backend: 提供 UTXO 集合与最低手续费率的节点。
+// - payments: 付款，同一笔交易可以向多个地址付款。
+// - options: 构造交易的选项。
+// 返回值:
+// 返回签名后的交易与选币结果；钱包锁定时返回 ErrLocked，余额不足时返回 *InsufficientFundsError。
+func (w *Wallet) CreateTransaction(backend Backend, payments []Payment, options SendOptions) (*data.Transaction, Selection, error) {
+	if len(payments) == 0 {
+		return nil, Selection{}, ErrNoPayments
+	}
+	outputs := make([]*data.UTXO, len(payments))
+	target := 0
+	for i, payment := range payments {
+		if payment.Amount <= 0 {
+			return nil, Selection{}, fmt.Errorf("payment to %s: %w", payment.Address, ErrInvalidAmount)
+		}
+		out, err := data.NewUTXOToAddress(payment.Address, payment.Amount)
+		if err != nil {
+			return nil, Selection{}, fmt.Errorf("payment to %s: %w", payment.Address, err)
+		}
+		outputs[i] = out
+		target += payment.Amount
+	}
+	if w.IsLocked() {
+		return nil, Selection{}, ErrLocked
+	}
+	changeAddress := options.ChangeAddress
+	if changeAddress == "" {
+		changeAddress = w.GetDefaultAddress()
+	}
+	if _, err := data.ParseWalletAddress(changeAddress); err != nil {
+		return nil, Selection{}, fmt.Errorf("change address %s: %w", changeAddress, err)
+	}
+	from := options.From
+	if len(from) == 0 {
+		for _, info := range w.GetAddresses() {
+			from = append(from, info.Address)
+		}
+	}
+	accounts := make(map[string]*data.Account, len(from))
+	coins := make([]Coin, 0)
+	for _, address := range from {
+		account, err := w.getAccount(address)
+		if err != nil {
+			return nil, Selection{}, err
+		}
+		accounts[address] = account
+		owned, err := backend.ListUnspent(address)
+		if err != nil {
+			return nil, Selection{}, err
+		}
+		coins = append(coins, owned...)
+	}
+
+	feeRate, err := backend.GetFeeRate()
+	if err != nil {
+		return nil, Selection{}, err
+	}
+	if options.FeeRate > feeRate {
+		feeRate = options.FeeRate
+	}
+	params := newSelectionParams(outputs, changeAddress, target, feeRate, accounts[from[0]].GetPublicKey())
+	selector := options.Selector
+	if selector == nil {
+		selector = DefaultSelector()
+	}
+	selected, err := selector.Select(coins, params)
+	if err != nil {
+		return nil, Selection{}, err
+	}
+	selection, ok := params.Finish(selected)
+	if !ok {
+		return nil, Selection{}, params.insufficient(selected)
+	}
+
+	inputs := make([]*data.TxInput, len(selection.Coins))
+	for i, coin := range selection.Coins {
+		inputs[i] = data.NewTxInput(coin.Outpoint, accounts[coin.Address].GetPublicKey())
+	}
+	if selection.Change > 0 {
+		change, err := data.NewUTXOToAddress(changeAddress, selection.Change)
+		if err != nil {
+			return nil, Selection{}, err
+		}
+		outputs = append(outputs, change)
+	}
+	tx := data.NewTransaction(inputs, outputs)
+	tx.SetReplaceable(options.Replaceable)
+	// 每个输入使用其所属地址的私钥签名，签名数据不包含签名本身，签名顺序不影响结果
+	for i, coin := range selection.Coins {
+		tx.SignInput(i, accounts[coin.Address].GetPrivateKey())
+	}
+	if minFee := (tx.Size()*feeRate + 999) / 1000; selection.Fee < minFee {
+		return nil, Selection{}, fmt.Errorf("fee %d is below the minimum %d for %d bytes", selection.Fee, minFee, tx.Size())
+	}
+	return tx, selection, nil
+}
+
+// Send 构造并签名付款交易后提交给节点，参数与返回值见 CreateTransaction。
+func (w *Wallet) Send(backend Backend, payments []Payment, options SendOptions) (*data.Transaction, Selection, error) {
+	tx, selection, err := w.CreateTransaction(backend, payments, options)
+	if err != nil {
+		return nil, Selection{}, err
+	}
+	if err := backend.Broadcast(tx); err != nil {
+		return nil, Selection{}, err
+	}
+	return tx, selection, nil
+}
+
+// newSelectionParams 以实际的付款输出与找零地址计算选币参数中的字节数。
+// 交易的规范编码中整数与签名都是定长的，因此字节数可以由未签名的交易加上签名的长度精确得到。
+func newSelectionParams(outputs []*data.UTXO, changeAddress string, target int, feeRate int, publicKey ecdsa.PublicKey) SelectionParams {
+	// 占位输入引用的交易标识与真实的交易标识等长
+	placeholder := data.NewTxInput(data.NewOutpoint(strings.Repeat("0", 64), 0), publicKey)
+	change, _ := data.NewUTXOToAddress(changeAddress, 1)
+	size := func(inputs int, withChange bool) int {
+		txInputs := make([]*data.TxInput, inputs)
+		for i := range txInputs {
+			txInputs[i] = placeholder
+		}
+		txOutputs := outputs
+		if withChange {
+			txOutputs = append(append([]*data.UTXO(nil), outputs...), change)
+		}
+		return data.NewTransaction(txInputs, txOutputs).Size() + inputs*signatureSize
+	}
+	base := size(0, false)
+	params := SelectionParams{
+		Target:     target,
+		FeeRate:    feeRate,
+		BaseSize:   base,
+		InputSize:  size(1, false) - base,
+		ChangeSize: size(0, true) - base,
+	}
+	// 金额低于花费它所需手续费的找零不值得创建
+	params.MinChange = (params.InputSize*feeRate + 999) / 1000
+	if params.MinChange < 1 {
+		params.MinChange = 1
+	}
+	return params
+}
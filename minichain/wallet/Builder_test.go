@@ -0,0 +1,90 @@
+package wallet
+
+import (
+	"Go-Minichain/config"
+	"Go-Minichain/network"
+	"encoding/hex"
+	"errors"
+	"os"
+	"testing"
+)
+
+func TestMain(m *testing.M) {
+	c, err := config.Load("wallet.test", []string{"-difficulty=1", "-nbAccount=4", "-spvEnabled=false",
+		"-minerThreads=1", "-dataDir="}, func(string) (string, bool) { return "", false })
+	if err != nil {
+		panic("test config: " + err.Error())
+	}
+	config.MiniChainConfig = c
+	os.Exit(m.Run())
+}
+
+// fundedWallet 创建一个模拟网络，并把创世块中第一个账户的私钥导入新钱包。
+func fundedWallet(t *testing.T) (*Wallet, *network.NetWork) {
+	t.Helper()
+	n := network.NewSimulatedNetWork(1, network.NewSimulatedClock(network.SimulationEpoch))
+	if err := n.Simulate(0); err != nil {
+		t.Fatal(err)
+	}
+	w, _ := createTestWallet(t, "correct horse")
+	account := n.GetAccounts()[0]
+	key := hex.EncodeToString(account.GetPrivateKey().D.FillBytes(make([]byte, 32)))
+	if _, err := w.ImportKey(key, "genesis"); err != nil {
+		t.Fatal(err)
+	}
+	return w, n
+}
+
+func TestSendIsAcceptedByPool(t *testing.T) {
+	w, n := fundedWallet(t)
+	backend := NewLedgerBackend(n)
+	balance, err := w.GetBalance(backend)
+	if err != nil || balance != config.MiniChainConfig.GetInitAmount() {
+		t.Fatalf("balance %d, %v, want %d", balance, err, config.MiniChainConfig.GetInitAmount())
+	}
+
+	payee := n.GetAccounts()[1].GetWalletAddress()
+	tx, selection, err := w.Send(backend, []Payment{{Address: payee, Amount: 1234}}, SendOptions{Replaceable: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := n.GetTransactionPool()
+	if !pool.Has(tx.TxID()) {
+		t.Fatalf("transaction %s is not in the pool", tx.TxID())
+	}
+	if fee, ok := pool.GetFee(tx.TxID()); !ok || fee != selection.Fee || fee < n.MinRelayFee(tx.Size()) {
+		t.Fatalf("pool fee %d, selection fee %d, minimum %d", fee, selection.Fee, n.MinRelayFee(tx.Size()))
+	}
+	if !tx.IsReplaceable() {
+		t.Fatal("transaction does not signal replaceability")
+	}
+
+	// 找零回到默认地址，钱包余额减少付款金额与手续费
+	if balance, _ := w.GetBalance(backend); balance != config.MiniChainConfig.GetInitAmount()-1234-selection.Fee {
+		t.Fatalf("balance after payment %d, want %d", balance, config.MiniChainConfig.GetInitAmount()-1234-selection.Fee)
+	}
+	if change := n.GetSpendableUTXOs(w.GetDefaultAddress()); len(change) != 1 || change[0].GetAmount() != selection.Change {
+		t.Fatalf("default address holds %v, want the change %d", change, selection.Change)
+	}
+
+	// 付款金额超过余额时不提交任何交易
+	count := pool.Count()
+	if _, _, err := w.Send(backend, []Payment{{Address: payee, Amount: balance}}, SendOptions{}); !errors.Is(err, ErrInsufficientFunds) {
+		t.Fatalf("overspending returned %v, want %v", err, ErrInsufficientFunds)
+	}
+	if pool.Count() != count {
+		t.Fatal("a rejected payment changed the pool")
+	}
+}
+
+func TestSendRequiresUnlockedWallet(t *testing.T) {
+	w, n := fundedWallet(t)
+	w.Lock()
+	payee := n.GetAccounts()[1].GetWalletAddress()
+	if _, _, err := w.Send(NewLedgerBackend(n), []Payment{{Address: payee, Amount: 10}}, SendOptions{}); !errors.Is(err, ErrLocked) {
+		t.Fatalf("send from a locked wallet returned %v, want %v", err, ErrLocked)
+	}
+	if _, _, err := w.CreateTransaction(NewLedgerBackend(n), []Payment{{Address: payee, Amount: 0}}, SendOptions{}); !errors.Is(err, ErrInvalidAmount) {
+		t.Fatalf("zero payment returned %v, want %v", err, ErrInvalidAmount)
+	}
+}
@@ -0,0 +1,320 @@
+package wallet
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+/**
+ * 选币
+ *
+ * 付款时需要从钱包的输出中选择一组输入，使输入金额足以支付付款金额与手续费。手续费按交易字节数计算，
+ * 每增加一个输入，交易变大，需要的手续费也随之增加，因此选择的结果取决于输入个数，而不只是金额之和。
+ * 输入金额减去付款金额与手续费之后的剩余部分作为找零输出返回钱包；剩余金额不足 MinChange 时不值得单独作为输出，
+ * 直接并入手续费。可以选择的策略：
+ * - LargestFirst: 从大到小依次选择，输入个数最少；
+ * - BranchAndBound: 深度优先搜索恰好支付付款金额与手续费、不需要找零的组合，找不到时使用后备策略；
+ * - MinimizeChange: 在能单独支付的最小输出与若干较小输出的组合之间选择剩余金额最少的一个。
+ */
+
+// defaultMaxTries BranchAndBound 默认最多搜索的节点个数。
+const defaultMaxTries = 100000
+
+var (
+	// ErrInsufficientFunds 可以花费的输出不足以支付付款金额与手续费。
+	ErrInsufficientFunds = errors.New("insufficient funds")
+	// ErrNoExactMatch BranchAndBound 没有找到不需要找零的组合，且没有后备策略。
+	ErrNoExactMatch = errors.New("no input combination avoids change")
+	// ErrUnknownSelector 选币策略的名称不存在。
+	ErrUnknownSelector = errors.New("unknown coin selection strategy")
+)
+
+// InsufficientFundsError 余额不足的详细信息，可以使用 errors.Is(err, ErrInsufficientFunds) 判断。
+// 字段说明：
+// - Available: 可以花费的金额之和。
+// - Required: 花费全部输出时需要的付款金额与手续费之和。
+type InsufficientFundsError struct {
+	Available int
+	Required  int
+}
+
+func (e *InsufficientFundsError) Error() string {
+	return fmt.Sprintf("insufficient funds: %d available, %d required including fee", e.Available, e.Required)
+}
+
+func (e *InsufficientFundsError) Unwrap() error {
+	return ErrInsufficientFunds
+}
+
+// SelectionParams 选币时需要的交易参数，字节数由 Builder 根据实际的付款输出计算。
+// 字段说明：
+// - Target: 付款金额之和。
+// - FeeRate: 每 1000 字节的手续费。
+// - BaseSize: 没有输入、也没有找零输出时交易的字节数。
+// - InputSize: 每个签名后的输入增加的字节数。
+// - ChangeSize: 找零输出增加的字节数。
+// - MinChange: 单独作为找零输出的最小金额，更少的剩余金额并入手续费。
+type SelectionParams struct {
+	Target     int
+	FeeRate    int
+	BaseSize   int
+	InputSize  int
+	ChangeSize int
+	MinChange  int
+}
+
+// Fee 返回指定输入个数、是否有找零输出时交易需要的手续费，与节点的最低手续费一样按字节数向上取整。
+func (p SelectionParams) Fee(inputs int, change bool) int {
+	size := p.BaseSize + inputs*p.InputSize
+	if change {
+		size += p.ChangeSize
+	}
+	return (size*p.FeeRate + 999) / 1000
+}
+
+// required 返回指定输入个数、没有找零输出时输入金额至少需要达到的数额。
+func (p SelectionParams) required(inputs int) int {
+	return p.Target + p.Fee(inputs, false)
+}
+
+// covers 判断一组输入是否足以在没有找零输出时支付付款金额与手续费。
+func (p SelectionParams) covers(coins []Coin) bool {
+	return len(coins) > 0 && sumCoins(coins) >= p.required(len(coins))
+}
+
+// insufficient 生成余额不足的错误。
+func (p SelectionParams) insufficient(coins []Coin) error {
+	inputs := len(coins)
+	if inputs == 0 {
+		inputs = 1
+	}
+	return &InsufficientFundsError{Available: sumCoins(coins), Required: p.required(inputs)}
+}
+
+// Selection 选币的结果。
+// 字段说明：
+// - Coins: 选择的输入。
+// - Fee: 交易支付的手续费，包括并入手续费的剩余金额。
+// - Change: 找零金额，为 0 时交易没有找零输出。
+type Selection struct {
+	Coins  []Coin
+	Fee    int
+	Change int
+}
+
+// Finish 根据选择的输入决定是否需要找零输出，并计算手续费与找零金额。
+// 返回值:
+// 输入不足以支付付款金额与手续费时返回 false。
+func (p SelectionParams) Finish(coins []Coin) (Selection, bool) {
+	if !p.covers(coins) {
+		return Selection{}, false
+	}
+	total := sumCoins(coins)
+	fee := p.Fee(len(coins), true)
+	if change := total - p.Target - fee; change >= p.MinChange && change > 0 {
+		return Selection{Coins: coins, Fee: fee, Change: change}, true
+	}
+	return Selection{Coins: coins, Fee: total - p.Target}, true
+}
+
+// CoinSelector 选币策略。
+type CoinSelector interface {
+	// Name 返回策略的名称。
+	Name() string
+	// Select 从可以花费的输出中选择输入，余额不足时返回 *InsufficientFundsError。
+	Select(coins []Coin, params SelectionParams) ([]Coin, error)
+}
+
+// DefaultSelector 返回默认的选币策略：先搜索不需要找零的组合，找不到时选择剩余金额最少的组合。
+func DefaultSelector() CoinSelector {
+	return &BranchAndBound{Fallback: MinimizeChange{}}
+}
+
+// SelectorByName 根据名称返回选币策略，名称为 "largest-first"、"branch-and-bound" 或 "minimize-change"。
+// branch-and-bound 以 minimize-change 作为后备策略。
+func SelectorByName(name string) (CoinSelector, error) {
+	switch name {
+	case "largest-first":
+		return LargestFirst{}, nil
+	case "branch-and-bound":
+		return DefaultSelector(), nil
+	case "minimize-change":
+		return MinimizeChange{}, nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnknownSelector, name)
+}
+
+// sortCoins 返回按金额排序的副本，金额相同时按 Outpoint 排序，使选择结果确定。
+func sortCoins(coins []Coin, descending bool) []Coin {
+	sorted := append([]Coin(nil), coins...)
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Amount != sorted[j].Amount {
+			return (sorted[i].Amount > sorted[j].Amount) == descending
+		}
+		return sorted[i].Outpoint.Less(sorted[j].Outpoint)
+	})
+	return sorted
+}
+
+// LargestFirst 从金额最大的输出开始依次选择，直到足以支付，输入个数最少，但通常会产生较大的找零。
+type LargestFirst struct{}
+
+func (LargestFirst) Name() string {
+	return "largest-first"
+}
+
+func (LargestFirst) Select(coins []Coin, params SelectionParams) ([]Coin, error) {
+	sorted := sortCoins(coins, true)
+	for i := range sorted {
+		if params.covers(sorted[:i+1]) {
+			return sorted[:i+1], nil
+		}
+	}
+	return nil, params.insufficient(coins)
+}
+
+// BranchAndBound 深度优先搜索输入金额落在 [付款金额 + 手续费, 付款金额 + 手续费 + 找零的成本] 之间的组合，
+// 这样的组合不需要找零输出，多出的金额少于创建并在以后花费找零输出的手续费。存在多个组合时选择多出金额最少的一个。
+// 字段说明：
+// - MaxTries: 最多搜索的节点个数，不大于 0 时使用 defaultMaxTries。
+// - Fallback: 找不到组合时使用的策略，为 nil 时返回 ErrNoExactMatch。
+type BranchAndBound struct {
+	MaxTries int
+	Fallback CoinSelector
+}
+
+func (s *BranchAndBound) Name() string {
+	return "branch-and-bound"
+}
+
+func (s *BranchAndBound) Select(coins []Coin, params SelectionParams) ([]Coin, error) {
+	// 以千分之一为单位计算有效金额（金额减去花费该输出增加的手续费），避免逐个输入向上取整带来的误差
+	type candidate struct {
+		coin  Coin
+		value int
+	}
+	inputCost := params.InputSize * params.FeeRate
+	candidates := make([]candidate, 0, len(coins))
+	remaining := 0
+	for _, coin := range sortCoins(coins, true) {
+		if value := coin.Amount*1000 - inputCost; value > 0 {
+			candidates = append(candidates, candidate{coin: coin, value: value})
+			remaining += value
+		}
+	}
+	// 加上 999 使按千分之一累计的金额足以支付向上取整后的手续费；
+	// 多出的金额严格少于找零输出的手续费加 MinChange 时，Finish 不会创建找零输出
+	lower := params.Target*1000 + params.BaseSize*params.FeeRate + 999
+	upper := params.Target*1000 + (params.BaseSize+params.ChangeSize)*params.FeeRate + params.MinChange*1000 - 1
+
+	tries := s.MaxTries
+	if tries <= 0 {
+		tries = defaultMaxTries
+	}
+	selected := make([]int, 0, len(candidates))
+	var best []int
+	bestValue := 0
+	var search func(i int, value int, remaining int)
+	search = func(i int, value int, remaining int) {
+		if tries <= 0 || value > upper || value+remaining < lower {
+			return
+		}
+		tries--
+		if value >= lower {
+			// 再加入输入只会多出更多金额
+			if best == nil || value < bestValue {
+				best = append([]int(nil), selected...)
+				bestValue = value
+			}
+			return
+		}
+		if i == len(candidates) {
+			return
+		}
+		value, remaining = value+candidates[i].value, remaining-candidates[i].value
+		selected = append(selected, i)
+		search(i+1, value, remaining)
+		selected = selected[:len(selected)-1]
+		search(i+1, value-candidates[i].value, remaining)
+	}
+	search(0, 0, remaining)
+
+	if best == nil {
+		if s.Fallback != nil {
+			return s.Fallback.Select(coins, params)
+		}
+		if !params.covers(coins) {
+			return nil, params.insufficient(coins)
+		}
+		return nil, ErrNoExactMatch
+	}
+	result := make([]Coin, len(best))
+	for i, index := range best {
+		result[i] = candidates[index].coin
+	}
+	return result, nil
+}
+
+// MinimizeChange 选择支付之后剩余金额（找零与并入手续费的部分）最少的组合，剩余金额相同时选择输入较少的组合。
+// 候选组合有两个：能单独支付的最小输出；不能单独支付的较小输出从大到小累加到足以支付，再去掉不必要的输入。
+// 都不存在时从全部输出中从大到小累加。
+type MinimizeChange struct{}
+
+func (MinimizeChange) Name() string {
+	return "minimize-change"
+}
+
+func (MinimizeChange) Select(coins []Coin, params SelectionParams) ([]Coin, error) {
+	var best []Coin
+	bestExcess := 0
+	consider := func(selection []Coin) {
+		if !params.covers(selection) {
+			return
+		}
+		excess := sumCoins(selection) - params.required(len(selection))
+		if best == nil || excess < bestExcess || (excess == bestExcess && len(selection) < len(best)) {
+			best = selection
+			bestExcess = excess
+		}
+	}
+	smaller := make([]Coin, 0, len(coins))
+	for _, coin := range sortCoins(coins, false) {
+		if coin.Amount >= params.required(1) {
+			consider([]Coin{coin})
+			break
+		}
+		smaller = append(smaller, coin)
+	}
+	consider(accumulate(sortCoins(smaller, true), params))
+	if best == nil {
+		consider(accumulate(sortCoins(coins, true), params))
+	}
+	if best == nil {
+		return nil, params.insufficient(coins)
+	}
+	return best, nil
+}
+
+// accumulate 按顺序累加输出直到足以支付，再从最后加入的输出开始去掉不影响支付的输入。
+// 返回值:
+// 返回选择的输入，全部输出都不足以支付时返回 nil。
+func accumulate(sorted []Coin, params SelectionParams) []Coin {
+	selection := make([]Coin, 0)
+	for _, coin := range sorted {
+		selection = append(selection, coin)
+		if params.covers(selection) {
+			break
+		}
+	}
+	if !params.covers(selection) {
+		return nil
+	}
+	for i := len(selection) - 1; i >= 0; i-- {
+		rest := append(append(make([]Coin, 0, len(selection)-1), selection[:i]...), selection[i+1:]...)
+		if params.covers(rest) {
+			selection = rest
+		}
+	}
+	return selection
+}
@@ -0,0 +1,141 @@
+package wallet
+
+import (
+	"Go-Minichain/data"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// testParams 每字节 1 的手续费：没有输入时交易 10 字节，每个输入 100 字节，找零输出 40 字节，少于 100 的剩余金额不找零。
+var testParams = SelectionParams{Target: 1000, FeeRate: 1000, BaseSize: 10, InputSize: 100, ChangeSize: 40, MinChange: 100}
+
+// testCoins 按金额生成输出，每个输出的 Outpoint 互不相同。
+func testCoins(amounts ...int) []Coin {
+	coins := make([]Coin, len(amounts))
+	for i, amount := range amounts {
+		coins[i] = Coin{Outpoint: data.NewOutpoint(strings.Repeat("A", 64), i), Address: "test", Amount: amount}
+	}
+	return coins
+}
+
+// amountsOf 返回输出的金额列表。
+func amountsOf(coins []Coin) []int {
+	amounts := make([]int, len(coins))
+	for i, coin := range coins {
+		amounts[i] = coin.Amount
+	}
+	return amounts
+}
+
+// sameAmounts 判断两组金额是否相同，不考虑顺序。
+func sameAmounts(coins []Coin, want ...int) bool {
+	if len(coins) != len(want) {
+		return false
+	}
+	remaining := make(map[int]int)
+	for _, amount := range want {
+		remaining[amount]++
+	}
+	for _, coin := range coins {
+		if remaining[coin.Amount] == 0 {
+			return false
+		}
+		remaining[coin.Amount]--
+	}
+	return true
+}
+
+func TestSelectorsExactMatchAndChange(t *testing.T) {
+	// 601 + 610 恰好支付 1000 的付款与两个输入的手续费 210，多出的 1 不足以找零；3000 单独支付时需要找零
+	coins := testCoins(50, 601, 3000, 610)
+	for _, tc := range []struct {
+		selector CoinSelector
+		want     []int
+		change   int
+		fee      int
+	}{
+		{LargestFirst{}, []int{3000}, 3000 - 1000 - 150, 150},
+		{&BranchAndBound{}, []int{601, 610}, 0, 211},
+		{DefaultSelector(), []int{601, 610}, 0, 211},
+		{MinimizeChange{}, []int{601, 610}, 0, 211},
+	} {
+		selected, err := tc.selector.Select(coins, testParams)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.selector.Name(), err)
+		}
+		if !sameAmounts(selected, tc.want...) {
+			t.Fatalf("%s selected %v, want %v", tc.selector.Name(), amountsOf(selected), tc.want)
+		}
+		selection, ok := testParams.Finish(selected)
+		if !ok {
+			t.Fatalf("%s: selection does not cover the payment", tc.selector.Name())
+		}
+		if selection.Change != tc.change || selection.Fee != tc.fee {
+			t.Fatalf("%s: change %d and fee %d, want %d and %d", tc.selector.Name(), selection.Change, selection.Fee, tc.change, tc.fee)
+		}
+		if sumCoins(selected) != testParams.Target+selection.Fee+selection.Change {
+			t.Fatalf("%s: inputs do not add up to payment, fee and change", tc.selector.Name())
+		}
+	}
+}
+
+func TestSelectorsChangeWhenNoExactMatch(t *testing.T) {
+	// 没有恰好支付的组合，每种策略都会产生找零
+	coins := testCoins(700, 800, 5000)
+	for _, tc := range []struct {
+		selector CoinSelector
+		want     []int
+	}{
+		{LargestFirst{}, []int{5000}},
+		{DefaultSelector(), []int{700, 800}},
+		{MinimizeChange{}, []int{700, 800}},
+	} {
+		selected, err := tc.selector.Select(coins, testParams)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.selector.Name(), err)
+		}
+		if !sameAmounts(selected, tc.want...) {
+			t.Fatalf("%s selected %v, want %v", tc.selector.Name(), amountsOf(selected), tc.want)
+		}
+		if selection, _ := testParams.Finish(selected); selection.Change < testParams.MinChange {
+			t.Fatalf("%s: change %d, want a change output", tc.selector.Name(), selection.Change)
+		}
+	}
+
+	// 没有后备策略时 BranchAndBound 返回 ErrNoExactMatch
+	if _, err := (&BranchAndBound{}).Select(coins, testParams); !errors.Is(err, ErrNoExactMatch) {
+		t.Fatalf("branch-and-bound without fallback returned %v, want %v", err, ErrNoExactMatch)
+	}
+}
+
+func TestSelectorsInsufficientFunds(t *testing.T) {
+	// 金额之和 1100 足以支付付款，但不足以同时支付两个输入的手续费
+	coins := testCoins(500, 600)
+	for _, selector := range []CoinSelector{LargestFirst{}, &BranchAndBound{}, DefaultSelector(), MinimizeChange{}} {
+		_, err := selector.Select(coins, testParams)
+		var insufficient *InsufficientFundsError
+		if !errors.Is(err, ErrInsufficientFunds) || !errors.As(err, &insufficient) {
+			t.Fatalf("%s returned %v, want %v", selector.Name(), err, ErrInsufficientFunds)
+		}
+		if insufficient.Available != 1100 || insufficient.Required != testParams.required(2) {
+			t.Fatalf("%s: %d available and %d required, want 1100 and %d", selector.Name(),
+				insufficient.Available, insufficient.Required, testParams.required(2))
+		}
+		if _, err := selector.Select(nil, testParams); !errors.Is(err, ErrInsufficientFunds) {
+			t.Fatalf("%s without coins returned %v, want %v", selector.Name(), err, ErrInsufficientFunds)
+		}
+	}
+}
+
+func TestSelectorByName(t *testing.T) {
+	for _, name := range []string{"largest-first", "branch-and-bound", "minimize-change"} {
+		selector, err := SelectorByName(name)
+		if err != nil || selector.Name() != name {
+			t.Fatalf("SelectorByName(%q) returned %v, %v", name, selector, err)
+		}
+	}
+	if _, err := SelectorByName("random"); !errors.Is(err, ErrUnknownSelector) {
+		t.Fatalf("unknown selector returned %v, want %v", err, ErrUnknownSelector)
+	}
+}
@@ -0,0 +1,196 @@
+package wallet
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+/**
+ * 钱包文件
+ *
+ * 钱包文件为 JSON 格式，地址、标签等公开信息以明文保存，不需要口令即可列出地址、查询余额；
+ * 私钥（data.Account 的规范编码）以 AES-256-GCM 加密保存，密钥由口令经 PBKDF2-HMAC-SHA256 派生，
+ * 每个私钥使用独立的随机 nonce，并以地址作为附加认证数据，私钥被调换到其他地址下时无法解密。
+ * check 字段为以同一密钥加密的空内容，用于在解密私钥之前判断口令是否正确。
+ * 文件先写入临时文件再替换，写入中途失败不会损坏原有的钱包文件。
+ */
+
+const (
+	// walletVersion 钱包文件格式的版本。
+	walletVersion = 1
+	// kdfName 口令派生密钥的算法名称。
+	kdfName = "pbkdf2-sha256"
+	// defaultIterations 新钱包 PBKDF2 的迭代次数。
+	defaultIterations = 100000
+	// saltSize PBKDF2 盐的字节数。
+	saltSize = 16
+	// checkLabel 口令校验数据的附加认证数据。
+	checkLabel = "minichain-wallet"
+)
+
+// walletFile 钱包文件的内容。
+// 字段说明：
+// - Version: 文件格式的版本。
+// - KDF: 口令派生密钥的参数。
+// - Check: 用于校验口令的加密数据。
+// - Keys: 钱包中的密钥，第一个密钥的地址为默认地址。
+type walletFile struct {
+	Version int         `json:"version"`
+	KDF     kdfParams   `json:"kdf"`
+	Check   sealed      `json:"check"`
+	Keys    []keyRecord `json:"keys"`
+}
+
+// kdfParams 口令派生密钥的参数。
+type kdfParams struct {
+	Name       string `json:"name"`
+	Iterations int    `json:"iterations"`
+	Salt       string `json:"salt"`
+}
+
+// sealed AES-256-GCM 加密的数据，均为十六进制字符串。
+type sealed struct {
+	Nonce      string `json:"nonce"`
+	Ciphertext string `json:"ciphertext"`
+}
+
+// keyRecord 钱包文件中的一个密钥。
+// 字段说明：
+// - Address: 钱包地址。
+// - Label: 用户指定的标签。
+// - Imported: 是否为导入的私钥。
+// - CreatedAt: 创建或导入的时间（Unix 秒）。
+// - Secret: 加密后的账户编码。
+type keyRecord struct {
+	Address   string `json:"address"`
+	Label     string `json:"label"`
+	Imported  bool   `json:"imported"`
+	CreatedAt int64  `json:"created"`
+	Secret    sealed `json:"secret"`
+}
+
+// readWalletFile 读取并检查钱包文件。
+func readWalletFile(path string) (walletFile, error) {
+	var file walletFile
+	raw, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return file, fmt.Errorf("%w: %s", ErrWalletNotFound, path)
+	}
+	if err != nil {
+		return file, err
+	}
+	if err := json.Unmarshal(raw, &file); err != nil {
+		return file, fmt.Errorf("parse wallet file %s error: %w", path, err)
+	}
+	if file.Version != walletVersion || file.KDF.Name != kdfName || file.KDF.Iterations <= 0 {
+		return file, fmt.Errorf("unsupported wallet file %s: version %d, kdf %s", path, file.Version, file.KDF.Name)
+	}
+	return file, nil
+}
+
+// writeWalletFile 先写入临时文件再替换钱包文件，文件只有所有者可以读写。
+func writeWalletFile(path string, file walletFile) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	raw, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, raw, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// deriveKey 由口令与钱包文件中的参数派生 32 字节的加密密钥。
+func deriveKey(passphrase string, params kdfParams) ([]byte, error) {
+	salt, err := hex.DecodeString(params.Salt)
+	if err != nil {
+		return nil, fmt.Errorf("invalid salt: %w", err)
+	}
+	return pbkdf2([]byte(passphrase), salt, params.Iterations, 32), nil
+}
+
+// pbkdf2 按 RFC 8018 以 HMAC-SHA256 作为伪随机函数派生密钥。
+func pbkdf2(password []byte, salt []byte, iterations int, keyLen int) []byte {
+	prf := hmac.New(sha256.New, password)
+	key := make([]byte, 0, keyLen+prf.Size())
+	block := make([]byte, 4)
+	for i := uint32(1); len(key) < keyLen; i++ {
+		prf.Reset()
+		prf.Write(salt)
+		binary.BigEndian.PutUint32(block, i)
+		prf.Write(block)
+		u := prf.Sum(nil)
+		t := append([]byte(nil), u...)
+		for n := 1; n < iterations; n++ {
+			prf.Reset()
+			prf.Write(u)
+			u = prf.Sum(u[:0])
+			for j := range t {
+				t[j] ^= u[j]
+			}
+		}
+		key = append(key, t...)
+	}
+	return key[:keyLen]
+}
+
+// seal 以 AES-256-GCM 加密数据。
+// 参数:
+// - key: 32 字节的密钥。
+// - plaintext: 明文。
+// - label: 附加认证数据，解密时必须相同。
+func seal(key []byte, plaintext []byte, label string) (sealed, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return sealed{}, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return sealed{}, err
+	}
+	ciphertext := aead.Seal(nil, nonce, plaintext, []byte(label))
+	return sealed{Nonce: hex.EncodeToString(nonce), Ciphertext: hex.EncodeToString(ciphertext)}, nil
+}
+
+// unseal 解密 seal 加密的数据，密钥错误或数据被篡改时返回 ErrWrongPassphrase。
+func unseal(key []byte, s sealed, label string) ([]byte, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := hex.DecodeString(s.Nonce)
+	if err != nil || len(nonce) != aead.NonceSize() {
+		return nil, errors.New("invalid nonce in wallet file")
+	}
+	ciphertext, err := hex.DecodeString(s.Ciphertext)
+	if err != nil {
+		return nil, errors.New("invalid ciphertext in wallet file")
+	}
+	plaintext, err := aead.Open(nil, nonce, ciphertext, []byte(label))
+	if err != nil {
+		return nil, ErrWrongPassphrase
+	}
+	return plaintext, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,170 @@
+package wallet
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// createTestWallet 在临时目录中创建钱包，并再生成一个地址。
+func createTestWallet(t *testing.T, passphrase string) (*Wallet, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "wallet.json")
+	w, err := Create(path, passphrase)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.NewAddress("second"); err != nil {
+		t.Fatal(err)
+	}
+	return w, path
+}
+
+// editWalletFile 读取钱包文件，交给 edit 修改后写回。
+func editWalletFile(t *testing.T, path string, edit func(file *walletFile)) {
+	t.Helper()
+	file, err := readWalletFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edit(&file)
+	if err := writeWalletFile(path, file); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestKeystoreRoundTrip(t *testing.T) {
+	w, path := createTestWallet(t, "correct horse")
+	keys := make(map[string]string)
+	for _, info := range w.GetAddresses() {
+		key, err := w.ExportKey(info.Address)
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys[info.Address] = key
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range keys {
+		if strings.Contains(strings.ToLower(string(raw)), key) {
+			t.Fatal("wallet file contains a private key in plain text")
+		}
+	}
+
+	// 重新打开的钱包处于锁定状态，地址不需要口令即可列出，解锁后私钥与原来相同
+	reopened, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reopened.IsLocked() {
+		t.Fatal("opened wallet is not locked")
+	}
+	if _, err := reopened.ExportKey(w.GetDefaultAddress()); !errors.Is(err, ErrLocked) {
+		t.Fatalf("export from a locked wallet returned %v, want %v", err, ErrLocked)
+	}
+	addresses := reopened.GetAddresses()
+	if len(addresses) != 2 || addresses[0].Address != w.GetDefaultAddress() || addresses[1].Label != "second" {
+		t.Fatalf("reopened wallet lists %v", addresses)
+	}
+	if err := reopened.Unlock("correct horse"); err != nil {
+		t.Fatal(err)
+	}
+	for address, want := range keys {
+		if key, err := reopened.ExportKey(address); err != nil || key != want {
+			t.Fatalf("key of %s after reopening: %v", address, err)
+		}
+	}
+
+	// 导出的私钥可以导入另一个钱包，得到相同的地址
+	other, _ := createTestWallet(t, "other")
+	address, err := other.ImportKey(keys[w.GetDefaultAddress()], "imported")
+	if err != nil || address != w.GetDefaultAddress() {
+		t.Fatalf("import returned %s, %v, want %s", address, err, w.GetDefaultAddress())
+	}
+	if _, err := other.ImportKey(keys[w.GetDefaultAddress()], ""); !errors.Is(err, ErrKeyExists) {
+		t.Fatalf("importing the same key twice returned %v, want %v", err, ErrKeyExists)
+	}
+}
+
+func TestKeystoreRejectsWrongPassphrase(t *testing.T) {
+	_, path := createTestWallet(t, "correct horse")
+	w, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, passphrase := range []string{"", "wrong", "correct horse "} {
+		if err := w.Unlock(passphrase); !errors.Is(err, ErrWrongPassphrase) {
+			t.Fatalf("unlock with %q returned %v, want %v", passphrase, err, ErrWrongPassphrase)
+		}
+		if !w.IsLocked() {
+			t.Fatal("wallet is unlocked by a wrong passphrase")
+		}
+	}
+}
+
+func TestKeystoreRejectsTamperedCiphertext(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		edit func(file *walletFile)
+	}{
+		{"flipped ciphertext", func(file *walletFile) {
+			secret := []byte(file.Keys[1].Secret.Ciphertext)
+			if secret[0] == '0' {
+				secret[0] = '1'
+			} else {
+				secret[0] = '0'
+			}
+			file.Keys[1].Secret.Ciphertext = string(secret)
+		}},
+		{"truncated ciphertext", func(file *walletFile) {
+			file.Keys[0].Secret.Ciphertext = file.Keys[0].Secret.Ciphertext[2:]
+		}},
+		// 以地址作为附加认证数据，私钥被调换到其他地址下时无法解密
+		{"swapped keys", func(file *walletFile) {
+			file.Keys[0].Secret, file.Keys[1].Secret = file.Keys[1].Secret, file.Keys[0].Secret
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, path := createTestWallet(t, "correct horse")
+			editWalletFile(t, path, tc.edit)
+			w, err := Open(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := w.Unlock("correct horse"); !errors.Is(err, ErrWrongPassphrase) {
+				t.Fatalf("unlock returned %v, want %v", err, ErrWrongPassphrase)
+			}
+			if !w.IsLocked() {
+				t.Fatal("wallet is unlocked with a tampered key")
+			}
+		})
+	}
+}
+
+func TestKeystoreRejectsUnsupportedFile(t *testing.T) {
+	_, path := createTestWallet(t, "correct horse")
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var file map[string]interface{}
+	if err := json.Unmarshal(raw, &file); err != nil {
+		t.Fatal(err)
+	}
+	file["version"] = walletVersion + 1
+	raw, _ = json.Marshal(file)
+	if err := os.WriteFile(path, raw, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Open(path); err == nil || !strings.Contains(err.Error(), "unsupported wallet file") {
+		t.Fatalf("open returned %v, want an unsupported wallet file error", err)
+	}
+	if _, err := Open(filepath.Join(t.TempDir(), "missing.json")); !errors.Is(err, ErrWalletNotFound) {
+		t.Fatalf("open of a missing file returned %v, want %v", err, ErrWalletNotFound)
+	}
+}
@@ -0,0 +1,343 @@
+package wallet
+
+import (
+	"Go-Minichain/data"
+	"Go-Minichain/utils"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/dustinxie/ecc"
+)
+
+/**
+ * 钱包
+ *
+ * 节点的 data.Account 只保存在 NetWork 中，用于模拟运行；钱包则为用户持有密钥：
+ * 创建新地址、导入与导出私钥、通过节点的 UTXO 集合查询属于钱包的输出，并构造、签名、提交付款交易（见 Builder.go）。
+ * 钱包保存在一个加密的钱包文件中（见 Keystore.go）。打开钱包后即可列出地址、查询余额；
+ * 创建地址、导入导出私钥与付款需要先用口令解锁。
+ */
+
+var (
+	// ErrWalletExists 创建钱包时钱包文件已经存在。
+	ErrWalletExists = errors.New("wallet file already exists")
+	// ErrWalletNotFound 钱包文件不存在。
+	ErrWalletNotFound = errors.New("wallet file not found")
+	// ErrEmptyPassphrase 口令为空。
+	ErrEmptyPassphrase = errors.New("passphrase must not be empty")
+	// ErrWrongPassphrase 口令错误，或钱包文件中的加密数据被篡改。
+	ErrWrongPassphrase = errors.New("wrong passphrase")
+	// ErrLocked 钱包尚未解锁。
+	ErrLocked = errors.New("wallet is locked")
+	// ErrUnknownAddress 地址不属于钱包。
+	ErrUnknownAddress = errors.New("address is not in the wallet")
+	// ErrKeyExists 导入的私钥已经在钱包中。
+	ErrKeyExists = errors.New("key is already in the wallet")
+	// ErrInvalidKey 导入的私钥不是合法的 secp256k1 私钥。
+	ErrInvalidKey = errors.New("invalid private key")
+)
+
+// AddressInfo 钱包中一个地址的公开信息。
+// 字段说明：
+// - Address: 钱包地址。
+// - Label: 用户指定的标签。
+// - Imported: 是否为导入的私钥。
+// - CreatedAt: 创建或导入的时间。
+type AddressInfo struct {
+	Address   string
+	Label     string
+	Imported  bool
+	CreatedAt time.Time
+}
+
+// Wallet 钱包。
+// 字段说明：
+// - path: 钱包文件的路径。
+// - file: 钱包文件的内容。
+// - key: 由口令派生的加密密钥，锁定时为 nil。
+// - accounts: 解锁后以地址索引的账户。
+// - mutex: 保护以上状态的互斥锁。
+type Wallet struct {
+	path     string
+	file     walletFile
+	key      []byte
+	accounts map[string]*data.Account
+	mutex    sync.Mutex
+}
+
+// Create 创建新的钱包文件并生成第一个地址，该地址为钱包的默认地址，返回的钱包处于解锁状态。
+// 参数:
+// - path: 钱包文件的路径，文件已存在时返回 ErrWalletExists。
+// - passphrase: 加密私钥的口令，不能为空。
+// 返回值:
+// 返回新创建的钱包以及可能出现的错误。
+func Create(path string, passphrase string) (*Wallet, error) {
+	if passphrase == "" {
+		return nil, ErrEmptyPassphrase
+	}
+	if _, err := os.Stat(path); err == nil {
+		return nil, fmt.Errorf("%w: %s", ErrWalletExists, path)
+	}
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	params := kdfParams{Name: kdfName, Iterations: defaultIterations, Salt: hex.EncodeToString(salt)}
+	key, err := deriveKey(passphrase, params)
+	if err != nil {
+		return nil, err
+	}
+	check, err := seal(key, nil, checkLabel)
+	if err != nil {
+		return nil, err
+	}
+	w := &Wallet{
+		path:     path,
+		file:     walletFile{Version: walletVersion, KDF: params, Check: check, Keys: []keyRecord{}},
+		key:      key,
+		accounts: make(map[string]*data.Account),
+	}
+	if _, err := w.addAccount(data.NewAccount(), "default", false); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Open 打开已有的钱包文件，返回的钱包处于锁定状态。
+// 参数:
+// - path: 钱包文件的路径，文件不存在时返回 ErrWalletNotFound。
+// 返回值:
+// 返回钱包以及可能出现的错误。
+func Open(path string) (*Wallet, error) {
+	file, err := readWalletFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return &Wallet{path: path, file: file}, nil
+}
+
+// Unlock 用口令解密全部私钥，并检查每个私钥与其地址是否对应。
+// 返回值:
+// 口令错误时返回 ErrWrongPassphrase。
+func (w *Wallet) Unlock(passphrase string) error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	key, err := deriveKey(passphrase, w.file.KDF)
+	if err != nil {
+		return err
+	}
+	if _, err := unseal(key, w.file.Check, checkLabel); err != nil {
+		return err
+	}
+	accounts := make(map[string]*data.Account, len(w.file.Keys))
+	for _, record := range w.file.Keys {
+		encoded, err := unseal(key, record.Secret, record.Address)
+		if err != nil {
+			return fmt.Errorf("decrypt key of %s: %w", record.Address, err)
+		}
+		account, err := data.DecodeAccount(encoded)
+		if err != nil {
+			return fmt.Errorf("decode key of %s: %w", record.Address, err)
+		}
+		if account.GetWalletAddress() != record.Address {
+			return fmt.Errorf("key of %s belongs to another address", record.Address)
+		}
+		accounts[record.Address] = account
+	}
+	w.key = key
+	w.accounts = accounts
+	return nil
+}
+
+// Lock 丢弃解密后的私钥与加密密钥。
+func (w *Wallet) Lock() {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.key = nil
+	w.accounts = nil
+}
+
+// IsLocked 判断钱包是否处于锁定状态。
+func (w *Wallet) IsLocked() bool {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return w.key == nil
+}
+
+// GetPath 返回钱包文件的路径。
+func (w *Wallet) GetPath() string {
+	return w.path
+}
+
+// GetAddresses 按创建顺序返回钱包中全部地址的公开信息，不需要解锁。
+func (w *Wallet) GetAddresses() []AddressInfo {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	infos := make([]AddressInfo, len(w.file.Keys))
+	for i, record := range w.file.Keys {
+		infos[i] = AddressInfo{Address: record.Address, Label: record.Label, Imported: record.Imported,
+			CreatedAt: time.Unix(record.CreatedAt, 0)}
+	}
+	return infos
+}
+
+// GetDefaultAddress 返回钱包的默认地址，即第一个地址，默认的找零地址也是该地址。
+func (w *Wallet) GetDefaultAddress() string {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if len(w.file.Keys) == 0 {
+		return ""
+	}
+	return w.file.Keys[0].Address
+}
+
+// HasAddress 判断地址是否属于钱包。
+func (w *Wallet) HasAddress(address string) bool {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return w.indexOf(address) >= 0
+}
+
+// NewAddress 生成新的密钥并保存到钱包文件。
+// 参数:
+// - label: 地址的标签，可以为空。
+// 返回值:
+// 返回新地址；钱包锁定时返回 ErrLocked。
+func (w *Wallet) NewAddress(label string) (string, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if w.key == nil {
+		return "", ErrLocked
+	}
+	return w.addAccount(data.NewAccount(), label, false)
+}
+
+// ImportKey 导入以十六进制表示的私钥并保存到钱包文件，格式与 ExportKey 的输出相同。
+// 参数:
+// - privateKey: 私钥标量的十六进制大端表示。
+// - label: 地址的标签，可以为空。
+// 返回值:
+// 返回私钥对应的地址；钱包锁定时返回 ErrLocked，私钥不合法时返回 ErrInvalidKey，已在钱包中时返回 ErrKeyExists。
+func (w *Wallet) ImportKey(privateKey string, label string) (string, error) {
+	d, err := hex.DecodeString(privateKey)
+	if err != nil || len(d) == 0 || len(d) > 32 {
+		return "", ErrInvalidKey
+	}
+	scalar := new(big.Int).SetBytes(d)
+	if scalar.Sign() == 0 || scalar.Cmp(ecc.P256k1().Params().N) >= 0 {
+		return "", ErrInvalidKey
+	}
+	private, public := utils.Secp256k1FromBytes(d)
+	account := &data.Account{PublicKey: public, PrivateKey: private}
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if w.key == nil {
+		return "", ErrLocked
+	}
+	if w.indexOf(account.GetWalletAddress()) >= 0 {
+		return "", fmt.Errorf("%w: %s", ErrKeyExists, account.GetWalletAddress())
+	}
+	return w.addAccount(account, label, true)
+}
+
+// ExportKey 导出地址对应的私钥。
+// 参数:
+// - address: 钱包中的地址。
+// 返回值:
+// 返回 32 字节私钥标量的十六进制大端表示；钱包锁定时返回 ErrLocked，地址不属于钱包时返回 ErrUnknownAddress。
+func (w *Wallet) ExportKey(address string) (string, error) {
+	account, err := w.getAccount(address)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(account.GetPrivateKey().D.FillBytes(make([]byte, 32))), nil
+}
+
+// ListUnspent 通过 Backend 查询钱包中全部地址可以花费的输出，不需要解锁。
+// 参数:
+// - backend: 提供 UTXO 集合的节点。
+// 返回值:
+// 按地址的创建顺序返回可以花费的输出。
+func (w *Wallet) ListUnspent(backend Backend) ([]Coin, error) {
+	coins := make([]Coin, 0)
+	for _, info := range w.GetAddresses() {
+		owned, err := backend.ListUnspent(info.Address)
+		if err != nil {
+			return nil, err
+		}
+		coins = append(coins, owned...)
+	}
+	return coins, nil
+}
+
+// GetBalance 返回钱包中全部地址可以花费的金额之和，包括交易池中未确认交易的找零。
+func (w *Wallet) GetBalance(backend Backend) (int, error) {
+	coins, err := w.ListUnspent(backend)
+	if err != nil {
+		return 0, err
+	}
+	return sumCoins(coins), nil
+}
+
+// getAccount 返回地址对应的已解锁账户。
+func (w *Wallet) getAccount(address string) (*data.Account, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if w.key == nil {
+		return nil, ErrLocked
+	}
+	account, ok := w.accounts[address]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownAddress, address)
+	}
+	return account, nil
+}
+
+// addAccount 加密账户并写入钱包文件，写入失败时钱包保持不变。调用方需要持有 w.mutex，且钱包已解锁。
+func (w *Wallet) addAccount(account *data.Account, label string, imported bool) (string, error) {
+	address := account.GetWalletAddress()
+	secret, err := seal(w.key, account.Encode(), address)
+	if err != nil {
+		return "", err
+	}
+	file := w.file
+	file.Keys = append(append(make([]keyRecord, 0, len(w.file.Keys)+1), w.file.Keys...), keyRecord{
+		Address:   address,
+		Label:     label,
+		Imported:  imported,
+		CreatedAt: time.Now().Unix(),
+		Secret:    secret,
+	})
+	if err := writeWalletFile(w.path, file); err != nil {
+		return "", err
+	}
+	w.file = file
+	w.accounts[address] = account
+	return address, nil
+}
+
+// indexOf 返回地址在钱包文件中的序号，不存在时返回 -1。调用方需要持有 w.mutex。
+func (w *Wallet) indexOf(address string) int {
+	for i, record := range w.file.Keys {
+		if record.Address == address {
+			return i
+		}
+	}
+	return -1
+}
+
+// sumCoins 返回输出的金额之和。
+func sumCoins(coins []Coin) int {
+	total := 0
+	for _, coin := range coins {
+		total += coin.Amount
+	}
+	return total
+}
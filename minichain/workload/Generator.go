@@ -0,0 +1,169 @@
+package workload
+
+import (
+	"Go-Minichain/data"
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+/**
+ * 交易负载生成器
+ *
+ * 生成器按照可替换的策略（Strategy）不断构造交易并提交给交易池，用于模拟与压力测试：
+ * 均匀随机转账、集中在少数热点账户的转账、多输入合并、一对多付款，以及故意构造的无效交易与双花交易。
+ * 生成器的全部随机性来自同一个种子，交易时间戳来自账本的时钟（Ledger.Now），
+ * 相同的种子、时钟与账本状态会生成相同的交易序列，便于复现问题。
+ *
+ * 生成器只依赖 data 包，通过 Ledger 读取账户与可花费的 UTXO，通过 Sink 提交交易，
+ * 因此既可以由 network.NetWork 驱动，也可以在测试或其他工具中单独使用。
+ */
+
+// retryInterval 策略暂时无法构造交易（例如账户余额不足）时，再次尝试之前等待的时间。
+const retryInterval = 100 * time.Millisecond
+
+// Ledger 定义了生成交易时需要读取的账本状态。
+type Ledger interface {
+	// GetAccounts 返回可以发起交易的全部账户。
+	GetAccounts() []data.Account
+	// GetSpendableUTXOs 返回指定钱包地址当前可以花费的 UTXO，包括交易池中未确认交易的找零。
+	GetSpendableUTXOs(address string) []*data.UTXO
+	// GetTrueUTXOs 返回指定钱包地址已确认的 UTXO。
+	GetTrueUTXOs(address string) []*data.UTXO
+	// MinRelayFee 返回指定大小的交易需要支付的最低手续费。
+	MinRelayFee(size int) int
+	// Now 返回当前时间，用作交易的时间戳。
+	Now() time.Time
+}
+
+// Sink 定义了接收生成的交易的一方，通常为交易池。
+type Sink interface {
+	// AcceptTransaction 提交一笔交易，交易被拒绝时返回错误。
+	AcceptTransaction(transaction data.Transaction) error
+	// WaitForSpace 等待可以继续提交交易，ctx 被取消时返回 false。
+	WaitForSpace(ctx context.Context) bool
+}
+
+// Strategy 定义了一种构造交易的方式。
+type Strategy interface {
+	// Name 返回策略的名称。
+	Name() string
+	// Generate 使用给定的随机数生成器构造一笔交易，暂时无法构造时返回 nil。
+	Generate(r *rand.Rand, ledger Ledger) *data.Transaction
+}
+
+// Stats 生成器的累计统计信息。
+// 字段说明：
+// - Generated: 生成的交易个数。
+// - Accepted: 被接收的交易个数。
+// - Rejected: 被拒绝的交易个数，使用 Invalid 策略时其中包括故意构造的无效交易。
+type Stats struct {
+	Generated int
+	Accepted  int
+	Rejected  int
+}
+
+// Generator 交易负载生成器。
+// 字段说明：
+// - ledger: 账本状态。
+// - strategy: 构造交易的策略。
+// - seed: 随机数种子。
+// - rand: 由种子初始化的随机数生成器，所有随机选择都来自它。
+// - stats: 累计的统计信息。
+// - mutex: 保护 rand 与 stats 的互斥锁。
+type Generator struct {
+	ledger   Ledger
+	strategy Strategy
+	seed     int64
+	rand     *rand.Rand
+	stats    Stats
+	mutex    sync.Mutex
+}
+
+// NewGenerator 创建一个交易负载生成器。
+// 参数:
+// - ledger: 账本状态。
+// - strategy: 构造交易的策略。
+// - seed: 随机数种子。
+// 返回值:
+// 返回一个指向新创建的生成器的指针。
+func NewGenerator(ledger Ledger, strategy Strategy, seed int64) *Generator {
+	return &Generator{
+		ledger:   ledger,
+		strategy: strategy,
+		seed:     seed,
+		rand:     rand.New(rand.NewSource(seed)),
+	}
+}
+
+// GetStrategy 返回生成器使用的策略。
+func (g *Generator) GetStrategy() Strategy {
+	return g.strategy
+}
+
+// GetSeed 返回生成器的随机数种子。
+func (g *Generator) GetSeed() int64 {
+	return g.seed
+}
+
+// GetStats 返回累计的统计信息。
+func (g *Generator) GetStats() Stats {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	return g.stats
+}
+
+// Next 按策略构造下一笔交易，暂时无法构造时返回 nil。
+func (g *Generator) Next() *data.Transaction {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	transaction := g.strategy.Generate(g.rand, g.ledger)
+	if transaction != nil {
+		g.stats.Generated++
+	}
+	return transaction
+}
+
+// record 记录一笔交易的提交结果。
+func (g *Generator) record(err error) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	if err != nil {
+		g.stats.Rejected++
+	} else {
+		g.stats.Accepted++
+	}
+}
+
+// Step 生成一笔交易并提交给 sink，不检查 sink 是否还有空余位置。模拟运行时用于与出块交替进行。
+// 被拒绝的交易只计入 Stats.Rejected，不输出日志：Invalid 策略故意构造的交易全部会被拒绝。
+// 参数:
+// - sink: 接收交易的一方。
+// 返回值:
+// 策略暂时无法构造交易时返回 false。
+func (g *Generator) Step(sink Sink) bool {
+	transaction := g.Next()
+	if transaction == nil {
+		return false
+	}
+	g.record(sink.AcceptTransaction(*transaction))
+	return true
+}
+
+// Run 不断生成交易并提交给 sink，直到 ctx 被取消。
+// 参数:
+// - ctx: 停止生成交易的上下文。
+// - sink: 接收交易的一方。
+func (g *Generator) Run(ctx context.Context, sink Sink) {
+	for sink.WaitForSpace(ctx) {
+		if g.Step(sink) {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(retryInterval):
+		}
+	}
+}
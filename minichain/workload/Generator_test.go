@@ -0,0 +1,130 @@
+package workload_test
+
+import (
+	"Go-Minichain/config"
+	"Go-Minichain/data"
+	"Go-Minichain/network"
+	"Go-Minichain/workload"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestMain(m *testing.M) {
+	c, err := config.Load("workload.test", []string{"-difficulty=1", "-nbAccount=10", "-spvEnabled=false",
+		"-minerThreads=1", "-dataDir="}, func(string) (string, bool) { return "", false })
+	if err != nil {
+		panic("test config: " + err.Error())
+	}
+	config.MiniChainConfig = c
+	os.Exit(m.Run())
+}
+
+// recordingSink 把交易提交给模拟网络的交易池，并记录每笔交易及其提交结果。
+type recordingSink struct {
+	*network.NetWork
+	transactions []data.Transaction
+	errs         []error
+}
+
+func (s *recordingSink) AcceptTransaction(transaction data.Transaction) error {
+	err := s.NetWork.AcceptTransaction(transaction)
+	s.transactions = append(s.transactions, transaction)
+	s.errs = append(s.errs, err)
+	return err
+}
+
+// newSink 创建一个由固定种子生成账户与创世块的模拟网络。
+func newSink(t *testing.T) *recordingSink {
+	t.Helper()
+	n := network.NewSimulatedNetWork(1, network.NewSimulatedClock(network.SimulationEpoch))
+	if err := n.Simulate(0); err != nil {
+		t.Fatal(err)
+	}
+	return &recordingSink{NetWork: n}
+}
+
+// run 使用指定的策略与种子生成 steps 笔交易，返回记录了交易的 sink。
+func run(t *testing.T, sink *recordingSink, name string, seed int64, steps int) *workload.Generator {
+	t.Helper()
+	strategy, err := workload.ByName(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := workload.NewGenerator(sink, strategy, seed)
+	for i := 0; i < steps; i++ {
+		g.Step(sink)
+	}
+	return g
+}
+
+// txIDs 返回交易标识的列表。
+func txIDs(transactions []data.Transaction) []string {
+	ids := make([]string, len(transactions))
+	for i := range transactions {
+		ids[i] = transactions[i].TxID()
+	}
+	return ids
+}
+
+func TestSameSeedGeneratesSameSequence(t *testing.T) {
+	first, second, other := newSink(t), newSink(t), newSink(t)
+	run(t, first, "mixed", 42, 20)
+	run(t, second, "mixed", 42, 20)
+	run(t, other, "mixed", 43, 20)
+	if len(first.transactions) == 0 {
+		t.Fatal("no transactions were generated")
+	}
+	a, b := strings.Join(txIDs(first.transactions), ","), strings.Join(txIDs(second.transactions), ",")
+	if a != b {
+		t.Fatalf("the same seed generated different sequences:\n%s\n%s", a, b)
+	}
+	if c := strings.Join(txIDs(other.transactions), ","); c == a {
+		t.Fatal("a different seed generated the same sequence")
+	}
+}
+
+func TestByName(t *testing.T) {
+	for _, name := range []string{"uniform", "hot", "consolidation", "fanout", "invalid", "mixed", "Uniform", "FANOUT"} {
+		strategy, err := workload.ByName(name)
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if !strings.HasPrefix(strategy.Name(), strings.ToLower(name)) {
+			t.Errorf("%s: strategy is named %s", name, strategy.Name())
+		}
+	}
+	for _, name := range []string{"", "random", "mixed(uniform)"} {
+		if _, err := workload.ByName(name); !errors.Is(err, workload.ErrUnknownStrategy) {
+			t.Errorf("%q returned %v, want %v", name, err, workload.ErrUnknownStrategy)
+		}
+	}
+}
+
+func TestInvalidTransactionsAreRejected(t *testing.T) {
+	sink := newSink(t)
+	// 先提交一些正常的转账，使双花交易可以花费已被交易池中交易花费的已确认输出
+	run(t, sink, "uniform", 1, 10)
+	pooled := sink.GetTransactionPool().Count()
+	if pooled == 0 {
+		t.Fatal("no valid transactions were accepted")
+	}
+
+	invalid := &recordingSink{NetWork: sink.NetWork}
+	g := run(t, invalid, "invalid", 2, 30)
+	stats := g.GetStats()
+	if stats.Generated == 0 || stats.Accepted != 0 || stats.Rejected != stats.Generated {
+		t.Fatalf("stats %+v, want every generated transaction rejected", stats)
+	}
+	for i, err := range invalid.errs {
+		var rejectErr *network.TxRejectError
+		if !errors.As(err, &rejectErr) {
+			t.Fatalf("transaction %s returned %v, want a *TxRejectError", invalid.transactions[i].TxID(), err)
+		}
+	}
+	if count := sink.GetTransactionPool().Count(); count != pooled {
+		t.Fatalf("pool holds %d transactions after the invalid workload, want %d", count, pooled)
+	}
+}
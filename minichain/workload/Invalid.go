@@ -0,0 +1,134 @@
+package workload
+
+import (
+	"Go-Minichain/data"
+	"Go-Minichain/utils"
+	"math/rand"
+)
+
+/**
+ * 无效交易
+ *
+ * 用于检查交易池与区块验证能否拒绝错误的交易，生成的交易都应该被交易池拒绝：
+ * - 双花：花费已被交易池中其他交易花费的已确认输出，或在同一交易中重复花费同一个输出；
+ * - 错误签名：使用接收方的私钥为发送方的输入签名；
+ * - 凭空造币：输出金额之和大于输入金额之和；
+ * - 不存在的输入：引用一个随机生成的交易输出。
+ */
+
+// 无效交易的种类。
+const (
+	invalidDoubleSpend = iota
+	invalidSignature
+	invalidValueCreated
+	invalidMissingInput
+	invalidKinds
+)
+
+// Invalid 随机构造一种故意无效的交易。
+type Invalid struct{}
+
+func (Invalid) Name() string {
+	return "invalid"
+}
+
+func (Invalid) Generate(r *rand.Rand, ledger Ledger) *data.Transaction {
+	accounts := ledger.GetAccounts()
+	if len(accounts) < 2 {
+		return nil
+	}
+	for i := 0; i < maxAttempts; i++ {
+		from := accounts[r.Intn(len(accounts))]
+		to := accounts[r.Intn(len(accounts))]
+		if from.GetWalletAddress() == to.GetWalletAddress() {
+			continue
+		}
+		var tx *data.Transaction
+		switch r.Intn(invalidKinds) {
+		case invalidDoubleSpend:
+			tx = doubleSpend(r, ledger, from, to)
+		case invalidSignature:
+			tx = wrongSignature(r, ledger, from, to)
+		case invalidValueCreated:
+			tx = valueCreated(r, ledger, from, to)
+		case invalidMissingInput:
+			tx = missingInput(r, ledger, from, to)
+		}
+		if tx != nil {
+			return tx
+		}
+	}
+	return nil
+}
+
+// doubleSpend 构造双花交易：优先花费已确认、但已被交易池中其他交易花费的输出，否则在同一交易中两次花费同一个输出。
+func doubleSpend(r *rand.Rand, ledger Ledger, from data.Account, to data.Account) *data.Transaction {
+	spendable := make(map[data.Outpoint]bool)
+	for _, utxo := range ledger.GetSpendableUTXOs(from.GetWalletAddress()) {
+		spendable[utxo.GetOutpoint()] = true
+	}
+	var target *data.UTXO
+	for _, utxo := range ledger.GetTrueUTXOs(from.GetWalletAddress()) {
+		if !spendable[utxo.GetOutpoint()] {
+			target = utxo
+			break
+		}
+	}
+	inputs := make([]*data.TxInput, 0, 2)
+	var amount int
+	if target != nil {
+		inputs = append(inputs, data.NewTxInput(target.GetOutpoint(), from.GetPublicKey()))
+		amount = target.GetAmount()
+	} else {
+		utxos := ledger.GetSpendableUTXOs(from.GetWalletAddress())
+		if len(utxos) == 0 {
+			return nil
+		}
+		utxo := utxos[r.Intn(len(utxos))]
+		inputs = append(inputs,
+			data.NewTxInput(utxo.GetOutpoint(), from.GetPublicKey()),
+			data.NewTxInput(utxo.GetOutpoint(), from.GetPublicKey()))
+		amount = utxo.GetAmount()
+	}
+	if amount <= maxRandomFee {
+		return nil
+	}
+	tx := newTransaction(ledger, inputs, []*data.UTXO{data.NewUTXO(amount-maxRandomFee, to.GetPublicKey())})
+	tx.Sign(from.GetPrivateKey())
+	return tx
+}
+
+// wrongSignature 构造一笔正常的转账，但使用接收方的私钥为发送方的输入签名。
+func wrongSignature(r *rand.Rand, ledger Ledger, from data.Account, to data.Account) *data.Transaction {
+	tx := randomTransfer(r, ledger, from, to)
+	if tx == nil {
+		return nil
+	}
+	tx.Sign(to.GetPrivateKey())
+	return tx
+}
+
+// valueCreated 构造输出金额之和比输入金额之和多出随机金额的交易。
+func valueCreated(r *rand.Rand, ledger Ledger, from data.Account, to data.Account) *data.Transaction {
+	utxos := ledger.GetSpendableUTXOs(from.GetWalletAddress())
+	if len(utxos) == 0 {
+		return nil
+	}
+	utxo := utxos[r.Intn(len(utxos))]
+	amount := utxo.GetAmount() + r.Intn(utxo.GetAmount()) + 1
+	inputs := []*data.TxInput{data.NewTxInput(utxo.GetOutpoint(), from.GetPublicKey())}
+	tx := newTransaction(ledger, inputs, []*data.UTXO{data.NewUTXO(amount, to.GetPublicKey())})
+	tx.Sign(from.GetPrivateKey())
+	return tx
+}
+
+// missingInput 构造引用随机交易输出的交易，该输出在链上与交易池中都不存在。
+func missingInput(r *rand.Rand, ledger Ledger, from data.Account, to data.Account) *data.Transaction {
+	seed := make([]byte, 32)
+	r.Read(seed)
+	outpoint := data.NewOutpoint(utils.GetBytesSha256Digest(seed), r.Intn(4))
+	inputs := []*data.TxInput{data.NewTxInput(outpoint, from.GetPublicKey())}
+	tx := newTransaction(ledger, inputs, []*data.UTXO{data.NewUTXO(r.Intn(1000)+1, to.GetPublicKey())})
+	tx.Sign(from.GetPrivateKey())
+	return tx
+}
@@ -0,0 +1,337 @@
+package workload
+
+import (
+	"Go-Minichain/data"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strings"
+)
+
+/**
+ * 交易构造策略
+ *
+ * 每种策略在一次 Generate 调用中最多尝试 maxAttempts 次随机选择，仍然无法构造交易时返回 nil，
+ * 由生成器稍后重试，而不是无限循环。
+ */
+
+const (
+	// maxAttempts 一次 Generate 调用中随机选择账户的最多尝试次数。
+	maxAttempts = 10
+	// maxRandomFee 在最低手续费之外随机多付的手续费上限。
+	maxRandomFee = 20
+)
+
+// ErrUnknownStrategy 策略名称无法识别。
+var ErrUnknownStrategy = errors.New("unknown workload strategy")
+
+// ByName 根据名称返回使用默认参数的策略。
+// 参数:
+// - name: uniform、hot、consolidation、fanout、invalid 或 mixed，不区分大小写。
+// 返回值:
+// 返回对应的策略，名称无法识别时返回 ErrUnknownStrategy。
+func ByName(name string) (Strategy, error) {
+	switch strings.ToLower(name) {
+	case "uniform":
+		return Uniform{}, nil
+	case "hot":
+		return HotAccounts{Skew: 1.5}, nil
+	case "consolidation":
+		return Consolidation{MinInputs: 3, MaxInputs: 10}, nil
+	case "fanout":
+		return FanOut{Outputs: 5}, nil
+	case "invalid":
+		return Invalid{}, nil
+	case "mixed":
+		return NewMix(
+			Weighted{Strategy: Uniform{}, Weight: 5},
+			Weighted{Strategy: HotAccounts{Skew: 1.5}, Weight: 2},
+			Weighted{Strategy: Consolidation{MinInputs: 3, MaxInputs: 10}, Weight: 1},
+			Weighted{Strategy: FanOut{Outputs: 5}, Weight: 1},
+			Weighted{Strategy: Invalid{}, Weight: 1},
+		), nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnknownStrategy, name)
+}
+
+// Uniform 均匀随机选择两个不同的账户，由发送方向接收方转账随机金额。
+type Uniform struct{}
+
+func (Uniform) Name() string {
+	return "uniform"
+}
+
+func (Uniform) Generate(r *rand.Rand, ledger Ledger) *data.Transaction {
+	accounts := ledger.GetAccounts()
+	if len(accounts) < 2 {
+		return nil
+	}
+	for i := 0; i < maxAttempts; i++ {
+		from := accounts[r.Intn(len(accounts))]
+		to := accounts[r.Intn(len(accounts))]
+		if from.GetWalletAddress() == to.GetWalletAddress() {
+			continue
+		}
+		if tx := randomTransfer(r, ledger, from, to); tx != nil {
+			return tx
+		}
+	}
+	return nil
+}
+
+// HotAccounts 按 Zipf 分布选择发送方与接收方，少数排在前面的账户参与了大部分交易，
+// 用于模拟热点账户造成的长交易链与输入竞争。
+// 字段说明：
+// - Skew: Zipf 分布的参数，必须大于 1，越大越集中。
+type HotAccounts struct {
+	Skew float64
+}
+
+func (HotAccounts) Name() string {
+	return "hot"
+}
+
+func (h HotAccounts) Generate(r *rand.Rand, ledger Ledger) *data.Transaction {
+	accounts := ledger.GetAccounts()
+	if len(accounts) < 2 || h.Skew <= 1 {
+		return nil
+	}
+	zipf := rand.NewZipf(r, h.Skew, 1, uint64(len(accounts)-1))
+	for i := 0; i < maxAttempts; i++ {
+		from := accounts[zipf.Uint64()]
+		to := accounts[zipf.Uint64()]
+		if from.GetWalletAddress() == to.GetWalletAddress() {
+			continue
+		}
+		if tx := randomTransfer(r, ledger, from, to); tx != nil {
+			return tx
+		}
+	}
+	return nil
+}
+
+// Consolidation 选择一个拥有多个 UTXO 的账户，把其中的若干个合并为一个转给自己的输出。
+// 字段说明：
+// - MinInputs: 账户至少拥有的 UTXO 个数。
+// - MaxInputs: 一笔交易最多合并的 UTXO 个数。
+type Consolidation struct {
+	MinInputs int
+	MaxInputs int
+}
+
+func (Consolidation) Name() string {
+	return "consolidation"
+}
+
+func (c Consolidation) Generate(r *rand.Rand, ledger Ledger) *data.Transaction {
+	accounts := ledger.GetAccounts()
+	if len(accounts) == 0 {
+		return nil
+	}
+	minInputs := c.MinInputs
+	if minInputs < 2 {
+		minInputs = 2
+	}
+	maxInputs := c.MaxInputs
+	if maxInputs < minInputs {
+		maxInputs = minInputs
+	}
+	// 从随机位置开始依次查找，保证只要有满足条件的账户就能找到
+	start := r.Intn(len(accounts))
+	for i := range accounts {
+		account := accounts[(start+i)%len(accounts)]
+		utxos := ledger.GetSpendableUTXOs(account.GetWalletAddress())
+		if len(utxos) < minInputs {
+			continue
+		}
+		limit := maxInputs
+		if len(utxos) < limit {
+			limit = len(utxos)
+		}
+		n := minInputs + r.Intn(limit-minInputs+1)
+		return consolidate(r, ledger, account, utxos[:n])
+	}
+	return nil
+}
+
+// FanOut 由一个账户向多个不同的账户分别转账随机金额。
+// 字段说明：
+// - Outputs: 接收方个数。
+type FanOut struct {
+	Outputs int
+}
+
+func (FanOut) Name() string {
+	return "fanout"
+}
+
+func (f FanOut) Generate(r *rand.Rand, ledger Ledger) *data.Transaction {
+	accounts := ledger.GetAccounts()
+	if f.Outputs <= 0 || len(accounts) <= f.Outputs {
+		return nil
+	}
+	for i := 0; i < maxAttempts; i++ {
+		from := accounts[r.Intn(len(accounts))]
+		utxos := ledger.GetSpendableUTXOs(from.GetWalletAddress())
+		balance := from.GetAmount(utxos)
+		// 每个接收方至少 1，并为手续费留出余量
+		if balance < f.Outputs+maxRandomFee+ledger.MinRelayFee(1000) {
+			continue
+		}
+		share := (balance - maxRandomFee - ledger.MinRelayFee(1000)) / f.Outputs
+		outputs := make([]*data.UTXO, 0, f.Outputs)
+		for _, j := range r.Perm(len(accounts)) {
+			to := accounts[j]
+			if to.GetWalletAddress() == from.GetWalletAddress() {
+				continue
+			}
+			outputs = append(outputs, data.NewUTXO(r.Intn(share)+1, to.GetPublicKey()))
+			if len(outputs) == f.Outputs {
+				break
+			}
+		}
+		if tx := pay(r, ledger, from, utxos, outputs); tx != nil {
+			return tx
+		}
+	}
+	return nil
+}
+
+// Weighted 带权重的策略。
+type Weighted struct {
+	Strategy Strategy
+	Weight   int
+}
+
+// Mix 按权重随机选择一种策略构造交易。
+type Mix struct {
+	strategies []Weighted
+	total      int
+}
+
+// NewMix 创建按权重组合的策略，权重不大于 0 的策略会被忽略。
+func NewMix(strategies ...Weighted) *Mix {
+	m := &Mix{}
+	for _, s := range strategies {
+		if s.Weight > 0 {
+			m.strategies = append(m.strategies, s)
+			m.total += s.Weight
+		}
+	}
+	return m
+}
+
+func (m *Mix) Name() string {
+	names := make([]string, len(m.strategies))
+	for i, s := range m.strategies {
+		names[i] = s.Strategy.Name()
+	}
+	return "mixed(" + strings.Join(names, ",") + ")"
+}
+
+func (m *Mix) Generate(r *rand.Rand, ledger Ledger) *data.Transaction {
+	if m.total == 0 {
+		return nil
+	}
+	n := r.Intn(m.total)
+	for _, s := range m.strategies {
+		if n < s.Weight {
+			return s.Strategy.Generate(r, ledger)
+		}
+		n -= s.Weight
+	}
+	return nil
+}
+
+// randomTransfer 由发送方向接收方转账随机金额，并支付不低于最低手续费的随机手续费。
+// 发送方可以花费的余额不足时返回 nil。
+func randomTransfer(r *rand.Rand, ledger Ledger, from data.Account, to data.Account) *data.Transaction {
+	utxos := ledger.GetSpendableUTXOs(from.GetWalletAddress())
+	balance := from.GetAmount(utxos)
+	reserve := maxRandomFee + ledger.MinRelayFee(1000)
+	if balance <= reserve {
+		return nil
+	}
+	amount := r.Intn(balance-reserve) + 1
+	outputs := []*data.UTXO{data.NewUTXO(amount, to.GetPublicKey())}
+	return pay(r, ledger, from, utxos, outputs)
+}
+
+// newTransaction 创建交易，并以账本时钟的当前时间作为交易时间戳。
+func newTransaction(ledger Ledger, inputs []*data.TxInput, outputs []*data.UTXO) *data.Transaction {
+	tx := data.NewTransaction(inputs, outputs)
+	tx.SetTimestamp(int(ledger.Now().Unix()))
+	return tx
+}
+
+// pay 从发送方的 UTXO 中依次选择输入支付给定的输出与手续费，多余的金额找零给发送方，并签名交易。
+// 手续费为交易大小对应的最低手续费再加上随机的额外手续费。
+// 参数:
+// - r: 随机数生成器。
+// - ledger: 账本状态，用于计算最低手续费。
+// - from: 发送方账户。
+// - utxos: 发送方可以花费的 UTXO。
+// - outputs: 付款输出，不包括找零。
+// 返回值:
+// 返回签名后的交易；余额不足时返回 nil。
+func pay(r *rand.Rand, ledger Ledger, from data.Account, utxos []*data.UTXO, outputs []*data.UTXO) *data.Transaction {
+	outAmount := 0
+	for _, out := range outputs {
+		outAmount += out.GetAmount()
+	}
+	extra := r.Intn(maxRandomFee + 1)
+	fee := extra
+	// 交易大小取决于输入个数，最低手续费确定后可能需要更多的输入，因此最多重新构造几次
+	for attempt := 0; attempt < 3; attempt++ {
+		inputs := make([]*data.TxInput, 0)
+		inAmount := 0
+		for _, utxo := range utxos {
+			if inAmount >= outAmount+fee {
+				break
+			}
+			inAmount += utxo.GetAmount()
+			inputs = append(inputs, data.NewTxInput(utxo.GetOutpoint(), from.GetPublicKey()))
+		}
+		if inAmount < outAmount+fee {
+			return nil
+		}
+		txOutputs := append([]*data.UTXO(nil), outputs...)
+		if change := inAmount - outAmount - fee; change > 0 {
+			txOutputs = append(txOutputs, data.NewUTXO(change, from.GetPublicKey()))
+		}
+		tx := newTransaction(ledger, inputs, txOutputs)
+		tx.Sign(from.GetPrivateKey())
+		minFee := ledger.MinRelayFee(tx.Size())
+		if fee >= minFee {
+			return tx
+		}
+		fee = minFee + extra
+	}
+	return nil
+}
+
+// consolidate 花费全部给定的 UTXO，合并为一个转给账户自己的输出，手续费从该输出中扣除。
+// 输出金额扣除手续费后不为正数时返回 nil。
+func consolidate(r *rand.Rand, ledger Ledger, account data.Account, utxos []*data.UTXO) *data.Transaction {
+	amount := account.GetAmount(utxos)
+	extra := r.Intn(maxRandomFee + 1)
+	fee := extra
+	for attempt := 0; attempt < 3; attempt++ {
+		if amount-fee <= 0 {
+			return nil
+		}
+		inputs := make([]*data.TxInput, len(utxos))
+		for i, utxo := range utxos {
+			inputs[i] = data.NewTxInput(utxo.GetOutpoint(), account.GetPublicKey())
+		}
+		out := data.NewUTXO(amount-fee, account.GetPublicKey())
+		tx := newTransaction(ledger, inputs, []*data.UTXO{out})
+		tx.Sign(account.GetPrivateKey())
+		minFee := ledger.MinRelayFee(tx.Size())
+		if fee >= minFee {
+			return tx
+		}
+		fee = minFee + extra
+	}
+	return nil
+}